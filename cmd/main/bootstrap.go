@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"log"
+	"os"
+
+	bootstrap "github.com/nivschuman/VotingBlockchain/internal/bootstrap"
+	config "github.com/nivschuman/VotingBlockchain/internal/config"
+	nodes "github.com/nivschuman/VotingBlockchain/internal/nodes"
+)
+
+const exportBlocksUsage = "usage: exportblocks <block file>"
+const importBlocksUsage = "usage: importblocks <block file>"
+
+// exportBlocks writes the active chain to a block file without starting the node
+func exportBlocks(conf *config.Config, args []string) error {
+	if len(args) != 1 {
+		return errors.New(exportBlocksUsage)
+	}
+
+	chainStorage, err := nodes.OpenChainStorage(conf)
+	if err != nil {
+		return err
+	}
+	defer chainStorage.Close()
+
+	file, err := os.Create(args[0])
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	buffered := bufio.NewWriter(file)
+	writer := bootstrap.NewBlockFileWriter(buffered, chainStorage.GetChainParams().MagicBytes)
+
+	exported, err := bootstrap.ExportChain(chainStorage.GetBlockRepository(), writer)
+	if err != nil {
+		return err
+	}
+
+	if err := buffered.Flush(); err != nil {
+		return err
+	}
+
+	log.Printf("|Main| Exported %d blocks to %s", exported, args[0])
+	return nil
+}
+
+// importBlocks validates and stores the blocks of a block file without starting the node
+func importBlocks(conf *config.Config, args []string) error {
+	if len(args) != 1 {
+		return errors.New(importBlocksUsage)
+	}
+
+	file, err := os.Open(args[0])
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	fileInfo, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
+	chainStorage, err := nodes.OpenChainStorage(conf)
+	if err != nil {
+		return err
+	}
+	defer chainStorage.Close()
+
+	chainParams := chainStorage.GetChainParams()
+	reader := bootstrap.NewBlockFileReader(bufio.NewReader(file), chainParams.MagicBytes, chainParams.Election.MaxBlockSize)
+
+	result, err := bootstrap.ImportChain(reader, chainStorage, fileInfo.Size())
+	if err != nil {
+		return err
+	}
+
+	log.Printf("|Main| Imported %d blocks from %s, %d were already stored, tip is %x", result.Imported, args[0], result.Skipped, chainStorage.GetBlockRepository().GetActiveChainTipId())
+	return nil
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+
+	config "github.com/nivschuman/VotingBlockchain/internal/config"
+	database "github.com/nivschuman/VotingBlockchain/internal/database/connection"
+	migrations "github.com/nivschuman/VotingBlockchain/internal/database/migrations"
+	nodes "github.com/nivschuman/VotingBlockchain/internal/nodes"
+)
+
+const migrateUsage = "usage: migrate"
+const backupUsage = "usage: backup <backup file>"
+const reindexUsage = "usage: reindex"
+const verifyChainUsage = "usage: verifychain [depth]"
+
+// migrate brings the node database to the latest schema without starting the node, it's backed up next to the database first
+func migrate(conf *config.Config, args []string) error {
+	if len(args) != 0 {
+		return errors.New(migrateUsage)
+	}
+
+	db, err := database.OpenDatabaseConnection(conf.DatabaseConfig.File)
+	if err != nil {
+		return err
+	}
+	defer database.CloseDatabaseConnection(db)
+
+	version, err := migrations.CurrentVersion(db)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("|Main| Schema version of %s is %d, latest is %d", conf.DatabaseConfig.File, version, migrations.LatestVersion())
+	return database.MigrateDatabase(db, conf.DatabaseConfig.File)
+}
+
+// backup writes a consistent copy of the node database, it works while the node is running
+func backup(conf *config.Config, args []string) error {
+	if len(args) != 1 {
+		return errors.New(backupUsage)
+	}
+
+	db, err := database.OpenDatabaseConnection(conf.DatabaseConfig.File)
+	if err != nil {
+		return err
+	}
+	defer database.CloseDatabaseConnection(db)
+
+	if err := migrations.Backup(db, args[0]); err != nil {
+		return err
+	}
+
+	log.Printf("|Main| Backed up %s to %s", conf.DatabaseConfig.File, args[0])
+	return nil
+}
+
+// reindex rebuilds the chain state from the stored block headers without starting the node
+func reindex(conf *config.Config, args []string) error {
+	if len(args) != 0 {
+		return errors.New(reindexUsage)
+	}
+
+	conf.DatabaseConfig.Reindex = true
+
+	chainStorage, err := nodes.OpenChainStorage(conf)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("|Main| Reindexed chain, tip is %x", chainStorage.GetBlockRepository().GetActiveChainTipId())
+	return chainStorage.Close()
+}
+
+// verifyChain checks the last depth blocks of the active chain, the whole chain when no depth is given
+func verifyChain(conf *config.Config, args []string) error {
+	if len(args) > 1 {
+		return errors.New(verifyChainUsage)
+	}
+
+	depth := uint64(0)
+	if len(args) == 1 {
+		var err error
+		depth, err = strconv.ParseUint(args[0], 10, 64)
+		if err != nil || depth < 1 {
+			return fmt.Errorf("invalid depth %q, %s", args[0], verifyChainUsage)
+		}
+	}
+
+	chainStorage, err := nodes.OpenChainStorage(conf)
+	if err != nil {
+		return err
+	}
+	defer chainStorage.Close()
+
+	checked, err := chainStorage.VerifyChain(depth)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("|Main| Verified %d blocks of the active chain", checked)
+	return nil
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+
+	config "github.com/nivschuman/VotingBlockchain/internal/config"
+	nodes "github.com/nivschuman/VotingBlockchain/internal/nodes"
+)
+
+const generateUsage = "usage: generate <count> [miner keystore file]"
+
+// generate mines count blocks right away, signed with the key in the given keystore file or in the configured one when none is given
+func generate(conf *config.Config, args []string) error {
+	if len(args) < 1 || len(args) > 2 {
+		return errors.New(generateUsage)
+	}
+
+	count, err := strconv.Atoi(args[0])
+	if err != nil || count < 1 {
+		return fmt.Errorf("invalid block count %q, %s", args[0], generateUsage)
+	}
+
+	if len(args) == 2 {
+		conf.MinerConfig.KeystoreFile = args[1]
+		conf.MinerConfig.PublicKey = nil
+	}
+
+	nodeBuilder, err := nodes.NewNodeBuilderImpl(conf)
+	if err != nil {
+		return err
+	}
+
+	node, err := nodeBuilder.BuildNode()
+	if err != nil {
+		return err
+	}
+
+	node.Start()
+	defer node.Stop()
+
+	blocks, err := node.GetMiner().Generate(count)
+	for _, block := range blocks {
+		log.Printf("|Main| Generated block %x", block.Header.Id)
+	}
+
+	return err
+}
@@ -10,7 +10,6 @@ import (
 	config "github.com/nivschuman/VotingBlockchain/internal/config"
 	nodes "github.com/nivschuman/VotingBlockchain/internal/nodes"
 	app "github.com/nivschuman/VotingBlockchain/internal/ui/app"
-	test "github.com/nivschuman/VotingBlockchain/tests/init"
 )
 
 func main() {
@@ -25,11 +24,54 @@ func main() {
 		log.Fatalf("Failed to load config file: %v", err)
 	}
 
-	//Test Environment
-	environment := os.Getenv("ENVIRONMENT")
-	if environment == "test" {
-		log.Println("|Main| Running in test environment")
-		test.SetupTestEnvironmentConstants()
+	//Subcommands
+	if len(os.Args) > 1 && os.Args[1] == "generate" {
+		if err := generate(conf, os.Args[2:]); err != nil {
+			log.Fatalf("Failed to generate blocks: %v", err)
+		}
+		return
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := migrate(conf, os.Args[2:]); err != nil {
+			log.Fatalf("Failed to migrate database: %v", err)
+		}
+		return
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "backup" {
+		if err := backup(conf, os.Args[2:]); err != nil {
+			log.Fatalf("Failed to back up database: %v", err)
+		}
+		return
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "reindex" {
+		if err := reindex(conf, os.Args[2:]); err != nil {
+			log.Fatalf("Failed to reindex chain: %v", err)
+		}
+		return
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "verifychain" {
+		if err := verifyChain(conf, os.Args[2:]); err != nil {
+			log.Fatalf("Failed to verify chain: %v", err)
+		}
+		return
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "exportblocks" {
+		if err := exportBlocks(conf, os.Args[2:]); err != nil {
+			log.Fatalf("Failed to export blocks: %v", err)
+		}
+		return
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "importblocks" {
+		if err := importBlocks(conf, os.Args[2:]); err != nil {
+			log.Fatalf("Failed to import blocks: %v", err)
+		}
+		return
 	}
 
 	//Build node
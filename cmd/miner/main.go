@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"runtime"
+	"strconv"
+	"syscall"
+	"time"
+
+	keystore "github.com/nivschuman/VotingBlockchain/internal/crypto/keystore"
+	ppk "github.com/nivschuman/VotingBlockchain/internal/crypto/ppk"
+	work "github.com/nivschuman/VotingBlockchain/internal/mining/work"
+)
+
+const usage = "usage: miner <work server address> <miner keystore file> [workers]"
+
+// templates are refreshed this often so new votes and chain tips are picked up
+const TEMPLATE_REFRESH_INTERVAL = 10 * time.Second
+const RETRY_INTERVAL = 5 * time.Second
+
+func main() {
+	if len(os.Args) < 3 || len(os.Args) > 4 {
+		log.Fatal(usage)
+	}
+
+	minerKeyPair, created, err := keystore.LoadOrCreateKeyPair(os.Args[2])
+	if err != nil {
+		log.Fatalf("Failed to load miner key: %v", err)
+	}
+
+	if created {
+		log.Printf("|Remote Miner| Created miner key in %s", os.Args[2])
+	}
+
+	workers := runtime.NumCPU()
+	if len(os.Args) == 4 {
+		workers, err = strconv.Atoi(os.Args[3])
+		if err != nil || workers < 1 {
+			log.Fatalf("Invalid number of workers %q, %s", os.Args[3], usage)
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	client := work.NewWorkClient(os.Args[1])
+	log.Printf("|Remote Miner| Mining for %s with %d workers to %x", os.Args[1], workers, minerKeyPair.PublicKey.AsBytes())
+
+	for ctx.Err() == nil {
+		if err := mineTemplate(ctx, client, minerKeyPair, workers); err != nil {
+			log.Printf("|Remote Miner| %v", err)
+
+			select {
+			case <-ctx.Done():
+			case <-time.After(RETRY_INTERVAL):
+			}
+		}
+	}
+
+	log.Printf("|Remote Miner| Stopped")
+}
+
+// mineTemplate works on one block template until it's solved or it's time to refresh it
+func mineTemplate(ctx context.Context, client *work.WorkClient, minerKeyPair *ppk.KeyPair, workers int) error {
+	minerPublicKey := minerKeyPair.PublicKey.AsBytes()
+	template, err := client.GetBlockTemplate(minerPublicKey)
+	if err != nil {
+		return fmt.Errorf("failed to get block template: %w", err)
+	}
+
+	block, err := template.GetBlock()
+	if err != nil {
+		return fmt.Errorf("invalid block template: %w", err)
+	}
+
+	log.Printf("|Remote Miner| Mining block at height %d on %s", template.Height, template.PreviousBlockId)
+
+	//the node's clock is used, the local clock may be off
+	clockOffset := template.CurrentTime - time.Now().Unix()
+	getTime := func() int64 {
+		return max(template.MinTimestamp, time.Now().Unix()+clockOffset)
+	}
+
+	refreshCtx, cancel := context.WithTimeout(ctx, TEMPLATE_REFRESH_INTERVAL)
+	defer cancel()
+
+	startTime := time.Now()
+	solved, hashesTried := solveHeader(refreshCtx, &block.Header, workers, getTime)
+	hashRate := float64(hashesTried) / max(time.Since(startTime).Seconds(), 1e-6)
+
+	if !solved {
+		log.Printf("|Remote Miner| Refreshing template, %.2f Hashes/sec", hashRate)
+		return nil
+	}
+
+	if err := block.Header.Sign(minerKeyPair.PrivateKey); err != nil {
+		return fmt.Errorf("failed to sign block %x: %w", block.Header.Id, err)
+	}
+
+	response, err := client.SubmitBlock(block)
+	if err != nil {
+		return fmt.Errorf("failed to submit block %x: %w", block.Header.Id, err)
+	}
+
+	if !response.Accepted {
+		return fmt.Errorf("block %s was rejected: %s", response.BlockId, response.Reason)
+	}
+
+	log.Printf("|Remote Miner| Block %s was accepted, %.2f Hashes/sec", response.BlockId, hashRate)
+
+	credits, err := client.GetMinerCredits(minerPublicKey)
+	if err != nil {
+		return fmt.Errorf("failed to get miner credits: %w", err)
+	}
+
+	log.Printf("|Remote Miner| %d credits for %d blocks with %d votes", credits.Credits, credits.Blocks, credits.Votes)
+	return nil
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+
+	hash "github.com/nivschuman/VotingBlockchain/internal/crypto/hash"
+	difficulty "github.com/nivschuman/VotingBlockchain/internal/difficulty"
+	data_models "github.com/nivschuman/VotingBlockchain/internal/models"
+)
+
+type solution struct {
+	nonce     uint64
+	timestamp int64
+}
+
+// solveHeader splits the nonces between the workers like the node's miner, worker i tries i, i+workers, i+2*workers...
+// On success the header's nonce, timestamp and id are set.
+func solveHeader(ctx context.Context, header *data_models.BlockHeader, workers int, getTime func() int64) (bool, int64) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	target := header.GetTarget()
+	targetBytes := target.FillBytes(make([]byte, 32))
+
+	var found atomic.Pointer[solution]
+	var hashesTried atomic.Int64
+	var wg sync.WaitGroup
+
+	wg.Add(workers)
+	for worker := range workers {
+		go func() {
+			defer wg.Done()
+
+			step := uint64(workers)
+			nonce := uint64(worker)
+			timestamp := getTime()
+
+			blockHeaderBytes := header.AsBytes()
+			data_models.UpdateBlockHeaderBytes(blockHeaderBytes, timestamp, nonce)
+			blockHeaderHash := hash.HashBytesInto(blockHeaderBytes, make([]byte, 32))
+
+			tries := uint64(1)
+			defer func() { hashesTried.Add(int64(tries)) }()
+
+			for ; !difficulty.IsHashBelowTargetBytes(blockHeaderHash, targetBytes); tries++ {
+				select {
+				case <-ctx.Done():
+					return
+				default:
+					nonce += step
+
+					if tries&0x3ffff == 0 {
+						timestamp = getTime()
+					}
+
+					data_models.UpdateBlockHeaderBytes(blockHeaderBytes, timestamp, nonce)
+					hash.HashBytesInto(blockHeaderBytes, blockHeaderHash)
+				}
+			}
+
+			found.CompareAndSwap(nil, &solution{nonce: nonce, timestamp: timestamp})
+			cancel()
+		}()
+	}
+	wg.Wait()
+
+	s := found.Load()
+	if s == nil {
+		return false, hashesTried.Load()
+	}
+
+	header.Nonce = s.nonce
+	header.Timestamp = s.timestamp
+	header.SetId()
+	return true, hashesTried.Load()
+}
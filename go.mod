@@ -4,6 +4,7 @@ go 1.23.4
 
 require (
 	fyne.io/fyne/v2 v2.6.2
+	go.etcd.io/bbolt v1.3.11
 	gopkg.in/yaml.v2 v2.4.0
 	gorm.io/driver/sqlite v1.5.7
 	gorm.io/gorm v1.25.12
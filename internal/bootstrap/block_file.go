@@ -0,0 +1,86 @@
+package bootstrap
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	models "github.com/nivschuman/VotingBlockchain/internal/models"
+)
+
+var ErrMagicBytesMismatch = errors.New("magic bytes mismatch, the block file belongs to another chain")
+
+// BlockFileWriter writes blocks as records of the chain's magic bytes, the big endian length of the block and the block
+type BlockFileWriter struct {
+	writer     io.Writer
+	magicBytes []byte
+}
+
+func NewBlockFileWriter(writer io.Writer, magicBytes []byte) *BlockFileWriter {
+	return &BlockFileWriter{writer: writer, magicBytes: magicBytes}
+}
+
+func (blockFileWriter *BlockFileWriter) WriteBlock(block *models.Block) error {
+	blockBytes := block.AsBytes()
+
+	record := make([]byte, 0, len(blockFileWriter.magicBytes)+4+len(blockBytes))
+	record = append(record, blockFileWriter.magicBytes...)
+	record = binary.BigEndian.AppendUint32(record, uint32(len(blockBytes)))
+	record = append(record, blockBytes...)
+
+	_, err := blockFileWriter.writer.Write(record)
+	return err
+}
+
+// BlockFileReader reads the records of a BlockFileWriter
+type BlockFileReader struct {
+	reader       io.Reader
+	magicBytes   []byte
+	maxBlockSize int
+	bytesRead    int64
+}
+
+func NewBlockFileReader(reader io.Reader, magicBytes []byte, maxBlockSize int) *BlockFileReader {
+	return &BlockFileReader{reader: reader, magicBytes: magicBytes, maxBlockSize: maxBlockSize}
+}
+
+// ReadBlock returns io.EOF at the end of the file, a file ending inside a record is io.ErrUnexpectedEOF
+func (blockFileReader *BlockFileReader) ReadBlock() (*models.Block, error) {
+	header := make([]byte, len(blockFileReader.magicBytes)+4)
+	if err := blockFileReader.read(header); err != nil {
+		return nil, err
+	}
+
+	if !bytes.Equal(header[:len(blockFileReader.magicBytes)], blockFileReader.magicBytes) {
+		return nil, ErrMagicBytesMismatch
+	}
+
+	blockSize := binary.BigEndian.Uint32(header[len(blockFileReader.magicBytes):])
+	if int64(blockSize) > int64(blockFileReader.maxBlockSize) {
+		return nil, fmt.Errorf("block of %d bytes exceeds %d", blockSize, blockFileReader.maxBlockSize)
+	}
+
+	blockBytes := make([]byte, blockSize)
+	if err := blockFileReader.read(blockBytes); err != nil {
+		if err == io.EOF {
+			return nil, io.ErrUnexpectedEOF
+		}
+
+		return nil, err
+	}
+
+	return models.BlockFromBytes(blockBytes)
+}
+
+// BytesRead is the size of the records read so far
+func (blockFileReader *BlockFileReader) BytesRead() int64 {
+	return blockFileReader.bytesRead
+}
+
+func (blockFileReader *BlockFileReader) read(buffer []byte) error {
+	n, err := io.ReadFull(blockFileReader.reader, buffer)
+	blockFileReader.bytesRead += int64(n)
+	return err
+}
@@ -0,0 +1,107 @@
+package bootstrap
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"slices"
+
+	repositories "github.com/nivschuman/VotingBlockchain/internal/database/repositories"
+	models "github.com/nivschuman/VotingBlockchain/internal/models"
+)
+
+// blocks between progress logs of an export or import
+const PROGRESS_INTERVAL = 1000
+
+// BlockImporter accepts a block of a block file, imported is false for blocks that were already stored
+type BlockImporter interface {
+	ImportBlock(block *models.Block) (imported bool, err error)
+}
+
+// ImportResult counts the blocks of an import
+type ImportResult struct {
+	Read     uint64
+	Imported uint64
+	Skipped  uint64
+}
+
+// ExportChain writes the active chain from the genesis block to the tip, it returns the number of blocks written
+func ExportChain(blockRepository repositories.BlockRepository, writer *BlockFileWriter) (uint64, error) {
+	blockIds := make([][]byte, 0)
+	for currentId := blockRepository.GetActiveChainTipId(); currentId != nil; {
+		blockHeader, err := blockRepository.GetBlockHeader(currentId)
+		if err != nil {
+			return 0, err
+		}
+
+		blockIds = append(blockIds, currentId)
+		currentId = blockHeader.PreviousBlockId
+	}
+
+	slices.Reverse(blockIds)
+	total := uint64(len(blockIds))
+
+	for i, blockId := range blockIds {
+		block, err := blockRepository.GetBlock(blockId)
+		if err != nil {
+			return uint64(i), err
+		}
+
+		if err := writer.WriteBlock(block); err != nil {
+			return uint64(i), err
+		}
+
+		if exported := uint64(i + 1); exported%PROGRESS_INTERVAL == 0 || exported == total {
+			log.Printf("|Bootstrap| Exported %d/%d blocks", exported, total)
+		}
+	}
+
+	return total, nil
+}
+
+// ImportChain feeds the blocks of a block file to the importer in order, blocks must come after their parent.
+// totalBytes is the size of the file for progress logs, 0 when unknown.
+func ImportChain(reader *BlockFileReader, importer BlockImporter, totalBytes int64) (*ImportResult, error) {
+	result := &ImportResult{}
+
+	for {
+		block, err := reader.ReadBlock()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			return result, fmt.Errorf("block file record %d: %w", result.Read+1, err)
+		}
+
+		result.Read++
+
+		imported, err := importer.ImportBlock(block)
+		if err != nil {
+			return result, fmt.Errorf("block %x at record %d: %w", block.Header.Id, result.Read, err)
+		}
+
+		if imported {
+			result.Imported++
+		} else {
+			result.Skipped++
+		}
+
+		if result.Read%PROGRESS_INTERVAL == 0 {
+			logImportProgress(result, reader.BytesRead(), totalBytes)
+		}
+	}
+
+	logImportProgress(result, reader.BytesRead(), totalBytes)
+	return result, nil
+}
+
+func logImportProgress(result *ImportResult, bytesRead int64, totalBytes int64) {
+	if totalBytes > 0 {
+		log.Printf("|Bootstrap| Read %d blocks (%.1f%%), imported %d, skipped %d", result.Read, float64(bytesRead)*100/float64(totalBytes), result.Imported, result.Skipped)
+		return
+	}
+
+	log.Printf("|Bootstrap| Read %d blocks, imported %d, skipped %d", result.Read, result.Imported, result.Skipped)
+}
@@ -0,0 +1,361 @@
+package chainparams
+
+import (
+	"bytes"
+	"cmp"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	ppk "github.com/nivschuman/VotingBlockchain/internal/crypto/ppk"
+	models "github.com/nivschuman/VotingBlockchain/internal/models"
+)
+
+const (
+	MainNet = "mainnet"
+	TestNet = "testnet"
+	RegTest = "regtest"
+)
+
+const (
+	ProofOfWork      = "pow" //anyone can mine, the chain with the most work wins
+	ProofOfAuthority = "poa" //only authorized sealers sign blocks, taking turns
+)
+
+type DifficultyRules struct {
+	MinimumDifficulty uint32 //minimum nBits difficulty allowed for block
+	TargetTimespan    int64  //total time expected for interval in seconds
+	TargetSpacing     int64  //time expected per block in seconds
+	NoRetargeting     bool   //every block stays at the minimum difficulty
+}
+
+type ElectionRules struct {
+	MaxBlockSize          int    //maximum size of a serialized block in bytes
+	MaxBlockVotes         int    //maximum number of votes in a block
+	TallySnapshotInterval uint64 //blocks between snapshots of the tally, nodes of the chain snapshot the same heights
+}
+
+// RewardRules credit miners for the blocks of the active chain, the genesis block earns nothing
+type RewardRules struct {
+	BlockReward uint64 //credits per block
+	VoteFee     uint64 //credits per vote in the block
+}
+
+type Sealer struct {
+	PublicKey []byte //compressed public key the sealer signs blocks with
+	Weight    int    //turns the sealer gets in every round
+}
+
+// AuthorityRules are used by proof of authority chains, sealers take turns by their order and weight
+type AuthorityRules struct {
+	Sealers        []Sealer
+	Period         int64 //minimum seconds between blocks
+	OutOfTurnDelay int64 //extra seconds a sealer waits before sealing when it's not its turn
+}
+
+type ConsensusRules struct {
+	Engine        string //ProofOfWork or ProofOfAuthority
+	Authority     AuthorityRules
+	Checkpoints   []models.Checkpoint //blocks the active chain must keep, ordered by height
+	MaxReorgDepth uint64              //most blocks a reorganization may disconnect, 0 is unlimited
+	SignedHeaders uint64              //height from which block headers must be signed by the miner they credit, 0 never requires it
+}
+
+type ChainParams struct {
+	Name             string
+	MagicBytes       []byte //start of every message, differs between networks
+	DefaultPort      uint16
+	GenesisBlock     *models.Block
+	Difficulty       DifficultyRules
+	Election         ElectionRules
+	Rewards          RewardRules
+	Consensus        ConsensusRules
+	GenerateOnDemand bool //blocks can be generated on demand while the miner is disabled
+}
+
+func MainNetParams() *ChainParams {
+	difficultyRules := DifficultyRules{
+		MinimumDifficulty: uint32(0x1d00ffff),
+		TargetTimespan:    int64(14 * 24 * 60 * 60),
+		TargetSpacing:     int64(10 * 60),
+	}
+
+	return &ChainParams{
+		Name:         MainNet,
+		MagicBytes:   []byte{0xD9, 0xB4, 0xBE, 0xF9},
+		DefaultPort:  8333,
+		GenesisBlock: newGenesisBlock(difficultyRules.MinimumDifficulty, nil),
+		Difficulty:   difficultyRules,
+		Election:     defaultElectionRules(),
+		Rewards:      defaultRewardRules(),
+		Consensus:    defaultConsensusRules(),
+	}
+}
+
+func TestNetParams() *ChainParams {
+	difficultyRules := DifficultyRules{
+		MinimumDifficulty: uint32(0x1d80ffff),
+		TargetTimespan:    int64(6 * 5 * 60),
+		TargetSpacing:     int64(5 * 60),
+	}
+
+	return &ChainParams{
+		Name:         TestNet,
+		MagicBytes:   []byte{0x0B, 0x11, 0x09, 0x07},
+		DefaultPort:  18333,
+		GenesisBlock: newGenesisBlock(difficultyRules.MinimumDifficulty, nil),
+		Difficulty:   difficultyRules,
+		Election:     defaultElectionRules(),
+		Rewards:      defaultRewardRules(),
+		Consensus:    defaultConsensusRules(),
+	}
+}
+
+func RegTestParams() *ChainParams {
+	difficultyRules := DifficultyRules{
+		MinimumDifficulty: uint32(0x207fffff),
+		TargetTimespan:    int64(10 * 60),
+		TargetSpacing:     int64(1 * 60),
+		NoRetargeting:     true,
+	}
+
+	//regtest chains are created by this version, every block after the genesis block is signed
+	consensusRules := defaultConsensusRules()
+	consensusRules.SignedHeaders = 1
+
+	return &ChainParams{
+		Name:             RegTest,
+		MagicBytes:       []byte{0xFA, 0xBF, 0xB5, 0xDA},
+		DefaultPort:      18444,
+		GenesisBlock:     newGenesisBlock(difficultyRules.MinimumDifficulty, nil),
+		Difficulty:       difficultyRules,
+		Election:         defaultElectionRules(),
+		Rewards:          defaultRewardRules(),
+		Consensus:        consensusRules,
+		GenerateOnDemand: true,
+	}
+}
+
+// ParamsForName returns the parameters of a named network, an empty name is mainnet
+func ParamsForName(name string) (*ChainParams, error) {
+	switch name {
+	case MainNet, "":
+		return MainNetParams(), nil
+	case TestNet:
+		return TestNetParams(), nil
+	case RegTest:
+		return RegTestParams(), nil
+	default:
+		return nil, fmt.Errorf("unknown chain %q", name)
+	}
+}
+
+// Number of blocks between difficulty adjustments
+func (rules *DifficultyRules) Interval() int64 {
+	return rules.TargetTimespan / rules.TargetSpacing
+}
+
+// Minimum timespan allowed
+func (rules *DifficultyRules) MinTimespan() int64 {
+	return rules.TargetTimespan / 4
+}
+
+// Maximum timespan allowed
+func (rules *DifficultyRules) MaxTimespan() int64 {
+	return rules.TargetTimespan * 4
+}
+
+func defaultElectionRules() ElectionRules {
+	return ElectionRules{
+		MaxBlockSize:          1000000,
+		MaxBlockVotes:         5000,
+		TallySnapshotInterval: 100,
+	}
+}
+
+// IsTallySnapshotHeight tells if the tally is snapshot after connecting the block at height, the genesis block has no snapshot
+func (rules *ElectionRules) IsTallySnapshotHeight(height uint64) bool {
+	return rules.TallySnapshotInterval > 0 && height > 0 && height%rules.TallySnapshotInterval == 0
+}
+
+// Credits earned for a number of blocks holding a number of votes
+func (rules *RewardRules) Credits(blocks uint64, votes uint64) uint64 {
+	return blocks*rules.BlockReward + votes*rules.VoteFee
+}
+
+func defaultRewardRules() RewardRules {
+	return RewardRules{
+		BlockReward: 50,
+		VoteFee:     1,
+	}
+}
+
+func defaultConsensusRules() ConsensusRules {
+	return ConsensusRules{
+		Engine: ProofOfWork,
+	}
+}
+
+func (rules *ConsensusRules) Validate() error {
+	for i, checkpoint := range rules.Checkpoints {
+		if len(checkpoint.BlockId) != 32 {
+			return fmt.Errorf("checkpoint at height %d has a block id of %d bytes", checkpoint.Height, len(checkpoint.BlockId))
+		}
+
+		if i > 0 && checkpoint.Height <= rules.Checkpoints[i-1].Height {
+			return fmt.Errorf("checkpoint at height %d is out of order", checkpoint.Height)
+		}
+	}
+
+	switch rules.Engine {
+	case ProofOfWork:
+		return nil
+	case ProofOfAuthority:
+		return rules.Authority.Validate()
+	default:
+		return fmt.Errorf("unknown consensus engine %q", rules.Engine)
+	}
+}
+
+// RequiresSignedHeader tells if the block at a height must be signed by its miner, unsigned blocks below the height stay valid.
+// Mainnet and testnet chains hold unsigned blocks, they require signed headers only from a configured height
+func (rules *ConsensusRules) RequiresSignedHeader(height uint64) bool {
+	return rules.SignedHeaders > 0 && height >= rules.SignedHeaders
+}
+
+// AddCheckpoint keeps the checkpoints ordered by height, a checkpoint at a height that already has one must match it
+func (rules *ConsensusRules) AddCheckpoint(checkpoint models.Checkpoint) error {
+	index, found := slices.BinarySearchFunc(rules.Checkpoints, checkpoint.Height, func(c models.Checkpoint, height uint64) int {
+		return cmp.Compare(c.Height, height)
+	})
+
+	if found {
+		if !bytes.Equal(rules.Checkpoints[index].BlockId, checkpoint.BlockId) {
+			return fmt.Errorf("conflicting checkpoints at height %d", checkpoint.Height)
+		}
+		return nil
+	}
+
+	rules.Checkpoints = slices.Insert(rules.Checkpoints, index, checkpoint)
+	return nil
+}
+
+// CheckpointAt returns the checkpoint at a height, nil if there is none
+func (rules *ConsensusRules) CheckpointAt(height uint64) *models.Checkpoint {
+	index, found := slices.BinarySearchFunc(rules.Checkpoints, height, func(c models.Checkpoint, height uint64) int {
+		return cmp.Compare(c.Height, height)
+	})
+
+	if !found {
+		return nil
+	}
+
+	return &rules.Checkpoints[index]
+}
+
+// CheckpointBetween returns the first checkpoint above fromHeight and at most toHeight, nil if there is none
+func (rules *ConsensusRules) CheckpointBetween(fromHeight uint64, toHeight uint64) *models.Checkpoint {
+	for i := range rules.Checkpoints {
+		if rules.Checkpoints[i].Height > fromHeight && rules.Checkpoints[i].Height <= toHeight {
+			return &rules.Checkpoints[i]
+		}
+	}
+
+	return nil
+}
+
+func (rules *AuthorityRules) Validate() error {
+	if len(rules.Sealers) == 0 {
+		return errors.New("proof of authority needs at least one sealer")
+	}
+
+	if rules.Period < 0 || rules.OutOfTurnDelay < 0 {
+		return errors.New("proof of authority period and out of turn delay can't be negative")
+	}
+
+	sealerKeys := make(map[string]bool, len(rules.Sealers))
+	for _, sealer := range rules.Sealers {
+		if _, err := ppk.GetPublicKeyFromBytes(sealer.PublicKey); err != nil {
+			return fmt.Errorf("invalid sealer public key %x: %v", sealer.PublicKey, err)
+		}
+
+		if sealer.Weight < 1 {
+			return fmt.Errorf("sealer %x has weight %d, must be at least 1", sealer.PublicKey, sealer.Weight)
+		}
+
+		if sealerKeys[string(sealer.PublicKey)] {
+			return fmt.Errorf("sealer %x is listed twice", sealer.PublicKey)
+		}
+		sealerKeys[string(sealer.PublicKey)] = true
+	}
+
+	return nil
+}
+
+// IsSealer tells if the public key may seal blocks
+func (rules *AuthorityRules) IsSealer(publicKey []byte) bool {
+	for _, sealer := range rules.Sealers {
+		if bytes.Equal(sealer.PublicKey, publicKey) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// InTurnSealer is the sealer whose turn it is at a height, every sealer gets weight consecutive turns per round
+func (rules *AuthorityRules) InTurnSealer(height uint64) []byte {
+	totalWeight := 0
+	for _, sealer := range rules.Sealers {
+		totalWeight += sealer.Weight
+	}
+
+	if totalWeight == 0 {
+		return nil
+	}
+
+	turn := int(height % uint64(totalWeight))
+	for _, sealer := range rules.Sealers {
+		if turn < sealer.Weight {
+			return sealer.PublicKey
+		}
+		turn -= sealer.Weight
+	}
+
+	return nil
+}
+
+// SetGenesisBlock derives the genesis block from the government public key of the election, so nodes of other elections on the same network refuse each other.
+// Proof of authority chains start from a genesis without proof of work, like their sealed blocks, so the consensus engine must be set before.
+func (params *ChainParams) SetGenesisBlock(governmentPublicKey []byte) {
+	nBits := params.Difficulty.MinimumDifficulty
+	if params.Consensus.Engine == ProofOfAuthority {
+		nBits = 0
+	}
+
+	params.GenesisBlock = newGenesisBlock(nBits, governmentPublicKey)
+}
+
+// newGenesisBlock is mined by the government of the election, a zero key when it isn't known
+func newGenesisBlock(nBits uint32, governmentPublicKey []byte) *models.Block {
+	minerPublicKey := make([]byte, 33)
+	copy(minerPublicKey, governmentPublicKey)
+
+	genesisBlockHeader := &models.BlockHeader{
+		Version:         1,
+		PreviousBlockId: nil,
+		MerkleRoot:      make([]byte, 32),
+		Timestamp:       time.Date(2025, time.March, 30, 0, 0, 0, 0, time.UTC).Unix(),
+		NBits:           nBits,
+		Nonce:           50,
+		MinerPublicKey:  minerPublicKey,
+	}
+
+	genesisBlockHeader.SetId()
+
+	return &models.Block{
+		Header:       *genesisBlockHeader,
+		Transactions: make([]*models.Transaction, 0),
+	}
+}
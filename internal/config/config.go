@@ -14,6 +14,9 @@ type Config struct {
 	UiConfig         UiConfig         `yaml:"ui"`
 	DatabaseConfig   DatabaseConfig   `yaml:"database"`
 	VotersConfig     VotersConfig     `yaml:"voters"`
+	MempoolConfig    MempoolConfig    `yaml:"mempool"`
+	WorkServerConfig WorkServerConfig `yaml:"work-server"`
+	ConsensusConfig  ConsensusConfig  `yaml:"consensus"`
 }
 
 func LoadConfigFromFile(path string) (*Config, error) {
@@ -0,0 +1,79 @@
+package config
+
+import (
+	"encoding/hex"
+	"fmt"
+)
+
+type SealerConfig struct {
+	PublicKey []byte `yaml:"public-key"`
+	Weight    int    `yaml:"weight"` //turns per round, defaults to 1
+}
+
+type CheckpointConfig struct {
+	Height    uint64 `yaml:"height"`
+	BlockId   []byte `yaml:"block-id"`
+	Signature []byte `yaml:"signature"` //government signature of the checkpoint, empty for checkpoints trusted by the node operator
+}
+
+type ConsensusConfig struct {
+	Engine         string             `yaml:"engine"`            //"pow" or "poa", empty keeps the chain's engine
+	Sealers        []SealerConfig     `yaml:"sealers"`           //keys allowed to seal blocks under proof of authority, in turn order
+	Period         int64              `yaml:"period"`            //minimum seconds between sealed blocks
+	OutOfTurnDelay int64              `yaml:"out-of-turn-delay"` //extra seconds before a sealer may seal out of its turn
+	Checkpoints    []CheckpointConfig `yaml:"checkpoints"`       //blocks the active chain is never reorganized past
+	MaxReorgDepth  uint64             `yaml:"max-reorg-depth"`   //most blocks a reorganization may disconnect, 0 keeps the chain's limit
+	SignedHeaders  uint64             `yaml:"signed-headers"`    //height from which blocks must be signed by their miner, 0 keeps the chain's height
+}
+
+func (s *SealerConfig) UnmarshalYAML(unmarshal func(any) error) error {
+	var raw struct {
+		PublicKey string `yaml:"public-key"`
+		Weight    int    `yaml:"weight"`
+	}
+
+	if err := unmarshal(&raw); err != nil {
+		return err
+	}
+
+	publicKeyBytes, err := hex.DecodeString(raw.PublicKey)
+	if err != nil {
+		return fmt.Errorf("invalid sealer public key: %v", err)
+	}
+
+	s.PublicKey = publicKeyBytes
+
+	s.Weight = raw.Weight
+	if s.Weight == 0 {
+		s.Weight = 1
+	}
+
+	return nil
+}
+
+func (c *CheckpointConfig) UnmarshalYAML(unmarshal func(any) error) error {
+	var raw struct {
+		Height    uint64 `yaml:"height"`
+		BlockId   string `yaml:"block-id"`
+		Signature string `yaml:"signature"`
+	}
+
+	if err := unmarshal(&raw); err != nil {
+		return err
+	}
+
+	blockIdBytes, err := hex.DecodeString(raw.BlockId)
+	if err != nil {
+		return fmt.Errorf("invalid checkpoint block id: %v", err)
+	}
+
+	signatureBytes, err := hex.DecodeString(raw.Signature)
+	if err != nil {
+		return fmt.Errorf("invalid checkpoint signature: %v", err)
+	}
+
+	c.Height = raw.Height
+	c.BlockId = blockIdBytes
+	c.Signature = signatureBytes
+	return nil
+}
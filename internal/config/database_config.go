@@ -1,5 +1,74 @@
 package config
 
+import (
+	"errors"
+	"fmt"
+)
+
+const DEFAULT_VERIFY_BLOCKS = 6
+
+// fewest blocks a pruned node keeps, enough to follow reorganizations and serve recent blocks
+const MIN_PRUNE_BLOCKS = 288
+
+type StorageEngine string
+
+const (
+	SQLiteEngine StorageEngine = "sqlite" //chain data in the sqlite database through gorm
+	BoltEngine   StorageEngine = "bolt"   //chain data in an embedded key-value file with explicit indexes
+)
+
 type DatabaseConfig struct {
-	File string `yaml:"file"`
+	File      string        `yaml:"file"`
+	Engine    StorageEngine `yaml:"engine"`     //storage of blocks and transactions, peers and conflicting votes stay in File
+	ChainFile string        `yaml:"chain-file"` //key-value file of the bolt engine
+
+	Reindex      bool   `yaml:"reindex"`       //rebuild the chain state from the stored headers at startup
+	VerifyBlocks uint64 `yaml:"verify-blocks"` //blocks of the active chain verified at startup, 0 skips the verification
+	Prune        uint64 `yaml:"prune"`         //last blocks of the active chain whose transactions are kept, 0 keeps every block
+}
+
+func (d *DatabaseConfig) UnmarshalYAML(unmarshal func(any) error) error {
+	var raw struct {
+		File      string `yaml:"file"`
+		Engine    string `yaml:"engine"`
+		ChainFile string `yaml:"chain-file"`
+
+		Reindex      bool    `yaml:"reindex"`
+		VerifyBlocks *uint64 `yaml:"verify-blocks"`
+		Prune        uint64  `yaml:"prune"`
+	}
+
+	if err := unmarshal(&raw); err != nil {
+		return err
+	}
+
+	engine := StorageEngine(raw.Engine)
+	switch engine {
+	case "":
+		engine = SQLiteEngine
+	case SQLiteEngine:
+	case BoltEngine:
+		if raw.ChainFile == "" {
+			return errors.New("bolt storage engine needs a chain-file")
+		}
+	default:
+		return fmt.Errorf("unknown storage engine %q", raw.Engine)
+	}
+
+	if raw.Prune > 0 && raw.Prune < MIN_PRUNE_BLOCKS {
+		return fmt.Errorf("prune must keep at least %d blocks, got %d", MIN_PRUNE_BLOCKS, raw.Prune)
+	}
+
+	d.File = raw.File
+	d.Engine = engine
+	d.ChainFile = raw.ChainFile
+	d.Reindex = raw.Reindex
+	d.Prune = raw.Prune
+
+	d.VerifyBlocks = DEFAULT_VERIFY_BLOCKS
+	if raw.VerifyBlocks != nil {
+		d.VerifyBlocks = *raw.VerifyBlocks
+	}
+
+	return nil
 }
@@ -0,0 +1,50 @@
+package config
+
+import (
+	"fmt"
+)
+
+type ConflictPolicy string
+
+const (
+	FirstSeenPolicy ConflictPolicy = "first-seen" //the vote seen first is kept
+	LowestIdPolicy  ConflictPolicy = "lowest-id"  //the vote with the lowest id is kept, independent of arrival order
+)
+
+type MempoolConfig struct {
+	MaxTransactions      int            `yaml:"max-transactions"`
+	Expiry               uint32         `yaml:"expiry"` //seconds a transaction may wait in the mempool
+	File                 string         `yaml:"file"`
+	ConflictPolicy       ConflictPolicy `yaml:"conflict-policy"`
+	ConflictingVotesFile string         `yaml:"conflicting-votes-file"` //export destination of the conflicting votes log
+}
+
+func (m *MempoolConfig) UnmarshalYAML(unmarshal func(any) error) error {
+	var raw struct {
+		MaxTransactions      int    `yaml:"max-transactions"`
+		Expiry               uint32 `yaml:"expiry"`
+		File                 string `yaml:"file"`
+		ConflictPolicy       string `yaml:"conflict-policy"`
+		ConflictingVotesFile string `yaml:"conflicting-votes-file"`
+	}
+
+	if err := unmarshal(&raw); err != nil {
+		return err
+	}
+
+	policy := ConflictPolicy(raw.ConflictPolicy)
+	switch policy {
+	case "":
+		policy = FirstSeenPolicy
+	case FirstSeenPolicy, LowestIdPolicy:
+	default:
+		return fmt.Errorf("unknown mempool conflict policy %q", raw.ConflictPolicy)
+	}
+
+	m.MaxTransactions = raw.MaxTransactions
+	m.Expiry = raw.Expiry
+	m.File = raw.File
+	m.ConflictPolicy = policy
+	m.ConflictingVotesFile = raw.ConflictingVotesFile
+	return nil
+}
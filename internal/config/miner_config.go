@@ -2,17 +2,30 @@ package config
 
 import (
 	"encoding/hex"
+	"runtime"
 )
 
+const DEFAULT_MAX_BLOCK_SIZE = 1000000
+const DEFAULT_MAX_BLOCK_TRANSACTIONS = 5000
+const DEFAULT_KEYSTORE_FILE = "keystore/miner-key.json"
+
 type MinerConfig struct {
-	PublicKey []byte `yaml:"public-key"`
-	Enabled   bool   `yaml:"enabled"`
+	PublicKey            []byte `yaml:"public-key"`    //optional, must match the public key in the keystore file
+	KeystoreFile         string `yaml:"keystore-file"` //key pair the miner signs its blocks with, created if missing
+	Enabled              bool   `yaml:"enabled"`
+	MaxBlockSize         int    `yaml:"max-block-size"`         //bytes of the blocks the miner creates
+	MaxBlockTransactions int    `yaml:"max-block-transactions"` //transactions in the blocks the miner creates
+	Workers              int    `yaml:"workers"`                //goroutines mining each block, defaults to the number of CPUs
 }
 
 func (m *MinerConfig) UnmarshalYAML(unmarshal func(any) error) error {
 	var raw struct {
-		PublicKey string `yaml:"public-key"`
-		Enabled   bool   `yaml:"enabled"`
+		PublicKey            string `yaml:"public-key"`
+		KeystoreFile         string `yaml:"keystore-file"`
+		Enabled              bool   `yaml:"enabled"`
+		MaxBlockSize         int    `yaml:"max-block-size"`
+		MaxBlockTransactions int    `yaml:"max-block-transactions"`
+		Workers              int    `yaml:"workers"`
 	}
 
 	if err := unmarshal(&raw); err != nil {
@@ -25,6 +38,28 @@ func (m *MinerConfig) UnmarshalYAML(unmarshal func(any) error) error {
 	}
 
 	m.PublicKey = publicKeyBytes
+
+	m.KeystoreFile = raw.KeystoreFile
+	if m.KeystoreFile == "" {
+		m.KeystoreFile = DEFAULT_KEYSTORE_FILE
+	}
+
 	m.Enabled = raw.Enabled
+
+	m.MaxBlockSize = raw.MaxBlockSize
+	if m.MaxBlockSize <= 0 {
+		m.MaxBlockSize = DEFAULT_MAX_BLOCK_SIZE
+	}
+
+	m.MaxBlockTransactions = raw.MaxBlockTransactions
+	if m.MaxBlockTransactions <= 0 {
+		m.MaxBlockTransactions = DEFAULT_MAX_BLOCK_TRANSACTIONS
+	}
+
+	m.Workers = raw.Workers
+	if m.Workers <= 0 {
+		m.Workers = runtime.NumCPU()
+	}
+
 	return nil
 }
@@ -1,6 +1,8 @@
 package config
 
 type NodeConfig struct {
-	Version int32  `yaml:"version"`
-	Type    uint32 `yaml:"type"`
+	Version         int32  `yaml:"version"`
+	Type            uint32 `yaml:"type"`
+	Chain           string `yaml:"chain"`            //mainnet, testnet or regtest
+	ElectionGenesis bool   `yaml:"election-genesis"` //derive the genesis block from the election, existing chains keep the network's genesis
 }
@@ -0,0 +1,6 @@
+package config
+
+type WorkServerConfig struct {
+	Enabled bool   `yaml:"enabled"`
+	Address string `yaml:"address"` //host:port remote miners get block templates from and submit blocks to
+}
@@ -0,0 +1,54 @@
+package consensus
+
+import (
+	"errors"
+	"math/big"
+
+	chainparams "github.com/nivschuman/VotingBlockchain/internal/chainparams"
+	models "github.com/nivschuman/VotingBlockchain/internal/models"
+)
+
+var ErrInvalidHeader = errors.New("invalid header")
+var ErrNotSealer = errors.New("not an authorized sealer")
+var ErrRecentlySealed = errors.New("sealer sealed a recent block")
+
+// ChainReader is the part of the block repository the engines read the chain from
+type ChainReader interface {
+	GetNextWorkRequired(lastBlockId []byte) (uint32, error)
+	GetBlockHeader(blockId []byte) (*models.BlockHeader, error)
+	GetBlockHeight(blockId []byte) (uint64, error)
+}
+
+// ConsensusEngine decides who may create blocks and which chain is the active one.
+// Errors wrapping ErrInvalidHeader mean the header breaks the rules, other errors mean the chain couldn't be read.
+type ConsensusEngine interface {
+	Name() string
+
+	//CheckHeader runs the checks that don't depend on the chain the header extends
+	CheckHeader(header *models.BlockHeader) error
+
+	//VerifyHeader checks the header against the chain it extends, its previous block must be known
+	VerifyHeader(chain ChainReader, header *models.BlockHeader) error
+
+	//PrepareHeader sets the consensus fields of a block template extending the header's previous block
+	PrepareHeader(chain ChainReader, header *models.BlockHeader) error
+
+	//RequiresProofOfWork tells if block templates are mined by searching nonces
+	RequiresProofOfWork() bool
+
+	//SealTime is the earliest timestamp the header's miner may seal it with, for engines without proof of work
+	SealTime(chain ChainReader, header *models.BlockHeader) (int64, error)
+
+	//BlockWeight is added to the weight of every chain containing the block, the heaviest chain is the active one
+	BlockWeight(header *models.BlockHeader, height uint64) *big.Int
+}
+
+// NewConsensusEngine returns the engine selected in the chain parameters, proof of work unless proof of authority is selected
+func NewConsensusEngine(chainParams *chainparams.ChainParams) ConsensusEngine {
+	switch chainParams.Consensus.Engine {
+	case chainparams.ProofOfAuthority:
+		return NewProofOfAuthority(&chainParams.Consensus.Authority)
+	default:
+		return NewProofOfWork(&chainParams.Difficulty)
+	}
+}
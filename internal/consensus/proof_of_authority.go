@@ -0,0 +1,138 @@
+package consensus
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+
+	chainparams "github.com/nivschuman/VotingBlockchain/internal/chainparams"
+	models "github.com/nivschuman/VotingBlockchain/internal/models"
+)
+
+// blocks sealed in turn outweigh blocks sealed by backup sealers, so chains of equal length settle on the in turn one
+const IN_TURN_WEIGHT = 2
+const OUT_OF_TURN_WEIGHT = 1
+
+// ProofOfAuthority lets only the authorized sealers create blocks, the miner's signature seals the block instead of proof of work
+type ProofOfAuthority struct {
+	rules *chainparams.AuthorityRules
+}
+
+func NewProofOfAuthority(rules *chainparams.AuthorityRules) *ProofOfAuthority {
+	return &ProofOfAuthority{
+		rules: rules,
+	}
+}
+
+func (engine *ProofOfAuthority) Name() string {
+	return chainparams.ProofOfAuthority
+}
+
+func (engine *ProofOfAuthority) CheckHeader(header *models.BlockHeader) error {
+	//Sealed blocks carry no proof of work
+	if header.NBits != 0 {
+		return fmt.Errorf("%w: NBits %d in a sealed block", ErrInvalidHeader, header.NBits)
+	}
+
+	//Sealer must be authorized and sign, its signature is checked with every miner signature
+	if !header.IsSigned() {
+		return fmt.Errorf("%w: version %d isn't signed by its sealer", ErrInvalidHeader, header.Version)
+	}
+
+	if !engine.rules.IsSealer(header.MinerPublicKey) {
+		return fmt.Errorf("%w: %w %x", ErrInvalidHeader, ErrNotSealer, header.MinerPublicKey)
+	}
+
+	return nil
+}
+
+func (engine *ProofOfAuthority) VerifyHeader(chain ChainReader, header *models.BlockHeader) error {
+	sealTime, err := engine.SealTime(chain, header)
+	if err != nil {
+		return err
+	}
+
+	if header.Timestamp < sealTime {
+		return fmt.Errorf("%w: sealed at %d before the sealer's time %d", ErrInvalidHeader, header.Timestamp, sealTime)
+	}
+
+	return nil
+}
+
+func (engine *ProofOfAuthority) PrepareHeader(chain ChainReader, header *models.BlockHeader) error {
+	header.NBits = 0
+	return nil
+}
+
+func (engine *ProofOfAuthority) RequiresProofOfWork() bool {
+	return false
+}
+
+// SealTime gives the in turn sealer the period after the previous block, the other sealers wait for the out of turn delay as well.
+// A sealer of one of the recent blocks has no seal time, it waits for the other sealers
+func (engine *ProofOfAuthority) SealTime(chain ChainReader, header *models.BlockHeader) (int64, error) {
+	if !engine.rules.IsSealer(header.MinerPublicKey) {
+		return 0, fmt.Errorf("%w: %w %x", ErrInvalidHeader, ErrNotSealer, header.MinerPublicKey)
+	}
+
+	previousHeader, err := chain.GetBlockHeader(header.PreviousBlockId)
+	if err != nil {
+		return 0, err
+	}
+
+	previousHeight, err := chain.GetBlockHeight(header.PreviousBlockId)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := engine.checkRecentlySealed(chain, header, previousHeader, previousHeight); err != nil {
+		return 0, err
+	}
+
+	sealTime := previousHeader.Timestamp + engine.rules.Period
+	if !engine.isInTurn(header, previousHeight+1) {
+		sealTime += engine.rules.OutOfTurnDelay
+	}
+
+	return sealTime, nil
+}
+
+func (engine *ProofOfAuthority) BlockWeight(header *models.BlockHeader, height uint64) *big.Int {
+	if engine.isInTurn(header, height) {
+		return big.NewInt(IN_TURN_WEIGHT)
+	}
+
+	return big.NewInt(OUT_OF_TURN_WEIGHT)
+}
+
+// checkRecentlySealed refuses an out of turn sealer that sealed one of the last floor(N/2) blocks of N sealers,
+// so a chain sealed by less than half of the sealers can't grow on its own however far ahead its timestamps are.
+// The in turn sealer is fixed by the height, a sealer with a weight above 1 seals its turns in a row
+func (engine *ProofOfAuthority) checkRecentlySealed(chain ChainReader, header *models.BlockHeader, previousHeader *models.BlockHeader, previousHeight uint64) error {
+	if engine.isInTurn(header, previousHeight+1) {
+		return nil
+	}
+
+	recentBlocks := uint64(len(engine.rules.Sealers) / 2)
+
+	recentHeader := previousHeader
+	for height := previousHeight; height > 0 && previousHeight-height < recentBlocks; height-- {
+		if height < previousHeight {
+			var err error
+			recentHeader, err = chain.GetBlockHeader(recentHeader.PreviousBlockId)
+			if err != nil {
+				return err
+			}
+		}
+
+		if bytes.Equal(recentHeader.MinerPublicKey, header.MinerPublicKey) {
+			return fmt.Errorf("%w: %w %x at height %d", ErrInvalidHeader, ErrRecentlySealed, header.MinerPublicKey, height)
+		}
+	}
+
+	return nil
+}
+
+func (engine *ProofOfAuthority) isInTurn(header *models.BlockHeader, height uint64) bool {
+	return bytes.Equal(header.MinerPublicKey, engine.rules.InTurnSealer(height))
+}
@@ -0,0 +1,75 @@
+package consensus
+
+import (
+	"fmt"
+	"math/big"
+
+	chainparams "github.com/nivschuman/VotingBlockchain/internal/chainparams"
+	difficulty "github.com/nivschuman/VotingBlockchain/internal/difficulty"
+	models "github.com/nivschuman/VotingBlockchain/internal/models"
+)
+
+type ProofOfWork struct {
+	rules *chainparams.DifficultyRules
+}
+
+func NewProofOfWork(rules *chainparams.DifficultyRules) *ProofOfWork {
+	return &ProofOfWork{
+		rules: rules,
+	}
+}
+
+func (engine *ProofOfWork) Name() string {
+	return chainparams.ProofOfWork
+}
+
+func (engine *ProofOfWork) CheckHeader(header *models.BlockHeader) error {
+	//Proof of Work must be valid
+	if !header.IsHashBelowTarget() {
+		return fmt.Errorf("%w: hash does not satisfy target", ErrInvalidHeader)
+	}
+
+	//NBits must not be below minimum work
+	target := difficulty.GetTargetFromNBits(header.NBits)
+	if target.Cmp(difficulty.GetTargetFromNBits(engine.rules.MinimumDifficulty)) > 0 {
+		return fmt.Errorf("%w: NBits below minimum difficulty (%d)", ErrInvalidHeader, header.NBits)
+	}
+
+	return nil
+}
+
+func (engine *ProofOfWork) VerifyHeader(chain ChainReader, header *models.BlockHeader) error {
+	requiredNBits, err := chain.GetNextWorkRequired(header.PreviousBlockId)
+	if err != nil {
+		return err
+	}
+
+	if header.NBits != requiredNBits {
+		return fmt.Errorf("%w: NBits %d != required %d", ErrInvalidHeader, header.NBits, requiredNBits)
+	}
+
+	return nil
+}
+
+func (engine *ProofOfWork) PrepareHeader(chain ChainReader, header *models.BlockHeader) error {
+	nbits, err := chain.GetNextWorkRequired(header.PreviousBlockId)
+	if err != nil {
+		return err
+	}
+
+	header.NBits = nbits
+	return nil
+}
+
+func (engine *ProofOfWork) RequiresProofOfWork() bool {
+	return true
+}
+
+// SealTime doesn't limit proof of work blocks, their timestamps are checked against the median time past
+func (engine *ProofOfWork) SealTime(chain ChainReader, header *models.BlockHeader) (int64, error) {
+	return 0, nil
+}
+
+func (engine *ProofOfWork) BlockWeight(header *models.BlockHeader, height uint64) *big.Int {
+	return difficulty.CalculateWork(header.NBits)
+}
@@ -0,0 +1,119 @@
+package keystore
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	hash "github.com/nivschuman/VotingBlockchain/internal/crypto/hash"
+	ppk "github.com/nivschuman/VotingBlockchain/internal/crypto/ppk"
+)
+
+// keyFile is the json stored in a keystore file, keys are hex encoded like in the voters file
+type keyFile struct {
+	PublicKey  string `json:"public_key"`  //compressed
+	PrivateKey string `json:"private_key"` //DER
+}
+
+// LoadKeyPair reads a key pair and makes sure its public key belongs to its private key
+func LoadKeyPair(path string) (*ppk.KeyPair, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var file keyFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("invalid keystore file %s: %v", path, err)
+	}
+
+	publicKeyBytes, err := hex.DecodeString(file.PublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid public key in keystore file %s: %v", path, err)
+	}
+
+	publicKey, err := ppk.GetPublicKeyFromBytes(publicKeyBytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid public key in keystore file %s: %v", path, err)
+	}
+
+	privateKeyBytes, err := hex.DecodeString(file.PrivateKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid private key in keystore file %s: %v", path, err)
+	}
+
+	privateKey, err := ppk.GetPrivateKeyFromBytes(privateKeyBytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid private key in keystore file %s: %v", path, err)
+	}
+
+	keyPair := &ppk.KeyPair{PublicKey: publicKey, PrivateKey: privateKey}
+	if err := checkKeyPair(keyPair); err != nil {
+		return nil, fmt.Errorf("keystore file %s: %v", path, err)
+	}
+
+	return keyPair, nil
+}
+
+// SaveKeyPair writes the key pair readable only by its owner
+func SaveKeyPair(path string, keyPair *ppk.KeyPair) error {
+	privateKeyBytes, err := keyPair.PrivateKey.AsBytes()
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(keyFile{
+		PublicKey:  hex.EncodeToString(keyPair.PublicKey.AsBytes()),
+		PrivateKey: hex.EncodeToString(privateKeyBytes),
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, data, 0600)
+}
+
+// LoadOrCreateKeyPair generates and saves a new key pair when the file doesn't exist yet, it reports whether it did
+func LoadOrCreateKeyPair(path string) (*ppk.KeyPair, bool, error) {
+	keyPair, err := LoadKeyPair(path)
+	if err == nil {
+		return keyPair, false, nil
+	}
+
+	if !errors.Is(err, os.ErrNotExist) {
+		return nil, false, err
+	}
+
+	keyPair, err = ppk.GenerateKeyPair()
+	if err != nil {
+		return nil, false, err
+	}
+
+	if err := SaveKeyPair(path, keyPair); err != nil {
+		return nil, false, err
+	}
+
+	return keyPair, true, nil
+}
+
+func checkKeyPair(keyPair *ppk.KeyPair) error {
+	testHash := hash.HashBytes(keyPair.PublicKey.AsBytes())
+
+	signature, err := keyPair.PrivateKey.CreateSignature(testHash)
+	if err != nil {
+		return err
+	}
+
+	if !keyPair.PublicKey.VerifySignature(signature, testHash) {
+		return errors.New("public key doesn't belong to the private key")
+	}
+
+	return nil
+}
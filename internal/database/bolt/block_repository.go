@@ -0,0 +1,1122 @@
+package db_bolt
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"slices"
+	"strings"
+	"sync"
+
+	chainparams "github.com/nivschuman/VotingBlockchain/internal/chainparams"
+	consensus "github.com/nivschuman/VotingBlockchain/internal/consensus"
+	db_models "github.com/nivschuman/VotingBlockchain/internal/database/models"
+	repositories "github.com/nivschuman/VotingBlockchain/internal/database/repositories"
+	types "github.com/nivschuman/VotingBlockchain/internal/database/types"
+	events "github.com/nivschuman/VotingBlockchain/internal/events"
+	mapping "github.com/nivschuman/VotingBlockchain/internal/mapping"
+	models "github.com/nivschuman/VotingBlockchain/internal/models"
+	structures "github.com/nivschuman/VotingBlockchain/internal/structures"
+	voters "github.com/nivschuman/VotingBlockchain/internal/voters"
+	bolt "go.etcd.io/bbolt"
+)
+
+// BlockRepositoryImpl keeps the chain in a bolt file, the active chain, tally and miner credits are indexes updated on connect and disconnect
+type BlockRepositoryImpl struct {
+	db *bolt.DB
+
+	activeChainTipId      []byte
+	activeChainTipIdMutex sync.Mutex
+
+	eventBus        events.EventBus
+	chainParams     *chainparams.ChainParams
+	consensusEngine consensus.ConsensusEngine
+}
+
+func NewBlockRepositoryImpl(db *bolt.DB, eventBus events.EventBus, chainParams *chainparams.ChainParams) *BlockRepositoryImpl {
+	return &BlockRepositoryImpl{
+		db:              db,
+		eventBus:        eventBus,
+		chainParams:     chainParams,
+		consensusEngine: consensus.NewConsensusEngine(chainParams),
+	}
+}
+
+func (repo *BlockRepositoryImpl) Initialize() error {
+	if err := repo.CheckGenesis(); err != nil {
+		return err
+	}
+
+	err := repo.InsertIfNotExists(repo.GenesisBlock())
+	if err != nil {
+		return err
+	}
+
+	return repo.SetActiveChainTipId()
+}
+
+// CheckGenesis refuses a stored chain that starts at another genesis block, an empty database passes
+func (repo *BlockRepositoryImpl) CheckGenesis() error {
+	genesisBlock := repo.GenesisBlock()
+
+	var storedGenesisId []byte
+	repo.db.View(func(tx *bolt.Tx) error {
+		storedGenesisId = getActiveChainBlockId(tx, 0)
+		return nil
+	})
+
+	if storedGenesisId != nil && !bytes.Equal(storedGenesisId, genesisBlock.Header.Id) {
+		return fmt.Errorf("%w: stored chain starts at %x, the election's genesis block is %x", repositories.ErrGenesisMismatch, storedGenesisId, genesisBlock.Header.Id)
+	}
+
+	return nil
+}
+
+func (repo *BlockRepositoryImpl) GetActiveChainHeight() (uint64, error) {
+	var height uint64
+
+	err := repo.db.View(func(tx *bolt.Tx) error {
+		lastHeight, _ := tx.Bucket(activeChainBucket).Cursor().Last()
+		if lastHeight != nil {
+			height = binary.BigEndian.Uint64(lastHeight)
+		}
+		return nil
+	})
+
+	return height, err
+}
+
+func (repo *BlockRepositoryImpl) GetNextWorkRequired(lastBlockId []byte) (uint32, error) {
+	rules := &repo.chainParams.Difficulty
+
+	if lastBlockId == nil || rules.NoRetargeting {
+		return rules.MinimumDifficulty, nil
+	}
+
+	nBits := rules.MinimumDifficulty
+	err := repo.db.View(func(tx *bolt.Tx) error {
+		lastHeader, err := getBlockHeader(tx, lastBlockId)
+		if err != nil {
+			return err
+		}
+
+		lastRecord, err := getBlockRecord(tx, lastBlockId)
+		if err != nil {
+			return err
+		}
+
+		if (lastRecord.Height+1)%uint64(rules.Interval()) != 0 {
+			nBits = lastHeader.NBits
+			return nil
+		}
+
+		firstHeader := lastHeader
+		for range rules.Interval() {
+			if firstHeader.PreviousBlockId == nil {
+				break
+			}
+
+			firstHeader, err = getBlockHeader(tx, firstHeader.PreviousBlockId)
+			if err != nil {
+				return err
+			}
+		}
+
+		nBits = repositories.RetargetWork(rules, lastHeader.NBits, firstHeader.Timestamp, lastHeader.Timestamp)
+		return nil
+	})
+
+	if err != nil {
+		return rules.MinimumDifficulty, err
+	}
+
+	return nBits, nil
+}
+
+func (repo *BlockRepositoryImpl) HaveBlock(blockId []byte) (bool, error) {
+	haveBlock := false
+
+	err := repo.db.View(func(tx *bolt.Tx) error {
+		haveBlock = len(blockId) > 0 && tx.Bucket(headersBucket).Get(blockId) != nil
+		return nil
+	})
+
+	return haveBlock, err
+}
+
+func (repo *BlockRepositoryImpl) BlockIsOrphan(block *models.Block) (bool, error) {
+	haveParent, err := repo.HaveBlock(block.Header.PreviousBlockId)
+	if err != nil {
+		return true, err
+	}
+
+	return !haveParent, nil
+}
+
+func (repo *BlockRepositoryImpl) GetMedianTimePast(startBlockId []byte, numberOfBlocks int) (int64, error) {
+	times := make([]int64, 0, numberOfBlocks)
+
+	err := repo.db.View(func(tx *bolt.Tx) error {
+		currentId := startBlockId
+
+		for range numberOfBlocks {
+			if tx.Bucket(headersBucket).Get(currentId) == nil {
+				break
+			}
+
+			blockHeader, err := getBlockHeader(tx, currentId)
+			if err != nil {
+				return err
+			}
+
+			times = append(times, blockHeader.Timestamp)
+
+			if blockHeader.PreviousBlockId == nil {
+				break
+			}
+
+			currentId = blockHeader.PreviousBlockId
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return -1, err
+	}
+
+	slices.Sort(times)
+	medianTime := times[len(times)/2]
+
+	return medianTime, nil
+}
+
+func (repo *BlockRepositoryImpl) GetNextBlocksIds(blockLocator *structures.BlockLocator, stopHash []byte, limit int) (*structures.BytesSet, error) {
+	blocksIds := structures.NewBytesSet()
+
+	err := repo.db.View(func(tx *bolt.Tx) error {
+		var height uint64
+		found := false
+
+		for _, id := range blockLocator.Ids() {
+			record, err := getBlockRecord(tx, id)
+			if err == nil && record.InActiveChain {
+				height = record.Height
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return nil
+		}
+
+		for range limit {
+			height++
+			nextId := getActiveChainBlockId(tx, height)
+
+			if nextId == nil {
+				break
+			}
+
+			if stopHash != nil && bytes.Equal(nextId, stopHash) {
+				break
+			}
+
+			blocksIds.Add(nextId)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return blocksIds, nil
+}
+
+func (repo *BlockRepositoryImpl) GetActiveChainBlockLocator() (*structures.BlockLocator, error) {
+	repo.activeChainTipIdMutex.Lock()
+	defer repo.activeChainTipIdMutex.Unlock()
+
+	locator := structures.NewBlockLocator()
+
+	err := repo.db.View(func(tx *bolt.Tx) error {
+		tipRecord, err := getBlockRecord(tx, repo.activeChainTipId)
+		if err != nil {
+			return err
+		}
+
+		height := tipRecord.Height
+		currentId := repo.activeChainTipId
+		step := uint64(1)
+
+		for {
+			locator.Add(currentId)
+
+			if height == 0 {
+				break
+			}
+
+			if locator.Length() >= 10 {
+				step *= 2
+			}
+
+			if step > height {
+				height = 0
+			} else {
+				height = height - step
+			}
+
+			currentId = getActiveChainBlockId(tx, height)
+			if currentId == nil {
+				return fmt.Errorf("active chain block at height %d: %w", height, ErrNotFound)
+			}
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return locator, nil
+}
+
+func (repo *BlockRepositoryImpl) GetBlockLocator(startBlockId []byte) (*structures.BlockLocator, error) {
+	locator := structures.NewBlockLocator()
+
+	err := repo.db.View(func(tx *bolt.Tx) error {
+		startRecord, err := getBlockRecord(tx, startBlockId)
+		if err != nil {
+			return err
+		}
+
+		height := startRecord.Height
+		currentId := startBlockId
+		step := uint64(1)
+
+		for {
+			locator.Add(currentId)
+
+			if height == 0 {
+				break
+			}
+
+			if locator.Length() >= 10 {
+				step *= 2
+			}
+
+			toHeight := height - step
+			if step > height {
+				toHeight = 0
+			}
+
+			for height > toHeight {
+				blockHeader, err := getBlockHeader(tx, currentId)
+				if err != nil {
+					return err
+				}
+
+				height--
+				currentId = blockHeader.PreviousBlockId
+			}
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return locator, nil
+}
+
+func (repo *BlockRepositoryImpl) GetBlock(blockId []byte) (*models.Block, error) {
+	var block *models.Block
+
+	err := repo.db.View(func(tx *bolt.Tx) error {
+		var err error
+		block, err = getBlock(tx, blockId)
+		return err
+	})
+
+	return block, err
+}
+
+// GetBlocks leaves out unknown and pruned blocks
+func (repo *BlockRepositoryImpl) GetBlocks(ids *structures.BytesSet) ([]*models.Block, error) {
+	blocks := make([]*models.Block, 0, ids.Length())
+
+	err := repo.db.View(func(tx *bolt.Tx) error {
+		for _, id := range ids.ToBytesSlice() {
+			if tx.Bucket(headersBucket).Get(id) == nil || isPruned(tx, id) {
+				continue
+			}
+
+			block, err := getBlock(tx, id)
+			if err != nil {
+				return err
+			}
+
+			blocks = append(blocks, block)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return blocks, nil
+}
+
+func (repo *BlockRepositoryImpl) GetMissingBlockIds(ids *structures.BytesSet) (*structures.BytesSet, error) {
+	missingIds := structures.NewBytesSet()
+
+	err := repo.db.View(func(tx *bolt.Tx) error {
+		for _, id := range ids.ToBytesSlice() {
+			if tx.Bucket(headersBucket).Get(id) == nil {
+				missingIds.Add(id)
+			}
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return missingIds, nil
+}
+
+func (repo *BlockRepositoryImpl) GetBlockHeader(blockId []byte) (*models.BlockHeader, error) {
+	var blockHeader *models.BlockHeader
+
+	err := repo.db.View(func(tx *bolt.Tx) error {
+		var err error
+		blockHeader, err = getBlockHeader(tx, blockId)
+		return err
+	})
+
+	return blockHeader, err
+}
+
+func (repo *BlockRepositoryImpl) GetBlockHeight(blockId []byte) (uint64, error) {
+	var record *blockRecord
+
+	err := repo.db.View(func(tx *bolt.Tx) error {
+		var err error
+		record, err = getBlockRecord(tx, blockId)
+		return err
+	})
+
+	if err != nil {
+		return 0, err
+	}
+
+	return record.Height, nil
+}
+
+func (repo *BlockRepositoryImpl) GetBlockChainWeight(blockHeaderId []byte) (*big.Int, error) {
+	var record *blockRecord
+
+	err := repo.db.View(func(tx *bolt.Tx) error {
+		var err error
+		record, err = getBlockRecord(tx, blockHeaderId)
+		return err
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return record.ChainWeight, nil
+}
+
+func (blockRepository *BlockRepositoryImpl) InsertIfNotExists(block *models.Block) error {
+	blockRepository.activeChainTipIdMutex.Lock()
+	defer blockRepository.activeChainTipIdMutex.Unlock()
+
+	oldTipId := blockRepository.activeChainTipId
+	var chainUpdate *events.ChainUpdate
+
+	err := blockRepository.db.Update(func(tx *bolt.Tx) error {
+		if tx.Bucket(headersBucket).Get(block.Header.Id) != nil {
+			return nil
+		}
+
+		record := &blockRecord{}
+
+		if block.Header.PreviousBlockId == nil {
+			record.Height = 0
+			record.InActiveChain = true
+			record.ChainWeight = blockRepository.consensusEngine.BlockWeight(&block.Header, 0)
+		} else {
+			prevRecord, err := getBlockRecord(tx, block.Header.PreviousBlockId)
+			if err != nil {
+				return fmt.Errorf("orphan")
+			}
+
+			record.Height = prevRecord.Height + 1
+
+			checkpoint := blockRepository.chainParams.Consensus.CheckpointAt(record.Height)
+			if checkpoint != nil && !bytes.Equal(checkpoint.BlockId, block.Header.Id) {
+				return fmt.Errorf("%w: block %x at height %d, checkpoint is %x", repositories.ErrCheckpointViolation, block.Header.Id, record.Height, checkpoint.BlockId)
+			}
+
+			record.InActiveChain = bytes.Equal(block.Header.PreviousBlockId, blockRepository.activeChainTipId)
+			blockWeight := blockRepository.consensusEngine.BlockWeight(&block.Header, record.Height)
+			record.ChainWeight = blockWeight.Add(blockWeight, prevRecord.ChainWeight)
+		}
+
+		if err := blockRepository.putBlock(tx, block, record); err != nil {
+			return err
+		}
+
+		if record.InActiveChain {
+			if err := blockRepository.connectBlock(tx, block.Header.Id, record.Height); err != nil {
+				return err
+			}
+
+			if err := blockRepository.setActiveChainTip(tx, block.Header.Id); err != nil {
+				return err
+			}
+
+			var err error
+			chainUpdate, err = blockRepository.createChainUpdate(tx, oldTipId, block, [][]byte{block.Header.Id}, nil)
+			return err
+		}
+
+		activeTipRecord, err := getBlockRecord(tx, blockRepository.activeChainTipId)
+		if err != nil {
+			return fmt.Errorf("active chain tip not found: %v", err)
+		}
+
+		//the consensus engine weighs the chains, a side chain must be strictly heavier to become active
+		if record.ChainWeight.Cmp(activeTipRecord.ChainWeight) > 0 {
+			connectedIds, disconnectedIds, err := blockRepository.reorganizeChain(tx, block.Header.Id)
+			if err != nil {
+				return err
+			}
+
+			chainUpdate, err = blockRepository.createChainUpdate(tx, oldTipId, block, connectedIds, disconnectedIds)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		blockRepository.activeChainTipId = oldTipId
+		return err
+	}
+
+	//genesis block or block on a side chain
+	if oldTipId == nil || chainUpdate == nil {
+		return nil
+	}
+
+	if len(chainUpdate.DisconnectedBlocks) > 0 {
+		blockRepository.eventBus.Publish(events.ChainReorganizedEvent{ChainUpdate: *chainUpdate})
+	}
+
+	blockRepository.eventBus.Publish(events.ChainTipChangedEvent{ChainUpdate: *chainUpdate})
+	return nil
+}
+
+// putBlock stores the block with its transactions and adds its votes to the voter index
+func (blockRepository *BlockRepositoryImpl) putBlock(tx *bolt.Tx, block *models.Block, record *blockRecord) error {
+	if err := putBlockHeader(tx, &block.Header); err != nil {
+		return err
+	}
+
+	if err := putBlockRecord(tx, block.Header.Id, record); err != nil {
+		return err
+	}
+
+	transactionIds := make([]byte, 0, 32*len(block.Transactions))
+	for _, transaction := range block.Transactions {
+		if err := putTransactionIfNotExists(tx, transaction); err != nil {
+			return err
+		}
+
+		if err := tx.Bucket(voterBlocksBucket).Put(voterBlockKey(transaction.VoterPublicKey, block.Header.Id), transaction.Id); err != nil {
+			return err
+		}
+
+		transactionIds = append(transactionIds, transaction.Id...)
+	}
+
+	if err := putBlockVotes(tx, block.Header.Id, block.Transactions); err != nil {
+		return err
+	}
+
+	return tx.Bucket(blockTransactionsBucket).Put(block.Header.Id, transactionIds)
+}
+
+// connectBlock adds a block to the active chain indexes, the tally is snapshot at snapshot heights
+func (blockRepository *BlockRepositoryImpl) connectBlock(tx *bolt.Tx, blockId []byte, height uint64) error {
+	if err := tx.Bucket(activeChainBucket).Put(heightKey(height), blockId); err != nil {
+		return err
+	}
+
+	if err := blockRepository.countBlock(tx, blockId, height, 1); err != nil {
+		return err
+	}
+
+	if !blockRepository.chainParams.Election.IsTallySnapshotHeight(height) {
+		return nil
+	}
+
+	return putTallySnapshot(tx, voters.NewTallySnapshot(height, blockId, getTally(tx)))
+}
+
+// disconnectBlock removes a block from the active chain indexes
+func (blockRepository *BlockRepositoryImpl) disconnectBlock(tx *bolt.Tx, blockId []byte, height uint64) error {
+	if err := tx.Bucket(activeChainBucket).Delete(heightKey(height)); err != nil {
+		return err
+	}
+
+	if err := tx.Bucket(tallySnapshotsBucket).Delete(heightKey(height)); err != nil {
+		return err
+	}
+
+	return blockRepository.countBlock(tx, blockId, height, -1)
+}
+
+// countBlock adds or removes the block's votes from the tally and the block from its miner's credits, the genesis block earns nothing
+func (blockRepository *BlockRepositoryImpl) countBlock(tx *bolt.Tx, blockId []byte, height uint64, delta int64) error {
+	transactionIds := getBlockTransactionIds(tx, blockId)
+
+	for _, transactionId := range transactionIds {
+		transaction, err := getTransaction(tx, transactionId)
+		if err != nil {
+			return err
+		}
+
+		if err := addToCounter(tx.Bucket(tallyBucket), candidateKey(transaction.CandidateId), 0, delta); err != nil {
+			return err
+		}
+	}
+
+	if height == 0 {
+		return nil
+	}
+
+	blockHeader, err := getBlockHeader(tx, blockId)
+	if err != nil {
+		return err
+	}
+
+	minerCredits := tx.Bucket(minerCreditsBucket)
+	if err := addToCounter(minerCredits, blockHeader.MinerPublicKey, 0, delta); err != nil {
+		return err
+	}
+
+	return addToCounter(minerCredits, blockHeader.MinerPublicKey, 8, delta*int64(len(transactionIds)))
+}
+
+func (blockRepository *BlockRepositoryImpl) setActiveChainTip(tx *bolt.Tx, blockId []byte) error {
+	if err := tx.Bucket(metaBucket).Put(activeChainTipKey, blockId); err != nil {
+		return err
+	}
+
+	blockRepository.activeChainTipId = blockId
+	return nil
+}
+
+func (blockRepository *BlockRepositoryImpl) createChainUpdate(tx *bolt.Tx, oldTipId []byte, insertedBlock *models.Block, connectedIds [][]byte, disconnectedIds [][]byte) (*events.ChainUpdate, error) {
+	chainUpdate := &events.ChainUpdate{
+		OldTipId:           oldTipId,
+		NewTipId:           blockRepository.activeChainTipId,
+		ConnectedBlocks:    make([]*models.Block, len(connectedIds)),
+		DisconnectedBlocks: make([]*models.Block, len(disconnectedIds)),
+	}
+
+	for i, id := range connectedIds {
+		if bytes.Equal(id, insertedBlock.Header.Id) {
+			chainUpdate.ConnectedBlocks[i] = insertedBlock
+			continue
+		}
+
+		block, err := getBlock(tx, id)
+		if err != nil {
+			return nil, err
+		}
+		chainUpdate.ConnectedBlocks[i] = block
+	}
+
+	for i, id := range disconnectedIds {
+		block, err := getBlock(tx, id)
+		if err != nil {
+			return nil, err
+		}
+		chainUpdate.DisconnectedBlocks[i] = block
+	}
+
+	chainUpdate.ForkPointId = chainUpdate.ConnectedBlocks[0].Header.PreviousBlockId
+	return chainUpdate, nil
+}
+
+func (blockRepository *BlockRepositoryImpl) GenesisBlock() *models.Block {
+	return blockRepository.chainParams.GenesisBlock
+}
+
+func (blockRepository *BlockRepositoryImpl) SetActiveChainTipId() error {
+	blockRepository.activeChainTipIdMutex.Lock()
+	defer blockRepository.activeChainTipIdMutex.Unlock()
+
+	return blockRepository.db.View(func(tx *bolt.Tx) error {
+		tipId := tx.Bucket(metaBucket).Get(activeChainTipKey)
+		if len(tipId) == 0 {
+			return fmt.Errorf("no chain tip found")
+		}
+
+		blockRepository.activeChainTipId = bytes.Clone(tipId)
+		return nil
+	})
+}
+
+func (blockRepository *BlockRepositoryImpl) GetActiveChainTipId() []byte {
+	blockRepository.activeChainTipIdMutex.Lock()
+	defer blockRepository.activeChainTipIdMutex.Unlock()
+
+	return blockRepository.activeChainTipId
+}
+
+// GetActiveBlocksPaged matches the search text against the hex encoded block id, like the sqlite storage
+func (blockRepository *BlockRepositoryImpl) GetActiveBlocksPaged(searchText string, offset int, pageSize int, sortAsc bool) ([]*db_models.BlockDB, int64, error) {
+	blocks := make([]*db_models.BlockDB, 0, pageSize)
+	var total int64
+
+	searchText = strings.ToUpper(searchText)
+
+	err := blockRepository.db.View(func(tx *bolt.Tx) error {
+		cursor := tx.Bucket(activeChainBucket).Cursor()
+
+		first, next := cursor.Last, cursor.Prev
+		if sortAsc {
+			first, next = cursor.First, cursor.Next
+		}
+
+		for key, blockId := first(); key != nil; key, blockId = next() {
+			if searchText != "" && !strings.Contains(strings.ToUpper(hex.EncodeToString(blockId)), searchText) {
+				continue
+			}
+
+			total++
+			if total <= int64(offset) || len(blocks) >= pageSize {
+				continue
+			}
+
+			blockDB, err := getBlockDB(tx, blockId)
+			if err != nil {
+				return err
+			}
+
+			blocks = append(blocks, blockDB)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return blocks, total, nil
+}
+
+func getBlockDB(tx *bolt.Tx, blockId []byte) (*db_models.BlockDB, error) {
+	blockHeader, err := getBlockHeader(tx, blockId)
+	if err != nil {
+		return nil, err
+	}
+
+	record, err := getBlockRecord(tx, blockId)
+	if err != nil {
+		return nil, err
+	}
+
+	blockDB := mapping.BlockToBlockDB(&models.Block{Header: *blockHeader})
+	blockDB.Height = record.Height
+	blockDB.InActiveChain = record.InActiveChain
+	blockDB.ChainWeight = types.NewBigInt(record.ChainWeight)
+
+	return blockDB, nil
+}
+
+// GetMinerCredits is read from the miner credits index, which follows the active chain
+func (blockRepository *BlockRepositoryImpl) GetMinerCredits(minerPublicKey []byte) (*models.MinerCredits, error) {
+	minerCredits := &models.MinerCredits{MinerPublicKey: minerPublicKey}
+
+	err := blockRepository.db.View(func(tx *bolt.Tx) error {
+		value := tx.Bucket(minerCreditsBucket).Get(minerPublicKey)
+		if value != nil {
+			minerCredits = blockRepository.toMinerCredits(bytes.Clone(minerPublicKey), value)
+		}
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return minerCredits, nil
+}
+
+// GetMinerCreditsPaged is the ledger of every miner in the active chain, most credits first
+func (blockRepository *BlockRepositoryImpl) GetMinerCreditsPaged(offset int, pageSize int) ([]*models.MinerCredits, int64, error) {
+	ledger := make([]*models.MinerCredits, 0)
+
+	err := blockRepository.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(minerCreditsBucket).ForEach(func(minerPublicKey []byte, value []byte) error {
+			ledger = append(ledger, blockRepository.toMinerCredits(bytes.Clone(minerPublicKey), value))
+			return nil
+		})
+	})
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	slices.SortStableFunc(ledger, func(a *models.MinerCredits, b *models.MinerCredits) int {
+		return cmp.Or(cmp.Compare(b.Credits, a.Credits), bytes.Compare(a.MinerPublicKey, b.MinerPublicKey))
+	})
+
+	total := int64(len(ledger))
+	start := min(offset, len(ledger))
+	end := min(start+pageSize, len(ledger))
+
+	return ledger[start:end], total, nil
+}
+
+func (blockRepository *BlockRepositoryImpl) toMinerCredits(minerPublicKey []byte, value []byte) *models.MinerCredits {
+	blocks := binary.BigEndian.Uint64(value[0:8])
+	votes := binary.BigEndian.Uint64(value[8:16])
+
+	return &models.MinerCredits{
+		MinerPublicKey: minerPublicKey,
+		Blocks:         blocks,
+		Votes:          votes,
+		Credits:        blockRepository.chainParams.Rewards.Credits(blocks, votes),
+	}
+}
+
+// reorganizeChain makes newTipId the active chain tip, it returns the connected block ids from the fork point to the new tip
+// and the disconnected block ids from the old tip to the fork point
+func (blockRepository *BlockRepositoryImpl) reorganizeChain(tx *bolt.Tx, newTipId []byte) ([][]byte, [][]byte, error) {
+	oldTipId := blockRepository.activeChainTipId
+
+	connectedIds := make([][]byte, 0)
+	disconnectedIds := make([][]byte, 0)
+
+	var forkPoint *blockRecord
+	curId := newTipId
+
+	for {
+		record, err := getBlockRecord(tx, curId)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if record.InActiveChain {
+			forkPoint = record
+			break
+		}
+
+		connectedIds = append(connectedIds, curId)
+
+		blockHeader, err := getBlockHeader(tx, curId)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		curId = blockHeader.PreviousBlockId
+	}
+
+	oldTipRecord, err := getBlockRecord(tx, oldTipId)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := repositories.CheckReorganization(&blockRepository.chainParams.Consensus, forkPoint.Height, oldTipRecord.Height); err != nil {
+		return nil, nil, err
+	}
+
+	if err := repositories.CheckPrunedReorganization(getPrunedHeight(tx), forkPoint.Height); err != nil {
+		return nil, nil, err
+	}
+
+	for height := oldTipRecord.Height; height > forkPoint.Height; height-- {
+		blockId := getActiveChainBlockId(tx, height)
+		disconnectedIds = append(disconnectedIds, blockId)
+
+		if err := blockRepository.setInActiveChain(tx, blockId, false); err != nil {
+			return nil, nil, err
+		}
+
+		if err := blockRepository.disconnectBlock(tx, blockId, height); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	slices.Reverse(connectedIds)
+
+	for i, blockId := range connectedIds {
+		if err := blockRepository.setInActiveChain(tx, blockId, true); err != nil {
+			return nil, nil, err
+		}
+
+		if err := blockRepository.connectBlock(tx, blockId, forkPoint.Height+uint64(i)+1); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if err := blockRepository.setActiveChainTip(tx, newTipId); err != nil {
+		return nil, nil, err
+	}
+
+	return connectedIds, disconnectedIds, nil
+}
+
+func (blockRepository *BlockRepositoryImpl) setInActiveChain(tx *bolt.Tx, blockId []byte, inActiveChain bool) error {
+	record, err := getBlockRecord(tx, blockId)
+	if err != nil {
+		return err
+	}
+
+	record.InActiveChain = inActiveChain
+	return putBlockRecord(tx, blockId, record)
+}
+
+// Reindex recomputes the block records from the stored headers and rebuilds the active chain, voter, tally, tally snapshots and miner credits indexes
+func (blockRepository *BlockRepositoryImpl) Reindex() error {
+	blockRepository.activeChainTipIdMutex.Lock()
+	defer blockRepository.activeChainTipIdMutex.Unlock()
+
+	oldTipId := blockRepository.activeChainTipId
+
+	err := blockRepository.db.Update(func(tx *bolt.Tx) error {
+		if getPrunedHeight(tx) > 0 {
+			return fmt.Errorf("%w: reindexing needs the transactions of every block, resync the node instead", repositories.ErrBlockPruned)
+		}
+
+		headers := make([]*models.BlockHeader, 0)
+		err := tx.Bucket(headersBucket).ForEach(func(_ []byte, value []byte) error {
+			block, err := models.BlockFromBytes(bytes.Clone(value))
+			if err != nil {
+				return err
+			}
+
+			headers = append(headers, &block.Header)
+			return nil
+		})
+
+		if err != nil {
+			return err
+		}
+
+		index, tipId, err := repositories.IndexChain(headers, blockRepository.consensusEngine, &blockRepository.chainParams.Consensus, oldTipId)
+		if err != nil {
+			return err
+		}
+
+		for _, bucket := range [][]byte{activeChainBucket, voterBlocksBucket, tallyBucket, minerCreditsBucket} {
+			if err := tx.DeleteBucket(bucket); err != nil && err != bolt.ErrBucketNotFound {
+				return err
+			}
+
+			if _, err := tx.CreateBucket(bucket); err != nil {
+				return err
+			}
+		}
+
+		for _, header := range headers {
+			record := &blockRecord{}
+
+			indexedBlock, indexed := index.Get(header.Id)
+			if indexed {
+				record.Height = indexedBlock.Height
+				record.InActiveChain = indexedBlock.InActiveChain
+				record.ChainWeight = indexedBlock.ChainWeight
+			} else {
+				//blocks left out of the index stay stored outside of the active chain
+				record, err = getBlockRecord(tx, header.Id)
+				if err != nil {
+					continue
+				}
+
+				record.InActiveChain = false
+			}
+
+			if err := putBlockRecord(tx, header.Id, record); err != nil {
+				return err
+			}
+
+			for _, transactionId := range getBlockTransactionIds(tx, header.Id) {
+				transaction, err := getTransaction(tx, transactionId)
+				if err != nil {
+					return err
+				}
+
+				if err := tx.Bucket(voterBlocksBucket).Put(voterBlockKey(transaction.VoterPublicKey, header.Id), transaction.Id); err != nil {
+					return err
+				}
+			}
+
+			if record.InActiveChain {
+				if err := blockRepository.connectBlock(tx, header.Id, record.Height); err != nil {
+					return err
+				}
+			}
+		}
+
+		//blocks are connected out of height order, so the snapshots taken while connecting are taken again
+		if err := blockRepository.rebuildTallySnapshots(tx); err != nil {
+			return err
+		}
+
+		return blockRepository.setActiveChainTip(tx, tipId)
+	})
+
+	if err != nil {
+		blockRepository.activeChainTipId = oldTipId
+		return err
+	}
+
+	return nil
+}
+
+// Prune deletes the transactions of the blocks older than the last keepBlocks blocks of the active chain, side chain blocks included.
+// Headers, the voter index, the tally and miner credits are kept, the genesis block is never pruned. It returns the number of blocks pruned.
+func (blockRepository *BlockRepositoryImpl) Prune(keepBlocks uint64) (uint64, error) {
+	blockRepository.activeChainTipIdMutex.Lock()
+	defer blockRepository.activeChainTipIdMutex.Unlock()
+
+	var pruned uint64
+
+	err := blockRepository.db.Update(func(tx *bolt.Tx) error {
+		tipRecord, err := getBlockRecord(tx, blockRepository.activeChainTipId)
+		if err != nil {
+			return err
+		}
+
+		if tipRecord.Height <= keepBlocks {
+			return nil
+		}
+
+		pruneHeight := tipRecord.Height - keepBlocks
+		prunedBlocks := tx.Bucket(prunedBlocksBucket)
+
+		blockIds := make([][]byte, 0)
+		err = tx.Bucket(blocksBucket).ForEach(func(blockId []byte, value []byte) error {
+			record, err := blockRecordFromBytes(value)
+			if err != nil {
+				return err
+			}
+
+			if record.Height > 0 && record.Height <= pruneHeight && prunedBlocks.Get(blockId) == nil {
+				blockIds = append(blockIds, bytes.Clone(blockId))
+			}
+
+			return nil
+		})
+
+		if err != nil {
+			return err
+		}
+
+		for _, blockId := range blockIds {
+			if err := prunedBlocks.Put(blockId, []byte{}); err != nil {
+				return err
+			}
+		}
+
+		for _, blockId := range blockIds {
+			//the votes of the block are kept for the tally at its height
+			if tx.Bucket(blockVotesBucket).Get(blockId) == nil {
+				blockVotes, err := getBlockVotes(tx, blockId)
+				if err != nil {
+					return err
+				}
+
+				if err := tx.Bucket(blockVotesBucket).Put(blockId, resultsAsBytes(blockVotes)); err != nil {
+					return err
+				}
+			}
+
+			for _, transactionId := range getBlockTransactionIds(tx, blockId) {
+				if err := pruneTransaction(tx, transactionId); err != nil {
+					return err
+				}
+			}
+
+			if err := tx.Bucket(blockTransactionsBucket).Delete(blockId); err != nil {
+				return err
+			}
+		}
+
+		if len(blockIds) > 0 && pruneHeight > getPrunedHeight(tx) {
+			if err := tx.Bucket(metaBucket).Put(prunedHeightKey, heightKey(pruneHeight)); err != nil {
+				return err
+			}
+		}
+
+		pruned = uint64(len(blockIds))
+		return nil
+	})
+
+	if err != nil {
+		return 0, err
+	}
+
+	return pruned, nil
+}
+
+// pruneTransaction deletes a transaction unless a block that wasn't pruned holds it, the voter index finds the blocks holding it
+func pruneTransaction(tx *bolt.Tx, transactionId []byte) error {
+	transaction, err := getTransaction(tx, transactionId)
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	held, err := voterBlockMatches(tx, transaction.VoterPublicKey, func(blockId []byte) (bool, error) {
+		key := voterBlockKey(transaction.VoterPublicKey, blockId)
+		return bytes.Equal(tx.Bucket(voterBlocksBucket).Get(key), transactionId) && !isPruned(tx, blockId), nil
+	})
+
+	if err != nil || held {
+		return err
+	}
+
+	return tx.Bucket(transactionsBucket).Delete(transactionId)
+}
+
+// GetPrunedHeight is the height of the highest pruned block, 0 when no block was pruned
+func (blockRepository *BlockRepositoryImpl) GetPrunedHeight() (uint64, error) {
+	var prunedHeight uint64
+
+	err := blockRepository.db.View(func(tx *bolt.Tx) error {
+		prunedHeight = getPrunedHeight(tx)
+		return nil
+	})
+
+	return prunedHeight, err
+}
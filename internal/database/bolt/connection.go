@@ -0,0 +1,82 @@
+package db_bolt
+
+import (
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// every bucket is an explicit index, keys sort as big endian bytes
+var (
+	headersBucket           = []byte("block_headers")       //block id -> header with the miner signature
+	blocksBucket            = []byte("blocks")              //block id -> height, in active chain and chain weight
+	blockTransactionsBucket = []byte("blocks_transactions") //block id -> ids of the block's transactions in order
+	transactionsBucket      = []byte("transactions")        //transaction id -> transaction
+	activeChainBucket       = []byte("active_chain")        //height -> block id of the active chain
+	voterBlocksBucket       = []byte("voters_blocks")       //voter public key, block id -> transaction id, for blocks of every branch
+	blockVotesBucket        = []byte("blocks_votes")        //block id -> candidate ids and votes of the block's transactions
+	tallyBucket             = []byte("tally")               //candidate id -> votes in the active chain
+	tallySnapshotsBucket    = []byte("tally_snapshots")     //height -> block id, hash, candidate ids and votes of the tally up to the block
+	minerCreditsBucket      = []byte("miner_credits")       //miner public key -> blocks and votes in the active chain
+	prunedBlocksBucket      = []byte("pruned_blocks")       //block id -> empty, for blocks whose transactions were deleted
+	metaBucket              = []byte("meta")                //active chain tip id and pruned height
+)
+
+var allBuckets = [][]byte{
+	headersBucket,
+	blocksBucket,
+	blockTransactionsBucket,
+	transactionsBucket,
+	activeChainBucket,
+	voterBlocksBucket,
+	blockVotesBucket,
+	tallyBucket,
+	tallySnapshotsBucket,
+	minerCreditsBucket,
+	prunedBlocksBucket,
+	metaBucket,
+}
+
+var activeChainTipKey = []byte("active_chain_tip")
+var prunedHeightKey = []byte("pruned_height")
+
+func GetDatabaseConnection(dbFile string) (*bolt.DB, error) {
+	db, err := bolt.Open(dbFile, 0600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.Update(createBuckets)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return db, nil
+}
+
+func CloseDatabaseConnection(db *bolt.DB) error {
+	return db.Close()
+}
+
+func ResetDatabase(db *bolt.DB) error {
+	return db.Update(func(tx *bolt.Tx) error {
+		for _, bucket := range allBuckets {
+			if err := tx.DeleteBucket(bucket); err != nil && err != bolt.ErrBucketNotFound {
+				return err
+			}
+		}
+
+		return createBuckets(tx)
+	})
+}
+
+func createBuckets(tx *bolt.Tx) error {
+	for _, bucket := range allBuckets {
+		if _, err := tx.CreateBucketIfNotExists(bucket); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,210 @@
+package db_bolt
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+
+	repositories "github.com/nivschuman/VotingBlockchain/internal/database/repositories"
+	models "github.com/nivschuman/VotingBlockchain/internal/models"
+	bolt "go.etcd.io/bbolt"
+)
+
+var ErrNotFound = errors.New("record not found")
+
+type blockRecord struct {
+	Height        uint64
+	InActiveChain bool
+	ChainWeight   *big.Int //weight of the chain up to the block
+}
+
+func (record *blockRecord) AsBytes() []byte {
+	buf := new(bytes.Buffer)
+
+	binary.Write(buf, binary.BigEndian, record.Height)
+	binary.Write(buf, binary.BigEndian, record.InActiveChain)
+	buf.Write(record.ChainWeight.Bytes())
+
+	return buf.Bytes()
+}
+
+func blockRecordFromBytes(b []byte) (*blockRecord, error) {
+	if len(b) < 9 {
+		return nil, fmt.Errorf("block record of %d bytes is too short", len(b))
+	}
+
+	return &blockRecord{
+		Height:        binary.BigEndian.Uint64(b[:8]),
+		InActiveChain: b[8] == 1,
+		ChainWeight:   new(big.Int).SetBytes(b[9:]),
+	}, nil
+}
+
+func heightKey(height uint64) []byte {
+	return binary.BigEndian.AppendUint64(nil, height)
+}
+
+func candidateKey(candidateId uint32) []byte {
+	return binary.BigEndian.AppendUint32(nil, candidateId)
+}
+
+// voterPrefix is length prefixed, so the keys of a voter never share a prefix with a longer key
+func voterPrefix(voterPublicKey []byte) []byte {
+	return append([]byte{byte(len(voterPublicKey))}, voterPublicKey...)
+}
+
+func voterBlockKey(voterPublicKey []byte, blockId []byte) []byte {
+	return append(voterPrefix(voterPublicKey), blockId...)
+}
+
+func getBlockRecord(tx *bolt.Tx, blockId []byte) (*blockRecord, error) {
+	value := tx.Bucket(blocksBucket).Get(blockId)
+	if value == nil {
+		return nil, fmt.Errorf("block %x: %w", blockId, ErrNotFound)
+	}
+
+	return blockRecordFromBytes(value)
+}
+
+func putBlockRecord(tx *bolt.Tx, blockId []byte, record *blockRecord) error {
+	return tx.Bucket(blocksBucket).Put(blockId, record.AsBytes())
+}
+
+// the header is stored as a block without transactions, which keeps the miner signature
+func getBlockHeader(tx *bolt.Tx, blockId []byte) (*models.BlockHeader, error) {
+	value := tx.Bucket(headersBucket).Get(blockId)
+	if value == nil {
+		return nil, fmt.Errorf("block header %x: %w", blockId, ErrNotFound)
+	}
+
+	block, err := models.BlockFromBytes(value)
+	if err != nil {
+		return nil, err
+	}
+
+	return &block.Header, nil
+}
+
+func putBlockHeader(tx *bolt.Tx, blockHeader *models.BlockHeader) error {
+	headerOnly := &models.Block{Header: *blockHeader}
+	return tx.Bucket(headersBucket).Put(blockHeader.Id, headerOnly.AsBytes())
+}
+
+func getBlockTransactionIds(tx *bolt.Tx, blockId []byte) [][]byte {
+	value := tx.Bucket(blockTransactionsBucket).Get(blockId)
+
+	ids := make([][]byte, 0, len(value)/32)
+	for offset := 0; offset+32 <= len(value); offset += 32 {
+		ids = append(ids, bytes.Clone(value[offset:offset+32]))
+	}
+
+	return ids
+}
+
+func getTransaction(tx *bolt.Tx, transactionId []byte) (*models.Transaction, error) {
+	value := tx.Bucket(transactionsBucket).Get(transactionId)
+	if value == nil {
+		return nil, fmt.Errorf("transaction %x: %w", transactionId, ErrNotFound)
+	}
+
+	return models.TransactionFromBytes(bytes.Clone(value))
+}
+
+func getBlock(tx *bolt.Tx, blockId []byte) (*models.Block, error) {
+	blockHeader, err := getBlockHeader(tx, blockId)
+	if err != nil {
+		return nil, err
+	}
+
+	if isPruned(tx, blockId) {
+		return nil, fmt.Errorf("block %x: %w", blockId, repositories.ErrBlockPruned)
+	}
+
+	transactionIds := getBlockTransactionIds(tx, blockId)
+	transactions := make([]*models.Transaction, len(transactionIds))
+	for i, transactionId := range transactionIds {
+		transactions[i], err = getTransaction(tx, transactionId)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &models.Block{Header: *blockHeader, Transactions: transactions}, nil
+}
+
+func isPruned(tx *bolt.Tx, blockId []byte) bool {
+	return tx.Bucket(prunedBlocksBucket).Get(blockId) != nil
+}
+
+// getPrunedHeight is the height of the highest pruned block, 0 when no block was pruned
+func getPrunedHeight(tx *bolt.Tx) uint64 {
+	value := tx.Bucket(metaBucket).Get(prunedHeightKey)
+	if len(value) != 8 {
+		return 0
+	}
+
+	return binary.BigEndian.Uint64(value)
+}
+
+func getActiveChainBlockId(tx *bolt.Tx, height uint64) []byte {
+	return bytes.Clone(tx.Bucket(activeChainBucket).Get(heightKey(height)))
+}
+
+// isAncestor tells if a block is in the chain ending at chainTipId, the walk back stops once it reaches the active chain
+func isAncestor(tx *bolt.Tx, chainTipId []byte, blockId []byte) (bool, error) {
+	blockRecord, err := getBlockRecord(tx, blockId)
+	if err != nil {
+		return false, err
+	}
+
+	currentId := chainTipId
+	for currentId != nil {
+		currentRecord, err := getBlockRecord(tx, currentId)
+		if err != nil {
+			return false, err
+		}
+
+		if currentRecord.Height < blockRecord.Height {
+			return false, nil
+		}
+
+		if currentRecord.InActiveChain {
+			return bytes.Equal(getActiveChainBlockId(tx, blockRecord.Height), blockId), nil
+		}
+
+		if currentRecord.Height == blockRecord.Height {
+			return bytes.Equal(currentId, blockId), nil
+		}
+
+		currentHeader, err := getBlockHeader(tx, currentId)
+		if err != nil {
+			return false, err
+		}
+
+		currentId = currentHeader.PreviousBlockId
+	}
+
+	return false, nil
+}
+
+// addToCounter adds delta to a big endian counter, counters that drop to zero are removed
+func addToCounter(bucket *bolt.Bucket, key []byte, offset int, delta int64) error {
+	value := bytes.Clone(bucket.Get(key))
+	if len(value) < offset+8 {
+		value = append(value, make([]byte, offset+8-len(value))...)
+	}
+
+	counter := int64(binary.BigEndian.Uint64(value[offset:offset+8])) + delta
+	if counter < 0 {
+		return fmt.Errorf("counter %x dropped below zero", key)
+	}
+	binary.BigEndian.PutUint64(value[offset:offset+8], uint64(counter))
+
+	if bytes.Equal(value, make([]byte, len(value))) {
+		return bucket.Delete(key)
+	}
+
+	return bucket.Put(key, value)
+}
@@ -0,0 +1,169 @@
+package db_bolt
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+
+	models "github.com/nivschuman/VotingBlockchain/internal/models"
+	voters "github.com/nivschuman/VotingBlockchain/internal/voters"
+	bolt "go.etcd.io/bbolt"
+)
+
+// a result is stored as the candidate id followed by its votes
+const resultLength = 4 + 8
+
+func resultsAsBytes(results []*voters.VotingResult) []byte {
+	b := make([]byte, 0, resultLength*len(results))
+	for _, result := range results {
+		b = binary.BigEndian.AppendUint32(b, result.CandidateId)
+		b = binary.BigEndian.AppendUint64(b, uint64(result.Votes))
+	}
+
+	return b
+}
+
+func resultsFromBytes(b []byte) ([]*voters.VotingResult, error) {
+	if len(b)%resultLength != 0 {
+		return nil, fmt.Errorf("voting results of %d bytes aren't whole", len(b))
+	}
+
+	results := make([]*voters.VotingResult, 0, len(b)/resultLength)
+	for offset := 0; offset < len(b); offset += resultLength {
+		results = append(results, &voters.VotingResult{
+			CandidateId: binary.BigEndian.Uint32(b[offset : offset+4]),
+			Votes:       int(binary.BigEndian.Uint64(b[offset+4 : offset+resultLength])),
+		})
+	}
+
+	return results, nil
+}
+
+func countVotes(transactions []*models.Transaction) []*voters.VotingResult {
+	results := make([]*voters.VotingResult, len(transactions))
+	for i, transaction := range transactions {
+		results[i] = &voters.VotingResult{CandidateId: transaction.CandidateId, Votes: 1}
+	}
+
+	return voters.AddResults(nil, results)
+}
+
+// putBlockVotes keeps the votes of a block per candidate, so the tally at a height doesn't need the transactions of pruned blocks
+func putBlockVotes(tx *bolt.Tx, blockId []byte, transactions []*models.Transaction) error {
+	return tx.Bucket(blockVotesBucket).Put(blockId, resultsAsBytes(countVotes(transactions)))
+}
+
+// getBlockVotes counts the transactions of blocks stored before their votes were kept
+func getBlockVotes(tx *bolt.Tx, blockId []byte) ([]*voters.VotingResult, error) {
+	value := tx.Bucket(blockVotesBucket).Get(blockId)
+	if value != nil {
+		return resultsFromBytes(value)
+	}
+
+	transactionIds := getBlockTransactionIds(tx, blockId)
+	transactions := make([]*models.Transaction, len(transactionIds))
+	for i, transactionId := range transactionIds {
+		transaction, err := getTransaction(tx, transactionId)
+		if err != nil {
+			return nil, err
+		}
+
+		transactions[i] = transaction
+	}
+
+	return countVotes(transactions), nil
+}
+
+func getTally(tx *bolt.Tx) []*voters.VotingResult {
+	var results []*voters.VotingResult
+
+	tx.Bucket(tallyBucket).ForEach(func(candidateId []byte, votes []byte) error {
+		results = append(results, &voters.VotingResult{
+			CandidateId: binary.BigEndian.Uint32(candidateId),
+			Votes:       int(binary.BigEndian.Uint64(votes)),
+		})
+		return nil
+	})
+
+	return results
+}
+
+// a snapshot is stored as the block id, the snapshot hash and the results
+func putTallySnapshot(tx *bolt.Tx, snapshot *voters.TallySnapshot) error {
+	value := make([]byte, 0, len(snapshot.BlockId)+len(snapshot.Hash)+resultLength*len(snapshot.Results))
+	value = append(value, snapshot.BlockId...)
+	value = append(value, snapshot.Hash...)
+	value = append(value, resultsAsBytes(snapshot.Results)...)
+
+	return tx.Bucket(tallySnapshotsBucket).Put(heightKey(snapshot.Height), value)
+}
+
+func tallySnapshotFromBytes(height uint64, b []byte) (*voters.TallySnapshot, error) {
+	if len(b) < 64 {
+		return nil, fmt.Errorf("tally snapshot of %d bytes is too short", len(b))
+	}
+
+	results, err := resultsFromBytes(b[64:])
+	if err != nil {
+		return nil, err
+	}
+
+	return &voters.TallySnapshot{
+		Height:  height,
+		BlockId: bytes.Clone(b[:32]),
+		Results: results,
+		Hash:    bytes.Clone(b[32:64]),
+	}, nil
+}
+
+// getLatestTallySnapshot returns the snapshot taken at the highest snapshot height up to height, nil when there is none
+func getLatestTallySnapshot(tx *bolt.Tx, height uint64) (*voters.TallySnapshot, error) {
+	cursor := tx.Bucket(tallySnapshotsBucket).Cursor()
+
+	key, value := cursor.Seek(heightKey(height))
+	if key == nil {
+		key, value = cursor.Last()
+	} else if binary.BigEndian.Uint64(key) > height {
+		key, value = cursor.Prev()
+	}
+
+	if key == nil {
+		return nil, nil
+	}
+
+	return tallySnapshotFromBytes(binary.BigEndian.Uint64(key), value)
+}
+
+// rebuildTallySnapshots takes the snapshots again walking the active chain up from the genesis block
+func (blockRepository *BlockRepositoryImpl) rebuildTallySnapshots(tx *bolt.Tx) error {
+	if err := tx.DeleteBucket(tallySnapshotsBucket); err != nil && err != bolt.ErrBucketNotFound {
+		return err
+	}
+
+	if _, err := tx.CreateBucket(tallySnapshotsBucket); err != nil {
+		return err
+	}
+
+	var results []*voters.VotingResult
+	cursor := tx.Bucket(activeChainBucket).Cursor()
+
+	for key, blockId := cursor.First(); key != nil; key, blockId = cursor.Next() {
+		blockVotes, err := getBlockVotes(tx, blockId)
+		if err != nil {
+			return err
+		}
+
+		results = voters.AddResults(results, blockVotes)
+
+		height := binary.BigEndian.Uint64(key)
+		if !blockRepository.chainParams.Election.IsTallySnapshotHeight(height) {
+			continue
+		}
+
+		if err := putTallySnapshot(tx, voters.NewTallySnapshot(height, bytes.Clone(blockId), results)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,360 @@
+package db_bolt
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+
+	models "github.com/nivschuman/VotingBlockchain/internal/models"
+	structures "github.com/nivschuman/VotingBlockchain/internal/structures"
+	voters "github.com/nivschuman/VotingBlockchain/internal/voters"
+	bolt "go.etcd.io/bbolt"
+)
+
+// TransactionRepositoryImpl answers voter queries from the voter index, without walking the chain
+type TransactionRepositoryImpl struct {
+	db *bolt.DB
+}
+
+func NewTransactionRepositoryImpl(db *bolt.DB) *TransactionRepositoryImpl {
+	return &TransactionRepositoryImpl{db: db}
+}
+
+// TransactionsValidInChain looks up the blocks holding votes of the voters, a vote counts if its block is an ancestor of the chain tip
+func (repo *TransactionRepositoryImpl) TransactionsValidInChain(chainTipId []byte, transactions []*models.Transaction) (bool, error) {
+	if chainTipId == nil {
+		return true, nil
+	}
+
+	voterPublicKeys := structures.NewBytesSet()
+	for _, tx := range transactions {
+		voterPublicKeys.Add(tx.VoterPublicKey)
+	}
+
+	valid := true
+	err := repo.db.View(func(tx *bolt.Tx) error {
+		for _, voterPublicKey := range voterPublicKeys.ToBytesSlice() {
+			voted, err := voterBlockMatches(tx, voterPublicKey, func(blockId []byte) (bool, error) {
+				return isAncestor(tx, chainTipId, blockId)
+			})
+
+			if err != nil {
+				return err
+			}
+
+			if voted {
+				valid = false
+				return nil
+			}
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return false, err
+	}
+
+	return valid, nil
+}
+
+func (repo *TransactionRepositoryImpl) GetTransaction(txId []byte) (*models.Transaction, error) {
+	var transaction *models.Transaction
+
+	err := repo.db.View(func(tx *bolt.Tx) error {
+		var err error
+		transaction, err = getTransaction(tx, txId)
+		return err
+	})
+
+	return transaction, err
+}
+
+func (repo *TransactionRepositoryImpl) GetTransactions(ids *structures.BytesSet) ([]*models.Transaction, error) {
+	transactions := make([]*models.Transaction, 0, ids.Length())
+
+	err := repo.db.View(func(tx *bolt.Tx) error {
+		for _, id := range ids.ToBytesSlice() {
+			if tx.Bucket(transactionsBucket).Get(id) == nil {
+				continue
+			}
+
+			transaction, err := getTransaction(tx, id)
+			if err != nil {
+				return err
+			}
+
+			transactions = append(transactions, transaction)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return transactions, nil
+}
+
+func (repo *TransactionRepositoryImpl) GetMissingTransactionIds(ids *structures.BytesSet) (*structures.BytesSet, error) {
+	missingIds := structures.NewBytesSet()
+
+	err := repo.db.View(func(tx *bolt.Tx) error {
+		for _, id := range ids.ToBytesSlice() {
+			if tx.Bucket(transactionsBucket).Get(id) == nil {
+				missingIds.Add(id)
+			}
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return missingIds, nil
+}
+
+func (repo *TransactionRepositoryImpl) InsertIfNotExists(transaction *models.Transaction) error {
+	return repo.db.Update(func(tx *bolt.Tx) error {
+		return putTransactionIfNotExists(tx, transaction)
+	})
+}
+
+func putTransactionIfNotExists(tx *bolt.Tx, transaction *models.Transaction) error {
+	transactions := tx.Bucket(transactionsBucket)
+	if transactions.Get(transaction.Id) != nil {
+		return nil
+	}
+
+	return transactions.Put(transaction.Id, transaction.AsBytes())
+}
+
+func (repo *TransactionRepositoryImpl) TransactionValidInActiveChain(transaction *models.Transaction) (bool, error) {
+	voted := false
+
+	err := repo.db.View(func(tx *bolt.Tx) error {
+		var err error
+		voted, err = voterBlockMatches(tx, transaction.VoterPublicKey, func(blockId []byte) (bool, error) {
+			return blockInActiveChain(tx, blockId)
+		})
+		return err
+	})
+
+	if err != nil {
+		return false, err
+	}
+
+	return !voted, nil
+}
+
+func (repo *TransactionRepositoryImpl) GetActiveChainVote(voterPublicKey []byte) (*models.Transaction, error) {
+	var transaction *models.Transaction
+
+	err := repo.db.View(func(tx *bolt.Tx) error {
+		cursor := tx.Bucket(voterBlocksBucket).Cursor()
+		prefix := voterPrefix(voterPublicKey)
+
+		for key, transactionId := cursor.Seek(prefix); key != nil && bytes.HasPrefix(key, prefix); key, transactionId = cursor.Next() {
+			inActiveChain, err := blockInActiveChain(tx, key[len(prefix):])
+			if err != nil {
+				return err
+			}
+
+			if inActiveChain {
+				transaction, err = getTransaction(tx, transactionId)
+				return err
+			}
+		}
+
+		return fmt.Errorf("active chain vote of voter %x: %w", voterPublicKey, ErrNotFound)
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return transaction, nil
+}
+
+// GetConfirmedTransactionsPaged walks the active chain from the tip, the total is the sum of the tally
+func (repo *TransactionRepositoryImpl) GetConfirmedTransactionsPaged(offset int, limit int) ([]*models.Transaction, int, error) {
+	transactions := make([]*models.Transaction, 0, limit)
+	total := 0
+
+	err := repo.db.View(func(tx *bolt.Tx) error {
+		err := tx.Bucket(tallyBucket).ForEach(func(_ []byte, votes []byte) error {
+			total += int(binary.BigEndian.Uint64(votes))
+			return nil
+		})
+
+		if err != nil {
+			return err
+		}
+
+		skipped := 0
+		cursor := tx.Bucket(activeChainBucket).Cursor()
+
+		for key, blockId := cursor.Last(); key != nil && len(transactions) < limit; key, blockId = cursor.Prev() {
+			transactionIds := getBlockTransactionIds(tx, blockId)
+
+			if skipped+len(transactionIds) <= offset {
+				skipped += len(transactionIds)
+				continue
+			}
+
+			for _, transactionId := range transactionIds {
+				if skipped < offset {
+					skipped++
+					continue
+				}
+
+				if len(transactions) >= limit {
+					break
+				}
+
+				transaction, err := getTransaction(tx, transactionId)
+				if err != nil {
+					return err
+				}
+
+				transactions = append(transactions, transaction)
+			}
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return transactions, total, nil
+}
+
+func (repo *TransactionRepositoryImpl) GetVotersInActiveChain(voterPublicKeys *structures.BytesSet) (*structures.BytesSet, error) {
+	voters := structures.NewBytesSet()
+
+	err := repo.db.View(func(tx *bolt.Tx) error {
+		for _, voterPublicKey := range voterPublicKeys.ToBytesSlice() {
+			voted, err := voterBlockMatches(tx, voterPublicKey, func(blockId []byte) (bool, error) {
+				return blockInActiveChain(tx, blockId)
+			})
+
+			if err != nil {
+				return err
+			}
+
+			if voted {
+				voters.Add(voterPublicKey)
+			}
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return voters, nil
+}
+
+// GetVotingResults reads the tally index, candidates are ordered by id
+func (repo *TransactionRepositoryImpl) GetVotingResults() ([]*voters.VotingResult, error) {
+	var results []*voters.VotingResult
+
+	err := repo.db.View(func(tx *bolt.Tx) error {
+		results = getTally(tx)
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// GetVotingResultsAtHeight starts from the latest tally snapshot up to height and adds the votes of the active chain blocks after it
+func (repo *TransactionRepositoryImpl) GetVotingResultsAtHeight(height uint64) ([]*voters.VotingResult, error) {
+	var results []*voters.VotingResult
+
+	err := repo.db.View(func(tx *bolt.Tx) error {
+		snapshot, err := getLatestTallySnapshot(tx, height)
+		if err != nil {
+			return err
+		}
+
+		fromHeight := uint64(0)
+		if snapshot != nil {
+			results = snapshot.Results
+			fromHeight = snapshot.Height + 1
+		}
+
+		cursor := tx.Bucket(activeChainBucket).Cursor()
+		for key, blockId := cursor.Seek(heightKey(fromHeight)); key != nil && binary.BigEndian.Uint64(key) <= height; key, blockId = cursor.Next() {
+			blockVotes, err := getBlockVotes(tx, blockId)
+			if err != nil {
+				return err
+			}
+
+			results = voters.AddResults(results, blockVotes)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return voters.SortedResults(results), nil
+}
+
+func (repo *TransactionRepositoryImpl) GetTallySnapshot(height uint64) (*voters.TallySnapshot, error) {
+	var snapshot *voters.TallySnapshot
+
+	err := repo.db.View(func(tx *bolt.Tx) error {
+		value := tx.Bucket(tallySnapshotsBucket).Get(heightKey(height))
+		if value == nil {
+			return fmt.Errorf("tally snapshot at height %d: %w", height, ErrNotFound)
+		}
+
+		var err error
+		snapshot, err = tallySnapshotFromBytes(height, value)
+		return err
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return snapshot, nil
+}
+
+// voterBlockMatches tells if any block holding a vote of the voter matches
+func voterBlockMatches(tx *bolt.Tx, voterPublicKey []byte, match func(blockId []byte) (bool, error)) (bool, error) {
+	cursor := tx.Bucket(voterBlocksBucket).Cursor()
+	prefix := voterPrefix(voterPublicKey)
+
+	for key, _ := cursor.Seek(prefix); key != nil && bytes.HasPrefix(key, prefix); key, _ = cursor.Next() {
+		matched, err := match(key[len(prefix):])
+		if err != nil || matched {
+			return matched, err
+		}
+	}
+
+	return false, nil
+}
+
+func blockInActiveChain(tx *bolt.Tx, blockId []byte) (bool, error) {
+	record, err := getBlockRecord(tx, blockId)
+	if err != nil {
+		return false, err
+	}
+
+	return record.InActiveChain, nil
+}
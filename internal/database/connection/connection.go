@@ -1,35 +1,88 @@
 package db_connection
 
 import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 
 	config "github.com/nivschuman/VotingBlockchain/internal/database/config"
-	models "github.com/nivschuman/VotingBlockchain/internal/database/models"
+	migrations "github.com/nivschuman/VotingBlockchain/internal/database/migrations"
 )
 
-var modelsToMigrate = []any{
-	&models.TransactionDB{},
-	&models.BlockDB{},
-	&models.BlockHeaderDB{},
-	&models.TransactionBlockDB{},
-	&models.AddressDB{},
-}
-
+// GetDatabaseConnection opens the database and migrates it to the latest schema, an existing database is backed up first
 func GetDatabaseConnection(dbFile string) (*gorm.DB, error) {
-	db, err := gorm.Open(sqlite.Open(dbFile), config.GetGormConfig())
+	db, err := OpenDatabaseConnection(dbFile)
 	if err != nil {
 		return nil, err
 	}
 
-	err = db.AutoMigrate(modelsToMigrate...)
-	if err != nil {
+	if err := MigrateDatabase(db, dbFile); err != nil {
+		CloseDatabaseConnection(db)
 		return nil, err
 	}
 
 	return db, nil
 }
 
+// OpenDatabaseConnection opens the database without migrating it
+func OpenDatabaseConnection(dbFile string) (*gorm.DB, error) {
+	return gorm.Open(sqlite.Open(dbFile), config.GetGormConfig())
+}
+
+// MigrateDatabase applies the pending migrations, when the database already has tables it's backed up next to dbFile before
+func MigrateDatabase(db *gorm.DB, dbFile string) error {
+	pending, err := migrations.PendingMigrations(db)
+	if err != nil {
+		return err
+	}
+
+	if len(pending) == 0 {
+		return nil
+	}
+
+	tables, err := db.Migrator().GetTables()
+	if err != nil {
+		return err
+	}
+
+	if len(tables) > 0 {
+		version, err := migrations.CurrentVersion(db)
+		if err != nil {
+			return err
+		}
+
+		backupFile := nextBackupFile(dbFile, version)
+		if err := migrations.Backup(db, backupFile); err != nil {
+			return fmt.Errorf("failed to back up database before migrating: %w", err)
+		}
+
+		log.Printf("|Database| Backed up schema version %d to %s", version, backupFile)
+	}
+
+	applied, err := migrations.Migrate(db)
+	for _, migration := range applied {
+		log.Printf("|Database| Applied migration %d: %s", migration.Version, migration.Name)
+	}
+
+	return err
+}
+
+// nextBackupFile names a backup that doesn't exist yet, a retry after a failed migration keeps the earlier backups
+func nextBackupFile(dbFile string, version uint) string {
+	backupFile := fmt.Sprintf("%s.v%d.bak", dbFile, version)
+	for i := 1; ; i++ {
+		if _, err := os.Stat(backupFile); err != nil {
+			return backupFile
+		}
+
+		backupFile = fmt.Sprintf("%s.v%d.%d.bak", dbFile, version, i)
+	}
+}
+
 func CloseDatabaseConnection(db *gorm.DB) error {
 	sqlDB, err := db.DB()
 	if err != nil {
@@ -39,11 +92,24 @@ func CloseDatabaseConnection(db *gorm.DB) error {
 	return sqlDB.Close()
 }
 
+// ResetDatabase drops every table and migrates the empty database
 func ResetDatabase(db *gorm.DB) error {
-	err := db.Migrator().DropTable(modelsToMigrate...)
+	tables, err := db.Migrator().GetTables()
 	if err != nil {
 		return err
 	}
 
-	return db.AutoMigrate(modelsToMigrate...)
+	for _, table := range tables {
+		//tables of sqlite itself can't be dropped
+		if strings.HasPrefix(table, "sqlite_") {
+			continue
+		}
+
+		if err := db.Migrator().DropTable(table); err != nil {
+			return err
+		}
+	}
+
+	_, err = migrations.Migrate(db)
+	return err
 }
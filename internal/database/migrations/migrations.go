@@ -0,0 +1,209 @@
+package db_migrations
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	models "github.com/nivschuman/VotingBlockchain/internal/database/models"
+	"gorm.io/gorm"
+)
+
+var ErrNewerSchema = errors.New("database schema is newer than this node")
+
+type Migration struct {
+	Version uint
+	Name    string
+	Up      func(tx *gorm.DB) error
+}
+
+// migrations are numbered from 1 and never change once released, schema changes are added as new migrations
+var migrations = []Migration{
+	{
+		Version: 1,
+		Name:    "create chain and peer tables",
+		Up: func(tx *gorm.DB) error {
+			//databases created before versioning already have these tables, AutoMigrate only adds what's missing
+			return tx.AutoMigrate(
+				&transactionV1{},
+				&blockV1{},
+				&blockHeaderV1{},
+				&transactionBlockV1{},
+				&addressV1{},
+				&conflictingVoteV1{},
+			)
+		},
+	},
+	{
+		Version: 2,
+		Name:    "index spent voters of the active chain",
+		Up: func(tx *gorm.DB) error {
+			if err := tx.AutoMigrate(&spentVoterV2{}); err != nil {
+				return err
+			}
+
+			if err := tx.Exec("DELETE FROM spent_voters").Error; err != nil {
+				return err
+			}
+
+			return tx.Exec(`INSERT INTO spent_voters (voter_public_key, transaction_id, block_header_id, height)
+				SELECT t.voter_public_key, t.id, b.block_header_id, b.height
+				FROM transactions t
+				JOIN transactions_blocks tb ON tb.transaction_id = t.id
+				JOIN blocks b ON b.block_header_id = tb.block_header_id
+				WHERE b.in_active_chain = ?`, true).Error
+		},
+	},
+	{
+		Version: 3,
+		Name:    "keep the tally of pruned blocks",
+		Up: func(tx *gorm.DB) error {
+			if err := addColumn(tx, &blockV3{}, "Pruned"); err != nil {
+				return err
+			}
+
+			if err := addColumn(tx, &spentVoterV3{}, "CandidateId"); err != nil {
+				return err
+			}
+
+			return tx.Exec(`UPDATE spent_voters
+				SET candidate_id = (SELECT t.candidate_id FROM transactions t WHERE t.id = spent_voters.transaction_id)
+				WHERE EXISTS (SELECT 1 FROM transactions t WHERE t.id = spent_voters.transaction_id)`).Error
+		},
+	},
+	{
+		Version: 4,
+		Name:    "count the tally incrementally",
+		Up: func(tx *gorm.DB) error {
+			if err := tx.AutoMigrate(&tallyV4{}, &tallySnapshotV4{}, &tallySnapshotVoteV4{}); err != nil {
+				return err
+			}
+
+			if err := tx.Exec("DELETE FROM tally").Error; err != nil {
+				return err
+			}
+
+			//snapshots start with the next snapshot height, reindexing takes the ones of the stored chain
+			return tx.Exec(`INSERT INTO tally (candidate_id, votes)
+				SELECT candidate_id, COUNT(*) FROM spent_voters GROUP BY candidate_id`).Error
+		},
+	},
+	{
+		Version: 5,
+		Name:    "keep one conflicting vote per voter",
+		Up: func(tx *gorm.DB) error {
+			if err := addColumn(tx, &conflictingVoteV5{}, "Conflicts"); err != nil {
+				return err
+			}
+
+			err := tx.Exec(`UPDATE conflicting_votes
+				SET conflicts = (SELECT COUNT(*) FROM conflicting_votes c WHERE c.voter_public_key = conflicting_votes.voter_public_key)`).Error
+			if err != nil {
+				return err
+			}
+
+			return tx.Exec(`DELETE FROM conflicting_votes
+				WHERE id NOT IN (SELECT MIN(id) FROM conflicting_votes GROUP BY voter_public_key)`).Error
+		},
+	},
+}
+
+// addColumn adds the field's column unless an earlier build of the migration already did
+func addColumn(tx *gorm.DB, table any, field string) error {
+	if tx.Migrator().HasColumn(table, field) {
+		return nil
+	}
+
+	return tx.Migrator().AddColumn(table, field)
+}
+
+func LatestVersion() uint {
+	return migrations[len(migrations)-1].Version
+}
+
+// CurrentVersion is the version of the last applied migration, 0 for a database without a schema version
+func CurrentVersion(db *gorm.DB) (uint, error) {
+	if !db.Migrator().HasTable(&models.SchemaVersionDB{}) {
+		return 0, nil
+	}
+
+	var version uint
+	err := db.Model(&models.SchemaVersionDB{}).Select("COALESCE(MAX(version), 0)").Scan(&version).Error
+	if err != nil {
+		return 0, err
+	}
+
+	return version, nil
+}
+
+// PendingMigrations returns the migrations that aren't applied yet, it refuses a schema newer than the latest migration
+func PendingMigrations(db *gorm.DB) ([]Migration, error) {
+	version, err := CurrentVersion(db)
+	if err != nil {
+		return nil, err
+	}
+
+	if version > LatestVersion() {
+		return nil, fmt.Errorf("%w: schema version is %d, latest known version is %d", ErrNewerSchema, version, LatestVersion())
+	}
+
+	pending := make([]Migration, 0)
+	for _, migration := range migrations {
+		if migration.Version > version {
+			pending = append(pending, migration)
+		}
+	}
+
+	return pending, nil
+}
+
+// Migrate applies the pending migrations in order, each one in its own transaction along with its schema version
+func Migrate(db *gorm.DB) ([]Migration, error) {
+	return MigrateTo(db, LatestVersion())
+}
+
+// MigrateTo applies the pending migrations up to and including version
+func MigrateTo(db *gorm.DB, version uint) ([]Migration, error) {
+	pending, err := PendingMigrations(db)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, migration := range pending {
+		if migration.Version > version {
+			pending = pending[:i]
+			break
+		}
+	}
+
+	if err := db.AutoMigrate(&models.SchemaVersionDB{}); err != nil {
+		return nil, err
+	}
+
+	for i, migration := range pending {
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := migration.Up(tx); err != nil {
+				return err
+			}
+
+			schemaVersion := &models.SchemaVersionDB{
+				Version:   migration.Version,
+				Name:      migration.Name,
+				AppliedAt: time.Now(),
+			}
+
+			return tx.Create(schemaVersion).Error
+		})
+
+		if err != nil {
+			return pending[:i], fmt.Errorf("migration %d (%s) failed: %w", migration.Version, migration.Name, err)
+		}
+	}
+
+	return pending, nil
+}
+
+// Backup writes a consistent copy of the database to a new file
+func Backup(db *gorm.DB, backupFile string) error {
+	return db.Exec("VACUUM INTO ?", backupFile).Error
+}
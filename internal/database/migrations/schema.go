@@ -0,0 +1,164 @@
+package db_migrations
+
+import (
+	"time"
+
+	types "github.com/nivschuman/VotingBlockchain/internal/database/types"
+)
+
+// the tables as each migration creates them, frozen so that a schema version always means the same tables
+// the models in db_models follow the latest version and are never used by a migration
+
+// === version 1 ===
+
+type transactionV1 struct {
+	Id                  []byte `gorm:"primaryKey;column:id"`
+	Version             int32  `gorm:"column:version;not null"`
+	CandidateId         uint32 `gorm:"column:candidate_id;not null"`
+	VoterPublicKey      []byte `gorm:"column:voter_public_key;not null"`
+	GovernmentSignature []byte `gorm:"column:government_signature;not null"`
+	Signature           []byte `gorm:"column:signature;not null"`
+}
+
+type blockHeaderV1 struct {
+	Id             []byte `gorm:"primaryKey;column:id"`
+	Version        int32  `gorm:"column:version;not null"`
+	MerkleRoot     []byte `gorm:"column:merkle_root;not null"`
+	Timestamp      int64  `gorm:"column:timestamp;not null"`
+	NBits          uint32 `gorm:"column:nbits;not null"`
+	Nonce          uint64 `gorm:"column:nonce;not null"`
+	MinerPublicKey []byte `gorm:"column:miner_public_key;not null"`
+	MinerSignature []byte `gorm:"column:miner_signature"`
+
+	PreviousBlockHeaderId *[]byte        `gorm:"column:previous_block_header_id"`
+	PreviousBlockHeader   *blockHeaderV1 `gorm:"foreignKey:PreviousBlockHeaderId;references:Id;constraint:OnDelete:CASCADE"`
+}
+
+type blockV1 struct {
+	Height        uint64       `gorm:"column:height;not null"`
+	InActiveChain bool         `gorm:"column:in_active_chain;not null"`
+	ChainWeight   types.BigInt `gorm:"column:cumulative_work;not null"`
+
+	BlockHeaderId []byte        `gorm:"primaryKey;column:block_header_id"`
+	BlockHeader   blockHeaderV1 `gorm:"foreignKey:BlockHeaderId;references:Id"`
+}
+
+type transactionBlockV1 struct {
+	BlockHeaderId []byte `gorm:"primaryKey;column:block_header_id;not null"`
+	TransactionId []byte `gorm:"primaryKey;column:transaction_id;not null"`
+	Order         uint32 `gorm:"column:order;not null"`
+
+	Block       blockV1       `gorm:"foreignKey:BlockHeaderId;references:BlockHeaderId;constraint:OnDelete:RESTRICT"`
+	Transaction transactionV1 `gorm:"foreignKey:TransactionId;references:Id;constraint:OnDelete:RESTRICT"`
+}
+
+type addressV1 struct {
+	Ip         string     `gorm:"primaryKey;column:ip"`
+	Port       uint16     `gorm:"primaryKey;column:port"`
+	NodeType   uint32     `gorm:"column:node_type;not null"`
+	CreatedAt  *time.Time `gorm:"column:created_at;autoCreateTime"`
+	LastSeen   *time.Time `gorm:"column:last_seen"`
+	LastFailed *time.Time `gorm:"column:last_failed"`
+
+	Attempts             uint32     `gorm:"column:attempts;not null;default:0"`
+	NextAttempt          *time.Time `gorm:"column:next_attempt"`
+	LastDisconnectReason string     `gorm:"column:last_disconnect_reason"`
+}
+
+type conflictingVoteV1 struct {
+	Id                       uint64    `gorm:"primaryKey;autoIncrement;column:id"`
+	VoterPublicKey           []byte    `gorm:"column:voter_public_key;not null;index:idx_conflicting_votes_voter_public_key"`
+	TransactionId            []byte    `gorm:"column:transaction_id;not null;uniqueIndex:idx_conflicting_votes_pair"`
+	Transaction              []byte    `gorm:"column:transaction;not null"`
+	ConflictingTransactionId []byte    `gorm:"column:conflicting_transaction_id;not null;uniqueIndex:idx_conflicting_votes_pair"`
+	ConflictingTransaction   []byte    `gorm:"column:conflicting_transaction;not null"`
+	Source                   string    `gorm:"column:source;not null"`
+	DetectedAt               time.Time `gorm:"column:detected_at;not null"`
+}
+
+func (transactionV1) TableName() string {
+	return "transactions"
+}
+
+func (blockHeaderV1) TableName() string {
+	return "block_headers"
+}
+
+func (blockV1) TableName() string {
+	return "blocks"
+}
+
+func (transactionBlockV1) TableName() string {
+	return "transactions_blocks"
+}
+
+func (addressV1) TableName() string {
+	return "addresses"
+}
+
+func (conflictingVoteV1) TableName() string {
+	return "conflicting_votes"
+}
+
+// === version 2 ===
+
+type spentVoterV2 struct {
+	VoterPublicKey []byte `gorm:"primaryKey;column:voter_public_key"`
+	TransactionId  []byte `gorm:"column:transaction_id;not null"`
+	BlockHeaderId  []byte `gorm:"column:block_header_id;not null;index:idx_spent_voters_block_header_id"`
+	Height         uint64 `gorm:"column:height;not null;index:idx_spent_voters_height"`
+}
+
+func (spentVoterV2) TableName() string {
+	return "spent_voters"
+}
+
+// === version 3 ===
+
+type blockV3 struct {
+	blockV1
+	Pruned bool `gorm:"column:pruned;not null;default:false"`
+}
+
+type spentVoterV3 struct {
+	spentVoterV2
+	CandidateId uint32 `gorm:"column:candidate_id;not null;default:0"`
+}
+
+// === version 4 ===
+
+type tallyV4 struct {
+	CandidateId uint32 `gorm:"primaryKey;autoIncrement:false;column:candidate_id"`
+	Votes       int64  `gorm:"column:votes;not null"`
+}
+
+type tallySnapshotV4 struct {
+	Height        uint64 `gorm:"primaryKey;autoIncrement:false;column:height"`
+	BlockHeaderId []byte `gorm:"column:block_header_id;not null"`
+	Hash          []byte `gorm:"column:hash;not null"`
+}
+
+type tallySnapshotVoteV4 struct {
+	Height      uint64 `gorm:"primaryKey;autoIncrement:false;column:height"`
+	CandidateId uint32 `gorm:"primaryKey;autoIncrement:false;column:candidate_id"`
+	Votes       int64  `gorm:"column:votes;not null"`
+}
+
+func (tallyV4) TableName() string {
+	return "tally"
+}
+
+func (tallySnapshotV4) TableName() string {
+	return "tally_snapshots"
+}
+
+func (tallySnapshotVoteV4) TableName() string {
+	return "tally_snapshot_votes"
+}
+
+// === version 5 ===
+
+type conflictingVoteV5 struct {
+	conflictingVoteV1
+	Conflicts uint64 `gorm:"column:conflicts;not null;default:1"`
+}
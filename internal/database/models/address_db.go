@@ -9,6 +9,10 @@ type AddressDB struct {
 	CreatedAt  *time.Time `gorm:"column:created_at;autoCreateTime"` // Timestamp when the peer was first recorded
 	LastSeen   *time.Time `gorm:"column:last_seen"`                 // Timestamp of the last successful interaction with the address
 	LastFailed *time.Time `gorm:"column:last_failed"`               // Timestamp of the last failed attempt to interact with address
+
+	Attempts             uint32     `gorm:"column:attempts;not null;default:0"` // Number of failed attempts since the address was last seen
+	NextAttempt          *time.Time `gorm:"column:next_attempt"`                // Earliest time the address may be dialed again
+	LastDisconnectReason string     `gorm:"column:last_disconnect_reason"`      // Reason the last connection to the address was closed
 }
 
 func (AddressDB) TableName() string {
@@ -13,15 +13,17 @@ type BlockHeaderDB struct {
 	NBits          uint32 `gorm:"column:nbits;not null"`
 	Nonce          uint64 `gorm:"column:nonce;not null"`
 	MinerPublicKey []byte `gorm:"column:miner_public_key;not null"`
+	MinerSignature []byte `gorm:"column:miner_signature"`
 
 	PreviousBlockHeaderId *[]byte        `gorm:"column:previous_block_header_id"`
 	PreviousBlockHeader   *BlockHeaderDB `gorm:"foreignKey:PreviousBlockHeaderId;references:Id;constraint:OnDelete:CASCADE"`
 }
 
 type BlockDB struct {
-	Height         uint64       `gorm:"column:height;not null"`
-	InActiveChain  bool         `gorm:"column:in_active_chain;not null"`
-	CumulativeWork types.BigInt `gorm:"column:cumulative_work;not null"`
+	Height        uint64       `gorm:"column:height;not null"`
+	InActiveChain bool         `gorm:"column:in_active_chain;not null"`
+	ChainWeight   types.BigInt `gorm:"column:cumulative_work;not null"`      //weight of the chain up to the block, the cumulative work under proof of work
+	Pruned        bool         `gorm:"column:pruned;not null;default:false"` //the block's transactions were deleted, only the header is kept
 
 	BlockHeaderId []byte        `gorm:"primaryKey;column:block_header_id"`
 	BlockHeader   BlockHeaderDB `gorm:"foreignKey:BlockHeaderId;references:Id"`
@@ -0,0 +1,19 @@
+package db_models
+
+import "time"
+
+type ConflictingVoteDB struct {
+	Id                       uint64    `gorm:"primaryKey;autoIncrement;column:id"`
+	VoterPublicKey           []byte    `gorm:"column:voter_public_key;not null;index"`                                            // Public key of the voter that signed both votes
+	TransactionId            []byte    `gorm:"column:transaction_id;not null;uniqueIndex:idx_conflicting_votes_pair"`             // Id of the vote that lost the conflict
+	Transaction              []byte    `gorm:"column:transaction;not null"`                                                       // Serialized vote that lost the conflict
+	ConflictingTransactionId []byte    `gorm:"column:conflicting_transaction_id;not null;uniqueIndex:idx_conflicting_votes_pair"` // Id of the vote that was kept
+	ConflictingTransaction   []byte    `gorm:"column:conflicting_transaction;not null"`                                           // Serialized vote that was kept
+	Source                   string    `gorm:"column:source;not null"`                                                            // Where the kept vote was found
+	DetectedAt               time.Time `gorm:"column:detected_at;not null"`                                                       // Timestamp when the conflict was first detected
+	Conflicts                uint64    `gorm:"column:conflicts;not null;default:1"`                                               // Conflicting votes detected for the voter, only the first pair is kept
+}
+
+func (ConflictingVoteDB) TableName() string {
+	return "conflicting_votes"
+}
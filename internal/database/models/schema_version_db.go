@@ -0,0 +1,13 @@
+package db_models
+
+import "time"
+
+type SchemaVersionDB struct {
+	Version   uint      `gorm:"primaryKey;autoIncrement:false;column:version"` // Number of the applied migration
+	Name      string    `gorm:"column:name;not null"`                          // Description of the applied migration
+	AppliedAt time.Time `gorm:"column:applied_at;not null"`                    // Timestamp when the migration was applied
+}
+
+func (SchemaVersionDB) TableName() string {
+	return "schema_version"
+}
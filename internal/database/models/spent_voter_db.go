@@ -0,0 +1,13 @@
+package db_models
+
+type SpentVoterDB struct {
+	VoterPublicKey []byte `gorm:"primaryKey;column:voter_public_key"`     // Public key of a voter with a vote in the active chain
+	TransactionId  []byte `gorm:"column:transaction_id;not null"`         // Id of the confirmed vote
+	BlockHeaderId  []byte `gorm:"column:block_header_id;not null;index"`  // Id of the active chain block holding the vote
+	Height         uint64 `gorm:"column:height;not null;index"`           // Height of the block holding the vote
+	CandidateId    uint32 `gorm:"column:candidate_id;not null;default:0"` // Candidate of the vote, the tally is kept when blocks are pruned
+}
+
+func (SpentVoterDB) TableName() string {
+	return "spent_voters"
+}
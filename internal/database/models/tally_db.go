@@ -0,0 +1,30 @@
+package db_models
+
+type TallyDB struct {
+	CandidateId uint32 `gorm:"primaryKey;autoIncrement:false;column:candidate_id"` // Candidate with votes in the active chain
+	Votes       int64  `gorm:"column:votes;not null"`                              // Votes of the candidate in the active chain
+}
+
+type TallySnapshotDB struct {
+	Height        uint64 `gorm:"primaryKey;autoIncrement:false;column:height"` // Height of the active chain block the tally was taken at
+	BlockHeaderId []byte `gorm:"column:block_header_id;not null"`              // Id of the active chain block the tally was taken at
+	Hash          []byte `gorm:"column:hash;not null"`                         // Hash of the height, block id and votes of every candidate
+}
+
+type TallySnapshotVoteDB struct {
+	Height      uint64 `gorm:"primaryKey;autoIncrement:false;column:height"`       // Height of the snapshot
+	CandidateId uint32 `gorm:"primaryKey;autoIncrement:false;column:candidate_id"` // Candidate with votes up to the snapshot
+	Votes       int64  `gorm:"column:votes;not null"`                              // Votes of the candidate up to the snapshot
+}
+
+func (TallyDB) TableName() string {
+	return "tally"
+}
+
+func (TallySnapshotDB) TableName() string {
+	return "tally_snapshots"
+}
+
+func (TallySnapshotVoteDB) TableName() string {
+	return "tally_snapshot_votes"
+}
@@ -15,11 +15,16 @@ type AddressRepository interface {
 	AddressExists(address *networking_models.Address) (bool, error)
 	InsertIfNotExists(address *networking_models.Address) error
 	UpdateLastSeen(address *networking_models.Address, lastSeen *time.Time) error
-	UpdateLastFailed(address *networking_models.Address, lastFailed *time.Time) error
+	RecordFailedAttempt(address *networking_models.Address, failedAt *time.Time, backoff BackoffFunc) error
+	UpdateLastDisconnectReason(address *networking_models.Address, reason string) error
 	GetAddresses(limit int, excludedAddresses []*networking_models.Address) ([]*networking_models.Address, error)
+	GetDialableAddresses(limit int, excludedAddresses []*networking_models.Address, now *time.Time) ([]*networking_models.Address, error)
 	GetAddressesPaged(offset int, pageSize int, excludedAddresses []*networking_models.Address) ([]*db_models.AddressDB, int64, error)
 }
 
+// BackoffFunc gives the delay before an address may be dialed again after the given number of failed attempts
+type BackoffFunc func(attempts uint32) time.Duration
+
 type AddressRepositoryImpl struct {
 	db *gorm.DB
 }
@@ -60,19 +65,62 @@ func (repo *AddressRepositoryImpl) InsertIfNotExists(address *networking_models.
 func (repo *AddressRepositoryImpl) UpdateLastSeen(address *networking_models.Address, lastSeen *time.Time) error {
 	return repo.db.Model(&db_models.AddressDB{}).
 		Where("ip = ? AND port = ?", address.Ip.String(), address.Port).
-		Update("last_seen", lastSeen).Error
+		Updates(map[string]any{
+			"last_seen":    lastSeen,
+			"attempts":     0,
+			"next_attempt": nil,
+		}).Error
+}
+
+func (repo *AddressRepositoryImpl) RecordFailedAttempt(address *networking_models.Address, failedAt *time.Time, backoff BackoffFunc) error {
+	return repo.db.Transaction(func(tx *gorm.DB) error {
+		addressDB := &db_models.AddressDB{}
+		result := tx.Where("ip = ? AND port = ?", address.Ip.String(), address.Port).Find(addressDB)
+
+		if result.Error != nil {
+			return result.Error
+		}
+
+		if result.RowsAffected == 0 {
+			return nil
+		}
+
+		attempts := addressDB.Attempts + 1
+		nextAttempt := failedAt.Add(backoff(attempts))
+
+		return tx.Model(&db_models.AddressDB{}).
+			Where("ip = ? AND port = ?", address.Ip.String(), address.Port).
+			Updates(map[string]any{
+				"last_failed":  failedAt,
+				"attempts":     attempts,
+				"next_attempt": &nextAttempt,
+			}).Error
+	})
 }
 
-func (repo *AddressRepositoryImpl) UpdateLastFailed(address *networking_models.Address, lastFailed *time.Time) error {
+func (repo *AddressRepositoryImpl) UpdateLastDisconnectReason(address *networking_models.Address, reason string) error {
 	return repo.db.Model(&db_models.AddressDB{}).
 		Where("ip = ? AND port = ?", address.Ip.String(), address.Port).
-		Update("last_failed", lastFailed).Error
+		Update("last_disconnect_reason", reason).Error
 }
 
 func (repo *AddressRepositoryImpl) GetAddresses(limit int, excludedAddresses []*networking_models.Address) ([]*networking_models.Address, error) {
+	return repo.getAddresses(limit, excludedAddresses, nil)
+}
+
+func (repo *AddressRepositoryImpl) GetDialableAddresses(limit int, excludedAddresses []*networking_models.Address, now *time.Time) ([]*networking_models.Address, error) {
+	return repo.getAddresses(limit, excludedAddresses, now)
+}
+
+func (repo *AddressRepositoryImpl) getAddresses(limit int, excludedAddresses []*networking_models.Address, dialableAt *time.Time) ([]*networking_models.Address, error) {
 	whereClauses := []string{}
 	args := make([]any, 0)
 
+	if dialableAt != nil {
+		whereClauses = append(whereClauses, "(next_attempt IS NULL OR CAST(strftime('%s', next_attempt) AS INTEGER) <= ?)")
+		args = append(args, dialableAt.Unix())
+	}
+
 	if len(excludedAddresses) > 0 {
 		pairs := make([]string, len(excludedAddresses))
 		for i, addr := range excludedAddresses {
@@ -8,19 +8,28 @@ import (
 	"math/big"
 	"slices"
 	"sync"
-	"time"
 
+	chainparams "github.com/nivschuman/VotingBlockchain/internal/chainparams"
+	consensus "github.com/nivschuman/VotingBlockchain/internal/consensus"
 	db_models "github.com/nivschuman/VotingBlockchain/internal/database/models"
 	types "github.com/nivschuman/VotingBlockchain/internal/database/types"
 	difficulty "github.com/nivschuman/VotingBlockchain/internal/difficulty"
+	events "github.com/nivschuman/VotingBlockchain/internal/events"
 	mapping "github.com/nivschuman/VotingBlockchain/internal/mapping"
 	models "github.com/nivschuman/VotingBlockchain/internal/models"
 	structures "github.com/nivschuman/VotingBlockchain/internal/structures"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+var ErrCheckpointViolation = errors.New("checkpoint violation")
+var ErrReorgTooDeep = errors.New("reorganization too deep")
+var ErrBlockPruned = errors.New("block pruned")
+var ErrGenesisMismatch = errors.New("genesis block mismatch")
+
 type BlockRepository interface {
 	Initialize() error
+	CheckGenesis() error
 	GetNextWorkRequired(lastBlockId []byte) (uint32, error)
 	HaveBlock(blockId []byte) (bool, error)
 	BlockIsOrphan(block *models.Block) (bool, error)
@@ -31,33 +40,48 @@ type BlockRepository interface {
 	GetBlock(blockId []byte) (*models.Block, error)
 	GetBlocks(ids *structures.BytesSet) ([]*models.Block, error)
 	GetMissingBlockIds(ids *structures.BytesSet) (*structures.BytesSet, error)
-	GetBlockCumulativeWork(blockHeaderId []byte) (*big.Int, error)
+	GetBlockHeader(blockId []byte) (*models.BlockHeader, error)
+	GetBlockHeight(blockId []byte) (uint64, error)
+	GetBlockChainWeight(blockHeaderId []byte) (*big.Int, error)
 	InsertIfNotExists(block *models.Block) error
 	GenesisBlock() *models.Block
 	SetActiveChainTipId() error
 	GetActiveChainTipId() []byte
 	GetActiveBlocksPaged(searchText string, offset int, pageSize int, sortAsc bool) ([]*db_models.BlockDB, int64, error)
 	GetActiveChainHeight() (uint64, error)
+	GetMinerCredits(minerPublicKey []byte) (*models.MinerCredits, error)
+	GetMinerCreditsPaged(offset int, pageSize int) ([]*models.MinerCredits, int64, error)
+	Reindex() error
+	Prune(keepBlocks uint64) (uint64, error)
+	GetPrunedHeight() (uint64, error)
 }
 
 type BlockRepositoryImpl struct {
-	db                    *gorm.DB
-	transactionRepository TransactionRepository
+	db *gorm.DB
 
 	activeChainTipId      []byte
 	activeChainTipIdMutex sync.Mutex
+
+	eventBus        events.EventBus
+	chainParams     *chainparams.ChainParams
+	consensusEngine consensus.ConsensusEngine
 }
 
-func NewBlockRepositoryImpl(db *gorm.DB, transactionRepository TransactionRepository) *BlockRepositoryImpl {
+func NewBlockRepositoryImpl(db *gorm.DB, eventBus events.EventBus, chainParams *chainparams.ChainParams) *BlockRepositoryImpl {
 	return &BlockRepositoryImpl{
-		db:                    db,
-		transactionRepository: transactionRepository,
+		db:              db,
+		eventBus:        eventBus,
+		chainParams:     chainParams,
+		consensusEngine: consensus.NewConsensusEngine(chainParams),
 	}
 }
 
 func (repo *BlockRepositoryImpl) Initialize() error {
-	genesisBlock := repo.GenesisBlock()
-	err := repo.InsertIfNotExists(genesisBlock)
+	if err := repo.CheckGenesis(); err != nil {
+		return err
+	}
+
+	err := repo.InsertIfNotExists(repo.GenesisBlock())
 	if err != nil {
 		return err
 	}
@@ -65,6 +89,23 @@ func (repo *BlockRepositoryImpl) Initialize() error {
 	return repo.SetActiveChainTipId()
 }
 
+// CheckGenesis refuses a stored chain that starts at another genesis block, an empty database passes
+func (repo *BlockRepositoryImpl) CheckGenesis() error {
+	genesisBlock := repo.GenesisBlock()
+
+	var storedGenesis db_models.BlockDB
+	result := repo.db.Where("height = ?", 0).Limit(1).Find(&storedGenesis)
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected > 0 && !bytes.Equal(storedGenesis.BlockHeaderId, genesisBlock.Header.Id) {
+		return fmt.Errorf("%w: stored chain starts at %x, the election's genesis block is %x", ErrGenesisMismatch, storedGenesis.BlockHeaderId, genesisBlock.Header.Id)
+	}
+
+	return nil
+}
+
 func (repo *BlockRepositoryImpl) GetActiveChainHeight() (uint64, error) {
 	var maxHeight uint64
 	err := repo.db.Model(&db_models.BlockDB{}).
@@ -80,8 +121,10 @@ func (repo *BlockRepositoryImpl) GetActiveChainHeight() (uint64, error) {
 }
 
 func (repo *BlockRepositoryImpl) GetNextWorkRequired(lastBlockId []byte) (uint32, error) {
-	if lastBlockId == nil {
-		return difficulty.MINIMUM_DIFFICULTY, nil
+	rules := &repo.chainParams.Difficulty
+
+	if lastBlockId == nil || rules.NoRetargeting {
+		return rules.MinimumDifficulty, nil
 	}
 
 	var lastBlockDB db_models.BlockDB
@@ -90,21 +133,21 @@ func (repo *BlockRepositoryImpl) GetNextWorkRequired(lastBlockId []byte) (uint32
 		First(&lastBlockDB).Error
 
 	if err != nil {
-		return difficulty.MINIMUM_DIFFICULTY, err
+		return rules.MinimumDifficulty, err
 	}
 
-	if (lastBlockDB.Height+1)%uint64(difficulty.INTERVAL) != 0 {
+	if (lastBlockDB.Height+1)%uint64(rules.Interval()) != 0 {
 		return lastBlockDB.BlockHeader.NBits, nil
 	}
 
 	var firstBlockDB db_models.BlockHeaderDB
 	currentId := slices.Clone(lastBlockDB.BlockHeaderId)
 
-	for range difficulty.INTERVAL {
+	for range rules.Interval() {
 		err = repo.db.Raw("SELECT * FROM block_headers WHERE id = ?", currentId).First(&firstBlockDB).Error
 
 		if err != nil {
-			return difficulty.MINIMUM_DIFFICULTY, err
+			return rules.MinimumDifficulty, err
 		}
 
 		if firstBlockDB.PreviousBlockHeaderId == nil {
@@ -114,25 +157,30 @@ func (repo *BlockRepositoryImpl) GetNextWorkRequired(lastBlockId []byte) (uint32
 		currentId = slices.Clone(*firstBlockDB.PreviousBlockHeaderId)
 	}
 
-	actualTimespan := lastBlockDB.BlockHeader.Timestamp - firstBlockDB.Timestamp
+	return RetargetWork(rules, lastBlockDB.BlockHeader.NBits, firstBlockDB.Timestamp, lastBlockDB.BlockHeader.Timestamp), nil
+}
+
+// RetargetWork is the work required after an interval of blocks that started and ended at the given timestamps
+func RetargetWork(rules *chainparams.DifficultyRules, lastNBits uint32, firstTimestamp int64, lastTimestamp int64) uint32 {
+	actualTimespan := lastTimestamp - firstTimestamp
 
-	if actualTimespan < difficulty.MIN_TIMESPAN {
-		actualTimespan = difficulty.TARGET_TIMESPAN / 4
+	if actualTimespan < rules.MinTimespan() {
+		actualTimespan = rules.MinTimespan()
 	}
 
-	if actualTimespan > difficulty.MAX_TIMESPAN {
-		actualTimespan = difficulty.TARGET_TIMESPAN * 4
+	if actualTimespan > rules.MaxTimespan() {
+		actualTimespan = rules.MaxTimespan()
 	}
 
-	target := difficulty.GetTargetFromNBits(lastBlockDB.BlockHeader.NBits)
+	target := difficulty.GetTargetFromNBits(lastNBits)
 	target.Mul(target, big.NewInt(actualTimespan))
-	target.Div(target, big.NewInt(difficulty.TARGET_TIMESPAN))
+	target.Div(target, big.NewInt(rules.TargetTimespan))
 
-	if target.Cmp(difficulty.GetTargetFromNBits(difficulty.MINIMUM_DIFFICULTY)) > 0 {
-		return difficulty.MINIMUM_DIFFICULTY, nil
+	if target.Cmp(difficulty.GetTargetFromNBits(rules.MinimumDifficulty)) > 0 {
+		return rules.MinimumDifficulty
 	}
 
-	return difficulty.TargetToNBits(target), nil
+	return difficulty.TargetToNBits(target)
 }
 
 func (repo *BlockRepositoryImpl) HaveBlock(blockId []byte) (bool, error) {
@@ -345,8 +393,12 @@ func (repo *BlockRepositoryImpl) GetBlockLocator(startBlockId []byte) (*structur
 }
 
 func (repo *BlockRepositoryImpl) GetBlock(blockId []byte) (*models.Block, error) {
+	return repo.getBlock(repo.db, blockId)
+}
+
+func (repo *BlockRepositoryImpl) getBlock(db *gorm.DB, blockId []byte) (*models.Block, error) {
 	var blockDB db_models.BlockDB
-	err := repo.db.Preload("BlockHeader").
+	err := db.Preload("BlockHeader").
 		Where("block_header_id = ?", blockId).
 		First(&blockDB).Error
 
@@ -354,8 +406,12 @@ func (repo *BlockRepositoryImpl) GetBlock(blockId []byte) (*models.Block, error)
 		return nil, err
 	}
 
+	if blockDB.Pruned {
+		return nil, fmt.Errorf("block %x: %w", blockId, ErrBlockPruned)
+	}
+
 	var txsDB []db_models.TransactionBlockDB
-	err = repo.db.Preload("Transaction").
+	err = db.Preload("Transaction").
 		Where("block_header_id = ?", blockId).
 		Order("block_header_id, `order` ASC").
 		Find(&txsDB).Error
@@ -377,10 +433,11 @@ func (repo *BlockRepositoryImpl) GetBlock(blockId []byte) (*models.Block, error)
 	return block, nil
 }
 
+// GetBlocks leaves out unknown and pruned blocks
 func (repo *BlockRepositoryImpl) GetBlocks(ids *structures.BytesSet) ([]*models.Block, error) {
 	var blocksDB []db_models.BlockDB
 	err := repo.db.Preload("BlockHeader").
-		Where("block_header_id IN (?)", ids.ToBytesSlice()).
+		Where("block_header_id IN (?) AND pruned = ?", ids.ToBytesSlice(), false).
 		Find(&blocksDB).Error
 
 	if err != nil {
@@ -442,7 +499,29 @@ func (repo *BlockRepositoryImpl) GetMissingBlockIds(ids *structures.BytesSet) (*
 	return missingIds, nil
 }
 
-func (repo *BlockRepositoryImpl) GetBlockCumulativeWork(blockHeaderId []byte) (*big.Int, error) {
+func (repo *BlockRepositoryImpl) GetBlockHeader(blockId []byte) (*models.BlockHeader, error) {
+	var blockHeaderDB db_models.BlockHeaderDB
+	err := repo.db.Where("id = ?", blockId).First(&blockHeaderDB).Error
+
+	if err != nil {
+		return nil, err
+	}
+
+	return mapping.BlockHeaderDBToBlockHeader(&blockHeaderDB), nil
+}
+
+func (repo *BlockRepositoryImpl) GetBlockHeight(blockId []byte) (uint64, error) {
+	var blockDB db_models.BlockDB
+	err := repo.db.Where("block_header_id = ?", blockId).First(&blockDB).Error
+
+	if err != nil {
+		return 0, err
+	}
+
+	return blockDB.Height, nil
+}
+
+func (repo *BlockRepositoryImpl) GetBlockChainWeight(blockHeaderId []byte) (*big.Int, error) {
 	var blockDB db_models.BlockDB
 	err := repo.db.Where("block_header_id = ?", blockHeaderId).First(&blockDB).Error
 
@@ -450,14 +529,17 @@ func (repo *BlockRepositoryImpl) GetBlockCumulativeWork(blockHeaderId []byte) (*
 		return nil, err
 	}
 
-	return (*big.Int)(&blockDB.CumulativeWork), nil
+	return (*big.Int)(&blockDB.ChainWeight), nil
 }
 
 func (blockRepository *BlockRepositoryImpl) InsertIfNotExists(block *models.Block) error {
 	blockRepository.activeChainTipIdMutex.Lock()
 	defer blockRepository.activeChainTipIdMutex.Unlock()
 
-	return blockRepository.db.Transaction(func(tx *gorm.DB) error {
+	oldTipId := blockRepository.activeChainTipId
+	var chainUpdate *events.ChainUpdate
+
+	err := blockRepository.db.Transaction(func(tx *gorm.DB) error {
 		var count int64
 		err := tx.Table("blocks").Where("block_header_id = ?", block.Header.Id).Count(&count).Error
 
@@ -470,12 +552,11 @@ func (blockRepository *BlockRepositoryImpl) InsertIfNotExists(block *models.Bloc
 		}
 
 		blockDB := mapping.BlockToBlockDB(block)
-		blockWork := types.NewBigInt(block.GetBlockWork())
 
 		if block.Header.PreviousBlockId == nil {
 			blockDB.Height = 0
 			blockDB.InActiveChain = true
-			blockDB.CumulativeWork = blockWork
+			blockDB.ChainWeight = types.NewBigInt(blockRepository.consensusEngine.BlockWeight(&block.Header, 0))
 		} else {
 			var prevBlockDB db_models.BlockDB
 			result := tx.Where("block_header_id = ?", block.Header.PreviousBlockId).Find(&prevBlockDB)
@@ -486,8 +567,15 @@ func (blockRepository *BlockRepositoryImpl) InsertIfNotExists(block *models.Bloc
 
 			if result.RowsAffected > 0 {
 				blockDB.Height = prevBlockDB.Height + 1
+
+				checkpoint := blockRepository.chainParams.Consensus.CheckpointAt(blockDB.Height)
+				if checkpoint != nil && !bytes.Equal(checkpoint.BlockId, block.Header.Id) {
+					return fmt.Errorf("%w: block %x at height %d, checkpoint is %x", ErrCheckpointViolation, block.Header.Id, blockDB.Height, checkpoint.BlockId)
+				}
+
 				blockDB.InActiveChain = bytes.Equal(block.Header.PreviousBlockId, blockRepository.activeChainTipId)
-				blockDB.CumulativeWork = blockWork.Add(prevBlockDB.CumulativeWork)
+				blockWeight := types.NewBigInt(blockRepository.consensusEngine.BlockWeight(&block.Header, blockDB.Height))
+				blockDB.ChainWeight = blockWeight.Add(prevBlockDB.ChainWeight)
 			} else {
 				return fmt.Errorf("orphan")
 			}
@@ -503,7 +591,7 @@ func (blockRepository *BlockRepositoryImpl) InsertIfNotExists(block *models.Bloc
 		}
 
 		for i, transaction := range block.Transactions {
-			if err := blockRepository.transactionRepository.InsertIfNotExistsTransactional(transaction, tx); err != nil {
+			if err := insertTransactionIfNotExists(tx, transaction); err != nil {
 				return err
 			}
 
@@ -519,8 +607,13 @@ func (blockRepository *BlockRepositoryImpl) InsertIfNotExists(block *models.Bloc
 		}
 
 		if blockDB.InActiveChain {
+			if err := blockRepository.connectBlock(tx, blockDB.BlockHeaderId, blockDB.Height); err != nil {
+				return err
+			}
+
 			blockRepository.activeChainTipId = blockDB.BlockHeaderId
-			return nil
+			chainUpdate, err = blockRepository.createChainUpdate(tx, oldTipId, block, [][]byte{blockDB.BlockHeaderId}, nil)
+			return err
 		}
 
 		var activeTip db_models.BlockDB
@@ -528,33 +621,75 @@ func (blockRepository *BlockRepositoryImpl) InsertIfNotExists(block *models.Bloc
 			return fmt.Errorf("active chain tip not found: %v", err)
 		}
 
-		if blockDB.CumulativeWork.Cmp(activeTip.CumulativeWork) > 0 {
-			if err := blockRepository.reorganizeChain(tx, block.Header.Id); err != nil {
+		//the consensus engine weighs the chains, a side chain must be strictly heavier to become active
+		if blockDB.ChainWeight.Cmp(activeTip.ChainWeight) > 0 {
+			connectedIds, disconnectedIds, err := blockRepository.reorganizeChain(tx, block.Header.Id)
+			if err != nil {
+				return err
+			}
+
+			chainUpdate, err = blockRepository.createChainUpdate(tx, oldTipId, block, connectedIds, disconnectedIds)
+			if err != nil {
 				return err
 			}
 		}
 
 		return nil
 	})
+
+	if err != nil {
+		blockRepository.activeChainTipId = oldTipId
+		return err
+	}
+
+	//genesis block or block on a side chain
+	if oldTipId == nil || chainUpdate == nil {
+		return nil
+	}
+
+	if len(chainUpdate.DisconnectedBlocks) > 0 {
+		blockRepository.eventBus.Publish(events.ChainReorganizedEvent{ChainUpdate: *chainUpdate})
+	}
+
+	blockRepository.eventBus.Publish(events.ChainTipChangedEvent{ChainUpdate: *chainUpdate})
+	return nil
 }
 
-func (blockRepository *BlockRepositoryImpl) GenesisBlock() *models.Block {
-	genesisBlockHeader := &models.BlockHeader{
-		Version:         1,
-		PreviousBlockId: nil,
-		MerkleRoot:      make([]byte, 32),
-		Timestamp:       time.Date(2025, time.March, 30, 0, 0, 0, 0, time.UTC).Unix(),
-		NBits:           difficulty.MINIMUM_DIFFICULTY,
-		Nonce:           50,
-		MinerPublicKey:  make([]byte, 33),
+func (blockRepository *BlockRepositoryImpl) createChainUpdate(tx *gorm.DB, oldTipId []byte, insertedBlock *models.Block, connectedIds [][]byte, disconnectedIds [][]byte) (*events.ChainUpdate, error) {
+	chainUpdate := &events.ChainUpdate{
+		OldTipId:           oldTipId,
+		NewTipId:           blockRepository.activeChainTipId,
+		ConnectedBlocks:    make([]*models.Block, len(connectedIds)),
+		DisconnectedBlocks: make([]*models.Block, len(disconnectedIds)),
 	}
 
-	genesisBlockHeader.SetId()
+	for i, id := range connectedIds {
+		if bytes.Equal(id, insertedBlock.Header.Id) {
+			chainUpdate.ConnectedBlocks[i] = insertedBlock
+			continue
+		}
+
+		block, err := blockRepository.getBlock(tx, id)
+		if err != nil {
+			return nil, err
+		}
+		chainUpdate.ConnectedBlocks[i] = block
+	}
 
-	return &models.Block{
-		Header:       *genesisBlockHeader,
-		Transactions: make([]*models.Transaction, 0),
+	for i, id := range disconnectedIds {
+		block, err := blockRepository.getBlock(tx, id)
+		if err != nil {
+			return nil, err
+		}
+		chainUpdate.DisconnectedBlocks[i] = block
 	}
+
+	chainUpdate.ForkPointId = chainUpdate.ConnectedBlocks[0].Header.PreviousBlockId
+	return chainUpdate, nil
+}
+
+func (blockRepository *BlockRepositoryImpl) GenesisBlock() *models.Block {
+	return blockRepository.chainParams.GenesisBlock
 }
 
 func (blockRepository *BlockRepositoryImpl) SetActiveChainTipId() error {
@@ -628,51 +763,372 @@ func (blockRepository *BlockRepositoryImpl) GetActiveBlocksPaged(searchText stri
 	return blocks, total, nil
 }
 
-func (blockRepository *BlockRepositoryImpl) reorganizeChain(tx *gorm.DB, newTipId []byte) error {
+// GetMinerCredits is computed from the active chain, blocks of a disconnected branch stop counting on reorg
+func (blockRepository *BlockRepositoryImpl) GetMinerCredits(minerPublicKey []byte) (*models.MinerCredits, error) {
+	var rows []minerCreditsRow
+	err := blockRepository.minerCreditsQuery().
+		Where("bh.miner_public_key = ?", minerPublicKey).
+		Scan(&rows).Error
+
+	if err != nil {
+		return nil, err
+	}
+
+	minerCredits := &models.MinerCredits{MinerPublicKey: minerPublicKey}
+	if len(rows) > 0 {
+		minerCredits = blockRepository.toMinerCredits(rows[0])
+	}
+
+	return minerCredits, nil
+}
+
+// GetMinerCreditsPaged is the ledger of every miner in the active chain, most credits first
+func (blockRepository *BlockRepositoryImpl) GetMinerCreditsPaged(offset int, pageSize int) ([]*models.MinerCredits, int64, error) {
+	var total int64
+	err := blockRepository.db.Table("(?) AS c", blockRepository.minerCreditsQuery()).Count(&total).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	rewards := blockRepository.chainParams.Rewards
+
+	var rows []minerCreditsRow
+	err = blockRepository.minerCreditsQuery().
+		Order(clause.OrderBy{Expression: clause.Expr{
+			SQL:                "COUNT(*) * ? + COALESCE(SUM(v.votes), 0) * ? DESC, bh.miner_public_key ASC",
+			Vars:               []any{rewards.BlockReward, rewards.VoteFee},
+			WithoutParentheses: true,
+		}}).
+		Offset(offset).
+		Limit(pageSize).
+		Scan(&rows).Error
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	minerCredits := make([]*models.MinerCredits, len(rows))
+	for i, row := range rows {
+		minerCredits[i] = blockRepository.toMinerCredits(row)
+	}
+
+	return minerCredits, total, nil
+}
+
+type minerCreditsRow struct {
+	MinerPublicKey []byte
+	Blocks         uint64
+	Votes          uint64
+}
+
+// minerCreditsQuery counts the blocks and votes of every miner in the active chain, without the genesis block
+func (blockRepository *BlockRepositoryImpl) minerCreditsQuery() *gorm.DB {
+	votesPerBlock := blockRepository.db.Table("spent_voters").
+		Select("block_header_id, COUNT(*) AS votes").
+		Group("block_header_id")
+
+	return blockRepository.db.Table("blocks AS b").
+		Select("bh.miner_public_key AS miner_public_key, COUNT(*) AS blocks, COALESCE(SUM(v.votes), 0) AS votes").
+		Joins("JOIN block_headers bh ON bh.id = b.block_header_id").
+		Joins("LEFT JOIN (?) v ON v.block_header_id = b.block_header_id", votesPerBlock).
+		Where("b.in_active_chain = ? AND b.height > 0", true).
+		Group("bh.miner_public_key")
+}
+
+func (blockRepository *BlockRepositoryImpl) toMinerCredits(row minerCreditsRow) *models.MinerCredits {
+	return &models.MinerCredits{
+		MinerPublicKey: row.MinerPublicKey,
+		Blocks:         row.Blocks,
+		Votes:          row.Votes,
+		Credits:        blockRepository.chainParams.Rewards.Credits(row.Blocks, row.Votes),
+	}
+}
+
+// reorganizeChain makes newTipId the active chain tip, it returns the connected block ids from the fork point to the new tip
+// and the disconnected block ids from the old tip to the fork point
+func (blockRepository *BlockRepositoryImpl) reorganizeChain(tx *gorm.DB, newTipId []byte) ([][]byte, [][]byte, error) {
 	var forkPoint []byte
 	oldTipId := blockRepository.activeChainTipId
 
+	connectedIds := make([][]byte, 0)
+	connectedHeights := make([]uint64, 0)
+	disconnectedIds := make([][]byte, 0)
+
 	curId := newTipId
 
 	for {
 		var block db_models.BlockDB
 		if err := tx.Preload("BlockHeader").Where("block_header_id = ?", curId).First(&block).Error; err != nil {
-			return err
+			return nil, nil, err
 		}
 
 		if block.InActiveChain {
 			forkPoint = block.BlockHeaderId
+			if err := blockRepository.checkReorganization(tx, &block); err != nil {
+				return nil, nil, err
+			}
 			break
 		}
 
-		if err := tx.Model(&db_models.BlockDB{}).
-			Where("block_header_id = ?", block.BlockHeaderId).
-			Update("in_active_chain", true).Error; err != nil {
-			return err
-		}
-
+		connectedIds = append(connectedIds, block.BlockHeaderId)
+		connectedHeights = append(connectedHeights, block.Height)
 		curId = *block.BlockHeader.PreviousBlockHeaderId
 	}
 
+	//blocks are disconnected before connecting, both branches may hold the same vote
 	for {
 		if bytes.Equal(oldTipId, forkPoint) {
 			break
 		}
 
+		disconnectedIds = append(disconnectedIds, oldTipId)
+
 		if err := tx.Model(&db_models.BlockDB{}).
 			Where("block_header_id = ?", oldTipId).
 			Update("in_active_chain", false).Error; err != nil {
-			return err
+			return nil, nil, err
 		}
 
 		var oldBlock db_models.BlockDB
 		if err := tx.Preload("BlockHeader").Where("block_header_id = ?", oldTipId).First(&oldBlock).Error; err != nil {
-			return err
+			return nil, nil, err
+		}
+
+		if err := blockRepository.disconnectBlock(tx, oldTipId, oldBlock.Height); err != nil {
+			return nil, nil, err
 		}
 
 		oldTipId = *oldBlock.BlockHeader.PreviousBlockHeaderId
 	}
 
+	slices.Reverse(connectedIds)
+	slices.Reverse(connectedHeights)
+
+	for i, connectedId := range connectedIds {
+		if err := tx.Model(&db_models.BlockDB{}).
+			Where("block_header_id = ?", connectedId).
+			Update("in_active_chain", true).Error; err != nil {
+			return nil, nil, err
+		}
+
+		if err := blockRepository.connectBlock(tx, connectedId, connectedHeights[i]); err != nil {
+			return nil, nil, err
+		}
+	}
+
 	blockRepository.activeChainTipId = newTipId
+	return connectedIds, disconnectedIds, nil
+}
+
+// Reindex recomputes the height, chain weight and active chain of every block from the block headers, and the spent voter index, tally and tally snapshots from the active chain
+func (blockRepository *BlockRepositoryImpl) Reindex() error {
+	blockRepository.activeChainTipIdMutex.Lock()
+	defer blockRepository.activeChainTipIdMutex.Unlock()
+
+	prunedHeight, err := getPrunedHeight(blockRepository.db)
+	if err != nil {
+		return err
+	}
+
+	if prunedHeight > 0 {
+		return fmt.Errorf("%w: reindexing needs the transactions of every block, resync the node instead", ErrBlockPruned)
+	}
+
+	var tipId []byte
+
+	err = blockRepository.db.Transaction(func(tx *gorm.DB) error {
+		var headersDB []db_models.BlockHeaderDB
+		if err := tx.Find(&headersDB).Error; err != nil {
+			return err
+		}
+
+		headers := make([]*models.BlockHeader, len(headersDB))
+		for i := range headersDB {
+			headers[i] = mapping.BlockHeaderDBToBlockHeader(&headersDB[i])
+		}
+
+		index, indexedTipId, err := IndexChain(headers, blockRepository.consensusEngine, &blockRepository.chainParams.Consensus, blockRepository.activeChainTipId)
+		if err != nil {
+			return err
+		}
+
+		//blocks left out of the index stay stored outside of the active chain
+		if err := tx.Model(&db_models.BlockDB{}).Where("1 = 1").Update("in_active_chain", false).Error; err != nil {
+			return err
+		}
+
+		blocksDB := make([]*db_models.BlockDB, 0, index.Length())
+		for _, indexedBlock := range index.Values() {
+			blocksDB = append(blocksDB, &db_models.BlockDB{
+				BlockHeaderId: indexedBlock.Header.Id,
+				Height:        indexedBlock.Height,
+				InActiveChain: indexedBlock.InActiveChain,
+				ChainWeight:   types.NewBigInt(indexedBlock.ChainWeight),
+			})
+		}
+
+		err = tx.Omit(clause.Associations).
+			Clauses(clause.OnConflict{UpdateAll: true}).
+			CreateInBatches(blocksDB, 500).Error
+
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Where("1 = 1").Delete(&db_models.SpentVoterDB{}).Error; err != nil {
+			return err
+		}
+
+		err = tx.Exec(`INSERT INTO spent_voters (voter_public_key, transaction_id, block_header_id, height, candidate_id)
+			SELECT t.voter_public_key, t.id, b.block_header_id, b.height, t.candidate_id
+			FROM transactions t
+			JOIN transactions_blocks tb ON tb.transaction_id = t.id
+			JOIN blocks b ON b.block_header_id = tb.block_header_id
+			WHERE b.in_active_chain = ?`, true).Error
+
+		if err != nil {
+			return err
+		}
+
+		indexedTip, _ := index.Get(indexedTipId)
+		if err := blockRepository.rebuildTally(tx, indexedTip.Height); err != nil {
+			return err
+		}
+
+		tipId = indexedTipId
+		return nil
+	})
+
+	if err != nil {
+		return err
+	}
+
+	blockRepository.activeChainTipId = tipId
+	return nil
+}
+
+// connectSpentVoters adds the voters of a block that joined the active chain to the spent voter index
+func connectSpentVoters(tx *gorm.DB, blockId []byte) error {
+	return tx.Exec(`INSERT INTO spent_voters (voter_public_key, transaction_id, block_header_id, height, candidate_id)
+		SELECT t.voter_public_key, t.id, b.block_header_id, b.height, t.candidate_id
+		FROM transactions t
+		JOIN transactions_blocks tb ON tb.transaction_id = t.id
+		JOIN blocks b ON b.block_header_id = tb.block_header_id
+		WHERE b.block_header_id = ?`, blockId).Error
+}
+
+func disconnectSpentVoters(tx *gorm.DB, blockId []byte) error {
+	return tx.Where("block_header_id = ?", blockId).Delete(&db_models.SpentVoterDB{}).Error
+}
+
+// checkReorganization refuses to disconnect blocks of the active chain past a checkpoint, deeper than the maximum reorg depth or into pruned blocks
+func (blockRepository *BlockRepositoryImpl) checkReorganization(tx *gorm.DB, forkPoint *db_models.BlockDB) error {
+	var activeTip db_models.BlockDB
+	if err := tx.Where("block_header_id = ?", blockRepository.activeChainTipId).First(&activeTip).Error; err != nil {
+		return fmt.Errorf("active chain tip not found: %v", err)
+	}
+
+	if err := CheckReorganization(&blockRepository.chainParams.Consensus, forkPoint.Height, activeTip.Height); err != nil {
+		return err
+	}
+
+	prunedHeight, err := getPrunedHeight(tx)
+	if err != nil {
+		return err
+	}
+
+	return CheckPrunedReorganization(prunedHeight, forkPoint.Height)
+}
+
+// CheckPrunedReorganization refuses a fork below the pruned height, the transactions of the blocks it would connect or disconnect are gone
+func CheckPrunedReorganization(prunedHeight uint64, forkHeight uint64) error {
+	if forkHeight < prunedHeight {
+		return fmt.Errorf("%w: fork at height %d reaches blocks pruned up to height %d", ErrReorgTooDeep, forkHeight, prunedHeight)
+	}
+
+	return nil
+}
+
+// Prune deletes the transactions of the blocks older than the last keepBlocks blocks of the active chain, side chain blocks included.
+// Headers, the spent voter index and the tally are kept, the genesis block is never pruned. It returns the number of blocks pruned.
+func (blockRepository *BlockRepositoryImpl) Prune(keepBlocks uint64) (uint64, error) {
+	blockRepository.activeChainTipIdMutex.Lock()
+	defer blockRepository.activeChainTipIdMutex.Unlock()
+
+	var pruned uint64
+
+	err := blockRepository.db.Transaction(func(tx *gorm.DB) error {
+		var activeTip db_models.BlockDB
+		if err := tx.Where("block_header_id = ?", blockRepository.activeChainTipId).First(&activeTip).Error; err != nil {
+			return fmt.Errorf("active chain tip not found: %v", err)
+		}
+
+		if activeTip.Height <= keepBlocks {
+			return nil
+		}
+
+		result := tx.Model(&db_models.BlockDB{}).
+			Where("height > 0 AND height <= ? AND pruned = ?", activeTip.Height-keepBlocks, false).
+			Update("pruned", true)
+
+		if result.Error != nil {
+			return result.Error
+		}
+
+		pruned = uint64(result.RowsAffected)
+		if pruned == 0 {
+			return nil
+		}
+
+		err := tx.Exec(`DELETE FROM transactions_blocks
+			WHERE block_header_id IN (SELECT block_header_id FROM blocks WHERE pruned = ?)`, true).Error
+
+		if err != nil {
+			return err
+		}
+
+		//a transaction stays while a block that wasn't pruned holds it
+		return tx.Exec(`DELETE FROM transactions
+			WHERE id NOT IN (SELECT transaction_id FROM transactions_blocks)`).Error
+	})
+
+	if err != nil {
+		return 0, err
+	}
+
+	return pruned, nil
+}
+
+// GetPrunedHeight is the height of the highest pruned block, 0 when no block was pruned
+func (blockRepository *BlockRepositoryImpl) GetPrunedHeight() (uint64, error) {
+	return getPrunedHeight(blockRepository.db)
+}
+
+func getPrunedHeight(db *gorm.DB) (uint64, error) {
+	var prunedHeight uint64
+	err := db.Model(&db_models.BlockDB{}).
+		Where("pruned = ?", true).
+		Select("COALESCE(MAX(height), 0)").
+		Scan(&prunedHeight).Error
+
+	if err != nil {
+		return 0, err
+	}
+
+	return prunedHeight, nil
+}
+
+// CheckReorganization tells if the active chain may be reorganized from a fork point, its blocks up to the tip are disconnected
+func CheckReorganization(consensusRules *chainparams.ConsensusRules, forkHeight uint64, tipHeight uint64) error {
+	depth := tipHeight - forkHeight
+
+	if consensusRules.MaxReorgDepth > 0 && depth > consensusRules.MaxReorgDepth {
+		return fmt.Errorf("%w: fork at height %d disconnects %d blocks, maximum is %d", ErrReorgTooDeep, forkHeight, depth, consensusRules.MaxReorgDepth)
+	}
+
+	if checkpoint := consensusRules.CheckpointBetween(forkHeight, tipHeight); checkpoint != nil {
+		return fmt.Errorf("%w: fork at height %d disconnects checkpoint %x at height %d", ErrCheckpointViolation, forkHeight, checkpoint.BlockId, checkpoint.Height)
+	}
+
 	return nil
 }
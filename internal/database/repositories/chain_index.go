@@ -0,0 +1,98 @@
+package repositories
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+
+	chainparams "github.com/nivschuman/VotingBlockchain/internal/chainparams"
+	consensus "github.com/nivschuman/VotingBlockchain/internal/consensus"
+	models "github.com/nivschuman/VotingBlockchain/internal/models"
+	structures "github.com/nivschuman/VotingBlockchain/internal/structures"
+)
+
+// IndexedBlock is the chain state of a block derived from the stored headers alone
+type IndexedBlock struct {
+	Header        *models.BlockHeader
+	Height        uint64
+	ChainWeight   *big.Int
+	InActiveChain bool
+}
+
+// IndexChain derives the heights, chain weights and active chain from the headers, as if the blocks were inserted again.
+// The active chain ends at the heaviest block, on a tie the current tip is kept. Blocks conflicting with a checkpoint
+// and blocks that aren't connected to the genesis block are left out of the index.
+func IndexChain(headers []*models.BlockHeader, consensusEngine consensus.ConsensusEngine, consensusRules *chainparams.ConsensusRules, currentTipId []byte) (*structures.BytesMap[*IndexedBlock], []byte, error) {
+	var genesisHeader *models.BlockHeader
+	children := structures.NewBytesMap[[]*models.BlockHeader]()
+
+	for _, header := range headers {
+		if header.PreviousBlockId == nil {
+			if genesisHeader != nil {
+				return nil, nil, errors.New("more than one genesis block")
+			}
+
+			genesisHeader = header
+			continue
+		}
+
+		children.Put(header.PreviousBlockId, append(children.GetOrDefault(header.PreviousBlockId, nil), header))
+	}
+
+	if genesisHeader == nil {
+		return nil, nil, errors.New("no genesis block")
+	}
+
+	genesis := &IndexedBlock{
+		Header:      genesisHeader,
+		Height:      0,
+		ChainWeight: consensusEngine.BlockWeight(genesisHeader, 0),
+	}
+
+	index := structures.NewBytesMap[*IndexedBlock]()
+	index.Put(genesisHeader.Id, genesis)
+
+	tip := genesis
+	queue := []*IndexedBlock{genesis}
+
+	for len(queue) > 0 {
+		parent := queue[0]
+		queue = queue[1:]
+
+		for _, header := range children.GetOrDefault(parent.Header.Id, nil) {
+			height := parent.Height + 1
+
+			checkpoint := consensusRules.CheckpointAt(height)
+			if checkpoint != nil && !bytes.Equal(checkpoint.BlockId, header.Id) {
+				continue
+			}
+
+			block := &IndexedBlock{
+				Header:      header,
+				Height:      height,
+				ChainWeight: new(big.Int).Add(parent.ChainWeight, consensusEngine.BlockWeight(header, height)),
+			}
+
+			index.Put(header.Id, block)
+			queue = append(queue, block)
+
+			weightComparison := block.ChainWeight.Cmp(tip.ChainWeight)
+			if weightComparison > 0 || (weightComparison == 0 && bytes.Equal(header.Id, currentTipId)) {
+				tip = block
+			}
+		}
+	}
+
+	for block := tip; block != nil; {
+		block.InActiveChain = true
+
+		previous, exists := index.Get(block.Header.PreviousBlockId)
+		if !exists {
+			break
+		}
+
+		block = previous
+	}
+
+	return index, tip.Header.Id, nil
+}
@@ -0,0 +1,126 @@
+package repositories
+
+import (
+	"bytes"
+
+	db_models "github.com/nivschuman/VotingBlockchain/internal/database/models"
+	mapping "github.com/nivschuman/VotingBlockchain/internal/mapping"
+	models "github.com/nivschuman/VotingBlockchain/internal/models"
+	"gorm.io/gorm"
+)
+
+type ConflictingVoteRepository interface {
+	InsertIfNotExists(conflictingVote *models.ConflictingVote) (bool, error)
+	GetConflictingVotes(voterPublicKey []byte) ([]*models.ConflictingVote, error)
+	GetAllConflictingVotes() ([]*models.ConflictingVote, error)
+	GetConflictingVotesPaged(offset int, limit int) ([]*models.ConflictingVote, int, error)
+}
+
+type ConflictingVoteRepositoryImpl struct {
+	db *gorm.DB
+}
+
+func NewConflictingVoteRepositoryImpl(db *gorm.DB) *ConflictingVoteRepositoryImpl {
+	return &ConflictingVoteRepositoryImpl{db: db}
+}
+
+// InsertIfNotExists reports whether the voter had no conflict recorded yet.
+// Only the first pair of votes of a voter is kept, a voter can sign votes for any number of candidates, later pairs are counted
+func (repo *ConflictingVoteRepositoryImpl) InsertIfNotExists(conflictingVote *models.ConflictingVote) (bool, error) {
+	inserted := false
+
+	err := repo.db.Transaction(func(tx *gorm.DB) error {
+		existingVote := &db_models.ConflictingVoteDB{}
+		result := tx.
+			Where("voter_public_key = ?", conflictingVote.VoterPublicKey).
+			Limit(1).
+			Find(existingVote)
+
+		if result.Error != nil {
+			return result.Error
+		}
+
+		if result.RowsAffected == 0 {
+			conflictingVoteDB := mapping.ConflictingVoteToConflictingVoteDB(conflictingVote)
+			conflictingVoteDB.Conflicts = 1
+
+			inserted = true
+			return tx.Create(conflictingVoteDB).Error
+		}
+
+		//the same conflict is announced by many peers
+		if bytes.Equal(existingVote.TransactionId, conflictingVote.Transaction.Id) && bytes.Equal(existingVote.ConflictingTransactionId, conflictingVote.ConflictingTransaction.Id) {
+			return nil
+		}
+
+		return tx.Model(existingVote).UpdateColumn("conflicts", gorm.Expr("conflicts + 1")).Error
+	})
+
+	if err != nil {
+		return false, err
+	}
+
+	return inserted, nil
+}
+
+func (repo *ConflictingVoteRepositoryImpl) GetConflictingVotes(voterPublicKey []byte) ([]*models.ConflictingVote, error) {
+	var conflictingVotesDB []db_models.ConflictingVoteDB
+	err := repo.db.
+		Where("voter_public_key = ?", voterPublicKey).
+		Order("id ASC").
+		Find(&conflictingVotesDB).Error
+
+	if err != nil {
+		return nil, err
+	}
+
+	return conflictingVotesFromDB(conflictingVotesDB)
+}
+
+func (repo *ConflictingVoteRepositoryImpl) GetAllConflictingVotes() ([]*models.ConflictingVote, error) {
+	var conflictingVotesDB []db_models.ConflictingVoteDB
+	if err := repo.db.Order("id ASC").Find(&conflictingVotesDB).Error; err != nil {
+		return nil, err
+	}
+
+	return conflictingVotesFromDB(conflictingVotesDB)
+}
+
+func (repo *ConflictingVoteRepositoryImpl) GetConflictingVotesPaged(offset int, limit int) ([]*models.ConflictingVote, int, error) {
+	var total int64
+	if err := repo.db.Model(&db_models.ConflictingVoteDB{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var conflictingVotesDB []db_models.ConflictingVoteDB
+	err := repo.db.
+		Order("id DESC").
+		Offset(offset).
+		Limit(limit).
+		Find(&conflictingVotesDB).Error
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	conflictingVotes, err := conflictingVotesFromDB(conflictingVotesDB)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return conflictingVotes, int(total), nil
+}
+
+func conflictingVotesFromDB(conflictingVotesDB []db_models.ConflictingVoteDB) ([]*models.ConflictingVote, error) {
+	conflictingVotes := make([]*models.ConflictingVote, len(conflictingVotesDB))
+	for i := range conflictingVotesDB {
+		conflictingVote, err := mapping.ConflictingVoteDBToConflictingVote(&conflictingVotesDB[i])
+		if err != nil {
+			return nil, err
+		}
+
+		conflictingVotes[i] = conflictingVote
+	}
+
+	return conflictingVotes, nil
+}
@@ -0,0 +1,202 @@
+package repositories
+
+import (
+	db_models "github.com/nivschuman/VotingBlockchain/internal/database/models"
+	voters "github.com/nivschuman/VotingBlockchain/internal/voters"
+	"gorm.io/gorm"
+)
+
+// connectBlock adds a block that joined the active chain to the spent voter index and the tally, the tally is snapshot at snapshot heights
+func (blockRepository *BlockRepositoryImpl) connectBlock(tx *gorm.DB, blockId []byte, height uint64) error {
+	if err := connectSpentVoters(tx, blockId); err != nil {
+		return err
+	}
+
+	if err := addBlockToTally(tx, blockId, 1); err != nil {
+		return err
+	}
+
+	if !blockRepository.chainParams.Election.IsTallySnapshotHeight(height) {
+		return nil
+	}
+
+	results, err := getTally(tx)
+	if err != nil {
+		return err
+	}
+
+	return saveTallySnapshot(tx, voters.NewTallySnapshot(height, blockId, results))
+}
+
+// disconnectBlock removes a block that left the active chain from the tally and the spent voter index, along with its snapshot
+func (blockRepository *BlockRepositoryImpl) disconnectBlock(tx *gorm.DB, blockId []byte, height uint64) error {
+	if err := addBlockToTally(tx, blockId, -1); err != nil {
+		return err
+	}
+
+	if err := disconnectSpentVoters(tx, blockId); err != nil {
+		return err
+	}
+
+	return deleteTallySnapshot(tx, height)
+}
+
+// rebuildTally counts the tally and takes the snapshots again from the spent voter index of the active chain up to tipHeight
+func (blockRepository *BlockRepositoryImpl) rebuildTally(tx *gorm.DB, tipHeight uint64) error {
+	for _, model := range []any{&db_models.TallyDB{}, &db_models.TallySnapshotDB{}, &db_models.TallySnapshotVoteDB{}} {
+		if err := tx.Where("1 = 1").Delete(model).Error; err != nil {
+			return err
+		}
+	}
+
+	err := tx.Exec(`INSERT INTO tally (candidate_id, votes)
+		SELECT candidate_id, COUNT(*) FROM spent_voters GROUP BY candidate_id`).Error
+
+	if err != nil {
+		return err
+	}
+
+	interval := blockRepository.chainParams.Election.TallySnapshotInterval
+	if interval == 0 {
+		return nil
+	}
+
+	for height := interval; height <= tipHeight; height += interval {
+		var blockDB db_models.BlockDB
+		if err := tx.Where("in_active_chain = ? AND height = ?", true, height).First(&blockDB).Error; err != nil {
+			return err
+		}
+
+		results, err := countSpentVoters(tx, 0, height)
+		if err != nil {
+			return err
+		}
+
+		if err := saveTallySnapshot(tx, voters.NewTallySnapshot(height, blockDB.BlockHeaderId, results)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// addBlockToTally adds or removes the votes of a block in the spent voter index from the tally, candidates without votes are removed
+func addBlockToTally(tx *gorm.DB, blockId []byte, sign int64) error {
+	err := tx.Exec(`INSERT INTO tally (candidate_id, votes)
+		SELECT candidate_id, ? * COUNT(*) FROM spent_voters WHERE block_header_id = ? GROUP BY candidate_id
+		ON CONFLICT (candidate_id) DO UPDATE SET votes = votes + excluded.votes`, sign, blockId).Error
+
+	if err != nil {
+		return err
+	}
+
+	return tx.Where("votes = 0").Delete(&db_models.TallyDB{}).Error
+}
+
+func getTally(db *gorm.DB) ([]*voters.VotingResult, error) {
+	var results []*voters.VotingResult
+
+	err := db.Model(&db_models.TallyDB{}).
+		Select("candidate_id, votes").
+		Order("candidate_id ASC").
+		Scan(&results).Error
+
+	if err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// countSpentVoters counts the votes of the active chain blocks above fromHeight up to toHeight
+func countSpentVoters(db *gorm.DB, fromHeight uint64, toHeight uint64) ([]*voters.VotingResult, error) {
+	var results []*voters.VotingResult
+
+	err := db.Model(&db_models.SpentVoterDB{}).
+		Select("candidate_id, COUNT(*) as votes").
+		Where("height > ? AND height <= ?", fromHeight, toHeight).
+		Group("candidate_id").
+		Order("candidate_id ASC").
+		Scan(&results).Error
+
+	if err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+func saveTallySnapshot(tx *gorm.DB, snapshot *voters.TallySnapshot) error {
+	if err := deleteTallySnapshot(tx, snapshot.Height); err != nil {
+		return err
+	}
+
+	snapshotDB := &db_models.TallySnapshotDB{
+		Height:        snapshot.Height,
+		BlockHeaderId: snapshot.BlockId,
+		Hash:          snapshot.Hash,
+	}
+
+	if err := tx.Create(snapshotDB).Error; err != nil {
+		return err
+	}
+
+	if len(snapshot.Results) == 0 {
+		return nil
+	}
+
+	votesDB := make([]*db_models.TallySnapshotVoteDB, len(snapshot.Results))
+	for i, result := range snapshot.Results {
+		votesDB[i] = &db_models.TallySnapshotVoteDB{
+			Height:      snapshot.Height,
+			CandidateId: result.CandidateId,
+			Votes:       int64(result.Votes),
+		}
+	}
+
+	return tx.CreateInBatches(votesDB, 500).Error
+}
+
+func deleteTallySnapshot(tx *gorm.DB, height uint64) error {
+	if err := tx.Where("height = ?", height).Delete(&db_models.TallySnapshotVoteDB{}).Error; err != nil {
+		return err
+	}
+
+	return tx.Where("height = ?", height).Delete(&db_models.TallySnapshotDB{}).Error
+}
+
+// getTallySnapshot returns the snapshot taken at the highest snapshot height up to height, nil when there is none
+func getTallySnapshot(db *gorm.DB, height uint64, exact bool) (*voters.TallySnapshot, error) {
+	query := db.Where("height <= ?", height)
+	if exact {
+		query = db.Where("height = ?", height)
+	}
+
+	var snapshotDB db_models.TallySnapshotDB
+	result := query.Order("height DESC").Limit(1).Find(&snapshotDB)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return nil, nil
+	}
+
+	var results []*voters.VotingResult
+	err := db.Model(&db_models.TallySnapshotVoteDB{}).
+		Select("candidate_id, votes").
+		Where("height = ?", snapshotDB.Height).
+		Order("candidate_id ASC").
+		Scan(&results).Error
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &voters.TallySnapshot{
+		Height:  snapshotDB.Height,
+		BlockId: snapshotDB.BlockHeaderId,
+		Results: results,
+		Hash:    snapshotDB.Hash,
+	}, nil
+}
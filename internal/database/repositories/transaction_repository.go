@@ -1,6 +1,8 @@
 package repositories
 
 import (
+	"fmt"
+
 	db_models "github.com/nivschuman/VotingBlockchain/internal/database/models"
 	mapping "github.com/nivschuman/VotingBlockchain/internal/mapping"
 	models "github.com/nivschuman/VotingBlockchain/internal/models"
@@ -15,12 +17,13 @@ type TransactionRepository interface {
 	GetTransactions(ids *structures.BytesSet) ([]*models.Transaction, error)
 	GetMissingTransactionIds(ids *structures.BytesSet) (*structures.BytesSet, error)
 	InsertIfNotExists(transaction *models.Transaction) error
-	GetMempool(limit int) ([]*models.Transaction, error)
 	TransactionValidInActiveChain(transaction *models.Transaction) (bool, error)
-	InsertIfNotExistsTransactional(transaction *models.Transaction, tx *gorm.DB) error
+	GetActiveChainVote(voterPublicKey []byte) (*models.Transaction, error)
 	GetConfirmedTransactionsPaged(offset int, limit int) ([]*models.Transaction, int, error)
-	GetMempoolPaged(offset int, limit int) ([]*models.Transaction, int, error)
+	GetVotersInActiveChain(voterPublicKeys *structures.BytesSet) (*structures.BytesSet, error)
 	GetVotingResults() ([]*voters.VotingResult, error)
+	GetVotingResultsAtHeight(height uint64) ([]*voters.VotingResult, error)
+	GetTallySnapshot(height uint64) (*voters.TallySnapshot, error)
 }
 
 type TransactionRepositoryImpl struct {
@@ -31,15 +34,31 @@ func NewTransactionRepositoryImpl(db *gorm.DB) *TransactionRepositoryImpl {
 	return &TransactionRepositoryImpl{db: db}
 }
 
+// TransactionsValidInChain walks back from the chain tip to the active chain, the votes below the fork point are found in the spent voter index
 func (repo *TransactionRepositoryImpl) TransactionsValidInChain(chainTipId []byte, transactions []*models.Transaction) (bool, error) {
-	currentId := chainTipId
+	if chainTipId == nil {
+		return true, nil
+	}
 
 	voterPublicKeys := structures.NewBytesSet()
 	for _, tx := range transactions {
 		voterPublicKeys.Add(tx.VoterPublicKey)
 	}
 
-	for currentId != nil {
+	currentId := chainTipId
+	var forkHeight uint64
+
+	for {
+		var block db_models.BlockDB
+		if err := repo.db.Preload("BlockHeader").Where("block_header_id = ?", currentId).First(&block).Error; err != nil {
+			return false, err
+		}
+
+		if block.InActiveChain {
+			forkHeight = block.Height
+			break
+		}
+
 		var count int64
 		err := repo.db.Table("transactions_blocks").
 			Joins("JOIN transactions ON transactions_blocks.transaction_id = transactions.id").
@@ -55,19 +74,19 @@ func (repo *TransactionRepositoryImpl) TransactionsValidInChain(chainTipId []byt
 			return false, nil
 		}
 
-		var prevId []byte
-		err = repo.db.Table("block_headers").
-			Select("previous_block_header_id").
-			Where("id = ?", currentId).Row().Scan(&prevId)
+		currentId = *block.BlockHeader.PreviousBlockHeaderId
+	}
 
-		if err != nil {
-			return false, err
-		}
+	var count int64
+	err := repo.db.Model(&db_models.SpentVoterDB{}).
+		Where("voter_public_key IN ? AND height <= ?", voterPublicKeys.ToBytesSlice(), forkHeight).
+		Count(&count).Error
 
-		currentId = prevId
+	if err != nil {
+		return false, err
 	}
 
-	return true, nil
+	return count == 0, nil
 }
 
 func (repo *TransactionRepositoryImpl) GetTransaction(txId []byte) (*models.Transaction, error) {
@@ -146,47 +165,10 @@ func (repo *TransactionRepositoryImpl) InsertIfNotExists(transaction *models.Tra
 	})
 }
 
-func (repo *TransactionRepositoryImpl) GetMempool(limit int) ([]*models.Transaction, error) {
-	var transactionsDB []*db_models.TransactionDB
-
-	subquery := repo.db.
-		Table("transactions AS t").
-		Select("1").
-		Joins("JOIN transactions_blocks tb ON t.id = tb.transaction_id").
-		Joins("JOIN blocks b ON tb.block_header_id = b.block_header_id").
-		Where("b.in_active_chain = ?", true).
-		Where("t.voter_public_key = transactions.voter_public_key")
-
-	query := repo.db.
-		Table("transactions").
-		Joins("LEFT JOIN transactions_blocks ON transactions.id = transactions_blocks.transaction_id").
-		Joins("LEFT JOIN blocks ON transactions_blocks.block_header_id = blocks.block_header_id").
-		Where("blocks.in_active_chain = ? OR blocks.in_active_chain IS NULL", false).
-		Where("NOT EXISTS (?)", subquery).
-		Group("transactions.voter_public_key"). //unique voter public keys - https://www.sqlite.org/lang_select.html#bare_columns_in_an_aggregate_query
-		Limit(limit)
-
-	err := query.Find(&transactionsDB).Error
-
-	if err != nil {
-		return nil, err
-	}
-
-	var transactions []*models.Transaction
-
-	for _, txDB := range transactionsDB {
-		transactions = append(transactions, mapping.TransactionDBToTransaction(txDB))
-	}
-
-	return transactions, nil
-}
-
 func (repo *TransactionRepositoryImpl) TransactionValidInActiveChain(transaction *models.Transaction) (bool, error) {
 	var count int64
-	err := repo.db.Table("transactions t").
-		Joins("JOIN transactions_blocks tb ON tb.transaction_id = t.id").
-		Joins("JOIN blocks b ON b.block_header_id = tb.block_header_id").
-		Where("t.voter_public_key = ? AND b.in_active_chain = ?", transaction.VoterPublicKey, true).
+	err := repo.db.Model(&db_models.SpentVoterDB{}).
+		Where("voter_public_key = ?", transaction.VoterPublicKey).
 		Count(&count).Error
 
 	if err != nil {
@@ -200,7 +182,27 @@ func (repo *TransactionRepositoryImpl) TransactionValidInActiveChain(transaction
 	return true, nil
 }
 
+func (repo *TransactionRepositoryImpl) GetActiveChainVote(voterPublicKey []byte) (*models.Transaction, error) {
+	var txDB db_models.TransactionDB
+	err := repo.db.
+		Table("transactions t").
+		Select("t.*").
+		Joins("JOIN spent_voters sv ON sv.transaction_id = t.id").
+		Where("sv.voter_public_key = ?", voterPublicKey).
+		Take(&txDB).Error
+
+	if err != nil {
+		return nil, err
+	}
+
+	return mapping.TransactionDBToTransaction(&txDB), nil
+}
+
 func (repo *TransactionRepositoryImpl) InsertIfNotExistsTransactional(transaction *models.Transaction, tx *gorm.DB) error {
+	return insertTransactionIfNotExists(tx, transaction)
+}
+
+func insertTransactionIfNotExists(tx *gorm.DB, transaction *models.Transaction) error {
 	existingTransaction := &db_models.TransactionDB{}
 	result := tx.Where("id = ?", transaction.Id).Find(existingTransaction)
 
@@ -250,70 +252,65 @@ func (repo *TransactionRepositoryImpl) GetConfirmedTransactionsPaged(offset int,
 	return transactions, int(total), nil
 }
 
-func (repo *TransactionRepositoryImpl) GetMempoolPaged(offset int, limit int) ([]*models.Transaction, int, error) {
-	var total int64
-
-	subquery := repo.db.
-		Table("transactions AS t2").
-		Select("1").
-		Joins("JOIN transactions_blocks tb2 ON t2.id = tb2.transaction_id").
-		Joins("JOIN blocks b2 ON tb2.block_header_id = b2.block_header_id").
-		Where("b2.in_active_chain = ?", true).
-		Where("t2.voter_public_key = t.voter_public_key")
-
-	err := repo.db.
-		Table("transactions t").
-		Joins("LEFT JOIN transactions_blocks tb ON t.id = tb.transaction_id").
-		Joins("LEFT JOIN blocks b ON tb.block_header_id = b.block_header_id").
-		Where("b.in_active_chain = ? OR b.in_active_chain IS NULL", false).
-		Where("NOT EXISTS (?)", subquery).
-		Count(&total).Error
-
-	if err != nil {
-		return nil, 0, err
+func (repo *TransactionRepositoryImpl) GetVotersInActiveChain(voterPublicKeys *structures.BytesSet) (*structures.BytesSet, error) {
+	voters := structures.NewBytesSet()
+	if voterPublicKeys.Length() == 0 {
+		return voters, nil
 	}
 
-	var txsDB []db_models.TransactionDB
-	err = repo.db.
-		Table("transactions t").
-		Joins("LEFT JOIN transactions_blocks tb ON t.id = tb.transaction_id").
-		Joins("LEFT JOIN blocks b ON tb.block_header_id = b.block_header_id").
-		Where("b.in_active_chain = ? OR b.in_active_chain IS NULL", false).
-		Where("NOT EXISTS (?)", subquery).
-		Group("t.voter_public_key"). // unique voter public keys
-		Order("t.id ASC").
-		Offset(offset).
-		Limit(limit).
-		Find(&txsDB).Error
+	var voterKeys [][]byte
+	err := repo.db.Model(&db_models.SpentVoterDB{}).
+		Where("voter_public_key IN ?", voterPublicKeys.ToBytesSlice()).
+		Pluck("voter_public_key", &voterKeys).Error
 
 	if err != nil {
-		return nil, 0, err
+		return nil, err
 	}
 
-	transactions := make([]*models.Transaction, len(txsDB))
-	for i, txDB := range txsDB {
-		transactions[i] = mapping.TransactionDBToTransaction(&txDB)
+	for _, voterKey := range voterKeys {
+		voters.Add(voterKey)
 	}
 
-	return transactions, int(total), nil
+	return voters, nil
 }
 
+// GetVotingResults reads the tally, which is kept up to date as blocks are connected and disconnected
 func (repo *TransactionRepositoryImpl) GetVotingResults() ([]*voters.VotingResult, error) {
+	return getTally(repo.db)
+}
+
+// GetVotingResultsAtHeight starts from the latest tally snapshot up to height and counts the votes of the blocks after it
+func (repo *TransactionRepositoryImpl) GetVotingResultsAtHeight(height uint64) ([]*voters.VotingResult, error) {
+	snapshot, err := getTallySnapshot(repo.db, height, false)
+	if err != nil {
+		return nil, err
+	}
+
 	var results []*voters.VotingResult
+	snapshotHeight := uint64(0)
 
-	err := repo.db.
-		Table("transactions t").
-		Select("t.candidate_id as candidate_id, COUNT(*) as votes").
-		Joins("JOIN transactions_blocks tb ON t.id = tb.transaction_id").
-		Joins("JOIN blocks b ON tb.block_header_id = b.block_header_id").
-		Where("b.in_active_chain = ?", true).
-		Group("t.candidate_id").
-		Order("t.candidate_id ASC").
-		Scan(&results).Error
+	if snapshot != nil {
+		results = snapshot.Results
+		snapshotHeight = snapshot.Height
+	}
 
+	recent, err := countSpentVoters(repo.db, snapshotHeight, height)
 	if err != nil {
 		return nil, err
 	}
 
-	return results, nil
+	return voters.AddResults(results, recent), nil
+}
+
+func (repo *TransactionRepositoryImpl) GetTallySnapshot(height uint64) (*voters.TallySnapshot, error) {
+	snapshot, err := getTallySnapshot(repo.db, height, true)
+	if err != nil {
+		return nil, err
+	}
+
+	if snapshot == nil {
+		return nil, fmt.Errorf("tally snapshot at height %d: %w", height, gorm.ErrRecordNotFound)
+	}
+
+	return snapshot, nil
 }
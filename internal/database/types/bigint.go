@@ -19,7 +19,7 @@ func (bi *BigInt) Scan(value any) error {
 		*bi = BigInt(*new(big.Int).SetBytes(v))
 		return nil
 	default:
-		return fmt.Errorf("failed to scan CumulativeWork: unsupported type %T", value)
+		return fmt.Errorf("failed to scan BigInt: unsupported type %T", value)
 	}
 }
 
@@ -4,24 +4,6 @@ import (
 	"math/big"
 )
 
-// Minimum nBits difficulty allowed for block
-var MINIMUM_DIFFICULTY = uint32(0x1d00ffff)
-
-// Total time expected for interval in seconds
-var TARGET_TIMESPAN = int64(14 * 24 * 60 * 60)
-
-// Time expected per block in seconds
-var TARGET_SPACING = int64(10 * 60)
-
-// Number of blocks between difficulty adjustments
-var INTERVAL = TARGET_TIMESPAN / TARGET_SPACING
-
-// Minimum timespan allowed
-var MIN_TIMESPAN = TARGET_TIMESPAN / 4
-
-// Maximum timespan allowed
-var MAX_TIMESPAN = TARGET_TIMESPAN * 4
-
 func GetTargetFromNBits(nBits uint32) *big.Int {
 	exponent := nBits >> 24
 	coefficient := nBits & 0x00FFFFFF
@@ -0,0 +1,191 @@
+package events
+
+import (
+	"log"
+	"slices"
+	"sync"
+)
+
+type Handler func(event Event)
+
+type EventBus interface {
+	Publish(event Event)
+	Subscribe(handler Handler, eventTypes ...EventType) *Subscription
+	Unsubscribe(subscription *Subscription)
+	UnsubscribeAll(eventType EventType)
+	Close()
+}
+
+// Subscription delivers events to its handler on its own goroutine, in the order they were published.
+// Publishing never blocks on a slow subscriber, pending events are queued.
+type Subscription struct {
+	handler Handler
+
+	mutex      sync.Mutex
+	cond       *sync.Cond
+	eventTypes []EventType
+	queue      []Event
+	closed     bool
+
+	done chan struct{}
+}
+
+type EventBusImpl struct {
+	mutex         sync.RWMutex
+	subscriptions []*Subscription
+	closed        bool
+}
+
+func NewEventBusImpl() *EventBusImpl {
+	return &EventBusImpl{
+		subscriptions: make([]*Subscription, 0),
+	}
+}
+
+func (bus *EventBusImpl) Publish(event Event) {
+	bus.mutex.RLock()
+	defer bus.mutex.RUnlock()
+
+	for _, subscription := range bus.subscriptions {
+		subscription.enqueue(event)
+	}
+}
+
+func (bus *EventBusImpl) Subscribe(handler Handler, eventTypes ...EventType) *Subscription {
+	subscription := &Subscription{
+		handler:    handler,
+		eventTypes: slices.Clone(eventTypes),
+		queue:      make([]Event, 0),
+		done:       make(chan struct{}),
+	}
+	subscription.cond = sync.NewCond(&subscription.mutex)
+
+	bus.mutex.Lock()
+	defer bus.mutex.Unlock()
+
+	if bus.closed {
+		subscription.closed = true
+		close(subscription.done)
+		return subscription
+	}
+
+	bus.subscriptions = append(bus.subscriptions, subscription)
+	go subscription.run()
+
+	return subscription
+}
+
+func (bus *EventBusImpl) Unsubscribe(subscription *Subscription) {
+	bus.mutex.Lock()
+	bus.subscriptions = slices.DeleteFunc(bus.subscriptions, func(s *Subscription) bool {
+		return s == subscription
+	})
+	bus.mutex.Unlock()
+
+	subscription.close()
+}
+
+// UnsubscribeAll stops delivering events of the given type to every subscriber
+func (bus *EventBusImpl) UnsubscribeAll(eventType EventType) {
+	bus.mutex.Lock()
+	defer bus.mutex.Unlock()
+
+	remaining := make([]*Subscription, 0, len(bus.subscriptions))
+	for _, subscription := range bus.subscriptions {
+		if subscription.removeEventType(eventType) {
+			subscription.close()
+			continue
+		}
+
+		remaining = append(remaining, subscription)
+	}
+
+	bus.subscriptions = remaining
+}
+
+func (bus *EventBusImpl) Close() {
+	bus.mutex.Lock()
+	defer bus.mutex.Unlock()
+
+	for _, subscription := range bus.subscriptions {
+		subscription.close()
+	}
+
+	bus.subscriptions = nil
+	bus.closed = true
+}
+
+// Wait blocks until the subscription was closed and its handler returned
+func (subscription *Subscription) Wait() {
+	<-subscription.done
+}
+
+func (subscription *Subscription) enqueue(event Event) {
+	subscription.mutex.Lock()
+	defer subscription.mutex.Unlock()
+
+	if subscription.closed || !slices.Contains(subscription.eventTypes, event.Type()) {
+		return
+	}
+
+	subscription.queue = append(subscription.queue, event)
+	subscription.cond.Signal()
+}
+
+// removeEventType reports whether the subscription is left without any event types
+func (subscription *Subscription) removeEventType(eventType EventType) bool {
+	subscription.mutex.Lock()
+	defer subscription.mutex.Unlock()
+
+	subscription.eventTypes = slices.DeleteFunc(subscription.eventTypes, func(t EventType) bool {
+		return t == eventType
+	})
+
+	subscription.queue = slices.DeleteFunc(subscription.queue, func(event Event) bool {
+		return event.Type() == eventType
+	})
+
+	return len(subscription.eventTypes) == 0
+}
+
+func (subscription *Subscription) close() {
+	subscription.mutex.Lock()
+	defer subscription.mutex.Unlock()
+
+	subscription.closed = true
+	subscription.queue = nil
+	subscription.cond.Signal()
+}
+
+func (subscription *Subscription) run() {
+	defer close(subscription.done)
+
+	for {
+		subscription.mutex.Lock()
+		for len(subscription.queue) == 0 && !subscription.closed {
+			subscription.cond.Wait()
+		}
+
+		if subscription.closed {
+			subscription.mutex.Unlock()
+			return
+		}
+
+		event := subscription.queue[0]
+		subscription.queue[0] = nil
+		subscription.queue = subscription.queue[1:]
+		subscription.mutex.Unlock()
+
+		subscription.handle(event)
+	}
+}
+
+func (subscription *Subscription) handle(event Event) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("|Events| Handler for %s panicked: %v", event.Type().String(), r)
+		}
+	}()
+
+	subscription.handler(event)
+}
@@ -0,0 +1,133 @@
+package events
+
+import (
+	data_models "github.com/nivschuman/VotingBlockchain/internal/models"
+	models "github.com/nivschuman/VotingBlockchain/internal/networking/models"
+	peer "github.com/nivschuman/VotingBlockchain/internal/networking/peer"
+)
+
+type EventType int
+
+const (
+	PeerConnected EventType = iota
+	PeerDisconnected
+	HandshakeFailed
+	PeerMisbehaved
+	BlockReceived
+	TransactionReceived
+	ChainTipChanged
+	ChainReorganized
+	MempoolTransactionAdded
+	MempoolTransactionRemoved
+	ConflictingVoteDetected
+)
+
+func (eventType EventType) String() string {
+	switch eventType {
+	case PeerConnected:
+		return "peer connected"
+	case PeerDisconnected:
+		return "peer disconnected"
+	case HandshakeFailed:
+		return "handshake failed"
+	case PeerMisbehaved:
+		return "peer misbehaved"
+	case BlockReceived:
+		return "block received"
+	case TransactionReceived:
+		return "transaction received"
+	case ChainTipChanged:
+		return "chain tip changed"
+	case ChainReorganized:
+		return "chain reorganized"
+	case MempoolTransactionAdded:
+		return "mempool transaction added"
+	case MempoolTransactionRemoved:
+		return "mempool transaction removed"
+	case ConflictingVoteDetected:
+		return "conflicting vote detected"
+	default:
+		return "unknown"
+	}
+}
+
+type Event interface {
+	Type() EventType
+}
+
+type PeerConnectedEvent struct {
+	Peer *peer.Peer
+}
+
+type PeerDisconnectedEvent struct {
+	Peer   *peer.Peer
+	Reason peer.DisconnectReason
+}
+
+type HandshakeFailedEvent struct {
+	Address *models.Address
+	Reason  peer.DisconnectReason
+	Err     error
+}
+
+type PeerMisbehavedEvent struct {
+	Peer   *peer.Peer
+	Reason string
+}
+
+// BlockReceivedEvent is published once a block was accepted, FromPeer is nil for locally mined blocks
+type BlockReceivedEvent struct {
+	Block    *data_models.Block
+	FromPeer *peer.Peer
+}
+
+// TransactionReceivedEvent is published once a transaction was accepted, FromPeer is nil for locally generated transactions
+type TransactionReceivedEvent struct {
+	Transaction *data_models.Transaction
+	FromPeer    *peer.Peer
+}
+
+// ChainUpdate describes how the active chain moved from OldTipId to NewTipId
+type ChainUpdate struct {
+	OldTipId    []byte
+	NewTipId    []byte
+	ForkPointId []byte
+
+	ConnectedBlocks    []*data_models.Block //blocks added to the active chain, from the fork point to the new tip
+	DisconnectedBlocks []*data_models.Block //blocks removed from the active chain, from the old tip to the fork point
+}
+
+type ChainTipChangedEvent struct {
+	ChainUpdate
+}
+
+// ChainReorganizedEvent is published before ChainTipChangedEvent when the update disconnected blocks
+type ChainReorganizedEvent struct {
+	ChainUpdate
+}
+
+type MempoolTransactionAddedEvent struct {
+	Transaction *data_models.Transaction
+}
+
+type MempoolTransactionRemovedEvent struct {
+	Transaction *data_models.Transaction
+	Reason      string
+}
+
+// ConflictingVoteDetectedEvent is published once per pair of conflicting votes
+type ConflictingVoteDetectedEvent struct {
+	ConflictingVote *data_models.ConflictingVote
+}
+
+func (PeerConnectedEvent) Type() EventType             { return PeerConnected }
+func (PeerDisconnectedEvent) Type() EventType          { return PeerDisconnected }
+func (HandshakeFailedEvent) Type() EventType           { return HandshakeFailed }
+func (PeerMisbehavedEvent) Type() EventType            { return PeerMisbehaved }
+func (BlockReceivedEvent) Type() EventType             { return BlockReceived }
+func (TransactionReceivedEvent) Type() EventType       { return TransactionReceived }
+func (ChainTipChangedEvent) Type() EventType           { return ChainTipChanged }
+func (ChainReorganizedEvent) Type() EventType          { return ChainReorganized }
+func (MempoolTransactionAddedEvent) Type() EventType   { return MempoolTransactionAdded }
+func (MempoolTransactionRemovedEvent) Type() EventType { return MempoolTransactionRemoved }
+func (ConflictingVoteDetectedEvent) Type() EventType   { return ConflictingVoteDetected }
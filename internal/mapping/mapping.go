@@ -47,6 +47,7 @@ func BlockHeaderToBlockHeaderDB(blockHeader *models.BlockHeader) *db_models.Bloc
 		NBits:                 blockHeader.NBits,
 		Nonce:                 blockHeader.Nonce,
 		MinerPublicKey:        slices.Clone(blockHeader.MinerPublicKey),
+		MinerSignature:        slices.Clone(blockHeader.MinerSignature),
 		PreviousBlockHeaderId: prevBlockHeaderId,
 	}
 }
@@ -66,6 +67,7 @@ func BlockHeaderDBToBlockHeader(blockHeaderDB *db_models.BlockHeaderDB) *models.
 		NBits:           blockHeaderDB.NBits,
 		Nonce:           blockHeaderDB.Nonce,
 		MinerPublicKey:  slices.Clone(blockHeaderDB.MinerPublicKey),
+		MinerSignature:  slices.Clone(blockHeaderDB.MinerSignature),
 		PreviousBlockId: prevBlockHeaderId,
 	}
 }
@@ -99,3 +101,37 @@ func AddressDBToAddress(addressDB *db_models.AddressDB) *networking_models.Addre
 		NodeType: addressDB.NodeType,
 	}
 }
+
+func ConflictingVoteToConflictingVoteDB(conflictingVote *models.ConflictingVote) *db_models.ConflictingVoteDB {
+	return &db_models.ConflictingVoteDB{
+		VoterPublicKey:           slices.Clone(conflictingVote.VoterPublicKey),
+		TransactionId:            slices.Clone(conflictingVote.Transaction.Id),
+		Transaction:              conflictingVote.Transaction.AsBytes(),
+		ConflictingTransactionId: slices.Clone(conflictingVote.ConflictingTransaction.Id),
+		ConflictingTransaction:   conflictingVote.ConflictingTransaction.AsBytes(),
+		Source:                   conflictingVote.Source,
+		DetectedAt:               conflictingVote.DetectedAt,
+		Conflicts:                conflictingVote.Conflicts,
+	}
+}
+
+func ConflictingVoteDBToConflictingVote(conflictingVoteDB *db_models.ConflictingVoteDB) (*models.ConflictingVote, error) {
+	transaction, err := models.TransactionFromBytes(conflictingVoteDB.Transaction)
+	if err != nil {
+		return nil, err
+	}
+
+	conflictingTransaction, err := models.TransactionFromBytes(conflictingVoteDB.ConflictingTransaction)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.ConflictingVote{
+		VoterPublicKey:         slices.Clone(conflictingVoteDB.VoterPublicKey),
+		Transaction:            transaction,
+		ConflictingTransaction: conflictingTransaction,
+		Source:                 conflictingVoteDB.Source,
+		DetectedAt:             conflictingVoteDB.DetectedAt,
+		Conflicts:              conflictingVoteDB.Conflicts,
+	}, nil
+}
@@ -0,0 +1,521 @@
+package mempool
+
+import (
+	"bytes"
+	"container/list"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	config "github.com/nivschuman/VotingBlockchain/internal/config"
+	repos "github.com/nivschuman/VotingBlockchain/internal/database/repositories"
+	events "github.com/nivschuman/VotingBlockchain/internal/events"
+	models "github.com/nivschuman/VotingBlockchain/internal/models"
+	structures "github.com/nivschuman/VotingBlockchain/internal/structures"
+)
+
+var EXPIRY_CHECK_INTERVAL = time.Minute
+
+var (
+	ErrAlreadyInMempool   = errors.New("transaction is already in the mempool")
+	ErrInvalidTransaction = errors.New("transaction has invalid signatures")
+	ErrAlreadyVoted       = errors.New("voter already voted in the active chain")
+	ErrConflict           = errors.New("voter already has a transaction in the mempool")
+	ErrMempoolFull        = errors.New("mempool is full")
+)
+
+type RemovalReason string
+
+const (
+	Confirmed  RemovalReason = "confirmed"  //included in a block of the active chain
+	Conflicted RemovalReason = "conflicted" //another vote of the same voter was included in the active chain
+	Expired    RemovalReason = "expired"
+	Replaced   RemovalReason = "replaced" //lost a conflict with another vote of the same voter
+)
+
+type Mempool interface {
+	Start()
+	Stop()
+	Add(transaction *models.Transaction) error
+	Get(transactionId []byte) (*models.Transaction, bool)
+	Contains(transactionId []byte) bool
+	GetByVoterPublicKey(voterPublicKey []byte) (*models.Transaction, bool)
+	GetTransactions(limit int) []*models.Transaction
+	GetTransactionsPaged(offset int, limit int) ([]*models.Transaction, int)
+	GetTransactionIds() [][]byte
+	GetBlockCandidates(maxTransactions int, maxSize int) ([]*models.Transaction, error)
+	RemoveExpired(now time.Time) int
+	Count() int
+	SaveToFile(path string) error
+	LoadFromFile(path string) error
+	GetConflictingVoteRepository() repos.ConflictingVoteRepository
+}
+
+type entry struct {
+	transaction *models.Transaction
+	addedAt     time.Time
+	element     *list.Element
+}
+
+type MempoolImpl struct {
+	transactionRepository     repos.TransactionRepository
+	conflictingVoteRepository repos.ConflictingVoteRepository
+	governmentPublicKey       []byte
+
+	maxTransactions int
+	expiry          time.Duration
+	conflictPolicy  config.ConflictPolicy
+
+	eventBus        events.EventBus
+	tipSubscription *events.Subscription
+
+	entries       *structures.BytesMap[*entry] //by transaction id
+	voters        *structures.BytesMap[*entry] //by voter public key
+	order         *list.List                   //entries in arrival order
+	tipGeneration uint64                       //counts the chain tip changes handled
+	mutex         sync.RWMutex
+
+	stopChannel chan bool
+	stopOnce    sync.Once
+	wg          sync.WaitGroup
+}
+
+func NewMempoolImpl(
+	transactionRepository repos.TransactionRepository,
+	conflictingVoteRepository repos.ConflictingVoteRepository,
+	eventBus events.EventBus,
+	governmentPublicKey []byte,
+	mempoolConfig *config.MempoolConfig) *MempoolImpl {
+	pool := &MempoolImpl{
+		transactionRepository:     transactionRepository,
+		conflictingVoteRepository: conflictingVoteRepository,
+		governmentPublicKey:       governmentPublicKey,
+		maxTransactions:           mempoolConfig.MaxTransactions,
+		expiry:                    time.Duration(mempoolConfig.Expiry) * time.Second,
+		conflictPolicy:            mempoolConfig.ConflictPolicy,
+		eventBus:                  eventBus,
+		entries:                   structures.NewBytesMap[*entry](),
+		voters:                    structures.NewBytesMap[*entry](),
+		order:                     list.New(),
+		stopChannel:               make(chan bool),
+	}
+
+	pool.tipSubscription = eventBus.Subscribe(pool.handleChainTipChanged, events.ChainTipChanged)
+	return pool
+}
+
+func (pool *MempoolImpl) Start() {
+	pool.wg.Add(1)
+	log.Printf("|Mempool| Starting")
+
+	go func() {
+		defer pool.wg.Done()
+
+		ticker := time.NewTicker(EXPIRY_CHECK_INTERVAL)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-pool.stopChannel:
+				return
+			case now := <-ticker.C:
+				if expired := pool.RemoveExpired(now); expired > 0 {
+					log.Printf("|Mempool| Removed %d expired transactions", expired)
+				}
+			}
+		}
+	}()
+}
+
+func (pool *MempoolImpl) Stop() {
+	pool.stopOnce.Do(func() {
+		log.Printf("|Mempool| Stopping")
+		close(pool.stopChannel)
+		pool.eventBus.Unsubscribe(pool.tipSubscription)
+	})
+	pool.wg.Wait()
+}
+
+func (pool *MempoolImpl) Add(transaction *models.Transaction) error {
+	return pool.add(transaction, time.Now())
+}
+
+func (pool *MempoolImpl) Get(transactionId []byte) (*models.Transaction, bool) {
+	pool.mutex.RLock()
+	defer pool.mutex.RUnlock()
+
+	e, exists := pool.entries.Get(transactionId)
+	if !exists {
+		return nil, false
+	}
+
+	return e.transaction, true
+}
+
+func (pool *MempoolImpl) Contains(transactionId []byte) bool {
+	pool.mutex.RLock()
+	defer pool.mutex.RUnlock()
+
+	return pool.entries.ContainsKey(transactionId)
+}
+
+func (pool *MempoolImpl) GetByVoterPublicKey(voterPublicKey []byte) (*models.Transaction, bool) {
+	pool.mutex.RLock()
+	defer pool.mutex.RUnlock()
+
+	e, exists := pool.voters.Get(voterPublicKey)
+	if !exists {
+		return nil, false
+	}
+
+	return e.transaction, true
+}
+
+// GetTransactions returns up to limit transactions, oldest first
+func (pool *MempoolImpl) GetTransactions(limit int) []*models.Transaction {
+	pool.mutex.RLock()
+	defer pool.mutex.RUnlock()
+
+	return pool.getTransactions(0, limit)
+}
+
+func (pool *MempoolImpl) GetTransactionsPaged(offset int, limit int) ([]*models.Transaction, int) {
+	pool.mutex.RLock()
+	defer pool.mutex.RUnlock()
+
+	return pool.getTransactions(offset, limit), pool.order.Len()
+}
+
+// GetTransactionIds returns the ids of all transactions, oldest first
+func (pool *MempoolImpl) GetTransactionIds() [][]byte {
+	pool.mutex.RLock()
+	defer pool.mutex.RUnlock()
+
+	ids := make([][]byte, 0, pool.order.Len())
+	for element := pool.order.Front(); element != nil; element = element.Next() {
+		ids = append(ids, element.Value.(*entry).transaction.Id)
+	}
+
+	return ids
+}
+
+// GetBlockCandidates returns up to maxTransactions transactions taking up to maxSize bytes in a block, oldest first,
+// skipping voters that already voted in the active chain.
+// Those are removed once their block's chain update is handled, but a template may be created before that.
+func (pool *MempoolImpl) GetBlockCandidates(maxTransactions int, maxSize int) ([]*models.Transaction, error) {
+	candidates := make([]*models.Transaction, 0)
+	size := 0
+
+	var last *list.Element
+	seen := structures.NewBytesSet()
+
+	for len(candidates) < maxTransactions {
+		var transactions []*models.Transaction
+		transactions, last = pool.nextTransactions(last, seen, maxTransactions)
+		if len(transactions) == 0 {
+			break
+		}
+
+		voterPublicKeys := structures.NewBytesSet()
+		for _, transaction := range transactions {
+			voterPublicKeys.Add(transaction.VoterPublicKey)
+		}
+
+		voted, err := pool.transactionRepository.GetVotersInActiveChain(voterPublicKeys)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, transaction := range transactions {
+			if len(candidates) >= maxTransactions {
+				break
+			}
+
+			if voted.Contains(transaction.VoterPublicKey) {
+				continue
+			}
+
+			transactionSize := models.TransactionSizeInBlock(transaction)
+			if size+transactionSize > maxSize {
+				continue
+			}
+
+			candidates = append(candidates, transaction)
+			size += transactionSize
+		}
+	}
+
+	return candidates, nil
+}
+
+func (pool *MempoolImpl) RemoveExpired(now time.Time) int {
+	if pool.expiry <= 0 {
+		return 0
+	}
+
+	pool.mutex.Lock()
+	var expired []*models.Transaction
+	for element := pool.order.Front(); element != nil; {
+		next := element.Next()
+
+		e := element.Value.(*entry)
+		if !now.Before(e.addedAt.Add(pool.expiry)) {
+			pool.remove(e)
+			expired = append(expired, e.transaction)
+		}
+
+		element = next
+	}
+	pool.mutex.Unlock()
+
+	for _, transaction := range expired {
+		pool.publishRemoved(transaction, Expired)
+	}
+
+	return len(expired)
+}
+
+func (pool *MempoolImpl) Count() int {
+	pool.mutex.RLock()
+	defer pool.mutex.RUnlock()
+
+	return pool.order.Len()
+}
+
+func (pool *MempoolImpl) GetConflictingVoteRepository() repos.ConflictingVoteRepository {
+	return pool.conflictingVoteRepository
+}
+
+func (pool *MempoolImpl) add(transaction *models.Transaction, addedAt time.Time) error {
+	valid, err := transaction.IsValid(pool.governmentPublicKey)
+	if err != nil {
+		return err
+	}
+
+	if !valid {
+		return ErrInvalidTransaction
+	}
+
+	var existing *entry
+	for {
+		pool.mutex.RLock()
+		tipGeneration := pool.tipGeneration
+		existing, err = pool.checkAdmission(transaction)
+		pool.mutex.RUnlock()
+
+		if err != nil {
+			return pool.rejectTransaction(transaction, existing, err)
+		}
+
+		//checked without the lock, a chain tip change handled meanwhile makes the check run again
+		valid, err = pool.transactionRepository.TransactionValidInActiveChain(transaction)
+		if err != nil {
+			return err
+		}
+
+		if !valid {
+			pool.recordActiveChainConflict(transaction)
+			return ErrAlreadyVoted
+		}
+
+		pool.mutex.Lock()
+		if pool.tipGeneration == tipGeneration {
+			break
+		}
+		pool.mutex.Unlock()
+	}
+
+	existing, err = pool.checkAdmission(transaction)
+	if err != nil {
+		pool.mutex.Unlock()
+		return pool.rejectTransaction(transaction, existing, err)
+	}
+
+	conflicts := existing != nil
+	if conflicts {
+		pool.remove(existing)
+	}
+
+	e := &entry{transaction: transaction, addedAt: addedAt}
+	e.element = pool.order.PushBack(e)
+	pool.entries.Put(transaction.Id, e)
+	pool.voters.Put(transaction.VoterPublicKey, e)
+
+	pool.mutex.Unlock()
+
+	if conflicts {
+		pool.publishRemoved(existing.transaction, Replaced)
+		pool.recordConflict(existing.transaction, transaction, models.ConflictInMempool)
+	}
+
+	pool.eventBus.Publish(events.MempoolTransactionAddedEvent{Transaction: transaction})
+	return nil
+}
+
+// checkAdmission checks a transaction against the pool, returning the entry of the voter's vote it replaces.
+// The caller holds the lock.
+func (pool *MempoolImpl) checkAdmission(transaction *models.Transaction) (*entry, error) {
+	if pool.entries.ContainsKey(transaction.Id) {
+		return nil, ErrAlreadyInMempool
+	}
+
+	existing, conflicts := pool.voters.Get(transaction.VoterPublicKey)
+	if conflicts && !pool.keepsNewVote(transaction, existing.transaction) {
+		return existing, ErrConflict
+	}
+
+	if !conflicts && pool.maxTransactions > 0 && pool.order.Len() >= pool.maxTransactions {
+		return nil, ErrMempoolFull
+	}
+
+	return existing, nil
+}
+
+func (pool *MempoolImpl) rejectTransaction(transaction *models.Transaction, existing *entry, err error) error {
+	if errors.Is(err, ErrConflict) {
+		pool.recordConflict(transaction, existing.transaction, models.ConflictInMempool)
+	}
+
+	return err
+}
+
+// keepsNewVote decides a conflict between two votes of the same voter by the conflict policy
+func (pool *MempoolImpl) keepsNewVote(newTransaction *models.Transaction, existingTransaction *models.Transaction) bool {
+	switch pool.conflictPolicy {
+	case config.LowestIdPolicy:
+		return bytes.Compare(newTransaction.Id, existingTransaction.Id) < 0
+	default:
+		return false
+	}
+}
+
+func (pool *MempoolImpl) recordActiveChainConflict(transaction *models.Transaction) {
+	confirmedTransaction, err := pool.transactionRepository.GetActiveChainVote(transaction.VoterPublicKey)
+	if err != nil {
+		log.Printf("|Mempool| Failed to get active chain vote of voter %x: %v", transaction.VoterPublicKey, err)
+		return
+	}
+
+	//the same vote announced again after it was mined
+	if bytes.Equal(confirmedTransaction.Id, transaction.Id) {
+		return
+	}
+
+	pool.recordConflict(transaction, confirmedTransaction, models.ConflictInActiveChain)
+}
+
+func (pool *MempoolImpl) recordConflict(lostTransaction *models.Transaction, keptTransaction *models.Transaction, source string) {
+	conflictingVote := &models.ConflictingVote{
+		VoterPublicKey:         lostTransaction.VoterPublicKey,
+		Transaction:            lostTransaction,
+		ConflictingTransaction: keptTransaction,
+		Source:                 source,
+		DetectedAt:             time.Now(),
+	}
+
+	inserted, err := pool.conflictingVoteRepository.InsertIfNotExists(conflictingVote)
+	if err != nil {
+		log.Printf("|Mempool| Failed to record conflicting votes of voter %x: %v", lostTransaction.VoterPublicKey, err)
+		return
+	}
+
+	if !inserted {
+		return
+	}
+
+	log.Printf("|Mempool| Voter %x signed conflicting votes %x and %x, kept the one in the %s", lostTransaction.VoterPublicKey, lostTransaction.Id, keptTransaction.Id, source)
+	pool.eventBus.Publish(events.ConflictingVoteDetectedEvent{ConflictingVote: conflictingVote})
+}
+
+func (pool *MempoolImpl) remove(e *entry) {
+	pool.order.Remove(e.element)
+	pool.entries.Remove(e.transaction.Id)
+	pool.voters.Remove(e.transaction.VoterPublicKey)
+}
+
+func (pool *MempoolImpl) getTransactions(offset int, limit int) []*models.Transaction {
+	transactions := make([]*models.Transaction, 0, max(0, min(limit, pool.order.Len()-offset)))
+
+	element := pool.order.Front()
+	for skipped := 0; element != nil && skipped < offset; skipped++ {
+		element = element.Next()
+	}
+
+	for ; element != nil && len(transactions) < limit; element = element.Next() {
+		transactions = append(transactions, element.Value.(*entry).transaction)
+	}
+
+	return transactions
+}
+
+// nextTransactions returns up to limit transactions after the element last, oldest first, along with the last element walked.
+// The walk starts over from the front when last was removed meanwhile, skipping the transactions already seen.
+func (pool *MempoolImpl) nextTransactions(last *list.Element, seen *structures.BytesSet, limit int) ([]*models.Transaction, *list.Element) {
+	pool.mutex.RLock()
+	defer pool.mutex.RUnlock()
+
+	element := pool.order.Front()
+	if last != nil {
+		lastEntry := last.Value.(*entry)
+		if e, exists := pool.entries.Get(lastEntry.transaction.Id); exists && e == lastEntry {
+			element = last.Next()
+		}
+	}
+
+	transactions := make([]*models.Transaction, 0, min(limit, pool.order.Len()))
+	for ; element != nil && len(transactions) < limit; element = element.Next() {
+		last = element
+
+		transaction := element.Value.(*entry).transaction
+		if seen.Contains(transaction.Id) {
+			continue
+		}
+
+		seen.Add(transaction.Id)
+		transactions = append(transactions, transaction)
+	}
+
+	return transactions, last
+}
+
+func (pool *MempoolImpl) publishRemoved(transaction *models.Transaction, reason RemovalReason) {
+	pool.eventBus.Publish(events.MempoolTransactionRemovedEvent{Transaction: transaction, Reason: string(reason)})
+}
+
+// handleChainTipChanged removes votes of voters that voted in the connected blocks.
+// Votes of disconnected blocks are returned by the node, which also announces them again.
+func (pool *MempoolImpl) handleChainTipChanged(event events.Event) {
+	tipChanged := event.(events.ChainTipChangedEvent)
+
+	pool.mutex.Lock()
+	pool.tipGeneration++
+	removed := make(map[*models.Transaction]RemovalReason)
+	keptBy := make(map[*models.Transaction]*models.Transaction)
+	for _, block := range tipChanged.ConnectedBlocks {
+		for _, transaction := range block.Transactions {
+			e, exists := pool.voters.Get(transaction.VoterPublicKey)
+			if !exists {
+				continue
+			}
+
+			reason := Confirmed
+			if !bytes.Equal(e.transaction.Id, transaction.Id) {
+				reason = Conflicted
+				keptBy[e.transaction] = transaction
+			}
+
+			pool.remove(e)
+			removed[e.transaction] = reason
+		}
+	}
+	pool.mutex.Unlock()
+
+	for transaction, reason := range removed {
+		pool.publishRemoved(transaction, reason)
+	}
+
+	for lostTransaction, keptTransaction := range keptBy {
+		pool.recordConflict(lostTransaction, keptTransaction, models.ConflictInActiveChain)
+	}
+}
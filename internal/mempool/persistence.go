@@ -0,0 +1,132 @@
+package mempool
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	models "github.com/nivschuman/VotingBlockchain/internal/models"
+)
+
+const MEMPOOL_FILE_VERSION = uint32(1)
+
+// SaveToFile writes the transactions in arrival order, each with the time it entered the mempool, an empty path disables persistence
+func (pool *MempoolImpl) SaveToFile(path string) error {
+	if path == "" {
+		return nil
+	}
+
+	buf := new(bytes.Buffer)
+
+	pool.mutex.RLock()
+	binary.Write(buf, binary.BigEndian, MEMPOOL_FILE_VERSION)
+	binary.Write(buf, binary.BigEndian, uint32(pool.order.Len()))
+
+	for element := pool.order.Front(); element != nil; element = element.Next() {
+		e := element.Value.(*entry)
+		transactionBytes := e.transaction.AsBytes()
+
+		binary.Write(buf, binary.BigEndian, e.addedAt.Unix())
+		binary.Write(buf, binary.BigEndian, uint32(len(transactionBytes)))
+		buf.Write(transactionBytes)
+	}
+	count := pool.order.Len()
+	pool.mutex.RUnlock()
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	//written to a temporary file first so a crash can't leave a truncated mempool file behind
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, buf.Bytes(), 0644); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		return err
+	}
+
+	log.Printf("|Mempool| Saved %d transactions to %s", count, path)
+	return nil
+}
+
+// LoadFromFile admits the transactions saved by SaveToFile again, a missing file is an empty mempool and an empty path disables persistence
+func (pool *MempoolImpl) LoadFromFile(path string) error {
+	if path == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	buf := bytes.NewReader(data)
+
+	var version uint32
+	if err := binary.Read(buf, binary.BigEndian, &version); err != nil {
+		return err
+	}
+
+	if version != MEMPOOL_FILE_VERSION {
+		return fmt.Errorf("unsupported mempool file version %d", version)
+	}
+
+	var count uint32
+	if err := binary.Read(buf, binary.BigEndian, &count); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	loaded := 0
+	for range count {
+		var addedAtUnix int64
+		if err := binary.Read(buf, binary.BigEndian, &addedAtUnix); err != nil {
+			return err
+		}
+
+		var transactionLength uint32
+		if err := binary.Read(buf, binary.BigEndian, &transactionLength); err != nil {
+			return err
+		}
+
+		if int64(transactionLength) > int64(buf.Len()) {
+			return io.ErrUnexpectedEOF
+		}
+
+		transactionBytes := make([]byte, transactionLength)
+		if _, err := io.ReadFull(buf, transactionBytes); err != nil {
+			return err
+		}
+
+		transaction, err := models.TransactionFromBytes(transactionBytes)
+		if err != nil {
+			return err
+		}
+
+		addedAt := time.Unix(addedAtUnix, 0)
+		if pool.expiry > 0 && !now.Before(addedAt.Add(pool.expiry)) {
+			continue
+		}
+
+		if err := pool.add(transaction, addedAt); err != nil {
+			log.Printf("|Mempool| Dropped saved transaction %x: %v", transaction.Id, err)
+			continue
+		}
+
+		loaded++
+	}
+
+	log.Printf("|Mempool| Loaded %d of %d saved transactions from %s", loaded, count, path)
+	return nil
+}
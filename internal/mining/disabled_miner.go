@@ -1,6 +1,10 @@
 package mining
 
-import "github.com/nivschuman/VotingBlockchain/internal/models"
+import (
+	"errors"
+
+	"github.com/nivschuman/VotingBlockchain/internal/models"
+)
 
 type DisabledMiner struct{}
 
@@ -25,6 +29,10 @@ func (m *DisabledMiner) CreateBlockTemplate() (*models.Block, error) {
 	return nil, nil
 }
 
+func (m *DisabledMiner) Generate(count int) ([]*models.Block, error) {
+	return nil, errors.New("mining is disabled")
+}
+
 func (m *DisabledMiner) Stop() {
 	// no-op
 }
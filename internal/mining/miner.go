@@ -2,18 +2,29 @@ package mining
 
 import (
 	"bytes"
+	"errors"
+	"fmt"
 	"log"
+	"math"
 	"slices"
 	"sync"
 	"sync/atomic"
 	"time"
 
+	chainparams "github.com/nivschuman/VotingBlockchain/internal/chainparams"
+	consensus "github.com/nivschuman/VotingBlockchain/internal/consensus"
 	hash "github.com/nivschuman/VotingBlockchain/internal/crypto/hash"
+	ppk "github.com/nivschuman/VotingBlockchain/internal/crypto/ppk"
 	repos "github.com/nivschuman/VotingBlockchain/internal/database/repositories"
 	"github.com/nivschuman/VotingBlockchain/internal/difficulty"
+	events "github.com/nivschuman/VotingBlockchain/internal/events"
+	mempool "github.com/nivschuman/VotingBlockchain/internal/mempool"
 	data_models "github.com/nivschuman/VotingBlockchain/internal/models"
 )
 
+// how often a sealer waiting for its seal time checks the clock and the chain tip
+const SEAL_POLL_INTERVAL = 500 * time.Millisecond
+
 type BlockHandler func(block *data_models.Block)
 
 type Miner interface {
@@ -21,41 +32,66 @@ type Miner interface {
 	Start()
 	MineBlockTemplate(blockTemplate *data_models.Block)
 	CreateBlockTemplate() (*data_models.Block, error)
+	Generate(count int) ([]*data_models.Block, error)
 	GetMiningStatistics() MiningStatistics
 	Stop()
 }
 
 type MinerProperties struct {
-	NodeVersion    int32
-	MinerPublicKey []byte
+	MinerKeyPair         *ppk.KeyPair //mined blocks are credited to its public key and signed by its private key
+	MaxBlockSize         int          //bytes of created block templates
+	MaxBlockTransactions int          //transactions in created block templates
+	Workers              int          //goroutines mining a block template together
 }
 
 type MinerImpl struct {
-	properties MinerProperties
+	properties      MinerProperties
+	chainParams     *chainparams.ChainParams
+	consensusEngine consensus.ConsensusEngine
 
-	blockRepository       repos.BlockRepository
-	transactionRepository repos.TransactionRepository
+	blockRepository repos.BlockRepository
+	mempool         mempool.Mempool
 
 	getNetworkTime func() int64
 
+	eventBus         events.EventBus
+	tipSubscription  *events.Subscription
+	latestChainTipId atomic.Pointer[[]byte]
+
 	handlers    []BlockHandler
 	handlersMux sync.Mutex
 
-	statistics MiningStatistics
+	statistics        MiningStatistics
+	workerHashesTried []atomic.Int64 //hashes tried on the current block template, per worker
 
 	stopChannel chan bool
 	stopOnce    sync.Once
 	wg          sync.WaitGroup
 }
 
-func NewMinerImpl(getNetworkTime func() int64, blockRepository repos.BlockRepository, transactionRepository repos.TransactionRepository, minerProperties MinerProperties) *MinerImpl {
-	return &MinerImpl{
-		stopChannel:           make(chan bool),
-		getNetworkTime:        getNetworkTime,
-		blockRepository:       blockRepository,
-		transactionRepository: transactionRepository,
-		properties:            minerProperties,
+func NewMinerImpl(
+	getNetworkTime func() int64,
+	blockRepository repos.BlockRepository,
+	mempool mempool.Mempool,
+	eventBus events.EventBus,
+	chainParams *chainparams.ChainParams,
+	minerProperties MinerProperties) *MinerImpl {
+	minerProperties.Workers = max(minerProperties.Workers, 1)
+
+	miner := &MinerImpl{
+		stopChannel:       make(chan bool),
+		getNetworkTime:    getNetworkTime,
+		blockRepository:   blockRepository,
+		mempool:           mempool,
+		eventBus:          eventBus,
+		chainParams:       chainParams,
+		consensusEngine:   consensus.NewConsensusEngine(chainParams),
+		properties:        minerProperties,
+		workerHashesTried: make([]atomic.Int64, minerProperties.Workers),
 	}
+
+	miner.tipSubscription = eventBus.Subscribe(miner.handleChainTipChanged, events.ChainTipChanged)
+	return miner
 }
 
 func (miner *MinerImpl) AddHandler(blockHandler BlockHandler) {
@@ -87,7 +123,15 @@ func (miner *MinerImpl) Start() {
 	}()
 }
 
+// MineBlockTemplate splits the nonces between the workers, worker i tries i, i+workers, i+2*workers...
+// All workers stop as soon as one of them finds a solution, the miner is stopped or the chain tip changes.
+// Without proof of work the template is sealed once it's the miner's time to seal it.
 func (miner *MinerImpl) MineBlockTemplate(blockTemplate *data_models.Block) {
+	if !miner.consensusEngine.RequiresProofOfWork() {
+		miner.sealBlockTemplate(blockTemplate)
+		return
+	}
+
 	medianPastTime, err := miner.blockRepository.GetMedianTimePast(blockTemplate.Header.PreviousBlockId, 11)
 	if err != nil {
 		log.Printf("|Miner| Failed to get median time past: %v", err)
@@ -97,35 +141,44 @@ func (miner *MinerImpl) MineBlockTemplate(blockTemplate *data_models.Block) {
 	startTime := time.Now()
 	atomic.StoreInt64(&miner.statistics.CurrentBlockStart, startTime.UnixNano())
 	atomic.StoreUint32(&miner.statistics.CurrentNBits, blockTemplate.Header.NBits)
-	atomic.StoreInt64(&miner.statistics.CurrentBlockHashesTried, 0)
+	for worker := range miner.workerHashesTried {
+		miner.workerHashesTried[worker].Store(0)
+	}
 
-	log.Printf("|Miner| Started mining block")
+	log.Printf("|Miner| Started mining block with %d workers", miner.properties.Workers)
 	blockTemplate.Header.Nonce = 0
 	blockTemplate.Header.Timestamp = max(medianPastTime+1, miner.getNetworkTime())
 
+	knownChainTipId := miner.latestChainTipId.Load()
+	if miner.isStale(blockTemplate) {
+		return
+	}
+
 	target := blockTemplate.Header.GetTarget()
 	targetBytes := target.FillBytes(make([]byte, 32))
-	blockHeaderBytes := blockTemplate.Header.AsBytes()
-	blockHeaderHash := hash.HashBytesInto(blockHeaderBytes, make([]byte, 32))
 
-	for !difficulty.IsHashBelowTargetBytes(blockHeaderHash, targetBytes) {
-		select {
-		case <-miner.stopChannel:
-			return
-		default:
-			blockTemplate.Header.Nonce++
-			atomic.AddInt64(&miner.statistics.CurrentBlockHashesTried, 1)
+	search := newNonceSearch()
+	search.wg.Add(miner.properties.Workers)
+	for worker := range miner.properties.Workers {
+		go miner.mineNonces(search, worker, blockTemplate, targetBytes, medianPastTime, knownChainTipId)
+	}
+	search.wg.Wait()
 
-			if blockTemplate.Header.Nonce&0x3ffff == 0 {
-				if !bytes.Equal(blockTemplate.Header.PreviousBlockId, miner.blockRepository.GetActiveChainTipId()) {
-					return
-				}
-				blockTemplate.Header.Timestamp = max(medianPastTime+1, miner.getNetworkTime())
-			}
+	solution := search.solution.Load()
+	if solution == nil {
+		return
+	}
 
-			data_models.UpdateBlockHeaderBytes(blockHeaderBytes, blockTemplate.Header.Timestamp, blockTemplate.Header.Nonce)
-			hash.HashBytesInto(blockHeaderBytes, blockHeaderHash)
-		}
+	blockTemplate.Header.Nonce = solution.nonce
+	blockTemplate.Header.Timestamp = solution.timestamp
+	miner.completeBlock(blockTemplate, startTime)
+}
+
+// completeBlock signs the finished block and hands it to the block handlers
+func (miner *MinerImpl) completeBlock(blockTemplate *data_models.Block, startTime time.Time) {
+	if err := miner.signBlockHeader(&blockTemplate.Header); err != nil {
+		log.Printf("|Miner| Failed to sign mined block: %v", err)
+		return
 	}
 
 	blockTemplate.Header.SetId()
@@ -142,15 +195,137 @@ func (miner *MinerImpl) MineBlockTemplate(blockTemplate *data_models.Block) {
 	}
 }
 
-func (miner *MinerImpl) CreateBlockTemplate() (*data_models.Block, error) {
-	activeChainTipId := slices.Clone(miner.blockRepository.GetActiveChainTipId())
+// sealBlockTemplate waits until the miner may seal the template.
+// When the miner isn't an authorized sealer or sealed a recent block it waits for another sealer to extend the chain
+func (miner *MinerImpl) sealBlockTemplate(blockTemplate *data_models.Block) {
+	knownChainTipId := miner.latestChainTipId.Load()
+	if miner.isStale(blockTemplate) {
+		return
+	}
 
-	txs, err := miner.transactionRepository.GetMempool(10)
+	medianPastTime, err := miner.blockRepository.GetMedianTimePast(blockTemplate.Header.PreviousBlockId, 11)
 	if err != nil {
-		return nil, err
+		log.Printf("|Miner| Failed to get median time past: %v", err)
+		return
+	}
+
+	sealTime, err := miner.consensusEngine.SealTime(miner.blockRepository, &blockTemplate.Header)
+	if errors.Is(err, consensus.ErrNotSealer) {
+		log.Printf("|Miner| %x isn't an authorized sealer", blockTemplate.Header.MinerPublicKey)
+		miner.waitForNewChainTip(blockTemplate, knownChainTipId)
+		return
+	}
+
+	if errors.Is(err, consensus.ErrRecentlySealed) {
+		log.Printf("|Miner| Waiting for other sealers: %v", err)
+		miner.waitForNewChainTip(blockTemplate, knownChainTipId)
+		return
 	}
 
-	nbits, err := miner.blockRepository.GetNextWorkRequired(activeChainTipId)
+	if err != nil {
+		log.Printf("|Miner| Failed to get seal time: %v", err)
+		return
+	}
+
+	startTime := time.Now()
+	atomic.StoreInt64(&miner.statistics.CurrentBlockStart, startTime.UnixNano())
+	atomic.StoreUint32(&miner.statistics.CurrentNBits, blockTemplate.Header.NBits)
+
+	sealTime = max(sealTime, medianPastTime+1)
+	if now := miner.getNetworkTime(); now < sealTime {
+		log.Printf("|Miner| Sealing block in %ds", sealTime-now)
+		if !miner.waitUntil(blockTemplate, knownChainTipId, sealTime) {
+			return
+		}
+	}
+
+	blockTemplate.Header.Nonce = 0
+	blockTemplate.Header.Timestamp = max(sealTime, miner.getNetworkTime())
+	miner.completeBlock(blockTemplate, startTime)
+}
+
+// waitUntil waits for the network time to reach timestamp, it's false when the miner stopped or the template went stale first
+func (miner *MinerImpl) waitUntil(blockTemplate *data_models.Block, knownChainTipId *[]byte, timestamp int64) bool {
+	for miner.getNetworkTime() < timestamp {
+		select {
+		case <-miner.stopChannel:
+			return false
+		case <-time.After(SEAL_POLL_INTERVAL):
+		}
+
+		if chainTipId := miner.latestChainTipId.Load(); chainTipId != knownChainTipId {
+			knownChainTipId = chainTipId
+			if miner.isStale(blockTemplate) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+func (miner *MinerImpl) waitForNewChainTip(blockTemplate *data_models.Block, knownChainTipId *[]byte) {
+	miner.waitUntil(blockTemplate, knownChainTipId, math.MaxInt64)
+}
+
+// mineNonces is run by every worker on its own copy of the header bytes, only worker 0 watches the chain tip
+func (miner *MinerImpl) mineNonces(
+	search *nonceSearch,
+	worker int,
+	blockTemplate *data_models.Block,
+	targetBytes []byte,
+	medianPastTime int64,
+	knownChainTipId *[]byte) {
+	defer search.wg.Done()
+
+	step := uint64(miner.properties.Workers)
+	nonce := uint64(worker)
+	timestamp := blockTemplate.Header.Timestamp
+	hashesTried := &miner.workerHashesTried[worker]
+
+	blockHeaderBytes := blockTemplate.Header.AsBytes()
+	data_models.UpdateBlockHeaderBytes(blockHeaderBytes, timestamp, nonce)
+	blockHeaderHash := hash.HashBytesInto(blockHeaderBytes, make([]byte, 32))
+
+	for tries := uint64(1); !difficulty.IsHashBelowTargetBytes(blockHeaderHash, targetBytes); tries++ {
+		select {
+		case <-miner.stopChannel:
+			return
+		case <-search.done:
+			return
+		default:
+			nonce += step
+			hashesTried.Add(1)
+
+			if worker == 0 {
+				if chainTipId := miner.latestChainTipId.Load(); chainTipId != knownChainTipId {
+					knownChainTipId = chainTipId
+					if miner.isStale(blockTemplate) {
+						search.cancel()
+						return
+					}
+				}
+			}
+
+			if tries&0x3ffff == 0 {
+				timestamp = max(medianPastTime+1, miner.getNetworkTime())
+			}
+
+			data_models.UpdateBlockHeaderBytes(blockHeaderBytes, timestamp, nonce)
+			hash.HashBytesInto(blockHeaderBytes, blockHeaderHash)
+		}
+	}
+
+	search.solve(nonce, timestamp)
+}
+
+func (miner *MinerImpl) CreateBlockTemplate() (*data_models.Block, error) {
+	activeChainTipId := slices.Clone(miner.blockRepository.GetActiveChainTipId())
+
+	maxBlockSize := min(miner.properties.MaxBlockSize, miner.chainParams.Election.MaxBlockSize)
+	maxBlockTransactions := min(miner.properties.MaxBlockTransactions, miner.chainParams.Election.MaxBlockVotes)
+
+	txs, err := miner.mempool.GetBlockCandidates(maxBlockTransactions, maxBlockSize-data_models.EMPTY_BLOCK_SIZE)
 	if err != nil {
 		return nil, err
 	}
@@ -158,11 +333,14 @@ func (miner *MinerImpl) CreateBlockTemplate() (*data_models.Block, error) {
 	merkleRoot := data_models.TransactionsMerkleRoot(txs)
 
 	templateHeader := data_models.BlockHeader{
-		Version:         miner.properties.NodeVersion,
+		Version:         data_models.BLOCK_VERSION_SIGNED,
 		PreviousBlockId: activeChainTipId,
 		MerkleRoot:      merkleRoot,
-		NBits:           nbits,
-		MinerPublicKey:  miner.properties.MinerPublicKey,
+		MinerPublicKey:  miner.properties.MinerKeyPair.PublicKey.AsBytes(),
+	}
+
+	if err := miner.consensusEngine.PrepareHeader(miner.blockRepository, &templateHeader); err != nil {
+		return nil, err
 	}
 
 	template := &data_models.Block{
@@ -173,21 +351,103 @@ func (miner *MinerImpl) CreateBlockTemplate() (*data_models.Block, error) {
 	return template, nil
 }
 
+// Generate mines count blocks right away, templates abandoned because the chain tip changed are mined again.
+// Every block goes through the block handlers and must be accepted by them before the next one is mined.
+// A miner that may not seal the next block fails instead of waiting for other sealers.
+func (miner *MinerImpl) Generate(count int) ([]*data_models.Block, error) {
+	blocks := make([]*data_models.Block, 0, count)
+
+	for len(blocks) < count {
+		select {
+		case <-miner.stopChannel:
+			return blocks, errors.New("miner stopped")
+		default:
+		}
+
+		template, err := miner.CreateBlockTemplate()
+		if err != nil {
+			return blocks, err
+		}
+
+		if !miner.consensusEngine.RequiresProofOfWork() {
+			_, err := miner.consensusEngine.SealTime(miner.blockRepository, &template.Header)
+			if errors.Is(err, consensus.ErrNotSealer) || errors.Is(err, consensus.ErrRecentlySealed) {
+				return blocks, err
+			}
+		}
+
+		miner.MineBlockTemplate(template)
+		if template.Header.Id == nil {
+			continue
+		}
+
+		accepted, err := miner.blockRepository.HaveBlock(template.Header.Id)
+		if err != nil {
+			return blocks, err
+		}
+
+		if !accepted {
+			return blocks, fmt.Errorf("generated block %x was rejected", template.Header.Id)
+		}
+
+		blocks = append(blocks, template)
+	}
+
+	log.Printf("|Miner| Generated %d blocks", len(blocks))
+	return blocks, nil
+}
+
 func (miner *MinerImpl) Stop() {
 	miner.stopOnce.Do(func() {
 		log.Printf("|Miner| Stopping")
 		close(miner.stopChannel)
+		miner.eventBus.Unsubscribe(miner.tipSubscription)
 	})
 	miner.wg.Wait()
 }
 
+// signBlockHeader signs headers mined to the miner's own key, blocks can't be signed for other keys
+func (miner *MinerImpl) signBlockHeader(blockHeader *data_models.BlockHeader) error {
+	minerPublicKey := miner.properties.MinerKeyPair.PublicKey.AsBytes()
+	if !bytes.Equal(blockHeader.MinerPublicKey, minerPublicKey) {
+		return fmt.Errorf("block is mined to %x, not to the miner's key %x", blockHeader.MinerPublicKey, minerPublicKey)
+	}
+
+	return blockHeader.Sign(miner.properties.MinerKeyPair.PrivateKey)
+}
+
+func (miner *MinerImpl) isStale(blockTemplate *data_models.Block) bool {
+	activeChainTipId := miner.blockRepository.GetActiveChainTipId()
+	if bytes.Equal(blockTemplate.Header.PreviousBlockId, activeChainTipId) {
+		return false
+	}
+
+	log.Printf("|Miner| Chain tip changed to %x, restarting", activeChainTipId)
+	return true
+}
+
+// handleChainTipChanged only signals the mining loop, which checks the block repository for the actual tip
+func (miner *MinerImpl) handleChainTipChanged(event events.Event) {
+	tipChanged := event.(events.ChainTipChangedEvent)
+	miner.latestChainTipId.Store(&tipChanged.NewTipId)
+}
+
 func (miner *MinerImpl) GetMiningStatistics() MiningStatistics {
+	workerHashesTried := make([]int64, len(miner.workerHashesTried))
+	currentBlockHashesTried := int64(0)
+	for worker := range miner.workerHashesTried {
+		workerHashesTried[worker] = miner.workerHashesTried[worker].Load()
+		currentBlockHashesTried += workerHashesTried[worker]
+	}
+
 	return MiningStatistics{
 		TotalBlocksMined:        atomic.LoadInt64(&miner.statistics.TotalBlocksMined),
-		CurrentBlockHashesTried: atomic.LoadInt64(&miner.statistics.CurrentBlockHashesTried),
+		CurrentBlockHashesTried: currentBlockHashesTried,
 		LastNonce:               atomic.LoadInt64(&miner.statistics.LastNonce),
 		LastBlockTimeNs:         atomic.LoadInt64(&miner.statistics.LastBlockTimeNs),
 		CurrentNBits:            atomic.LoadUint32(&miner.statistics.CurrentNBits),
 		CurrentBlockStart:       atomic.LoadInt64(&miner.statistics.CurrentBlockStart),
+		MinimumNBits:            miner.chainParams.Difficulty.MinimumDifficulty,
+		WorkerHashesTried:       workerHashesTried,
 	}
 }
@@ -0,0 +1,38 @@
+package mining
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+type nonceSolution struct {
+	nonce     uint64
+	timestamp int64
+}
+
+// nonceSearch is shared by the workers mining one block template, done is closed as soon as any worker solves it or gives up on it
+type nonceSearch struct {
+	solution atomic.Pointer[nonceSolution]
+	done     chan struct{}
+	doneOnce sync.Once
+	wg       sync.WaitGroup
+}
+
+func newNonceSearch() *nonceSearch {
+	return &nonceSearch{
+		done: make(chan struct{}),
+	}
+}
+
+func (search *nonceSearch) solve(nonce uint64, timestamp int64) {
+	search.doneOnce.Do(func() {
+		search.solution.Store(&nonceSolution{nonce: nonce, timestamp: timestamp})
+		close(search.done)
+	})
+}
+
+func (search *nonceSearch) cancel() {
+	search.doneOnce.Do(func() {
+		close(search.done)
+	})
+}
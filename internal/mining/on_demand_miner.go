@@ -0,0 +1,15 @@
+package mining
+
+// OnDemandMiner never mines in the background, blocks are only mined through Generate and MineBlockTemplate
+// or by remote miners working on its block templates
+type OnDemandMiner struct {
+	*MinerImpl
+}
+
+func NewOnDemandMiner(miner *MinerImpl) *OnDemandMiner {
+	return &OnDemandMiner{miner}
+}
+
+func (m *OnDemandMiner) Start() {
+	// no-op
+}
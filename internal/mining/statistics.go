@@ -13,7 +13,9 @@ type MiningStatistics struct {
 	LastNonce               int64
 	LastBlockTimeNs         int64 // store as nanoseconds
 	CurrentNBits            uint32
-	CurrentBlockStart       int64 // Unix nano timestamp
+	CurrentBlockStart       int64   // Unix nano timestamp
+	MinimumNBits            uint32  // minimum difficulty of the chain, difficulty is relative to it
+	WorkerHashesTried       []int64 // hashes tried on the current block per worker, they add up to CurrentBlockHashesTried
 }
 
 func (s *MiningStatistics) LastBlockTime() time.Duration {
@@ -42,8 +44,12 @@ func (s *MiningStatistics) CurrentHashRate() float64 {
 }
 
 func (s *MiningStatistics) Difficulty() float64 {
+	if s.CurrentNBits == 0 || s.MinimumNBits == 0 {
+		return 0
+	}
+
 	currentTarget := difficulty.GetTargetFromNBits(s.CurrentNBits)
-	minTarget := difficulty.GetTargetFromNBits(difficulty.MINIMUM_DIFFICULTY)
+	minTarget := difficulty.GetTargetFromNBits(s.MinimumNBits)
 
 	r := new(big.Rat).SetFrac(minTarget, currentTarget)
 	f, _ := r.Float64()
@@ -0,0 +1,84 @@
+package mining_work
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	data_models "github.com/nivschuman/VotingBlockchain/internal/models"
+)
+
+const CLIENT_TIMEOUT = 30 * time.Second
+
+type WorkClient struct {
+	baseUrl    string
+	httpClient *http.Client
+}
+
+// NewWorkClient talks to the work server listening on address, host:port
+func NewWorkClient(address string) *WorkClient {
+	return &WorkClient{
+		baseUrl:    "http://" + address,
+		httpClient: &http.Client{Timeout: CLIENT_TIMEOUT},
+	}
+}
+
+func (client *WorkClient) GetBlockTemplate(minerPublicKey []byte) (*BlockTemplate, error) {
+	request := GetBlockTemplateRequest{MinerPublicKey: hex.EncodeToString(minerPublicKey)}
+
+	var template BlockTemplate
+	if err := client.post(GET_BLOCK_TEMPLATE_PATH, request, &template); err != nil {
+		return nil, err
+	}
+
+	return &template, nil
+}
+
+func (client *WorkClient) SubmitBlock(block *data_models.Block) (*SubmitBlockResponse, error) {
+	request := SubmitBlockRequest{Block: hex.EncodeToString(block.AsBytes())}
+
+	var response SubmitBlockResponse
+	if err := client.post(SUBMIT_BLOCK_PATH, request, &response); err != nil {
+		return nil, err
+	}
+
+	return &response, nil
+}
+
+func (client *WorkClient) GetMinerCredits(minerPublicKey []byte) (*MinerCreditsResponse, error) {
+	request := GetMinerCreditsRequest{MinerPublicKey: hex.EncodeToString(minerPublicKey)}
+
+	var response MinerCreditsResponse
+	if err := client.post(GET_MINER_CREDITS_PATH, request, &response); err != nil {
+		return nil, err
+	}
+
+	return &response, nil
+}
+
+func (client *WorkClient) post(path string, request any, response any) error {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+
+	httpResponse, err := client.httpClient.Post(client.baseUrl+path, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer httpResponse.Body.Close()
+
+	if httpResponse.StatusCode != http.StatusOK {
+		var errResponse errorResponse
+		if err := json.NewDecoder(httpResponse.Body).Decode(&errResponse); err != nil {
+			return fmt.Errorf("work server returned %s", httpResponse.Status)
+		}
+
+		return fmt.Errorf("work server returned %s: %s", httpResponse.Status, errResponse.Error)
+	}
+
+	return json.NewDecoder(httpResponse.Body).Decode(response)
+}
@@ -0,0 +1,57 @@
+package mining_work
+
+import (
+	"encoding/hex"
+
+	data_models "github.com/nivschuman/VotingBlockchain/internal/models"
+)
+
+type GetBlockTemplateRequest struct {
+	MinerPublicKey string `json:"miner_public_key"` //hex, compressed secp256k1
+}
+
+// BlockTemplate is a block ready to be mined, the miner only changes the header's timestamp and nonce
+type BlockTemplate struct {
+	Block           string `json:"block"` //hex of the block bytes
+	PreviousBlockId string `json:"previous_block_id"`
+	Height          uint64 `json:"height"`
+	NBits           uint32 `json:"nbits"`
+	Target          string `json:"target"`        //hex, 32 bytes
+	MinTimestamp    int64  `json:"min_timestamp"` //median time past of the previous blocks +1
+	CurrentTime     int64  `json:"current_time"`  //network adjusted time of the node
+}
+
+type SubmitBlockRequest struct {
+	Block string `json:"block"` //hex of the block bytes
+}
+
+type SubmitBlockResponse struct {
+	Accepted bool   `json:"accepted"`
+	BlockId  string `json:"block_id"`
+	Reason   string `json:"reason,omitempty"` //why the block was rejected
+}
+
+type GetMinerCreditsRequest struct {
+	MinerPublicKey string `json:"miner_public_key"` //hex, compressed secp256k1
+}
+
+// MinerCreditsResponse is what the miner earned in the node's active chain
+type MinerCreditsResponse struct {
+	MinerPublicKey string `json:"miner_public_key"`
+	Blocks         uint64 `json:"blocks"`
+	Votes          uint64 `json:"votes"`
+	Credits        uint64 `json:"credits"`
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+func (template *BlockTemplate) GetBlock() (*data_models.Block, error) {
+	blockBytes, err := hex.DecodeString(template.Block)
+	if err != nil {
+		return nil, err
+	}
+
+	return data_models.BlockFromBytes(blockBytes)
+}
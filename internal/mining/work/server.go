@@ -0,0 +1,255 @@
+package mining_work
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"time"
+
+	chainparams "github.com/nivschuman/VotingBlockchain/internal/chainparams"
+	consensus "github.com/nivschuman/VotingBlockchain/internal/consensus"
+	ppk "github.com/nivschuman/VotingBlockchain/internal/crypto/ppk"
+	repos "github.com/nivschuman/VotingBlockchain/internal/database/repositories"
+	mining "github.com/nivschuman/VotingBlockchain/internal/mining"
+	data_models "github.com/nivschuman/VotingBlockchain/internal/models"
+)
+
+const GET_BLOCK_TEMPLATE_PATH = "/getblocktemplate"
+const SUBMIT_BLOCK_PATH = "/submitblock"
+const GET_MINER_CREDITS_PATH = "/getminercredits"
+
+const SHUTDOWN_TIMEOUT = 5 * time.Second
+
+// BlockSubmitter processes a solved block, a nil error means the block was accepted
+type BlockSubmitter func(block *data_models.Block) error
+
+// WorkServer lets miners outside of the node get block templates from the node's miner and submit the blocks they solve
+type WorkServer struct {
+	address string
+
+	miner           mining.Miner
+	blockRepository repos.BlockRepository
+	chainParams     *chainparams.ChainParams
+	consensusEngine consensus.ConsensusEngine
+	getNetworkTime  func() int64
+	submitBlock     BlockSubmitter
+
+	server   *http.Server
+	listener net.Listener
+}
+
+func NewWorkServer(
+	address string,
+	miner mining.Miner,
+	blockRepository repos.BlockRepository,
+	chainParams *chainparams.ChainParams,
+	getNetworkTime func() int64,
+	submitBlock BlockSubmitter) *WorkServer {
+	workServer := &WorkServer{
+		address:         address,
+		miner:           miner,
+		blockRepository: blockRepository,
+		chainParams:     chainParams,
+		consensusEngine: consensus.NewConsensusEngine(chainParams),
+		getNetworkTime:  getNetworkTime,
+		submitBlock:     submitBlock,
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST "+GET_BLOCK_TEMPLATE_PATH, workServer.handleGetBlockTemplate)
+	mux.HandleFunc("POST "+SUBMIT_BLOCK_PATH, workServer.handleSubmitBlock)
+	mux.HandleFunc("POST "+GET_MINER_CREDITS_PATH, workServer.handleGetMinerCredits)
+	workServer.server = &http.Server{Handler: mux}
+
+	return workServer
+}
+
+func (workServer *WorkServer) Start() error {
+	listener, err := net.Listen("tcp", workServer.address)
+	if err != nil {
+		return err
+	}
+
+	workServer.listener = listener
+	log.Printf("|Work Server| Listening on %s", listener.Addr().String())
+
+	go func() {
+		err := workServer.server.Serve(listener)
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("|Work Server| Stopped serving: %v", err)
+		}
+	}()
+
+	return nil
+}
+
+// Addr is the address the server listens on, it's known only after Start
+func (workServer *WorkServer) Addr() string {
+	if workServer.listener == nil {
+		return workServer.address
+	}
+
+	return workServer.listener.Addr().String()
+}
+
+func (workServer *WorkServer) Stop() error {
+	log.Printf("|Work Server| Stopping")
+
+	ctx, cancel := context.WithTimeout(context.Background(), SHUTDOWN_TIMEOUT)
+	defer cancel()
+
+	return workServer.server.Shutdown(ctx)
+}
+
+func (workServer *WorkServer) handleGetBlockTemplate(w http.ResponseWriter, r *http.Request) {
+	var request GetBlockTemplateRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1024)).Decode(&request); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid request: %v", err))
+		return
+	}
+
+	minerPublicKey, err := decodeMinerPublicKey(request.MinerPublicKey)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	//sealed blocks have no nonce to search, sealers seal them in their own nodes
+	if !workServer.consensusEngine.RequiresProofOfWork() {
+		writeError(w, http.StatusServiceUnavailable, fmt.Errorf("chain uses %s consensus, blocks aren't mined", workServer.consensusEngine.Name()))
+		return
+	}
+
+	template, err := workServer.miner.CreateBlockTemplate()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if template == nil {
+		writeError(w, http.StatusServiceUnavailable, errors.New("node doesn't create block templates"))
+		return
+	}
+
+	medianTimePast, err := workServer.blockRepository.GetMedianTimePast(template.Header.PreviousBlockId, 11)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	template.Header.MinerPublicKey = minerPublicKey
+	template.Header.Timestamp = max(medianTimePast+1, workServer.getNetworkTime())
+
+	height, err := workServer.blockRepository.GetActiveChainHeight()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	target := template.Header.GetTarget()
+	writeJSON(w, http.StatusOK, BlockTemplate{
+		Block:           hex.EncodeToString(template.AsBytes()),
+		PreviousBlockId: hex.EncodeToString(template.Header.PreviousBlockId),
+		Height:          height + 1,
+		NBits:           template.Header.NBits,
+		Target:          hex.EncodeToString(target.FillBytes(make([]byte, 32))),
+		MinTimestamp:    medianTimePast + 1,
+		CurrentTime:     workServer.getNetworkTime(),
+	})
+}
+
+func (workServer *WorkServer) handleSubmitBlock(w http.ResponseWriter, r *http.Request) {
+	//hex doubles the block size, the rest is room for the json
+	maxRequestSize := int64(2*workServer.chainParams.Election.MaxBlockSize + 1024)
+
+	var request SubmitBlockRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestSize)).Decode(&request); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid request: %v", err))
+		return
+	}
+
+	blockBytes, err := hex.DecodeString(request.Block)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid block hex: %v", err))
+		return
+	}
+
+	block, err := data_models.BlockFromBytes(blockBytes)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid block: %v", err))
+		return
+	}
+
+	response := SubmitBlockResponse{
+		Accepted: true,
+		BlockId:  hex.EncodeToString(block.Header.Id),
+	}
+
+	if err := workServer.submitBlock(block); err != nil {
+		log.Printf("|Work Server| Rejected submitted block %x: %v", block.Header.Id, err)
+		response.Accepted = false
+		response.Reason = err.Error()
+	} else {
+		log.Printf("|Work Server| Accepted submitted block %x", block.Header.Id)
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+func (workServer *WorkServer) handleGetMinerCredits(w http.ResponseWriter, r *http.Request) {
+	var request GetMinerCreditsRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1024)).Decode(&request); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid request: %v", err))
+		return
+	}
+
+	minerPublicKey, err := decodeMinerPublicKey(request.MinerPublicKey)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	minerCredits, err := workServer.blockRepository.GetMinerCredits(minerPublicKey)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, MinerCreditsResponse{
+		MinerPublicKey: hex.EncodeToString(minerCredits.MinerPublicKey),
+		Blocks:         minerCredits.Blocks,
+		Votes:          minerCredits.Votes,
+		Credits:        minerCredits.Credits,
+	})
+}
+
+func decodeMinerPublicKey(minerPublicKeyHex string) ([]byte, error) {
+	minerPublicKey, err := hex.DecodeString(minerPublicKeyHex)
+	if err == nil {
+		_, err = ppk.GetPublicKeyFromBytes(minerPublicKey)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("invalid miner public key: %v", err)
+	}
+
+	return minerPublicKey, nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		log.Printf("|Work Server| Failed to write response: %v", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, errorResponse{Error: err.Error()})
+}
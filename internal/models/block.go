@@ -3,12 +3,24 @@ package models
 import (
 	"bytes"
 	"encoding/binary"
+	"fmt"
+	"io"
 	"math/big"
 
 	"github.com/nivschuman/VotingBlockchain/internal/crypto/hash"
+	"github.com/nivschuman/VotingBlockchain/internal/crypto/ppk"
 	"github.com/nivschuman/VotingBlockchain/internal/difficulty"
 )
 
+const BLOCK_HEADER_SIZE = 121
+const MAX_MINER_SIGNATURE_SIZE = 72 //ASN1 ECDSA signature
+
+// header, miner signature and transaction count
+const EMPTY_BLOCK_SIZE = BLOCK_HEADER_SIZE + 4 + MAX_MINER_SIGNATURE_SIZE + 4
+
+// blocks from this version carry the miner's signature of the header
+const BLOCK_VERSION_SIGNED = 2
+
 type BlockHeader struct {
 	Id              []byte //hash of (Version, Timestamp, NBits, Nonce, PreviousBlockId, MerkleRoot, MinerPublicKey), 32 bytes
 	Version         int32  //version of block, 4 bytes
@@ -18,6 +30,7 @@ type BlockHeader struct {
 	NBits           uint32 //encoded version of target threshold this blocks header has must be less than or equal to, 4 bytes
 	Nonce           uint64 //arbitrary numbers miners change in order to produce hash less than or equal to the target threshold, 8 bytes
 	MinerPublicKey  []byte //public key of miner that made the block, marshal compressed, 33 bytes
+	MinerSignature  []byte //signature of Id, in ASN1 format, 70-72 bytes, signed by miner, only in signed versions and not part of Id
 }
 
 type Block struct {
@@ -38,6 +51,31 @@ func (blockHeader *BlockHeader) IsHashBelowTarget() bool {
 	return difficulty.IsHashBelowTarget(blockHeader.GetHash(), target)
 }
 
+func (blockHeader *BlockHeader) IsSigned() bool {
+	return blockHeader.Version >= BLOCK_VERSION_SIGNED
+}
+
+// Sign must be called after the header is mined, the signature is of the final Id
+func (blockHeader *BlockHeader) Sign(minerPrivateKey ppk.PrivateKey) error {
+	signature, err := minerPrivateKey.CreateSignature(blockHeader.GetHash())
+	if err != nil {
+		return err
+	}
+
+	blockHeader.MinerSignature = signature
+	return nil
+}
+
+func (blockHeader *BlockHeader) MinerSignatureIsValid() (bool, error) {
+	publicKey, err := ppk.GetPublicKeyFromBytes(blockHeader.MinerPublicKey)
+
+	if err != nil {
+		return false, err
+	}
+
+	return publicKey.VerifySignature(blockHeader.MinerSignature, blockHeader.GetHash()), nil
+}
+
 func (blockHeader *BlockHeader) GetTarget() *big.Int {
 	return difficulty.GetTargetFromNBits(blockHeader.NBits)
 }
@@ -113,6 +151,11 @@ func (block *Block) AsBytes() []byte {
 
 	buf.Write(block.Header.AsBytes())
 
+	if block.Header.IsSigned() {
+		binary.Write(buf, binary.BigEndian, uint32(len(block.Header.MinerSignature)))
+		buf.Write(block.Header.MinerSignature)
+	}
+
 	binary.Write(buf, binary.BigEndian, uint32(len(block.Transactions)))
 	for _, tx := range block.Transactions {
 		txBytes := tx.AsBytes()
@@ -123,6 +166,11 @@ func (block *Block) AsBytes() []byte {
 	return buf.Bytes()
 }
 
+// TransactionSizeInBlock is the number of bytes the transaction adds to a serialized block, its length and its bytes
+func TransactionSizeInBlock(tx *Transaction) int {
+	return 4 + len(tx.AsBytes())
+}
+
 func (block *Block) GetBlockWork() *big.Int {
 	return difficulty.CalculateWork(block.Header.NBits)
 }
@@ -131,7 +179,7 @@ func BlockFromBytes(b []byte) (*Block, error) {
 	buf := bytes.NewReader(b)
 	block := &Block{}
 
-	headerBytes := make([]byte, 121)
+	headerBytes := make([]byte, BLOCK_HEADER_SIZE)
 	if _, err := buf.Read(headerBytes); err != nil {
 		return nil, err
 	}
@@ -142,17 +190,42 @@ func BlockFromBytes(b []byte) (*Block, error) {
 	}
 	block.Header = *blockHeader
 
+	if block.Header.IsSigned() {
+		var signatureLength uint32
+		if err := binary.Read(buf, binary.BigEndian, &signatureLength); err != nil {
+			return nil, err
+		}
+
+		if signatureLength > MAX_MINER_SIGNATURE_SIZE {
+			return nil, fmt.Errorf("miner signature of %d bytes exceeds %d", signatureLength, MAX_MINER_SIGNATURE_SIZE)
+		}
+
+		block.Header.MinerSignature = make([]byte, signatureLength)
+		if _, err := io.ReadFull(buf, block.Header.MinerSignature); err != nil {
+			return nil, err
+		}
+	}
+
 	var numTransactions uint32
 	if err := binary.Read(buf, binary.BigEndian, &numTransactions); err != nil {
 		return nil, err
 	}
 
+	//every transaction takes at least its 4 length bytes
+	if int64(numTransactions)*4 > int64(buf.Len()) {
+		return nil, fmt.Errorf("block with %d transactions has only %d bytes left", numTransactions, buf.Len())
+	}
+
 	for i := uint32(0); i < numTransactions; i++ {
 		var txLength uint32
 		if err := binary.Read(buf, binary.BigEndian, &txLength); err != nil {
 			return nil, err
 		}
 
+		if int64(txLength) > int64(buf.Len()) {
+			return nil, fmt.Errorf("transaction %d of %d bytes exceeds the remaining %d", i, txLength, buf.Len())
+		}
+
 		txBytes := make([]byte, txLength)
 		if _, err := buf.Read(txBytes); err != nil {
 			return nil, err
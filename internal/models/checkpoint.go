@@ -0,0 +1,45 @@
+package models
+
+import (
+	"bytes"
+	"encoding/binary"
+
+	"github.com/nivschuman/VotingBlockchain/internal/crypto/hash"
+	"github.com/nivschuman/VotingBlockchain/internal/crypto/ppk"
+)
+
+// Checkpoint pins the block of the active chain at a height, the chain is never reorganized past it
+type Checkpoint struct {
+	Height    uint64 //height of the block
+	BlockId   []byte //id of the block at height, 32 bytes
+	Signature []byte //signature of hash of (Height, BlockId), in ASN1 format, signed by government, empty for hardcoded checkpoints
+}
+
+func (checkpoint *Checkpoint) GetHash() []byte {
+	buf := new(bytes.Buffer)
+
+	binary.Write(buf, binary.BigEndian, checkpoint.Height)
+	buf.Write(checkpoint.BlockId)
+
+	return hash.HashBytes(buf.Bytes())
+}
+
+func (checkpoint *Checkpoint) Sign(governmentPrivateKey ppk.PrivateKey) error {
+	signature, err := governmentPrivateKey.CreateSignature(checkpoint.GetHash())
+	if err != nil {
+		return err
+	}
+
+	checkpoint.Signature = signature
+	return nil
+}
+
+func (checkpoint *Checkpoint) SignatureIsValid(governmentPublicKey []byte) (bool, error) {
+	publicKey, err := ppk.GetPublicKeyFromBytes(governmentPublicKey)
+
+	if err != nil {
+		return false, err
+	}
+
+	return publicKey.VerifySignature(checkpoint.Signature, checkpoint.GetHash()), nil
+}
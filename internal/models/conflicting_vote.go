@@ -0,0 +1,48 @@
+package models
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"time"
+)
+
+const (
+	ConflictInMempool     = "mempool"      //the kept vote was waiting in the mempool
+	ConflictInActiveChain = "active chain" //the kept vote was already in the active chain
+)
+
+// ConflictingVote is evidence of a voter signing two different votes, both transactions carry the voter's signature
+type ConflictingVote struct {
+	VoterPublicKey         []byte
+	Transaction            *Transaction //vote that lost the conflict
+	ConflictingTransaction *Transaction //vote that was kept
+	Source                 string       //where the kept vote was found
+	DetectedAt             time.Time
+	Conflicts              uint64 //conflicting votes detected for the voter, only the first pair is kept
+}
+
+type conflictingVoteJSON struct {
+	VoterPublicKey         string `json:"voter_public_key"`
+	Transaction            string `json:"transaction"`
+	ConflictingTransaction string `json:"conflicting_transaction"`
+	Source                 string `json:"source"`
+	DetectedAt             string `json:"detected_at"`
+	Conflicts              uint64 `json:"conflicts"`
+}
+
+// ConflictingVotesToJSON encodes the votes with hex encoded keys and serialized transactions
+func ConflictingVotesToJSON(conflictingVotes []*ConflictingVote) ([]byte, error) {
+	votesJSON := make([]conflictingVoteJSON, 0, len(conflictingVotes))
+	for _, conflictingVote := range conflictingVotes {
+		votesJSON = append(votesJSON, conflictingVoteJSON{
+			VoterPublicKey:         hex.EncodeToString(conflictingVote.VoterPublicKey),
+			Transaction:            hex.EncodeToString(conflictingVote.Transaction.AsBytes()),
+			ConflictingTransaction: hex.EncodeToString(conflictingVote.ConflictingTransaction.AsBytes()),
+			Source:                 conflictingVote.Source,
+			DetectedAt:             conflictingVote.DetectedAt.UTC().Format(time.RFC3339),
+			Conflicts:              conflictingVote.Conflicts,
+		})
+	}
+
+	return json.MarshalIndent(votesJSON, "", "  ")
+}
@@ -0,0 +1,9 @@
+package models
+
+// MinerCredits is what a miner earned in the active chain, a reward per block plus a fee per vote in those blocks
+type MinerCredits struct {
+	MinerPublicKey []byte
+	Blocks         uint64
+	Votes          uint64
+	Credits        uint64
+}
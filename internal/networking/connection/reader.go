@@ -1,9 +1,12 @@
 package networking_connection
 
 import (
+	"bytes"
 	"context"
 	"encoding/binary"
 	"errors"
+	"fmt"
+	"io"
 	"net"
 	"slices"
 	"time"
@@ -11,13 +14,17 @@ import (
 	models "github.com/nivschuman/VotingBlockchain/internal/networking/models"
 )
 
+var ErrMagicBytesMismatch = errors.New("magic bytes mismatch, the stream belongs to another network")
+
 type Reader struct {
+	MagicBytes    []byte //expected start of every message
 	HeaderBuffer  [20]byte
 	PayloadBuffer []byte
 }
 
-func NewReader() *Reader {
+func NewReader(magicBytes []byte) *Reader {
 	return &Reader{
+		MagicBytes:    magicBytes,
 		PayloadBuffer: make([]byte, 0),
 	}
 }
@@ -69,27 +76,18 @@ func (reader *Reader) ReadMessage(conn net.Conn) (*models.Message, error) {
 	return reader.processMessage()
 }
 
+// readMagicBytes fails with ErrMagicBytesMismatch on any other bytes, the stream can't be trusted after that
 func (reader *Reader) readMagicBytes(conn net.Conn) error {
-	magicBytesIndex := 0
-	buf := make([]byte, 1)
-
-	for {
-		_, err := conn.Read(buf)
-
-		if err != nil {
-			return err
-		}
-
-		if buf[0] == models.MAGIC_BYTES[magicBytesIndex] {
-			magicBytesIndex++
+	magicBytes := make([]byte, len(reader.MagicBytes))
+	if _, err := io.ReadFull(conn, magicBytes); err != nil {
+		return err
+	}
 
-			if magicBytesIndex == len(models.MAGIC_BYTES) {
-				return nil
-			}
-		} else {
-			magicBytesIndex = 0
-		}
+	if !bytes.Equal(magicBytes, reader.MagicBytes) {
+		return fmt.Errorf("%w: expected %x, received %x", ErrMagicBytesMismatch, reader.MagicBytes, magicBytes)
 	}
+
+	return nil
 }
 
 func (reader *Reader) readHeader(conn net.Conn) error {
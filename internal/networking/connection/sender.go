@@ -7,10 +7,13 @@ import (
 )
 
 type Sender struct {
+	MagicBytes []byte //start of every message
 }
 
-func NewSender() *Sender {
-	return &Sender{}
+func NewSender(magicBytes []byte) *Sender {
+	return &Sender{
+		MagicBytes: magicBytes,
+	}
 }
 
 func (sender *Sender) SendMessage(conn net.Conn, Message *models.Message) error {
@@ -31,7 +34,7 @@ func (sender *Sender) SendMessage(conn net.Conn, Message *models.Message) error
 }
 
 func (sender *Sender) sendMagicBytes(conn net.Conn) error {
-	return sendBytes(conn, models.MAGIC_BYTES)
+	return sendBytes(conn, sender.MagicBytes)
 }
 
 func (sender *Sender) sendHeader(conn net.Conn, messageHeader *models.MessageHeader) error {
@@ -7,6 +7,8 @@ import (
 	compact "github.com/nivschuman/VotingBlockchain/internal/networking/utils/compact"
 )
 
+const MAX_INV_SIZE = 50000
+
 const MSG_TX = uint32(1)
 const MSG_BLOCK = uint32(2)
 
@@ -8,8 +8,6 @@ import (
 	chck "github.com/nivschuman/VotingBlockchain/internal/networking/utils/checksum"
 )
 
-var MAGIC_BYTES = []byte{0xD9, 0xB4, 0xBE, 0xF9}
-
 type MessageHeader struct {
 	Command  [12]byte
 	Length   uint32
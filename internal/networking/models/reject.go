@@ -0,0 +1,98 @@
+package networking_models
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	compact "github.com/nivschuman/VotingBlockchain/internal/networking/utils/compact"
+)
+
+const (
+	REJECT_MALFORMED = uint8(0x01)
+	REJECT_INVALID   = uint8(0x10)
+	REJECT_DUPLICATE = uint8(0x12)
+	REJECT_CONFLICT  = uint8(0x13) //vote conflicts with another vote of the same voter
+)
+
+const MAX_REJECT_REASON_SIZE = 111
+
+type Reject struct {
+	Message [12]byte //command of the rejected message
+	Code    uint8    //one of the REJECT_ codes
+	Reason  string   //human readable reason, at most MAX_REJECT_REASON_SIZE bytes
+	Hash    []byte   //id of the rejected transaction or block, 32 bytes
+}
+
+func NewRejectMessage(reject *Reject) (*Message, error) {
+	rejectBytes, err := reject.AsBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	return NewMessage(CommandReject, rejectBytes), nil
+}
+
+func (reject *Reject) AsBytes() ([]byte, error) {
+	var buf bytes.Buffer
+
+	reason := reject.Reason
+	if len(reason) > MAX_REJECT_REASON_SIZE {
+		reason = reason[:MAX_REJECT_REASON_SIZE]
+	}
+
+	reasonSize, err := compact.GetCompactSizeBytes(uint64(len(reason)))
+	if err != nil {
+		return nil, err
+	}
+
+	buf.Write(reject.Message[:])
+	buf.WriteByte(reject.Code)
+	buf.Write(reasonSize)
+	buf.WriteString(reason)
+	buf.Write(reject.Hash)
+
+	return buf.Bytes(), nil
+}
+
+func RejectFromBytes(data []byte) (*Reject, error) {
+	buf := bytes.NewReader(data)
+	reject := &Reject{}
+
+	if _, err := io.ReadFull(buf, reject.Message[:]); err != nil {
+		return nil, err
+	}
+
+	if err := binary.Read(buf, binary.BigEndian, &reject.Code); err != nil {
+		return nil, err
+	}
+
+	reasonSize, err := compact.ReadCompactSize(buf)
+	if err != nil {
+		return nil, err
+	}
+
+	if reasonSize > MAX_REJECT_REASON_SIZE {
+		return nil, fmt.Errorf("reject reason of %d bytes exceeds %d", reasonSize, MAX_REJECT_REASON_SIZE)
+	}
+
+	reason := make([]byte, reasonSize)
+	if _, err := io.ReadFull(buf, reason); err != nil {
+		return nil, err
+	}
+	reject.Reason = string(reason)
+
+	if buf.Len() > 0 {
+		reject.Hash = make([]byte, 32)
+		if _, err := io.ReadFull(buf, reject.Hash); err != nil {
+			return nil, err
+		}
+	}
+
+	return reject, nil
+}
+
+func (reject *Reject) CommandString() string {
+	return string(bytes.TrimRight(reject.Message[:], "\x00"))
+}
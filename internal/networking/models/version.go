@@ -3,6 +3,7 @@ package networking_models
 import (
 	"bytes"
 	"encoding/binary"
+	"slices"
 )
 
 type Version struct {
@@ -11,6 +12,8 @@ type Version struct {
 	Timestamp       int64  //UNIX timestamp of node
 	Nonce           uint64 //Random nonce for version packet
 	LastBlockHeight uint32 //Height of last block in active chain of node
+	GenesisBlockId  []byte //Id of the genesis block of the node's chain, nodes of different elections refuse each other, 32 bytes
+	PrunedHeight    uint32 //Height of the highest block the node pruned, it serves only headers up to it, 0 for a node keeping every block
 }
 
 type VersionProvider func() (*Version, error)
@@ -24,11 +27,17 @@ func (version *Version) AsBytes() []byte {
 	binary.Write(buf, binary.BigEndian, version.Nonce)
 	binary.Write(buf, binary.BigEndian, version.LastBlockHeight)
 
+	genesisBlockId := make([]byte, 32)
+	copy(genesisBlockId, version.GenesisBlockId)
+	buf.Write(genesisBlockId)
+
+	binary.Write(buf, binary.BigEndian, version.PrunedHeight)
+
 	return buf.Bytes()
 }
 
 func VersionFromBytes(bytes []byte) *Version {
-	if len(bytes) < 28 {
+	if len(bytes) < 60 {
 		return nil
 	}
 
@@ -37,6 +46,13 @@ func VersionFromBytes(bytes []byte) *Version {
 	timestamp := int64(binary.BigEndian.Uint64(bytes[8:16]))
 	nonce := binary.BigEndian.Uint64(bytes[16:24])
 	lastBlockHeight := binary.BigEndian.Uint32(bytes[24:28])
+	genesisBlockId := slices.Clone(bytes[28:60])
+
+	//nodes that don't prune may leave out the pruned height
+	prunedHeight := uint32(0)
+	if len(bytes) >= 64 {
+		prunedHeight = binary.BigEndian.Uint32(bytes[60:64])
+	}
 
 	return &Version{
 		ProtocolVersion: protocolVersion,
@@ -44,6 +60,8 @@ func VersionFromBytes(bytes []byte) *Version {
 		Timestamp:       timestamp,
 		Nonce:           nonce,
 		LastBlockHeight: lastBlockHeight,
+		GenesisBlockId:  genesisBlockId,
+		PrunedHeight:    prunedHeight,
 	}
 }
 
@@ -0,0 +1,20 @@
+package network
+
+import (
+	"math/rand"
+	"time"
+)
+
+const RECONNECT_BASE_DELAY = 30 * time.Second
+const RECONNECT_MAX_DELAY = 2 * time.Hour
+
+// ReconnectBackoff doubles the delay with every failed attempt, half of the delay is random jitter
+func ReconnectBackoff(attempts uint32) time.Duration {
+	delay := RECONNECT_MAX_DELAY
+	if attempts > 0 && attempts <= 16 {
+		delay = min(RECONNECT_BASE_DELAY<<(attempts-1), RECONNECT_MAX_DELAY)
+	}
+
+	half := delay / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
@@ -2,14 +2,17 @@ package network
 
 import (
 	"context"
+	"errors"
 	"log"
 	"net"
 	"slices"
 	"sync"
 	"time"
 
+	chainparams "github.com/nivschuman/VotingBlockchain/internal/chainparams"
 	config "github.com/nivschuman/VotingBlockchain/internal/config"
 	"github.com/nivschuman/VotingBlockchain/internal/database/repositories"
+	events "github.com/nivschuman/VotingBlockchain/internal/events"
 	connectors "github.com/nivschuman/VotingBlockchain/internal/networking/connectors"
 	models "github.com/nivschuman/VotingBlockchain/internal/networking/models"
 	peer "github.com/nivschuman/VotingBlockchain/internal/networking/peer"
@@ -19,17 +22,26 @@ import (
 
 type PeersMap map[string]*peer.Peer
 
+const DIAL_INTERVAL = 30 * time.Second
+const MAX_DISCONNECTED_PEERS = 50
+
+type DisconnectedPeer struct {
+	Address        *models.Address
+	Reason         peer.DisconnectReason
+	DisconnectedAt time.Time
+}
+
 type Network interface {
 	Start()
 	Stop()
 	AddCommandHandler(command [12]byte, handler peer.CommandHandler)
-	AddPeerEventHandler(event string, handler peer.PeerEventHandler)
-	RemovePeerEventHandlers(event string)
 	GetNetworkTime() int64
 	BroadcastItemToPeers(msgType uint32, id []byte, exceptPeer *peer.Peer)
 	DialAddress(address *models.Address) error
 	GetPeers() []*peer.Peer
-	RemovePeer(p *peer.Peer)
+	GetDisconnectedPeers() []*DisconnectedPeer
+	RemovePeer(p *peer.Peer, reason peer.DisconnectReason)
+	ReportMisbehavior(p *peer.Peer, reason string)
 	GetAddressRepository() repositories.AddressRepository
 }
 
@@ -40,8 +52,12 @@ type NetworkImpl struct {
 	Peers      PeersMap
 	PeersMutex sync.RWMutex
 
+	disconnectedPeersMutex sync.Mutex
+	disconnectedPeers      []*DisconnectedPeer
+
 	myVersion     models.VersionProvider
 	networkConfig *config.NetworkConfig
+	chainParams   *chainparams.ChainParams
 
 	addressRepository repositories.AddressRepository
 
@@ -51,8 +67,7 @@ type NetworkImpl struct {
 	commandHandlersMutex sync.Mutex
 	commandHandlers      *structures.BytesMap[[]peer.CommandHandler]
 
-	peerEventHandlersMutex sync.Mutex
-	peerEventHandlers      map[string][]peer.PeerEventHandler
+	eventBus events.EventBus
 
 	stopChannel chan bool
 	wg          sync.WaitGroup
@@ -61,24 +76,30 @@ type NetworkImpl struct {
 	cancelContext context.CancelFunc
 }
 
-func NewNetworkImpl(addressRepository repositories.AddressRepository, networkConfig *config.NetworkConfig, myVersion models.VersionProvider) *NetworkImpl {
+func NewNetworkImpl(
+	addressRepository repositories.AddressRepository,
+	networkConfig *config.NetworkConfig,
+	chainParams *chainparams.ChainParams,
+	myVersion models.VersionProvider,
+	eventBus events.EventBus) *NetworkImpl {
 	network := &NetworkImpl{}
+	network.networkConfig = networkConfig
+	network.chainParams = chainParams
 	network.Listener = connectors.NewListener(networkConfig.Ip, networkConfig.Port, network.handleConnection)
 	network.Dialer = connectors.NewDialer(network.handleConnection)
 	network.Peers = make(PeersMap)
 	network.stopChannel = make(chan bool)
 	network.stopContext, network.cancelContext = context.WithCancel(context.Background())
 	network.commandHandlers = structures.NewBytesMap[[]peer.CommandHandler]()
-	network.peerEventHandlers = make(map[string][]peer.PeerEventHandler)
+	network.eventBus = eventBus
 	network.addressRepository = addressRepository
-	network.networkConfig = networkConfig
 	network.myVersion = myVersion
 
 	return network
 }
 
 func (network *NetworkImpl) Start() {
-	log.Print("|Network| Starting")
+	log.Printf("|Network| Starting on %s", network.chainParams.Name)
 	network.Listener.Listen(&network.wg)
 	network.removePeers()
 	if network.networkConfig.Dial {
@@ -97,9 +118,8 @@ func (network *NetworkImpl) Stop() {
 	network.PeersMutex.Lock()
 	defer network.PeersMutex.Unlock()
 
-	for _, peer := range network.Peers {
-		log.Printf("|Network| Removing peer %s", peer.String())
-		peer.Disconnect()
+	for _, p := range network.Peers {
+		network.disconnectPeer(p, peer.DisconnectReasonShutdown)
 	}
 	network.Peers = make(PeersMap)
 }
@@ -112,20 +132,6 @@ func (network *NetworkImpl) AddCommandHandler(command [12]byte, handler peer.Com
 	network.commandHandlers.Put(command[:], append(handlers, handler))
 }
 
-func (network *NetworkImpl) AddPeerEventHandler(event string, handler peer.PeerEventHandler) {
-	network.peerEventHandlersMutex.Lock()
-	defer network.peerEventHandlersMutex.Unlock()
-
-	network.peerEventHandlers[event] = append(network.peerEventHandlers[event], handler)
-}
-
-func (network *NetworkImpl) RemovePeerEventHandlers(event string) {
-	network.peerEventHandlersMutex.Lock()
-	defer network.peerEventHandlersMutex.Unlock()
-
-	network.peerEventHandlers[event] = make([]peer.PeerEventHandler, 0)
-}
-
 func (network *NetworkImpl) GetNetworkTime() int64 {
 	network.networkTimeOffsetMutex.Lock()
 	defer network.networkTimeOffsetMutex.Unlock()
@@ -157,11 +163,7 @@ func (network *NetworkImpl) DialAddress(address *models.Address) error {
 	err := network.Dialer.DialContext(address.Ip, address.Port, network.stopContext)
 	if err != nil {
 		log.Printf("|Network| Failed to manually dial %s: %v", address.String(), err)
-
-		now := time.Now()
-		if err2 := network.addressRepository.UpdateLastFailed(address, &now); err2 != nil {
-			log.Printf("|Network| Failed to update last failed for address %s: %v", address.String(), err2)
-		}
+		network.recordFailedAttempt(address)
 
 		return err
 	}
@@ -180,7 +182,14 @@ func (network *NetworkImpl) GetPeers() []*peer.Peer {
 	return peers
 }
 
-func (network *NetworkImpl) RemovePeer(p *peer.Peer) {
+func (network *NetworkImpl) GetDisconnectedPeers() []*DisconnectedPeer {
+	network.disconnectedPeersMutex.Lock()
+	defer network.disconnectedPeersMutex.Unlock()
+
+	return slices.Clone(network.disconnectedPeers)
+}
+
+func (network *NetworkImpl) RemovePeer(p *peer.Peer, reason peer.DisconnectReason) {
 	if p == nil {
 		return
 	}
@@ -193,17 +202,28 @@ func (network *NetworkImpl) RemovePeer(p *peer.Peer) {
 		return
 	}
 
-	log.Printf("|Network| Removing peer %s", p.String())
-	p.Disconnect()
+	network.disconnectPeer(p, reason)
 	delete(network.Peers, key)
 
 	network.setNetworkTimeOffset()
 }
 
+// ReportMisbehavior marks a peer that violated the protocol, it is disconnected on the next peers cleanup
+func (network *NetworkImpl) ReportMisbehavior(p *peer.Peer, reason string) {
+	log.Printf("|Network| Peer %s misbehaved: %s", p.String(), reason)
+
+	p.MarkForRemoval(peer.DisconnectReasonProtocolError)
+	network.eventBus.Publish(events.PeerMisbehavedEvent{Peer: p, Reason: reason})
+}
+
 func (network *NetworkImpl) GetAddressRepository() repositories.AddressRepository {
 	return network.addressRepository
 }
 
+func (network *NetworkImpl) AddConnection(conn net.Conn, initializer bool) {
+	network.handleConnection(conn, initializer)
+}
+
 func (network *NetworkImpl) createPeerConfig() peer.PeerConfig {
 	sendDataInterval := time.Duration(network.networkConfig.SendDataInterval) * time.Second
 	pingInterval := time.Duration(network.networkConfig.PingInterval) * time.Second
@@ -213,6 +233,7 @@ func (network *NetworkImpl) createPeerConfig() peer.PeerConfig {
 		SendDataInterval: sendDataInterval,
 		PingInterval:     pingInterval,
 		GetAddrInterval:  getAddrInterval,
+		MagicBytes:       network.chainParams.MagicBytes,
 	}
 }
 
@@ -242,15 +263,19 @@ func (network *NetworkImpl) handleConnection(conn net.Conn, initializer bool) {
 	err = p.WaitForHandshake(time.Second * 10)
 	if err != nil {
 		log.Printf("|Network| Failed to complete handshake with peer %s: %v", p.String(), err)
-		p.Disconnect()
 
-		now := time.Now()
-		err := network.addressRepository.UpdateLastFailed(p.Address, &now)
-		if err != nil {
-			log.Printf("|Network| Failed to update last failed for address %s: %v", p.Address.String(), err)
+		reason := peer.DisconnectReasonProtocolError
+		if errors.Is(err, peer.ErrHandshakeTimeout) {
+			reason = peer.DisconnectReasonTimeout
+		} else if errors.Is(err, peer.ErrWrongNetwork) {
+			reason = peer.DisconnectReasonWrongNetwork
 		}
 
+		p.DisconnectWithReason(reason)
+		network.recordFailedAttempt(p.Address)
 		network.PeersMutex.Unlock()
+
+		network.eventBus.Publish(events.HandshakeFailedEvent{Address: p.Address, Reason: reason, Err: err})
 		return
 	}
 
@@ -301,15 +326,7 @@ func (network *NetworkImpl) handleConnection(conn net.Conn, initializer bool) {
 	p.StartProcessing()
 	network.PeersMutex.Unlock()
 
-	//peer connected event
-	network.peerEventHandlersMutex.Lock()
-	handlers, exists := network.peerEventHandlers["new_peer"]
-	if exists {
-		for _, handler := range handlers {
-			handler(peer.PeerEventData{Peer: p, Event: "new_peer"})
-		}
-	}
-	network.peerEventHandlersMutex.Unlock()
+	network.eventBus.Publish(events.PeerConnectedEvent{Peer: p})
 }
 
 func (network *NetworkImpl) setNetworkTimeOffset() {
@@ -331,7 +348,7 @@ func (network *NetworkImpl) setNetworkTimeOffset() {
 }
 
 func (network *NetworkImpl) dialPeers() {
-	ticker := time.NewTicker(2 * time.Minute)
+	ticker := time.NewTicker(DIAL_INTERVAL)
 	network.wg.Add(1)
 
 	dial := func() {
@@ -351,7 +368,8 @@ func (network *NetworkImpl) dialPeers() {
 		}
 		network.PeersMutex.RUnlock()
 
-		addresses, err := network.addressRepository.GetAddresses(neededAddresses, excludedAddresses)
+		now := time.Now()
+		addresses, err := network.addressRepository.GetDialableAddresses(neededAddresses, excludedAddresses, &now)
 		if err != nil {
 			log.Printf("|Network| Failed to get addresses: %v", err)
 			return
@@ -361,12 +379,7 @@ func (network *NetworkImpl) dialPeers() {
 			err := network.Dialer.DialContext(address.Ip, address.Port, network.stopContext)
 			if err != nil {
 				log.Printf("|Network| Failed to dial address %s: %v", address.String(), err)
-
-				now := time.Now()
-				err := network.addressRepository.UpdateLastFailed(address, &now)
-				if err != nil {
-					log.Printf("|Network| Failed to update last failed for address %s: %v", address.String(), err)
-				}
+				network.recordFailedAttempt(address)
 			}
 		}
 	}
@@ -403,26 +416,25 @@ func (network *NetworkImpl) removePeers() {
 				return
 			case <-ticker.C:
 				network.PeersMutex.RLock()
-				toRemove := make([]*peer.Peer, 0)
-				for _, peer := range network.Peers {
-					if peer.Remove || peer.Disconnected {
-						toRemove = append(toRemove, peer)
+				toRemove := make(map[*peer.Peer]peer.DisconnectReason)
+				for _, p := range network.Peers {
+					if p.Remove || p.Disconnected {
+						toRemove[p] = peer.DisconnectReasonConnectionClosed
 						continue
 					}
-					sinceLastPong := time.Since(peer.PingPongDetails.PongTime)
+					sinceLastPong := time.Since(p.PingPongDetails.PongTime)
 					pongTimeout := time.Duration(network.networkConfig.PongTimeout) * time.Second
 					if sinceLastPong > pongTimeout {
-						toRemove = append(toRemove, peer)
+						toRemove[p] = peer.DisconnectReasonTimeout
 						continue
 					}
 				}
 				network.PeersMutex.RUnlock()
 
 				network.PeersMutex.Lock()
-				for _, peer := range toRemove {
-					log.Printf("|Network| Removing peer %s", peer.String())
-					peer.Disconnect()
-					delete(network.Peers, peer.Conn.RemoteAddr().String())
+				for p, reason := range toRemove {
+					network.disconnectPeer(p, reason)
+					delete(network.Peers, p.Conn.RemoteAddr().String())
 				}
 				network.setNetworkTimeOffset()
 				network.PeersMutex.Unlock()
@@ -502,6 +514,7 @@ func (network *NetworkImpl) processAddr(fromPeer *peer.Peer, message *models.Mes
 
 	if addr.Count > models.MAX_ADDR_SIZE {
 		log.Printf("|Network| Received more than %d addresses from peer %s", models.MAX_ADDR_SIZE, fromPeer.String())
+		network.ReportMisbehavior(fromPeer, "sent too many addresses")
 		return
 	}
 
@@ -521,6 +534,40 @@ func (network *NetworkImpl) processAddr(fromPeer *peer.Peer, message *models.Mes
 	}
 }
 
+// disconnectPeer closes the connection to a peer and records why, the caller removes it from the peers map
+func (network *NetworkImpl) disconnectPeer(p *peer.Peer, reason peer.DisconnectReason) {
+	p.DisconnectWithReason(reason)
+	reason = p.GetDisconnectReason()
+
+	log.Printf("|Network| Removed peer %s: %s", p.String(), reason)
+
+	network.disconnectedPeersMutex.Lock()
+	network.disconnectedPeers = append(network.disconnectedPeers, &DisconnectedPeer{
+		Address:        p.Address,
+		Reason:         reason,
+		DisconnectedAt: time.Now(),
+	})
+	if len(network.disconnectedPeers) > MAX_DISCONNECTED_PEERS {
+		network.disconnectedPeers = network.disconnectedPeers[len(network.disconnectedPeers)-MAX_DISCONNECTED_PEERS:]
+	}
+	network.disconnectedPeersMutex.Unlock()
+
+	err := network.addressRepository.UpdateLastDisconnectReason(p.Address, string(reason))
+	if err != nil {
+		log.Printf("|Network| Failed to update disconnect reason for address %s: %v", p.Address.String(), err)
+	}
+
+	network.eventBus.Publish(events.PeerDisconnectedEvent{Peer: p, Reason: reason})
+}
+
+func (network *NetworkImpl) recordFailedAttempt(address *models.Address) {
+	now := time.Now()
+	err := network.addressRepository.RecordFailedAttempt(address, &now, ReconnectBackoff)
+	if err != nil {
+		log.Printf("|Network| Failed to record failed attempt for address %s: %v", address.String(), err)
+	}
+}
+
 func (network *NetworkImpl) addAddress(address *models.Address) error {
 	if address.NodeType != 1 {
 		return nil
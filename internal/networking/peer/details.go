@@ -10,6 +10,7 @@ type PeerDetails struct {
 	ProtocolVersion int32  //protocol version of peer
 	TimeOffset      int64  //difference between local time and peer time in seconds
 	BlockHeight     uint32 //peers block height
+	PrunedHeight    uint32 //height up to which the peer pruned its blocks, 0 when it serves every block
 }
 
 func (peer *Peer) SetPeerDetails(version *models.Version) {
@@ -17,5 +18,6 @@ func (peer *Peer) SetPeerDetails(version *models.Version) {
 		ProtocolVersion: version.ProtocolVersion,
 		TimeOffset:      time.Now().Unix() - version.Timestamp,
 		BlockHeight:     version.LastBlockHeight,
+		PrunedHeight:    version.PrunedHeight,
 	}
 }
@@ -0,0 +1,41 @@
+package networking_peer
+
+type DisconnectReason string
+
+const (
+	DisconnectReasonNone             DisconnectReason = ""
+	DisconnectReasonTimeout          DisconnectReason = "timeout"
+	DisconnectReasonProtocolError    DisconnectReason = "protocol error"
+	DisconnectReasonShutdown         DisconnectReason = "shutdown"
+	DisconnectReasonUserRequest      DisconnectReason = "user request"
+	DisconnectReasonConnectionClosed DisconnectReason = "connection closed"
+	DisconnectReasonWrongNetwork     DisconnectReason = "wrong network"
+)
+
+// SetDisconnectReason records why the peer is disconnected, the first reason recorded is kept
+func (peer *Peer) SetDisconnectReason(reason DisconnectReason) {
+	peer.disconnectReasonMutex.Lock()
+	defer peer.disconnectReasonMutex.Unlock()
+
+	if peer.disconnectReason == DisconnectReasonNone {
+		peer.disconnectReason = reason
+	}
+}
+
+func (peer *Peer) GetDisconnectReason() DisconnectReason {
+	peer.disconnectReasonMutex.Lock()
+	defer peer.disconnectReasonMutex.Unlock()
+
+	return peer.disconnectReason
+}
+
+func (peer *Peer) DisconnectWithReason(reason DisconnectReason) {
+	peer.SetDisconnectReason(reason)
+	peer.Disconnect()
+}
+
+// MarkForRemoval flags the peer so the network disconnects it on its next cleanup
+func (peer *Peer) MarkForRemoval(reason DisconnectReason) {
+	peer.SetDisconnectReason(reason)
+	peer.Remove = true
+}
@@ -2,6 +2,7 @@ package networking_peer
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"time"
 
@@ -19,6 +20,9 @@ const (
 	Completed
 )
 
+var ErrHandshakeTimeout = errors.New("timeout reached while waiting for handshake completion")
+var ErrWrongNetwork = errors.New("peer is on another network")
+
 type HandshakeDetails struct {
 	HandshakeState HandshakeState
 	Initializer    bool //true if we need to initialize handshake with peer
@@ -35,7 +39,7 @@ func (peer *Peer) WaitForHandshake(timeout time.Duration) error {
 	case err := <-result:
 		return err
 	case <-time.After(timeout):
-		return fmt.Errorf("timeout reached while waiting for handshake completion, state: %s", peer.HandshakeDetails.HandshakeState.AsString())
+		return fmt.Errorf("%w, state: %s", ErrHandshakeTimeout, peer.HandshakeDetails.HandshakeState.AsString())
 	}
 }
 
@@ -117,6 +121,9 @@ func (peer *Peer) receiveVersion() error {
 	message, ok := <-peer.readChannel
 
 	if !ok {
+		if peer.readErr != nil {
+			return fmt.Errorf("%w: %w", ErrWrongNetwork, peer.readErr)
+		}
 		return fmt.Errorf("peer %s read channel closed while waiting for version message", peer.Conn.RemoteAddr().String())
 	}
 
@@ -125,6 +132,18 @@ func (peer *Peer) receiveVersion() error {
 	}
 
 	version := models.VersionFromBytes(message.Payload)
+	if version == nil {
+		return fmt.Errorf("peer %s sent an invalid version message", peer.Conn.RemoteAddr().String())
+	}
+
+	myVersion, err := peer.myVersion()
+	if err != nil {
+		return err
+	}
+
+	if !bytes.Equal(version.GenesisBlockId, myVersion.GenesisBlockId) {
+		return fmt.Errorf("%w: peer %s genesis block %x, ours %x", ErrWrongNetwork, peer.Conn.RemoteAddr().String(), version.GenesisBlockId, myVersion.GenesisBlockId)
+	}
 
 	peer.SetPeerDetails(version)
 	peer.Address.NodeType = version.NodeType
@@ -146,6 +165,9 @@ func (peer *Peer) receiveVersion() error {
 func (peer *Peer) receiveVerAck() error {
 	message, ok := <-peer.readChannel
 	if !ok {
+		if peer.readErr != nil {
+			return fmt.Errorf("%w: %w", ErrWrongNetwork, peer.readErr)
+		}
 		return fmt.Errorf("Peer %s read channel closed while waiting for verAck message", peer.Conn.RemoteAddr().String())
 	}
 
@@ -21,16 +21,11 @@ const SEND_DATA_INTERVAL = 100 * time.Second
 
 type CommandHandler func(peer *Peer, message *models.Message)
 
-type PeerEventHandler func(eventData PeerEventData)
-type PeerEventData struct {
-	Event string
-	Peer  *Peer
-}
-
 type PeerConfig struct {
 	SendDataInterval time.Duration
 	PingInterval     time.Duration
 	GetAddrInterval  time.Duration
+	MagicBytes       []byte //magic bytes of the node's chain, streams starting with other bytes are refused
 }
 
 type Peer struct {
@@ -43,6 +38,9 @@ type Peer struct {
 	Remove       bool
 	Disconnected bool
 
+	disconnectReasonMutex sync.Mutex
+	disconnectReason      DisconnectReason
+
 	InventoryToSendMutex sync.Mutex
 	InventoryToSend      *models.Inv
 
@@ -63,6 +61,7 @@ type Peer struct {
 
 	readChannel chan models.Message
 	sendChannel chan models.Message
+	readErr     error //set before readChannel is closed because of a bad stream
 
 	stopChannel    chan bool
 	disconnectOnce sync.Once
@@ -70,8 +69,8 @@ type Peer struct {
 }
 
 func NewPeer(conn net.Conn, initializer bool, peerConfig PeerConfig, myVersion models.VersionProvider) *Peer {
-	reader := connection.NewReader()
-	sender := connection.NewSender()
+	reader := connection.NewReader(peerConfig.MagicBytes)
+	sender := connection.NewSender(peerConfig.MagicBytes)
 
 	readChannel := make(chan models.Message, 10)
 	sendChannel := make(chan models.Message, 10)
@@ -189,6 +188,16 @@ func (peer *Peer) readMessages() {
 
 			if err == io.EOF || err == io.ErrClosedPipe || errors.Is(err, net.ErrClosed) {
 				close(peer.readChannel)
+				peer.SetDisconnectReason(DisconnectReasonConnectionClosed)
+				peer.Disconnected = true
+				return
+			}
+
+			if errors.Is(err, connection.ErrMagicBytesMismatch) {
+				log.Printf("Peer %s is on another network: %v", peer.Conn.RemoteAddr().String(), err)
+				peer.readErr = err
+				close(peer.readChannel)
+				peer.SetDisconnectReason(DisconnectReasonWrongNetwork)
 				peer.Disconnected = true
 				return
 			}
@@ -220,6 +229,7 @@ func (peer *Peer) sendMessages() {
 			err := peer.sender.SendMessage(peer.Conn, &message)
 
 			if err == io.EOF || err == io.ErrClosedPipe || errors.Is(err, net.ErrClosed) {
+				peer.SetDisconnectReason(DisconnectReasonConnectionClosed)
 				peer.Disconnected = true
 				return
 			}
@@ -0,0 +1,156 @@
+package nodes
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	chainparams "github.com/nivschuman/VotingBlockchain/internal/chainparams"
+	config "github.com/nivschuman/VotingBlockchain/internal/config"
+	consensus "github.com/nivschuman/VotingBlockchain/internal/consensus"
+	database "github.com/nivschuman/VotingBlockchain/internal/database/connection"
+	repositories "github.com/nivschuman/VotingBlockchain/internal/database/repositories"
+	events "github.com/nivschuman/VotingBlockchain/internal/events"
+	data_models "github.com/nivschuman/VotingBlockchain/internal/models"
+	validation "github.com/nivschuman/VotingBlockchain/internal/validation"
+	"gorm.io/gorm"
+)
+
+// ChainStorage is the stored chain without a node around it, for tools that work on the chain while the node isn't running
+// it doesn't touch the miner keystore, the mempool or the peers and it never prunes
+type ChainStorage struct {
+	db                    *gorm.DB
+	closeChainStorage     func() error
+	blockRepository       repositories.BlockRepository
+	transactionRepository repositories.TransactionRepository
+	eventBus              *events.EventBusImpl
+	consensusEngine       consensus.ConsensusEngine
+	chainParams           *chainparams.ChainParams
+	governmentPublicKey   []byte
+}
+
+func OpenChainStorage(config *config.Config) (*ChainStorage, error) {
+	chainParams, err := ChainParamsForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := database.GetDatabaseConnection(config.DatabaseConfig.File)
+	if err != nil {
+		return nil, err
+	}
+
+	eventBus := events.NewEventBusImpl()
+	blockRepository, transactionRepository, closeChainStorage, err := openChainStorage(&config.DatabaseConfig, db, eventBus, chainParams)
+	if err != nil {
+		eventBus.Close()
+		database.CloseDatabaseConnection(db)
+		return nil, err
+	}
+
+	chainStorage := &ChainStorage{
+		db:                    db,
+		closeChainStorage:     closeChainStorage,
+		blockRepository:       blockRepository,
+		transactionRepository: transactionRepository,
+		eventBus:              eventBus,
+		consensusEngine:       consensus.NewConsensusEngine(chainParams),
+		chainParams:           chainParams,
+		governmentPublicKey:   config.GovernmentConfig.PublicKey,
+	}
+
+	if config.DatabaseConfig.Reindex {
+		log.Printf("|Chain Storage| Reindexing chain from stored block headers")
+		if err := reindexChain(blockRepository); err != nil {
+			chainStorage.Close()
+			return nil, err
+		}
+	}
+
+	if err := blockRepository.Initialize(); err != nil {
+		chainStorage.Close()
+		return nil, err
+	}
+
+	return chainStorage, nil
+}
+
+func (chainStorage *ChainStorage) GetBlockRepository() repositories.BlockRepository {
+	return chainStorage.blockRepository
+}
+
+func (chainStorage *ChainStorage) GetChainParams() *chainparams.ChainParams {
+	return chainStorage.chainParams
+}
+
+// VerifyChain checks the last depth blocks of the active chain, the whole chain when depth is 0
+func (chainStorage *ChainStorage) VerifyChain(depth uint64) (uint64, error) {
+	verifier := validation.NewChainVerifier(chainStorage.blockRepository, chainStorage.transactionRepository, chainStorage.chainParams, chainStorage.governmentPublicKey)
+	return verifier.VerifyChain(depth)
+}
+
+// ImportBlock runs a block of a block file through the checks of a received block, blocks that are already stored are skipped
+func (chainStorage *ChainStorage) ImportBlock(block *data_models.Block) (bool, error) {
+	exists, err := chainStorage.blockRepository.HaveBlock(block.Header.Id)
+	if err != nil {
+		return false, err
+	}
+
+	if exists {
+		return false, nil
+	}
+
+	isOrphan, err := chainStorage.blockRepository.BlockIsOrphan(block)
+	if err != nil {
+		return false, err
+	}
+
+	if isOrphan {
+		return false, fmt.Errorf("%w: previous block %x is unknown", ErrBlockRejected, block.Header.PreviousBlockId)
+	}
+
+	//Timestamp must be less than the local time +2 hours, there are no peers to adjust it
+	if block.Header.Timestamp > time.Now().Unix()+2*60*60 {
+		return false, fmt.Errorf("%w: timestamp too far in the future (%d)", ErrBlockRejected, block.Header.Timestamp)
+	}
+
+	if err := validation.CheckBlock(block, chainStorage.consensusEngine, &chainStorage.chainParams.Election, chainStorage.governmentPublicKey); err != nil {
+		return false, rejectedBlockError(err)
+	}
+
+	if err := validation.ValidateBlock(block, chainStorage.blockRepository, chainStorage.transactionRepository, chainStorage.consensusEngine, &chainStorage.chainParams.Consensus); err != nil {
+		return false, rejectedBlockError(err)
+	}
+
+	err = chainStorage.blockRepository.InsertIfNotExists(block)
+	if errors.Is(err, repositories.ErrCheckpointViolation) || errors.Is(err, repositories.ErrReorgTooDeep) {
+		return false, fmt.Errorf("%w: %w", ErrBlockRejected, err)
+	}
+
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// rejectedBlockError marks errors about the block as a rejection, other errors are returned as they are
+func rejectedBlockError(err error) error {
+	if errors.Is(err, validation.ErrInvalidBlock) {
+		return fmt.Errorf("%w: %w", ErrBlockRejected, err)
+	}
+
+	return err
+}
+
+// Close releases the databases of the chain storage
+func (chainStorage *ChainStorage) Close() error {
+	chainStorage.eventBus.Close()
+
+	if err := chainStorage.closeChainStorage(); err != nil {
+		return err
+	}
+
+	return database.CloseDatabaseConnection(chainStorage.db)
+}
@@ -2,27 +2,41 @@ package nodes
 
 import (
 	"bytes"
+	"errors"
+	"fmt"
 	"log"
 	"sync"
 
+	chainparams "github.com/nivschuman/VotingBlockchain/internal/chainparams"
+	consensus "github.com/nivschuman/VotingBlockchain/internal/consensus"
 	repos "github.com/nivschuman/VotingBlockchain/internal/database/repositories"
-	difficulty "github.com/nivschuman/VotingBlockchain/internal/difficulty"
+	events "github.com/nivschuman/VotingBlockchain/internal/events"
+	mempool "github.com/nivschuman/VotingBlockchain/internal/mempool"
 	mining "github.com/nivschuman/VotingBlockchain/internal/mining"
 	data_models "github.com/nivschuman/VotingBlockchain/internal/models"
 	models "github.com/nivschuman/VotingBlockchain/internal/networking/models"
 	network "github.com/nivschuman/VotingBlockchain/internal/networking/network"
 	peer "github.com/nivschuman/VotingBlockchain/internal/networking/peer"
 	structures "github.com/nivschuman/VotingBlockchain/internal/structures"
+	validation "github.com/nivschuman/VotingBlockchain/internal/validation"
 )
 
+var ErrBlockRejected = errors.New("block rejected")
+
 type FullNode struct {
-	network network.Network
-	miner   mining.Miner
+	network  network.Network
+	miner    mining.Miner
+	mempool  mempool.Mempool
+	eventBus events.EventBus
+
+	subscriptions []*events.Subscription
 
 	blockRepository       repos.BlockRepository
 	transactionRepository repos.TransactionRepository
 
 	governmentPublicKey []byte
+	chainParams         *chainparams.ChainParams
+	consensusEngine     consensus.ConsensusEngine
 
 	orphanBlocks      *structures.BytesMap[*data_models.Block]
 	orphanBlocksMutex sync.RWMutex
@@ -36,16 +50,23 @@ type FullNode struct {
 func NewFullNode(
 	network network.Network,
 	miner mining.Miner,
+	mempool mempool.Mempool,
 	blockRepository repos.BlockRepository,
 	transactionRepository repos.TransactionRepository,
+	eventBus events.EventBus,
+	chainParams *chainparams.ChainParams,
 	governmentPublicKey []byte) *FullNode {
 	fullNode := &FullNode{
 		network:               network,
 		miner:                 miner,
+		mempool:               mempool,
+		eventBus:              eventBus,
 		blockRepository:       blockRepository,
 		transactionRepository: transactionRepository,
 		orphanBlocks:          structures.NewBytesMap[*data_models.Block](),
 		governmentPublicKey:   governmentPublicKey,
+		chainParams:           chainParams,
+		consensusEngine:       consensus.NewConsensusEngine(chainParams),
 		shutdownHooks:         make([]func() error, 0),
 	}
 
@@ -55,16 +76,21 @@ func NewFullNode(
 	fullNode.network.AddCommandHandler(models.CommandGetData, fullNode.processGetData)
 	fullNode.network.AddCommandHandler(models.CommandInv, fullNode.processInv)
 	fullNode.network.AddCommandHandler(models.CommandBlock, fullNode.processBlock)
+	fullNode.network.AddCommandHandler(models.CommandReject, fullNode.processReject)
 
-	fullNode.network.AddPeerEventHandler("new_peer", fullNode.handleNewPeer)
+	fullNode.subscriptions = append(fullNode.subscriptions,
+		fullNode.eventBus.Subscribe(fullNode.handlePeerConnected, events.PeerConnected),
+		fullNode.eventBus.Subscribe(fullNode.handleChainReorganized, events.ChainReorganized),
+	)
 
-	fullNode.miner.AddHandler(fullNode.processMinedBlock)
+	fullNode.miner.AddHandler(fullNode.handleMinedBlock)
 
 	return fullNode
 }
 
 func (fullNode *FullNode) Start() {
 	log.Print("|Node| Starting full node")
+	fullNode.mempool.Start()
 	fullNode.network.Start()
 	fullNode.miner.Start()
 }
@@ -76,6 +102,10 @@ func (fullNode *FullNode) Stop() {
 	log.Print("|Node| Stopping full node")
 	fullNode.network.Stop()
 	fullNode.miner.Stop()
+	fullNode.mempool.Stop()
+	for _, subscription := range fullNode.subscriptions {
+		fullNode.eventBus.Unsubscribe(subscription)
+	}
 
 	for _, hook := range fullNode.shutdownHooks {
 		if err := hook(); err != nil {
@@ -99,6 +129,10 @@ func (fullNode *FullNode) GetNetwork() network.Network {
 	return fullNode.network
 }
 
+func (fullNode *FullNode) GetMempool() mempool.Mempool {
+	return fullNode.mempool
+}
+
 func (fullNode *FullNode) GetBlockRepository() repos.BlockRepository {
 	return fullNode.blockRepository
 }
@@ -107,49 +141,47 @@ func (fullNode *FullNode) GetTransactionRepository() repos.TransactionRepository
 	return fullNode.transactionRepository
 }
 
-func (fullNode *FullNode) ProcessGeneratedTransaction(transaction *data_models.Transaction) {
-	valid, err := transaction.IsValid(fullNode.governmentPublicKey)
-
-	if err != nil {
-		log.Printf("|Node| Failed to validate generated transaction: %v", err)
-		return
-	}
-
-	if !valid {
-		log.Printf("|Node| Received invalid generated transaction from")
-		return
-	}
+func (fullNode *FullNode) GetEventBus() events.EventBus {
+	return fullNode.eventBus
+}
 
+func (fullNode *FullNode) ProcessGeneratedTransaction(transaction *data_models.Transaction) {
 	fullNode.criticalMutex.Lock()
-	valid, err = fullNode.transactionRepository.TransactionValidInActiveChain(transaction)
+	err := fullNode.mempool.Add(transaction)
 	fullNode.criticalMutex.Unlock()
 
 	if err != nil {
-		log.Printf("|Node| Failed validating generated transaction: %v", err)
+		log.Printf("|Node| Rejected generated transaction %x: %v", transaction.Id, err)
 		return
 	}
 
-	if !valid {
-		log.Printf("|Node| Received invalid generated transaction")
-		return
-	}
+	fullNode.eventBus.Publish(events.TransactionReceivedEvent{Transaction: transaction, FromPeer: nil})
+	fullNode.network.BroadcastItemToPeers(models.MSG_TX, transaction.Id, nil)
+}
 
-	err = fullNode.transactionRepository.InsertIfNotExists(transaction)
+func (fullNode *FullNode) handlePeerConnected(event events.Event) {
+	peerConnected := event.(events.PeerConnectedEvent)
 
-	if err != nil {
-		log.Printf("|Node| Failed to insert generated transaction: %v", err)
-	}
+	//a pruned peer can't send the blocks between our tip and its pruned height
+	if peerDetails := peerConnected.Peer.PeerDetails; peerDetails != nil && peerDetails.PrunedHeight > 0 {
+		height, err := fullNode.blockRepository.GetActiveChainHeight()
+		if err != nil {
+			log.Printf("|Node| Failed to get active chain height for %s: %v", peerConnected.Peer.String(), err)
+			return
+		}
 
-	fullNode.network.BroadcastItemToPeers(models.MSG_TX, transaction.Id, nil)
-}
+		if height < uint64(peerDetails.PrunedHeight) {
+			log.Printf("|Node| Not syncing from %s, it pruned blocks up to height %d and our height is %d", peerConnected.Peer.String(), peerDetails.PrunedHeight, height)
+			return
+		}
+	}
 
-func (fullNode *FullNode) handleNewPeer(peerEventData peer.PeerEventData) {
 	fullNode.criticalMutex.Lock()
 	blockLocator, err := fullNode.blockRepository.GetActiveChainBlockLocator()
 	fullNode.criticalMutex.Unlock()
 
 	if err != nil {
-		log.Printf("|Node| Failed to get active chain block locator for %s: %v", peerEventData.Peer.String(), err)
+		log.Printf("|Node| Failed to get active chain block locator for %s: %v", peerConnected.Peer.String(), err)
 		return
 	}
 
@@ -157,13 +189,56 @@ func (fullNode *FullNode) handleNewPeer(peerEventData peer.PeerEventData) {
 	getBlocks := models.NewGetBlocks(blockLocator, stopHash)
 	msg, err := models.NewGetBlocksMessage(getBlocks)
 	if err != nil {
-		log.Printf("|Node| Failed to make get blocks message for %s: %v", peerEventData.Peer.String(), err)
+		log.Printf("|Node| Failed to make get blocks message for %s: %v", peerConnected.Peer.String(), err)
 		return
 	}
 
-	log.Printf("|Node| Sending getblocks to %s, stopHash=%x, ids=%d", peerEventData.Peer.String(), getBlocks.StopHash, getBlocks.BlockLocator.Length())
+	log.Printf("|Node| Sending getblocks to %s, stopHash=%x, ids=%d", peerConnected.Peer.String(), getBlocks.StopHash, getBlocks.BlockLocator.Length())
+
+	peerConnected.Peer.SendMessage(msg)
+}
+
+// handleChainReorganized re-validates the votes of disconnected blocks against the new active chain.
+// Votes that conflict with the new chain are evicted, the rest are announced to peers again.
+func (fullNode *FullNode) handleChainReorganized(event events.Event) {
+	reorganized := event.(events.ChainReorganizedEvent)
+
+	connectedTxIds := structures.NewBytesSet()
+	for _, block := range reorganized.ConnectedBlocks {
+		for _, tx := range block.Transactions {
+			connectedTxIds.Add(tx.Id)
+		}
+	}
+
+	returned := 0
+	evicted := 0
+	for _, block := range reorganized.DisconnectedBlocks {
+		for _, tx := range block.Transactions {
+			if connectedTxIds.Contains(tx.Id) {
+				continue
+			}
+
+			fullNode.criticalMutex.Lock()
+			err := fullNode.mempool.Add(tx)
+			fullNode.criticalMutex.Unlock()
+
+			if errors.Is(err, mempool.ErrAlreadyVoted) || errors.Is(err, mempool.ErrConflict) {
+				log.Printf("|Node| Evicted transaction %x from disconnected block %x: %v", tx.Id, block.Header.Id, err)
+				evicted++
+				continue
+			}
+
+			if err != nil {
+				log.Printf("|Node| Failed to return transaction %x from disconnected block %x to mempool: %v", tx.Id, block.Header.Id, err)
+				continue
+			}
 
-	peerEventData.Peer.SendMessage(msg)
+			fullNode.network.BroadcastItemToPeers(models.MSG_TX, tx.Id, nil)
+			returned++
+		}
+	}
+
+	log.Printf("|Node| Reorganized chain from %x to %x, returned %d transactions to mempool and evicted %d", reorganized.OldTipId, reorganized.NewTipId, returned, evicted)
 }
 
 func (fullNode *FullNode) processInv(fromPeer *peer.Peer, message *models.Message) {
@@ -176,6 +251,12 @@ func (fullNode *FullNode) processInv(fromPeer *peer.Peer, message *models.Messag
 
 	log.Printf("|Node| Received inv from %s with %d items", fromPeer.String(), inv.Count)
 
+	if inv.Count > models.MAX_INV_SIZE {
+		log.Printf("|Node| Received more than %d inv items from peer %s", models.MAX_INV_SIZE, fromPeer.String())
+		fullNode.network.ReportMisbehavior(fromPeer, "sent too many inv items")
+		return
+	}
+
 	getData := models.NewGetData()
 
 	blockHashes := structures.NewBytesSet()
@@ -190,6 +271,12 @@ func (fullNode *FullNode) processInv(fromPeer *peer.Peer, message *models.Messag
 		}
 	}
 
+	for _, id := range txHashes.ToBytesSlice() {
+		if fullNode.mempool.Contains(id) {
+			txHashes.Remove(id)
+		}
+	}
+
 	missingTransactions, err := fullNode.transactionRepository.GetMissingTransactionIds(txHashes)
 
 	if err != nil {
@@ -238,63 +325,81 @@ func (fullNode *FullNode) processTx(fromPeer *peer.Peer, message *models.Message
 
 	log.Printf("|Node| Received transaction %x from %s", transaction.Id, fromPeer.String())
 
-	valid, err := transaction.IsValid(fullNode.governmentPublicKey)
+	fullNode.criticalMutex.Lock()
+	err = fullNode.mempool.Add(transaction)
+	fullNode.criticalMutex.Unlock()
 
 	if err != nil {
-		log.Printf("|Node| Failed to validate transaction from %s: %v", fromPeer.String(), err)
+		log.Printf("|Node| Rejected transaction %x from %s: %v", transaction.Id, fromPeer.String(), err)
+		fullNode.rejectTransaction(fromPeer, transaction, err)
 		return
 	}
 
-	if !valid {
-		log.Printf("|Node| Received invalid transaction from %s", fromPeer.String())
+	fullNode.eventBus.Publish(events.TransactionReceivedEvent{Transaction: transaction, FromPeer: fromPeer})
+	fullNode.network.BroadcastItemToPeers(models.MSG_TX, transaction.Id, fromPeer)
+}
+
+// rejectTransaction reports a transaction the mempool refused back to the peer that sent it
+func (fullNode *FullNode) rejectTransaction(fromPeer *peer.Peer, transaction *data_models.Transaction, rejectErr error) {
+	var code uint8
+	switch {
+	case errors.Is(rejectErr, mempool.ErrConflict), errors.Is(rejectErr, mempool.ErrAlreadyVoted):
+		code = models.REJECT_CONFLICT
+	case errors.Is(rejectErr, mempool.ErrInvalidTransaction):
+		code = models.REJECT_INVALID
+	default:
 		return
 	}
 
-	fullNode.criticalMutex.Lock()
-	valid, err = fullNode.transactionRepository.TransactionValidInActiveChain(transaction)
-	fullNode.criticalMutex.Unlock()
+	reject := &models.Reject{
+		Message: models.CommandTx,
+		Code:    code,
+		Reason:  rejectErr.Error(),
+		Hash:    transaction.Id,
+	}
 
+	rejectMessage, err := models.NewRejectMessage(reject)
 	if err != nil {
-		log.Printf("|Node| Failed validating transaction from %s: %v", fromPeer.String(), err)
+		log.Printf("|Node| Failed to create reject message for %s: %v", fromPeer.String(), err)
 		return
 	}
 
-	if !valid {
-		log.Printf("|Node| Received invalid transaction from %s", fromPeer.String())
-		return
-	}
+	fromPeer.SendMessage(rejectMessage)
+}
 
-	err = fullNode.transactionRepository.InsertIfNotExists(transaction)
+func (fullNode *FullNode) processReject(fromPeer *peer.Peer, message *models.Message) {
+	reject, err := models.RejectFromBytes(message.Payload)
 
 	if err != nil {
-		log.Printf("|Node| Failed to insert transaction from %s: %v", fromPeer.String(), err)
+		log.Printf("|Node| Failed to parse reject from %s: %v", fromPeer.String(), err)
+		return
 	}
 
-	fullNode.network.BroadcastItemToPeers(models.MSG_TX, transaction.Id, fromPeer)
+	log.Printf("|Node| %s rejected %s %x with code 0x%02x: %s", fromPeer.String(), reject.CommandString(), reject.Hash, reject.Code, reject.Reason)
 }
 
+// processMemPool announces every transaction in the mempool, in as many invs as needed to stay within MAX_INV_SIZE
 func (fullNode *FullNode) processMemPool(fromPeer *peer.Peer, _ *models.Message) {
-	transactions, err := fullNode.transactionRepository.GetMempool(10)
+	ids := fullNode.mempool.GetTransactionIds()
 
-	if err != nil {
-		log.Printf("|Node| Failed to get mempool for %s: %v", fromPeer.String(), err)
-		return
-	}
+	for start := 0; start < len(ids); start += models.MAX_INV_SIZE {
+		inv := models.NewInv()
 
-	inv := models.NewInv()
+		for _, id := range ids[start:min(start+models.MAX_INV_SIZE, len(ids))] {
+			inv.AddItem(models.MSG_TX, id)
+		}
 
-	for _, tx := range transactions {
-		inv.AddItem(models.MSG_TX, tx.Id)
-	}
+		mempoolMessage, err := models.NewInvMessage(inv)
 
-	mempoolMessage, err := models.NewInvMessage(inv)
+		if err != nil {
+			log.Printf("|Node| Failed to create mempool inv message for %s: %v", fromPeer.String(), err)
+			return
+		}
 
-	if err != nil {
-		log.Printf("|Node| Failed to create mempool inv message for %s: %v", fromPeer.String(), err)
-		return
+		fromPeer.SendMessage(mempoolMessage)
 	}
 
-	fromPeer.SendMessage(mempoolMessage)
+	log.Printf("|Node| Sent %d mempool transactions to %s", len(ids), fromPeer.String())
 }
 
 func (fullNode *FullNode) processGetData(fromPeer *peer.Peer, message *models.Message) {
@@ -307,6 +412,12 @@ func (fullNode *FullNode) processGetData(fromPeer *peer.Peer, message *models.Me
 
 	log.Printf("|Node| Received getdata from %s with %d items", fromPeer.String(), len(getData.Items()))
 
+	if len(getData.Items()) > models.MAX_INV_SIZE {
+		log.Printf("|Node| Received more than %d getdata items from peer %s", models.MAX_INV_SIZE, fromPeer.String())
+		fullNode.network.ReportMisbehavior(fromPeer, "sent too many getdata items")
+		return
+	}
+
 	blockHashes := structures.NewBytesSet()
 	txHashes := structures.NewBytesSet()
 
@@ -319,13 +430,26 @@ func (fullNode *FullNode) processGetData(fromPeer *peer.Peer, message *models.Me
 		}
 	}
 
-	transactions, err := fullNode.transactionRepository.GetTransactions(txHashes)
+	transactions := make([]*data_models.Transaction, 0, txHashes.Length())
+	storedTxHashes := structures.NewBytesSet()
+
+	for _, id := range txHashes.ToBytesSlice() {
+		if tx, exists := fullNode.mempool.Get(id); exists {
+			transactions = append(transactions, tx)
+		} else {
+			storedTxHashes.Add(id)
+		}
+	}
+
+	storedTransactions, err := fullNode.transactionRepository.GetTransactions(storedTxHashes)
 
 	if err != nil {
 		log.Printf("|Node| Failed to get transactions : %v", err)
 		return
 	}
 
+	transactions = append(transactions, storedTransactions...)
+
 	blocks, err := fullNode.blockRepository.GetBlocks(blockHashes)
 
 	if err != nil {
@@ -340,6 +464,10 @@ func (fullNode *FullNode) processGetData(fromPeer *peer.Peer, message *models.Me
 		fromPeer.SendMessage(msg)
 	}
 
+	if refused := blockHashes.Length() - len(blocks); refused > 0 {
+		log.Printf("|Node| Refusing %d blocks to %s, they are pruned or unknown", refused, fromPeer.String())
+	}
+
 	log.Printf("|Node| Sending %d blocks to %s", len(blocks), fromPeer.String())
 
 	for _, block := range blocks {
@@ -391,6 +519,11 @@ func (fullNode *FullNode) processGetBlocks(fromPeer *peer.Peer, message *models.
 }
 
 func (fullNode *FullNode) processBlock(fromPeer *peer.Peer, message *models.Message) {
+	if len(message.Payload) > fullNode.chainParams.Election.MaxBlockSize {
+		log.Printf("|Node| Block of %d bytes from %s exceeds %d", len(message.Payload), fromPeer.String(), fullNode.chainParams.Election.MaxBlockSize)
+		return
+	}
+
 	//Parse block
 	block, err := data_models.BlockFromBytes(message.Payload)
 
@@ -494,12 +627,17 @@ func (fullNode *FullNode) processBlock(fromPeer *peer.Peer, message *models.Mess
 	//Insert block
 	err = fullNode.blockRepository.InsertIfNotExists(block)
 
+	if fullNode.alertFinalityViolation(block, fromPeer, err) {
+		return
+	}
+
 	if err != nil {
 		log.Printf("|Node| Failed to insert block from %s: %v", fromPeer.String(), err)
 		return
 	}
 
 	//Send block to peers
+	fullNode.eventBus.Publish(events.BlockReceivedEvent{Block: block, FromPeer: fromPeer})
 	fullNode.network.BroadcastItemToPeers(models.MSG_BLOCK, block.Header.Id, fromPeer)
 
 	//Process dependent orphans recursively
@@ -529,11 +667,17 @@ func (fullNode *FullNode) processBlock(fromPeer *peer.Peer, message *models.Mess
 
 			err = fullNode.blockRepository.InsertIfNotExists(child)
 
+			if fullNode.alertFinalityViolation(child, fromPeer, err) {
+				fullNode.orphanBlocks.Remove(child.Header.Id)
+				continue
+			}
+
 			if err != nil {
 				log.Printf("|Node| Failed to insert orphan child block %x: %v", child.Header.Id, err)
 				continue
 			}
 
+			fullNode.eventBus.Publish(events.BlockReceivedEvent{Block: child, FromPeer: fromPeer})
 			fullNode.network.BroadcastItemToPeers(models.MSG_BLOCK, child.Header.Id, fromPeer)
 
 			fullNode.orphanBlocks.Remove(child.Header.Id)
@@ -542,100 +686,55 @@ func (fullNode *FullNode) processBlock(fromPeer *peer.Peer, message *models.Mess
 	}
 }
 
-func (fullNode *FullNode) checkBlock(block *data_models.Block) (bool, error) {
-	//Timestamp must be less than the network adjusted time +2 hours.
-	if block.Header.Timestamp > fullNode.network.GetNetworkTime()+2*60*60 {
-		log.Printf("|Node| Invalid block %x: timestamp too far in the future (%d)", block.Header.Id, block.Header.Timestamp)
-		return false, nil
+// alertFinalityViolation tells if inserting the block failed because it would reorganize past a checkpoint or the maximum reorg depth,
+// a peer that sent a block conflicting with a checkpoint is disconnected
+func (fullNode *FullNode) alertFinalityViolation(block *data_models.Block, fromPeer *peer.Peer, err error) bool {
+	if !errors.Is(err, repos.ErrCheckpointViolation) && !errors.Is(err, repos.ErrReorgTooDeep) {
+		return false
 	}
 
-	//Proof of Work must be valid
-	if !block.Header.IsHashBelowTarget() {
-		log.Printf("|Node| Invalid block %x: hash does not satisfy target", block.Header.Id)
-		return false, nil
+	if fromPeer == nil {
+		log.Printf("|Node| ALERT: rejected local block %x: %v", block.Header.Id, err)
+		return true
 	}
 
-	//NBits must not be below minimum work
-	target := difficulty.GetTargetFromNBits(block.Header.NBits)
-	if target.Cmp(difficulty.GetTargetFromNBits(difficulty.MINIMUM_DIFFICULTY)) > 0 {
-		log.Printf("|Node| Invalid block %x: NBits below minimum difficulty (%d)", block.Header.Id, block.Header.NBits)
-		return false, nil
-	}
-
-	//Block transactions must be valid
-	txIds := structures.NewBytesSet()
-	voterKeys := structures.NewBytesSet()
-	for i, tx := range block.Transactions {
-		valid, err := tx.IsValid(fullNode.governmentPublicKey)
+	log.Printf("|Node| ALERT: rejected block %x from %s: %v", block.Header.Id, fromPeer.String(), err)
 
-		if err != nil {
-			return false, err
-		}
-
-		if !valid {
-			log.Printf("|Node| Block %x: transaction %d is invalid", block.Header.Id, i)
-			return false, nil
-		}
-
-		if txIds.Contains(tx.Id) {
-			log.Printf("|Node| Block %x: duplicate transaction id at index %d", block.Header.Id, i)
-			return false, nil
-		}
-
-		if voterKeys.Contains(tx.VoterPublicKey) {
-			log.Printf("|Node| Block %x: duplicate voter in same block at index %d", block.Header.Id, i)
-			return false, nil
-		}
-
-		txIds.Add(tx.Id)
-		voterKeys.Add(tx.VoterPublicKey)
+	if errors.Is(err, repos.ErrCheckpointViolation) {
+		fullNode.network.ReportMisbehavior(fromPeer, "sent a block conflicting with a checkpoint")
 	}
 
-	//Check merkle root
-	merkleRoot := data_models.TransactionsMerkleRoot(block.Transactions)
-	if !bytes.Equal(block.Header.MerkleRoot, merkleRoot) {
-		log.Printf("|Node| Block %x: merkle root mismatch", block.Header.Id)
+	return true
+}
+
+func (fullNode *FullNode) checkBlock(block *data_models.Block) (bool, error) {
+	//Timestamp must be less than the network adjusted time +2 hours.
+	if block.Header.Timestamp > fullNode.network.GetNetworkTime()+2*60*60 {
+		log.Printf("|Node| Invalid block %x: timestamp too far in the future (%d)", block.Header.Id, block.Header.Timestamp)
 		return false, nil
 	}
 
-	return true, nil
+	err := validation.CheckBlock(block, fullNode.consensusEngine, &fullNode.chainParams.Election, fullNode.governmentPublicKey)
+	return fullNode.blockValidationResult(block, err)
 }
 
 func (fullNode *FullNode) validateBlock(block *data_models.Block) (bool, error) {
-	//Timestamp must be greater than the median time of the last 11 blocks
-	medianTimePast, err := fullNode.blockRepository.GetMedianTimePast(block.Header.PreviousBlockId, 11)
-	if err != nil {
-		return false, err
-	}
-
-	if block.Header.Timestamp < medianTimePast {
-		log.Printf("|Node| Block %x: timestamp %d < median past %d", block.Header.Id, block.Header.Timestamp, medianTimePast)
-		return false, nil
-	}
-
-	//Validate transactions on this blocks chain
-	valid, err := fullNode.transactionRepository.TransactionsValidInChain(block.Header.PreviousBlockId, block.Transactions)
-	if err != nil {
-		return false, err
-	}
+	err := validation.ValidateBlock(block, fullNode.blockRepository, fullNode.transactionRepository, fullNode.consensusEngine, &fullNode.chainParams.Consensus)
+	return fullNode.blockValidationResult(block, err)
+}
 
-	if !valid {
-		log.Printf("|Node| Block %x: transactions invalid in chain", block.Header.Id)
-		return false, nil
+// blockValidationResult logs why a block broke the rules, errors that aren't about the block are returned
+func (fullNode *FullNode) blockValidationResult(block *data_models.Block, err error) (bool, error) {
+	if err == nil {
+		return true, nil
 	}
 
-	//Validate work
-	requiredNBits, err := fullNode.blockRepository.GetNextWorkRequired(block.Header.PreviousBlockId)
-	if err != nil {
+	if !errors.Is(err, validation.ErrInvalidBlock) {
 		return false, err
 	}
 
-	if block.Header.NBits != requiredNBits {
-		log.Printf("|Node| Block %x: NBits %d != required %d", block.Header.Id, block.Header.NBits, requiredNBits)
-		return false, nil
-	}
-
-	return true, nil
+	log.Printf("|Node| Block %x: %v", block.Header.Id, err)
+	return false, nil
 }
 
 func (fullNode *FullNode) getConnectedOrphans(blockId []byte) []*data_models.Block {
@@ -664,18 +763,28 @@ func (fullNode *FullNode) getOrphanRoot(orphanBlock *data_models.Block) *data_mo
 	return orphanBlock
 }
 
-func (fullNode *FullNode) processMinedBlock(block *data_models.Block) {
+// SubmitBlock takes a block solved outside of the node's miner, it's processed like a block from the node's miner
+func (fullNode *FullNode) SubmitBlock(block *data_models.Block) error {
+	return fullNode.processLocalBlock(block)
+}
+
+func (fullNode *FullNode) handleMinedBlock(block *data_models.Block) {
+	if err := fullNode.processLocalBlock(block); err != nil {
+		log.Printf("|Node| Failed to process mined block %x: %v", block.Header.Id, err)
+	}
+}
+
+// processLocalBlock accepts a block that didn't come from a peer, mined or submitted
+func (fullNode *FullNode) processLocalBlock(block *data_models.Block) error {
 	//Check block
 	isValid, err := fullNode.checkBlock(block)
 
 	if err != nil {
-		log.Printf("|Node| Failed to check block mined block: %v", err)
-		return
+		return err
 	}
 
 	if !isValid {
-		log.Print("|Node| Received invalid block from miner")
-		return
+		return fmt.Errorf("%w: block failed checks", ErrBlockRejected)
 	}
 
 	//Validate block
@@ -685,23 +794,26 @@ func (fullNode *FullNode) processMinedBlock(block *data_models.Block) {
 	isValid, err = fullNode.validateBlock(block)
 
 	if err != nil {
-		log.Printf("|Node| Failed to validate block miner: %v", err)
-		return
+		return err
 	}
 
 	if !isValid {
-		log.Print("|Node| Received invalid block from miner")
-		return
+		return fmt.Errorf("%w: block failed validation", ErrBlockRejected)
 	}
 
 	//Insert block
 	err = fullNode.blockRepository.InsertIfNotExists(block)
 
+	if fullNode.alertFinalityViolation(block, nil, err) {
+		return fmt.Errorf("%w: %w", ErrBlockRejected, err)
+	}
+
 	if err != nil {
-		log.Printf("|Node| Failed to insert block from miner: %v", err)
-		return
+		return err
 	}
 
 	//Send block to peers
+	fullNode.eventBus.Publish(events.BlockReceivedEvent{Block: block, FromPeer: nil})
 	fullNode.network.BroadcastItemToPeers(models.MSG_BLOCK, block.Header.Id, nil)
+	return nil
 }
@@ -1,16 +1,25 @@
 package nodes
 
 import (
+	"bytes"
 	"fmt"
 	"log"
 
+	chainparams "github.com/nivschuman/VotingBlockchain/internal/chainparams"
 	config "github.com/nivschuman/VotingBlockchain/internal/config"
+	keystore "github.com/nivschuman/VotingBlockchain/internal/crypto/keystore"
+	ppk "github.com/nivschuman/VotingBlockchain/internal/crypto/ppk"
+	db_bolt "github.com/nivschuman/VotingBlockchain/internal/database/bolt"
 	database "github.com/nivschuman/VotingBlockchain/internal/database/connection"
 	repositories "github.com/nivschuman/VotingBlockchain/internal/database/repositories"
+	events "github.com/nivschuman/VotingBlockchain/internal/events"
+	mempool "github.com/nivschuman/VotingBlockchain/internal/mempool"
 	mining "github.com/nivschuman/VotingBlockchain/internal/mining"
+	work "github.com/nivschuman/VotingBlockchain/internal/mining/work"
 	data_models "github.com/nivschuman/VotingBlockchain/internal/models"
 	network_models "github.com/nivschuman/VotingBlockchain/internal/networking/models"
 	network "github.com/nivschuman/VotingBlockchain/internal/networking/network"
+	validation "github.com/nivschuman/VotingBlockchain/internal/validation"
 	"gorm.io/gorm"
 )
 
@@ -22,9 +31,12 @@ type Node interface {
 	AddShutdownHook(func() error)
 	GetMiner() mining.Miner
 	GetNetwork() network.Network
+	GetMempool() mempool.Mempool
 	GetBlockRepository() repositories.BlockRepository
 	GetTransactionRepository() repositories.TransactionRepository
+	GetEventBus() events.EventBus
 	ProcessGeneratedTransaction(transaction *data_models.Transaction)
+	SubmitBlock(block *data_models.Block) error
 }
 
 type NodeBuilder interface {
@@ -34,38 +46,102 @@ type NodeBuilder interface {
 
 type NodeBuilderImpl struct {
 	db                    *gorm.DB
+	closeChainStorage     func() error
 	blockRepository       repositories.BlockRepository
 	transactionRepository repositories.TransactionRepository
 	addressRepository     repositories.AddressRepository
 	miner                 mining.Miner
+	mempool               *mempool.MempoolImpl
 	network               *network.NetworkImpl
+	eventBus              *events.EventBusImpl
+	chainParams           *chainparams.ChainParams
 	config                *config.Config
 }
 
 func NewNodeBuilderImpl(config *config.Config) (*NodeBuilderImpl, error) {
+	chainParams, err := ChainParamsForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("|Node Builder| Using chain %s with %s consensus, %d checkpoints", chainParams.Name, chainParams.Consensus.Engine, len(chainParams.Consensus.Checkpoints))
+
+	if err := checkPruneDepth(config.DatabaseConfig.Prune, &chainParams.Consensus); err != nil {
+		return nil, err
+	}
+
+	if config.NetworkConfig.Port == 0 {
+		config.NetworkConfig.Port = chainParams.DefaultPort
+	}
+
 	db, err := database.GetDatabaseConnection(config.DatabaseConfig.File)
 	if err != nil {
 		return nil, err
 	}
 
-	transactionRepository := repositories.NewTransactionRepositoryImpl(db)
-	blockRepository := repositories.NewBlockRepositoryImpl(db, transactionRepository)
+	eventBus := events.NewEventBusImpl()
+	blockRepository, transactionRepository, closeChainStorage, err := openChainStorage(&config.DatabaseConfig, db, eventBus, chainParams)
+	if err != nil {
+		return nil, err
+	}
+
+	if config.DatabaseConfig.Reindex {
+		log.Printf("|Node Builder| Reindexing chain from stored block headers")
+		if err := reindexChain(blockRepository); err != nil {
+			return nil, err
+		}
+	}
+
 	if err := blockRepository.Initialize(); err != nil {
 		return nil, err
 	}
 
+	if config.DatabaseConfig.VerifyBlocks > 0 {
+		verifier := validation.NewChainVerifier(blockRepository, transactionRepository, chainParams, config.GovernmentConfig.PublicKey)
+		checked, err := verifier.VerifyChain(config.DatabaseConfig.VerifyBlocks)
+		if err != nil {
+			return nil, fmt.Errorf("chain verification failed, reindex the chain or resync it from peers: %w", err)
+		}
+
+		log.Printf("|Node Builder| Verified the last %d blocks of the active chain", checked)
+	}
+
+	if keepBlocks := config.DatabaseConfig.Prune; keepBlocks > 0 {
+		pruneChain(blockRepository, keepBlocks)
+		eventBus.Subscribe(func(events.Event) {
+			pruneChain(blockRepository, keepBlocks)
+		}, events.ChainTipChanged)
+	}
+
+	conflictingVoteRepository := repositories.NewConflictingVoteRepositoryImpl(db)
+	memPool := mempool.NewMempoolImpl(transactionRepository, conflictingVoteRepository, eventBus, config.GovernmentConfig.PublicKey, &config.MempoolConfig)
+	if err := memPool.LoadFromFile(config.MempoolConfig.File); err != nil {
+		log.Printf("|Node Builder| Failed to load mempool file: %v", err)
+	}
+
 	addressRepository := repositories.NewAddressRepositoryImpl(db)
 	versionProvider := NewVersionProvider(blockRepository, config.NodeConfig)
-	netwrk := network.NewNetworkImpl(addressRepository, &config.NetworkConfig, versionProvider.GetVersion)
+	netwrk := network.NewNetworkImpl(addressRepository, &config.NetworkConfig, chainParams, versionProvider.GetVersion, eventBus)
+
+	minerKeyPair, err := loadMinerKeyPair(&config.MinerConfig)
+	if err != nil {
+		return nil, err
+	}
 
 	minerProps := mining.MinerProperties{
-		NodeVersion:    config.NodeConfig.Version,
-		MinerPublicKey: config.GovernmentConfig.PublicKey,
+		MinerKeyPair:         minerKeyPair,
+		MaxBlockSize:         config.MinerConfig.MaxBlockSize,
+		MaxBlockTransactions: config.MinerConfig.MaxBlockTransactions,
+		Workers:              config.MinerConfig.Workers,
 	}
 
 	var miner mining.Miner
-	miner = mining.NewMinerImpl(netwrk.GetNetworkTime, blockRepository, transactionRepository, minerProps)
-	if !config.MinerConfig.Enabled {
+	switch {
+	case config.MinerConfig.Enabled:
+		miner = mining.NewMinerImpl(netwrk.GetNetworkTime, blockRepository, memPool, eventBus, chainParams, minerProps)
+	case chainParams.GenerateOnDemand || config.WorkServerConfig.Enabled:
+		miner = mining.NewOnDemandMiner(mining.NewMinerImpl(netwrk.GetNetworkTime, blockRepository, memPool, eventBus, chainParams, minerProps))
+	default:
 		miner = mining.NewDisabledMiner()
 	}
 
@@ -81,11 +157,15 @@ func NewNodeBuilderImpl(config *config.Config) (*NodeBuilderImpl, error) {
 
 	return &NodeBuilderImpl{
 		db:                    db,
+		closeChainStorage:     closeChainStorage,
 		blockRepository:       blockRepository,
 		transactionRepository: transactionRepository,
 		addressRepository:     addressRepository,
 		miner:                 miner,
+		mempool:               memPool,
 		network:               netwrk,
+		eventBus:              eventBus,
+		chainParams:           chainParams,
 		config:                config,
 	}, nil
 }
@@ -96,14 +176,210 @@ func (nodeBuilder *NodeBuilderImpl) BuildNode() (Node, error) {
 	nodeType := nodeBuilder.config.NodeConfig.Type
 	switch nodeType {
 	case FULL_NODE:
-		node = NewFullNode(nodeBuilder.network, nodeBuilder.miner, nodeBuilder.blockRepository, nodeBuilder.transactionRepository, nodeBuilder.config.GovernmentConfig.PublicKey)
+		node = NewFullNode(nodeBuilder.network, nodeBuilder.miner, nodeBuilder.mempool, nodeBuilder.blockRepository, nodeBuilder.transactionRepository, nodeBuilder.eventBus, nodeBuilder.chainParams, nodeBuilder.config.GovernmentConfig.PublicKey)
 	default:
 		return nil, fmt.Errorf("unsupported node type: %v", nodeType)
 	}
 
+	if nodeBuilder.config.WorkServerConfig.Enabled {
+		workServer := work.NewWorkServer(
+			nodeBuilder.config.WorkServerConfig.Address,
+			nodeBuilder.miner,
+			nodeBuilder.blockRepository,
+			nodeBuilder.chainParams,
+			nodeBuilder.network.GetNetworkTime,
+			node.SubmitBlock,
+		)
+
+		if err := workServer.Start(); err != nil {
+			return nil, err
+		}
+
+		node.AddShutdownHook(workServer.Stop)
+	}
+
+	node.AddShutdownHook(func() error {
+		return nodeBuilder.mempool.SaveToFile(nodeBuilder.config.MempoolConfig.File)
+	})
+
+	node.AddShutdownHook(func() error {
+		nodeBuilder.eventBus.Close()
+		return nil
+	})
+
+	node.AddShutdownHook(nodeBuilder.closeChainStorage)
+
 	node.AddShutdownHook(func() error {
 		return database.CloseDatabaseConnection(nodeBuilder.db)
 	})
 
 	return node, nil
 }
+
+func (nodeBuilder *NodeBuilderImpl) GetBlockRepository() repositories.BlockRepository {
+	return nodeBuilder.blockRepository
+}
+
+// openChainStorage opens the block and transaction repositories in the configured storage engine, sqlite shares the node's database
+func openChainStorage(databaseConfig *config.DatabaseConfig, db *gorm.DB, eventBus events.EventBus, chainParams *chainparams.ChainParams) (repositories.BlockRepository, repositories.TransactionRepository, func() error, error) {
+	switch databaseConfig.Engine {
+	case config.BoltEngine:
+		chainDb, err := db_bolt.GetDatabaseConnection(databaseConfig.ChainFile)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+
+		log.Printf("|Node Builder| Storing chain data in %s", databaseConfig.ChainFile)
+
+		closeChainDb := func() error {
+			return db_bolt.CloseDatabaseConnection(chainDb)
+		}
+
+		return db_bolt.NewBlockRepositoryImpl(chainDb, eventBus, chainParams), db_bolt.NewTransactionRepositoryImpl(chainDb), closeChainDb, nil
+	case config.SQLiteEngine, "":
+		closeChainDb := func() error {
+			return nil
+		}
+
+		return repositories.NewBlockRepositoryImpl(db, eventBus, chainParams), repositories.NewTransactionRepositoryImpl(db), closeChainDb, nil
+	default:
+		return nil, nil, nil, fmt.Errorf("unknown storage engine %q", databaseConfig.Engine)
+	}
+}
+
+// reindexChain rebuilds the chain state of a database holding the chain of this genesis block.
+// The current tip breaks ties between chains of equal weight, a damaged index may not have one
+func reindexChain(blockRepository repositories.BlockRepository) error {
+	if err := blockRepository.CheckGenesis(); err != nil {
+		return err
+	}
+
+	if err := blockRepository.SetActiveChainTipId(); err != nil {
+		log.Printf("|Node Builder| Reindexing without the current chain tip: %v", err)
+	}
+
+	if err := blockRepository.Reindex(); err != nil {
+		return fmt.Errorf("failed to reindex chain: %w", err)
+	}
+
+	return nil
+}
+
+// checkPruneDepth refuses to prune blocks a reorganization may still disconnect
+// chains without a reorganization limit keep at least the last MIN_PRUNE_BLOCKS blocks
+func checkPruneDepth(keepBlocks uint64, consensusRules *chainparams.ConsensusRules) error {
+	if keepBlocks == 0 {
+		return nil
+	}
+
+	if keepBlocks < config.MIN_PRUNE_BLOCKS {
+		return fmt.Errorf("prune keeps %d blocks, at least %d must be kept", keepBlocks, config.MIN_PRUNE_BLOCKS)
+	}
+
+	if keepBlocks < consensusRules.MaxReorgDepth {
+		return fmt.Errorf("prune keeps %d blocks, less than the maximum reorganization depth %d", keepBlocks, consensusRules.MaxReorgDepth)
+	}
+
+	return nil
+}
+
+// pruneChain deletes the transactions of blocks older than the last keepBlocks blocks of the active chain
+func pruneChain(blockRepository repositories.BlockRepository, keepBlocks uint64) {
+	pruned, err := blockRepository.Prune(keepBlocks)
+	if err != nil {
+		log.Printf("|Node| Failed to prune blocks: %v", err)
+		return
+	}
+
+	if pruned > 0 {
+		log.Printf("|Node| Pruned %d blocks, keeping the last %d", pruned, keepBlocks)
+	}
+}
+
+func loadMinerKeyPair(minerConfig *config.MinerConfig) (*ppk.KeyPair, error) {
+	keyPair, created, err := keystore.LoadOrCreateKeyPair(minerConfig.KeystoreFile)
+	if err != nil {
+		return nil, err
+	}
+
+	publicKey := keyPair.PublicKey.AsBytes()
+	if created {
+		log.Printf("|Node Builder| Created miner key %x in %s", publicKey, minerConfig.KeystoreFile)
+	}
+
+	if len(minerConfig.PublicKey) > 0 && !bytes.Equal(minerConfig.PublicKey, publicKey) {
+		return nil, fmt.Errorf("miner public key %x doesn't match key %x in %s", minerConfig.PublicKey, publicKey, minerConfig.KeystoreFile)
+	}
+
+	return keyPair, nil
+}
+
+// ChainParamsForConfig returns the params of the configured chain with the configured consensus rules.
+// The genesis block is the election's when the config asks for it, otherwise the network's genesis block that existing chains start from
+func ChainParamsForConfig(config *config.Config) (*chainparams.ChainParams, error) {
+	chainParams, err := chainparams.ParamsForName(config.NodeConfig.Chain)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := applyConsensusConfig(chainParams, &config.ConsensusConfig, config.GovernmentConfig.PublicKey); err != nil {
+		return nil, err
+	}
+
+	if config.NodeConfig.ElectionGenesis {
+		chainParams.SetGenesisBlock(config.GovernmentConfig.PublicKey)
+	}
+
+	return chainParams, nil
+}
+
+// applyConsensusConfig replaces the chain's consensus rules when the config selects an engine, sealers are configured like the government key.
+// Checkpoints with a signature must be signed by the government, checkpoints without one are trusted as hardcoded
+func applyConsensusConfig(chainParams *chainparams.ChainParams, consensusConfig *config.ConsensusConfig, governmentPublicKey []byte) error {
+	if consensusConfig.Engine != "" {
+		sealers := make([]chainparams.Sealer, len(consensusConfig.Sealers))
+		for i, sealerConfig := range consensusConfig.Sealers {
+			sealers[i] = chainparams.Sealer{PublicKey: sealerConfig.PublicKey, Weight: sealerConfig.Weight}
+		}
+
+		chainParams.Consensus.Engine = consensusConfig.Engine
+		chainParams.Consensus.Authority = chainparams.AuthorityRules{
+			Sealers:        sealers,
+			Period:         consensusConfig.Period,
+			OutOfTurnDelay: consensusConfig.OutOfTurnDelay,
+		}
+	}
+
+	for _, checkpointConfig := range consensusConfig.Checkpoints {
+		checkpoint := data_models.Checkpoint{
+			Height:    checkpointConfig.Height,
+			BlockId:   checkpointConfig.BlockId,
+			Signature: checkpointConfig.Signature,
+		}
+
+		if len(checkpoint.Signature) > 0 {
+			valid, err := checkpoint.SignatureIsValid(governmentPublicKey)
+			if err != nil {
+				return fmt.Errorf("failed to verify checkpoint at height %d: %v", checkpoint.Height, err)
+			}
+
+			if !valid {
+				return fmt.Errorf("checkpoint at height %d isn't signed by the government", checkpoint.Height)
+			}
+		}
+
+		if err := chainParams.Consensus.AddCheckpoint(checkpoint); err != nil {
+			return err
+		}
+	}
+
+	if consensusConfig.MaxReorgDepth > 0 {
+		chainParams.Consensus.MaxReorgDepth = consensusConfig.MaxReorgDepth
+	}
+
+	if consensusConfig.SignedHeaders > 0 {
+		chainParams.Consensus.SignedHeaders = consensusConfig.SignedHeaders
+	}
+
+	return chainParams.Consensus.Validate()
+}
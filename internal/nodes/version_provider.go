@@ -24,12 +24,19 @@ func (vp *VersionProvider) GetVersion() (*networking_models.Version, error) {
 		return nil, err
 	}
 
+	prunedHeight, err := vp.blockRepo.GetPrunedHeight()
+	if err != nil {
+		return nil, err
+	}
+
 	version := &networking_models.Version{
 		ProtocolVersion: vp.nodeConfig.Version,
 		NodeType:        vp.nodeConfig.Type,
 		Timestamp:       now,
 		Nonce:           0,
 		LastBlockHeight: uint32(lastBlockHeight),
+		GenesisBlockId:  vp.blockRepo.GenesisBlock().Header.Id,
+		PrunedHeight:    uint32(prunedHeight),
 	}
 	return version, nil
 }
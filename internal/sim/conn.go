@@ -0,0 +1,201 @@
+package sim
+
+import (
+	"encoding/binary"
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+// size of magic bytes + message header preceding every payload on the wire
+const frameHeaderSize = 4 + 20
+
+type delivery struct {
+	at    time.Time
+	frame []byte
+}
+
+// byteQueue is the inbound side of a simulated connection
+type byteQueue struct {
+	mutex  sync.Mutex
+	cond   *sync.Cond
+	data   []byte
+	closed bool
+}
+
+func newByteQueue() *byteQueue {
+	queue := &byteQueue{}
+	queue.cond = sync.NewCond(&queue.mutex)
+	return queue
+}
+
+func (queue *byteQueue) write(b []byte) {
+	queue.mutex.Lock()
+	defer queue.mutex.Unlock()
+
+	if queue.closed {
+		return
+	}
+
+	queue.data = append(queue.data, b...)
+	queue.cond.Broadcast()
+}
+
+func (queue *byteQueue) read(b []byte) (int, error) {
+	queue.mutex.Lock()
+	defer queue.mutex.Unlock()
+
+	for len(queue.data) == 0 && !queue.closed {
+		queue.cond.Wait()
+	}
+
+	if len(queue.data) == 0 {
+		return 0, io.EOF
+	}
+
+	n := copy(b, queue.data)
+	queue.data = queue.data[n:]
+	return n, nil
+}
+
+func (queue *byteQueue) close() {
+	queue.mutex.Lock()
+	defer queue.mutex.Unlock()
+
+	queue.closed = true
+	queue.cond.Broadcast()
+}
+
+// Conn is one end of an in-memory link between two simulated nodes.
+// Writes are reassembled into whole protocol messages so that loss and partitions drop complete messages
+// instead of corrupting the stream.
+type Conn struct {
+	link *Link
+
+	localAddr  net.Addr
+	remoteAddr net.Addr
+
+	inbound  *byteQueue
+	outbound *byteQueue
+
+	pendingMutex sync.Mutex
+	pending      []byte
+
+	deliveries chan delivery
+
+	closeOnce sync.Once
+	closed    chan struct{}
+}
+
+func newConn(link *Link, localAddr net.Addr, remoteAddr net.Addr, inbound *byteQueue, outbound *byteQueue) *Conn {
+	conn := &Conn{
+		link:       link,
+		localAddr:  localAddr,
+		remoteAddr: remoteAddr,
+		inbound:    inbound,
+		outbound:   outbound,
+		deliveries: make(chan delivery, 1024),
+		closed:     make(chan struct{}),
+	}
+
+	go conn.deliver()
+	return conn
+}
+
+func (conn *Conn) Read(b []byte) (int, error) {
+	select {
+	case <-conn.closed:
+		return 0, net.ErrClosed
+	default:
+	}
+
+	return conn.inbound.read(b)
+}
+
+func (conn *Conn) Write(b []byte) (int, error) {
+	select {
+	case <-conn.closed:
+		return 0, net.ErrClosed
+	default:
+	}
+
+	conn.pendingMutex.Lock()
+	defer conn.pendingMutex.Unlock()
+
+	conn.pending = append(conn.pending, b...)
+
+	for len(conn.pending) >= frameHeaderSize {
+		payloadLength := binary.BigEndian.Uint32(conn.pending[16:20])
+		frameLength := frameHeaderSize + int(payloadLength)
+
+		if len(conn.pending) < frameLength {
+			break
+		}
+
+		frame := make([]byte, frameLength)
+		copy(frame, conn.pending[:frameLength])
+		conn.pending = conn.pending[frameLength:]
+
+		if conn.link.shouldDrop() {
+			continue
+		}
+
+		select {
+		case <-conn.closed:
+			return 0, net.ErrClosed
+		case conn.deliveries <- delivery{at: time.Now().Add(conn.link.delay()), frame: frame}:
+		}
+	}
+
+	return len(b), nil
+}
+
+func (conn *Conn) deliver() {
+	for {
+		select {
+		case <-conn.closed:
+			return
+		case d := <-conn.deliveries:
+			if wait := time.Until(d.at); wait > 0 {
+				select {
+				case <-conn.closed:
+					return
+				case <-time.After(wait):
+				}
+			}
+
+			conn.outbound.write(d.frame)
+		}
+	}
+}
+
+func (conn *Conn) Close() error {
+	conn.closeOnce.Do(func() {
+		close(conn.closed)
+		conn.inbound.close()
+		conn.outbound.close()
+	})
+
+	return nil
+}
+
+func (conn *Conn) LocalAddr() net.Addr {
+	return conn.localAddr
+}
+
+func (conn *Conn) RemoteAddr() net.Addr {
+	return conn.remoteAddr
+}
+
+func (conn *Conn) SetDeadline(t time.Time) error {
+	return nil
+}
+
+func (conn *Conn) SetReadDeadline(t time.Time) error {
+	return nil
+}
+
+func (conn *Conn) SetWriteDeadline(t time.Time) error {
+	return nil
+}
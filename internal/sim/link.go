@@ -0,0 +1,104 @@
+package sim
+
+import (
+	"math/rand"
+	"net"
+	"sync"
+	"time"
+)
+
+type LinkConfig struct {
+	Latency    time.Duration //one way delay added to every message
+	Jitter     time.Duration //maximum random delay added on top of latency
+	PacketLoss float64       //probability in [0, 1] that a message is dropped
+}
+
+type Link struct {
+	simulation *Simulation
+
+	From int
+	To   int
+
+	configMutex sync.RWMutex
+	config      LinkConfig
+
+	fromConn *Conn
+	toConn   *Conn
+}
+
+func newLink(simulation *Simulation, from int, to int, config LinkConfig) *Link {
+	link := &Link{
+		simulation: simulation,
+		From:       from,
+		To:         to,
+		config:     config,
+	}
+
+	fromAddr := nodeAddress(from, to)
+	toAddr := nodeAddress(to, from)
+
+	fromInbound := newByteQueue()
+	toInbound := newByteQueue()
+
+	link.fromConn = newConn(link, fromAddr, toAddr, fromInbound, toInbound)
+	link.toConn = newConn(link, toAddr, fromAddr, toInbound, fromInbound)
+
+	return link
+}
+
+func (link *Link) SetConfig(config LinkConfig) {
+	link.configMutex.Lock()
+	defer link.configMutex.Unlock()
+
+	link.config = config
+}
+
+func (link *Link) GetConfig() LinkConfig {
+	link.configMutex.RLock()
+	defer link.configMutex.RUnlock()
+
+	return link.config
+}
+
+func (link *Link) Close() {
+	link.fromConn.Close()
+	link.toConn.Close()
+}
+
+func (link *Link) shouldDrop() bool {
+	if !link.simulation.reachable(link.From, link.To) {
+		return true
+	}
+
+	packetLoss := link.GetConfig().PacketLoss
+	if packetLoss <= 0 {
+		return false
+	}
+
+	return link.simulation.random() < packetLoss
+}
+
+func (link *Link) delay() time.Duration {
+	config := link.GetConfig()
+	if config.Jitter <= 0 {
+		return config.Latency
+	}
+
+	return config.Latency + time.Duration(link.simulation.random()*float64(config.Jitter))
+}
+
+// nodeAddress gives every (node, remote node) pair a distinct private address so peers are keyed uniquely
+func nodeAddress(node int, remote int) net.Addr {
+	return &net.TCPAddr{
+		IP:   net.IPv4(10, 0, byte(node), byte(remote)),
+		Port: 18333,
+	}
+}
+
+func newRandom(seed int64) *rand.Rand {
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+
+	return rand.New(rand.NewSource(seed))
+}
@@ -0,0 +1,146 @@
+package sim
+
+import (
+	"fmt"
+	"time"
+
+	chainparams "github.com/nivschuman/VotingBlockchain/internal/chainparams"
+	config "github.com/nivschuman/VotingBlockchain/internal/config"
+	ppk "github.com/nivschuman/VotingBlockchain/internal/crypto/ppk"
+	database "github.com/nivschuman/VotingBlockchain/internal/database/connection"
+	repositories "github.com/nivschuman/VotingBlockchain/internal/database/repositories"
+	events "github.com/nivschuman/VotingBlockchain/internal/events"
+	mempool "github.com/nivschuman/VotingBlockchain/internal/mempool"
+	mining "github.com/nivschuman/VotingBlockchain/internal/mining"
+	data_models "github.com/nivschuman/VotingBlockchain/internal/models"
+	networking_models "github.com/nivschuman/VotingBlockchain/internal/networking/models"
+	network "github.com/nivschuman/VotingBlockchain/internal/networking/network"
+	nodes "github.com/nivschuman/VotingBlockchain/internal/nodes"
+	"github.com/nivschuman/VotingBlockchain/internal/voters"
+	"gorm.io/gorm"
+)
+
+type Node struct {
+	Index     int
+	ClockSkew time.Duration
+
+	FullNode *nodes.FullNode
+	Network  *network.NetworkImpl
+	Miner    *mining.MinerImpl
+	Mempool  *mempool.MempoolImpl
+
+	BlockRepository       repositories.BlockRepository
+	TransactionRepository repositories.TransactionRepository
+	AddressRepository     repositories.AddressRepository
+
+	networkConfig *config.NetworkConfig
+	db            *gorm.DB
+}
+
+func newNode(index int, simulationId int64, nodeConfig config.NodeConfig, networkConfig config.NetworkConfig, chainParams *chainparams.ChainParams, governmentPublicKey []byte, minerKeyPair *ppk.KeyPair, clockSkew time.Duration) (*Node, error) {
+	dsn := fmt.Sprintf("file:sim-%d-node-%d?mode=memory&cache=shared", simulationId, index)
+	db, err := database.GetDatabaseConnection(dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+	sqlDB.SetMaxOpenConns(1)
+
+	eventBus := events.NewEventBusImpl()
+	transactionRepository := repositories.NewTransactionRepositoryImpl(db)
+	blockRepository := repositories.NewBlockRepositoryImpl(db, eventBus, chainParams)
+	if err := blockRepository.Initialize(); err != nil {
+		return nil, err
+	}
+
+	addressRepository := repositories.NewAddressRepositoryImpl(db)
+
+	node := &Node{
+		Index:                 index,
+		ClockSkew:             clockSkew,
+		BlockRepository:       blockRepository,
+		TransactionRepository: transactionRepository,
+		AddressRepository:     addressRepository,
+		networkConfig:         &networkConfig,
+		db:                    db,
+	}
+
+	//unbounded and never expiring, scripts control what gets mined
+	node.Mempool = mempool.NewMempoolImpl(transactionRepository, repositories.NewConflictingVoteRepositoryImpl(db), eventBus, governmentPublicKey, &config.MempoolConfig{})
+
+	versionProvider := nodes.NewVersionProvider(blockRepository, nodeConfig)
+	node.Network = network.NewNetworkImpl(addressRepository, node.networkConfig, chainParams, func() (*networking_models.Version, error) {
+		version, err := versionProvider.GetVersion()
+		if err != nil {
+			return nil, err
+		}
+
+		version.Timestamp += int64(node.ClockSkew.Seconds())
+		return version, nil
+	}, eventBus)
+
+	minerProps := mining.MinerProperties{
+		MinerKeyPair:         minerKeyPair,
+		MaxBlockSize:         config.DEFAULT_MAX_BLOCK_SIZE,
+		MaxBlockTransactions: config.DEFAULT_MAX_BLOCK_TRANSACTIONS,
+		Workers:              1,
+	}
+	node.Miner = mining.NewMinerImpl(node.now, blockRepository, node.Mempool, eventBus, chainParams, minerProps)
+
+	node.FullNode = nodes.NewFullNode(node.Network, mining.NewOnDemandMiner(node.Miner), node.Mempool, blockRepository, transactionRepository, eventBus, chainParams, governmentPublicKey)
+	node.FullNode.AddShutdownHook(func() error {
+		eventBus.Close()
+		return database.CloseDatabaseConnection(db)
+	})
+
+	return node, nil
+}
+
+func (node *Node) String() string {
+	return fmt.Sprintf("node-%d", node.Index)
+}
+
+// now is the network adjusted time as seen by this node, shifted by its clock skew
+func (node *Node) now() int64 {
+	return node.Network.GetNetworkTime() + int64(node.ClockSkew.Seconds())
+}
+
+// MineBlocks mines count blocks, templates abandoned because the chain tip changed are mined again
+func (node *Node) MineBlocks(count int) error {
+	if _, err := node.Miner.Generate(count); err != nil {
+		return fmt.Errorf("%s failed to mine blocks: %v", node.String(), err)
+	}
+
+	return nil
+}
+
+func (node *Node) SubmitVote(voter *voters.Voter, candidateId uint32) error {
+	tx := &data_models.Transaction{
+		Version:             1,
+		CandidateId:         candidateId,
+		VoterPublicKey:      voter.KeyPair.PublicKey.AsBytes(),
+		GovernmentSignature: voter.GovernmentSignature,
+	}
+
+	tx.SetId()
+	signature, err := voter.KeyPair.PrivateKey.CreateSignature(tx.Id)
+	if err != nil {
+		return err
+	}
+	tx.Signature = signature
+
+	node.FullNode.ProcessGeneratedTransaction(tx)
+	return nil
+}
+
+func (node *Node) GetVotingResults() ([]*voters.VotingResult, error) {
+	return node.TransactionRepository.GetVotingResults()
+}
+
+func (node *Node) GetActiveChainTipId() []byte {
+	return node.BlockRepository.GetActiveChainTipId()
+}
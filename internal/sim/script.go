@@ -0,0 +1,137 @@
+package sim
+
+import (
+	"fmt"
+	"time"
+)
+
+type Step interface {
+	Apply(simulation *Simulation) error
+	String() string
+}
+
+type Script []Step
+
+type MineBlocks struct {
+	Node  int
+	Count int
+}
+
+type SubmitVote struct {
+	Node        int
+	Voter       int
+	CandidateId uint32
+}
+
+type Partition struct {
+	Groups [][]int
+}
+
+type Heal struct{}
+
+type SetLink struct {
+	From   int
+	To     int
+	Config LinkConfig
+}
+
+type Wait struct {
+	Duration time.Duration
+}
+
+type AssertConverged struct {
+	Timeout time.Duration
+}
+
+func (step MineBlocks) Apply(simulation *Simulation) error {
+	node, err := simulation.getNode(step.Node)
+	if err != nil {
+		return err
+	}
+
+	return node.MineBlocks(step.Count)
+}
+
+func (step MineBlocks) String() string {
+	return fmt.Sprintf("node-%d mines %d blocks", step.Node, step.Count)
+}
+
+func (step SubmitVote) Apply(simulation *Simulation) error {
+	node, err := simulation.getNode(step.Node)
+	if err != nil {
+		return err
+	}
+
+	if step.Voter < 0 || step.Voter >= len(simulation.Voters) {
+		return fmt.Errorf("no voter %d", step.Voter)
+	}
+
+	return node.SubmitVote(simulation.Voters[step.Voter], step.CandidateId)
+}
+
+func (step SubmitVote) String() string {
+	return fmt.Sprintf("voter %d votes for candidate %d through node-%d", step.Voter, step.CandidateId, step.Node)
+}
+
+func (step Partition) Apply(simulation *Simulation) error {
+	simulation.Partition(step.Groups...)
+	return nil
+}
+
+func (step Partition) String() string {
+	return fmt.Sprintf("partition %v", step.Groups)
+}
+
+func (step Heal) Apply(simulation *Simulation) error {
+	simulation.Heal()
+	return nil
+}
+
+func (step Heal) String() string {
+	return "heal partition"
+}
+
+func (step SetLink) Apply(simulation *Simulation) error {
+	found := false
+	for _, link := range simulation.GetLinks() {
+		if (link.From == step.From && link.To == step.To) || (link.From == step.To && link.To == step.From) {
+			link.SetConfig(step.Config)
+			found = true
+		}
+	}
+
+	if !found {
+		return fmt.Errorf("no link between node-%d and node-%d", step.From, step.To)
+	}
+
+	return nil
+}
+
+func (step SetLink) String() string {
+	return fmt.Sprintf("set link node-%d <-> node-%d to %+v", step.From, step.To, step.Config)
+}
+
+func (step Wait) Apply(simulation *Simulation) error {
+	time.Sleep(step.Duration)
+	return nil
+}
+
+func (step Wait) String() string {
+	return fmt.Sprintf("wait %s", step.Duration)
+}
+
+func (step AssertConverged) Apply(simulation *Simulation) error {
+	return simulation.WaitForConvergence(step.Timeout)
+}
+
+func (step AssertConverged) String() string {
+	return fmt.Sprintf("assert convergence within %s", step.Timeout)
+}
+
+func (simulation *Simulation) getNode(idx int) (*Node, error) {
+	if idx < 0 || idx >= len(simulation.Nodes) {
+		return nil, fmt.Errorf("no node %d", idx)
+	}
+
+	return simulation.Nodes[idx], nil
+}
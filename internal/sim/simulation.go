@@ -0,0 +1,352 @@
+package sim
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"net"
+	"slices"
+	"sync"
+	"time"
+
+	chainparams "github.com/nivschuman/VotingBlockchain/internal/chainparams"
+	config "github.com/nivschuman/VotingBlockchain/internal/config"
+	hash "github.com/nivschuman/VotingBlockchain/internal/crypto/hash"
+	ppk "github.com/nivschuman/VotingBlockchain/internal/crypto/ppk"
+	"github.com/nivschuman/VotingBlockchain/internal/voters"
+)
+
+type Config struct {
+	NumberOfNodes  int
+	NumberOfVoters int
+
+	Link       LinkConfig            //conditions of every link unless overridden in Links
+	Links      map[[2]int]LinkConfig //per link conditions, keyed by node indexes
+	Topology   [][2]int              //links to create, full mesh if empty
+	ClockSkews map[int]time.Duration //clock skew per node index
+
+	NodeConfig  config.NodeConfig
+	ChainParams *chainparams.ChainParams //regtest if nil
+	Seed        int64                    //seed for loss and jitter, random if 0
+}
+
+type Simulation struct {
+	Nodes  []*Node
+	Voters []*voters.Voter
+
+	GovernmentKeyPair *ppk.KeyPair
+
+	config Config
+
+	links      []*Link
+	linksMutex sync.Mutex
+
+	partitionMutex sync.RWMutex
+	partition      map[int]int //node index to partition group, nil when healed
+
+	randomMutex sync.Mutex
+	rand        func() float64
+}
+
+var simulationCounter int64
+var simulationCounterMutex sync.Mutex
+
+func NewSimulation(simConfig Config) (*Simulation, error) {
+	if simConfig.NumberOfNodes < 1 {
+		return nil, fmt.Errorf("simulation needs at least one node")
+	}
+
+	simulationCounterMutex.Lock()
+	simulationCounter++
+	simulationId := simulationCounter
+	simulationCounterMutex.Unlock()
+
+	if simConfig.ChainParams == nil {
+		simConfig.ChainParams = chainparams.RegTestParams()
+	}
+
+	random := newRandom(simConfig.Seed)
+	simulation := &Simulation{
+		config: simConfig,
+		rand:   random.Float64,
+	}
+
+	govKeyPair, err := ppk.GenerateKeyPair()
+	if err != nil {
+		return nil, err
+	}
+	simulation.GovernmentKeyPair = govKeyPair
+
+	//simulated nodes start from the genesis block of their election like real nodes
+	chainParams := *simConfig.ChainParams
+	chainParams.SetGenesisBlock(govKeyPair.PublicKey.AsBytes())
+	simConfig.ChainParams = &chainParams
+	simulation.config.ChainParams = &chainParams
+
+	for i := 1; i <= simConfig.NumberOfVoters; i++ {
+		voterKeyPair, err := ppk.GenerateKeyPair()
+		if err != nil {
+			return nil, err
+		}
+
+		govSig, err := govKeyPair.PrivateKey.CreateSignature(hash.HashBytes(voterKeyPair.PublicKey.AsBytes()))
+		if err != nil {
+			return nil, err
+		}
+
+		simulation.Voters = append(simulation.Voters, &voters.Voter{
+			Name:                fmt.Sprintf("Voter%d", i),
+			KeyPair:             *voterKeyPair,
+			GovernmentSignature: govSig,
+		})
+	}
+
+	for i := range simConfig.NumberOfNodes {
+		minerKeyPair, err := ppk.GenerateKeyPair()
+		if err != nil {
+			return nil, err
+		}
+
+		node, err := newNode(i, simulationId, simConfig.NodeConfig, simulationNetworkConfig(), simConfig.ChainParams, govKeyPair.PublicKey.AsBytes(), minerKeyPair, simConfig.ClockSkews[i])
+		if err != nil {
+			return nil, err
+		}
+
+		simulation.Nodes = append(simulation.Nodes, node)
+	}
+
+	return simulation, nil
+}
+
+func (simulation *Simulation) Start() error {
+	for _, node := range simulation.Nodes {
+		node.FullNode.Start()
+	}
+
+	topology := simulation.config.Topology
+	if len(topology) == 0 {
+		for i := range simulation.Nodes {
+			for j := i + 1; j < len(simulation.Nodes); j++ {
+				topology = append(topology, [2]int{i, j})
+			}
+		}
+	}
+
+	for _, edge := range topology {
+		if err := simulation.Connect(edge[0], edge[1]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (simulation *Simulation) Stop() {
+	simulation.linksMutex.Lock()
+	for _, link := range simulation.links {
+		link.Close()
+	}
+	simulation.links = nil
+	simulation.linksMutex.Unlock()
+
+	for _, node := range simulation.Nodes {
+		node.FullNode.Stop()
+	}
+}
+
+// Connect links two nodes and waits until both finished the handshake
+func (simulation *Simulation) Connect(from int, to int) error {
+	if from < 0 || from >= len(simulation.Nodes) || to < 0 || to >= len(simulation.Nodes) || from == to {
+		return fmt.Errorf("invalid link %d -> %d", from, to)
+	}
+
+	linkConfig, exists := simulation.config.Links[[2]int{from, to}]
+	if !exists {
+		linkConfig = simulation.config.Link
+	}
+
+	link := newLink(simulation, from, to, linkConfig)
+
+	simulation.linksMutex.Lock()
+	simulation.links = append(simulation.links, link)
+	simulation.linksMutex.Unlock()
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+
+	go func() {
+		defer wg.Done()
+		simulation.Nodes[from].Network.AddConnection(link.fromConn, true)
+	}()
+
+	go func() {
+		defer wg.Done()
+		simulation.Nodes[to].Network.AddConnection(link.toConn, false)
+	}()
+
+	wg.Wait()
+
+	if !simulation.connected(from, link.fromConn) || !simulation.connected(to, link.toConn) {
+		return fmt.Errorf("handshake between node-%d and node-%d failed", from, to)
+	}
+
+	return nil
+}
+
+func (simulation *Simulation) GetLinks() []*Link {
+	simulation.linksMutex.Lock()
+	defer simulation.linksMutex.Unlock()
+
+	return slices.Clone(simulation.links)
+}
+
+// Partition splits the nodes into groups, messages between different groups are dropped.
+// Nodes not listed in any group are isolated.
+func (simulation *Simulation) Partition(groups ...[]int) {
+	simulation.partitionMutex.Lock()
+	defer simulation.partitionMutex.Unlock()
+
+	simulation.partition = make(map[int]int)
+	for groupIdx, group := range groups {
+		for _, nodeIdx := range group {
+			simulation.partition[nodeIdx] = groupIdx
+		}
+	}
+
+	log.Printf("|Sim| Partitioned network into %v", groups)
+}
+
+func (simulation *Simulation) Heal() {
+	simulation.partitionMutex.Lock()
+	defer simulation.partitionMutex.Unlock()
+
+	simulation.partition = nil
+	log.Print("|Sim| Healed network partition")
+}
+
+// Converged reports whether all nodes agree on the active chain tip and the voting results
+func (simulation *Simulation) Converged() (bool, error) {
+	firstTip := simulation.Nodes[0].GetActiveChainTipId()
+	firstResults, err := simulation.Nodes[0].GetVotingResults()
+	if err != nil {
+		return false, err
+	}
+
+	for _, node := range simulation.Nodes[1:] {
+		if !bytes.Equal(firstTip, node.GetActiveChainTipId()) {
+			return false, nil
+		}
+
+		results, err := node.GetVotingResults()
+		if err != nil {
+			return false, err
+		}
+
+		if !votingResultsEqual(firstResults, results) {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+func (simulation *Simulation) WaitForConvergence(timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+
+	for {
+		converged, err := simulation.Converged()
+		if err != nil {
+			return err
+		}
+
+		if converged {
+			return nil
+		}
+
+		if time.Now().After(deadline) {
+			return fmt.Errorf("nodes did not converge within %s: %s", timeout, simulation.describeTips())
+		}
+
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+func (simulation *Simulation) Run(script Script) error {
+	for idx, step := range script {
+		log.Printf("|Sim| Step %d: %s", idx, step.String())
+
+		if err := step.Apply(simulation); err != nil {
+			return fmt.Errorf("step %d (%s) failed: %v", idx, step.String(), err)
+		}
+	}
+
+	return nil
+}
+
+func (simulation *Simulation) reachable(from int, to int) bool {
+	simulation.partitionMutex.RLock()
+	defer simulation.partitionMutex.RUnlock()
+
+	if simulation.partition == nil {
+		return true
+	}
+
+	fromGroup, fromExists := simulation.partition[from]
+	toGroup, toExists := simulation.partition[to]
+
+	return fromExists && toExists && fromGroup == toGroup
+}
+
+func (simulation *Simulation) random() float64 {
+	simulation.randomMutex.Lock()
+	defer simulation.randomMutex.Unlock()
+
+	return simulation.rand()
+}
+
+func (simulation *Simulation) connected(nodeIdx int, conn net.Conn) bool {
+	for _, p := range simulation.Nodes[nodeIdx].Network.GetPeers() {
+		if p.Conn == conn {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (simulation *Simulation) describeTips() string {
+	description := ""
+	for _, node := range simulation.Nodes {
+		height, _ := node.BlockRepository.GetActiveChainHeight()
+		description += fmt.Sprintf("[%s tip=%x height=%d] ", node.String(), node.GetActiveChainTipId(), height)
+	}
+
+	return description
+}
+
+func simulationNetworkConfig() config.NetworkConfig {
+	return config.NetworkConfig{
+		Ip:                     net.ParseIP("127.0.0.1"),
+		Port:                   0,
+		PingInterval:           30,
+		PongTimeout:            3600,
+		SendDataInterval:       1,
+		GetAddrInterval:        3600,
+		MaxNumberOfConnections: 0,
+		Dial:                   false,
+	}
+}
+
+func votingResultsEqual(a []*voters.VotingResult, b []*voters.VotingResult) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i].CandidateId != b[i].CandidateId || a[i].Votes != b[i].Votes {
+			return false
+		}
+	}
+
+	return true
+}
@@ -56,7 +56,7 @@ func (appBuilder *AppBuilderImpl) BuildApp() App {
 
 	t := container.NewAppTabs()
 
-	blocksTab := tabs.NewBlocksTab(appBuilder.blockRepository)
+	blocksTab := tabs.NewBlocksTab(appBuilder.blockRepository, appBuilder.node.GetEventBus())
 	t.Append(container.NewTabItem("Blocks", blocksTab.GetWidget()))
 
 	transactionsTab := tabs.NewTransactionsTab(appBuilder.node, appBuilder.voters)
@@ -65,7 +65,10 @@ func (appBuilder *AppBuilderImpl) BuildApp() App {
 	mempoolTab := tabs.NewMempoolTab(appBuilder.node)
 	t.Append(container.NewTabItem("Mempool", mempoolTab.GetWidget()))
 
-	peersTab := tabs.NewPeersTab(appBuilder.node.GetNetwork())
+	conflictingVotesTab := tabs.NewConflictingVotesTab(appBuilder.node.GetMempool().GetConflictingVoteRepository(), appBuilder.node.GetEventBus(), appBuilder.config.MempoolConfig.ConflictingVotesFile)
+	t.Append(container.NewTabItem("Conflicts", conflictingVotesTab.GetWidget()))
+
+	peersTab := tabs.NewPeersTab(appBuilder.node.GetNetwork(), appBuilder.node.GetEventBus())
 	t.Append(container.NewTabItem("Peers", peersTab.GetWidget()))
 
 	addressesTab := tabs.NewAddressesTab(appBuilder.node.GetNetwork().GetAddressRepository())
@@ -76,6 +79,9 @@ func (appBuilder *AppBuilderImpl) BuildApp() App {
 		t.Append(container.NewTabItem("Mining", miningTab.GetWidget()))
 	}
 
+	creditsTab := tabs.NewCreditsTab(appBuilder.blockRepository, appBuilder.node.GetEventBus())
+	t.Append(container.NewTabItem("Credits", creditsTab.GetWidget()))
+
 	votesTab := tabs.NewVotesTab(appBuilder.node)
 	t.Append(container.NewTabItem("Votes", votesTab.GetWidget()))
 
@@ -118,7 +118,7 @@ func (tab *AddressesTab) loadAddresses() {
 
 	// Build rows
 	rows := container.NewVBox()
-	widths := []float32{150, 90, 90, 120}
+	widths := []float32{150, 90, 90, 120, 90, 120}
 	h := float32(30)
 
 	// Header row
@@ -127,6 +127,8 @@ func (tab *AddressesTab) loadAddresses() {
 		tab.makeCell("Port", widths[1], h),
 		tab.makeCell("Node Type", widths[2], h),
 		tab.makeCell("Last Seen", widths[3], h),
+		tab.makeCell("Attempts", widths[4], h),
+		tab.makeCell("Disconnect Reason", widths[5], h),
 	)
 	rows.Add(headerGrid)
 
@@ -137,11 +139,18 @@ func (tab *AddressesTab) loadAddresses() {
 			lastSeen = a.LastSeen.String()
 		}
 
+		disconnectReason := "N/A"
+		if a.LastDisconnectReason != "" {
+			disconnectReason = a.LastDisconnectReason
+		}
+
 		row := container.NewGridWithColumns(len(widths),
 			tab.makeCell(a.Ip, widths[0], h),
 			tab.makeCell(fmt.Sprintf("%d", a.Port), widths[1], h),
 			tab.makeCell(fmt.Sprintf("%d", a.NodeType), widths[2], h),
 			tab.makeCell(lastSeen, widths[3], h),
+			tab.makeCell(fmt.Sprintf("%d", a.Attempts), widths[4], h),
+			tab.makeCell(disconnectReason, widths[5], h),
 		)
 		rows.Add(row)
 	}
@@ -14,6 +14,7 @@ import (
 
 	models "github.com/nivschuman/VotingBlockchain/internal/database/models"
 	"github.com/nivschuman/VotingBlockchain/internal/database/repositories"
+	events "github.com/nivschuman/VotingBlockchain/internal/events"
 )
 
 type BlocksTab struct {
@@ -48,7 +49,7 @@ type BlocksTab struct {
 	searchText string
 }
 
-func NewBlocksTab(blockRepository repositories.BlockRepository) *BlocksTab {
+func NewBlocksTab(blockRepository repositories.BlockRepository, eventBus events.EventBus) *BlocksTab {
 	t := &BlocksTab{
 		sortAsc:         true,
 		blockRepository: blockRepository,
@@ -58,6 +59,14 @@ func NewBlocksTab(blockRepository repositories.BlockRepository) *BlocksTab {
 	t.widget = t.buildUI()
 	t.loadPage()
 	t.load3Blocks()
+
+	eventBus.Subscribe(func(events.Event) {
+		fyne.Do(func() {
+			t.loadPage()
+			t.load3Blocks()
+		})
+	}, events.ChainTipChanged)
+
 	return t
 }
 
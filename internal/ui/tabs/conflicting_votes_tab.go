@@ -0,0 +1,212 @@
+package tabs
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/widget"
+
+	"github.com/nivschuman/VotingBlockchain/internal/database/repositories"
+	events "github.com/nivschuman/VotingBlockchain/internal/events"
+	"github.com/nivschuman/VotingBlockchain/internal/models"
+)
+
+type ConflictingVotesTab struct {
+	pageSize   int
+	repository repositories.ConflictingVoteRepository
+	exportFile string
+
+	widget fyne.CanvasObject
+
+	votesTable *widget.Table
+	votes      []*models.ConflictingVote
+	votesPage  int
+
+	countLabel  *widget.Label
+	exportLabel *widget.Label
+
+	prevBtn    *widget.Button
+	nextBtn    *widget.Button
+	refreshBtn *widget.Button
+	exportBtn  *widget.Button
+}
+
+func NewConflictingVotesTab(repository repositories.ConflictingVoteRepository, eventBus events.EventBus, exportFile string) *ConflictingVotesTab {
+	c := &ConflictingVotesTab{
+		repository: repository,
+		exportFile: exportFile,
+		pageSize:   10,
+	}
+	c.widget = c.buildUI()
+	c.refreshConflictingVotes()
+
+	eventBus.Subscribe(func(events.Event) {
+		fyne.Do(c.refreshConflictingVotes)
+	}, events.ConflictingVoteDetected)
+
+	return c
+}
+
+func (c *ConflictingVotesTab) buildUI() fyne.CanvasObject {
+	c.refreshBtn = widget.NewButton("Refresh", c.refreshConflictingVotes)
+	c.exportBtn = widget.NewButton("Export", c.exportConflictingVotes)
+	c.countLabel = widget.NewLabel("0 recorded")
+	c.exportLabel = widget.NewLabel("")
+
+	header := container.NewHBox(
+		widget.NewLabelWithStyle("Conflicting Votes", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+		c.countLabel,
+		layout.NewSpacer(),
+		c.exportBtn,
+		c.refreshBtn,
+	)
+
+	c.votesTable = widget.NewTable(
+		func() (int, int) { return len(c.votes) + 1, 8 },
+		func() fyne.CanvasObject {
+			lbl := widget.NewLabel("")
+			lbl.Wrapping = fyne.TextWrap(fyne.TextTruncateClip)
+			return lbl
+		},
+		c.updateVoteCell,
+	)
+	c.votesTable.SetColumnWidth(0, 200)
+	c.votesTable.SetColumnWidth(1, 200)
+	c.votesTable.SetColumnWidth(2, 90)
+	c.votesTable.SetColumnWidth(3, 200)
+	c.votesTable.SetColumnWidth(4, 90)
+	c.votesTable.SetColumnWidth(5, 100)
+	c.votesTable.SetColumnWidth(6, 150)
+	c.votesTable.SetColumnWidth(7, 80)
+
+	c.prevBtn = widget.NewButton("Prev", func() {
+		if c.votesPage > 0 {
+			c.votesPage--
+			c.refreshConflictingVotes()
+		}
+	})
+	c.nextBtn = widget.NewButton("Next", func() {
+		c.votesPage++
+		c.refreshConflictingVotes()
+	})
+
+	nav := container.NewHBox(c.prevBtn, c.nextBtn, layout.NewSpacer(), c.exportLabel)
+
+	scroll := container.NewVScroll(c.votesTable)
+	scroll.SetMinSize(fyne.NewSize(700, 200))
+
+	content := container.NewVBox(
+		header,
+		scroll,
+		nav,
+	)
+
+	return container.NewPadded(content)
+}
+
+func (c *ConflictingVotesTab) refreshConflictingVotes() {
+	offset := c.votesPage * c.pageSize
+	votes, total, err := c.repository.GetConflictingVotesPaged(offset, c.pageSize)
+	if err != nil {
+		c.votes = []*models.ConflictingVote{}
+	} else {
+		c.votes = votes
+	}
+
+	c.countLabel.SetText(fmt.Sprintf("%d recorded", total))
+
+	if c.votesPage > 0 {
+		c.prevBtn.Enable()
+	} else {
+		c.prevBtn.Disable()
+	}
+
+	if offset+len(c.votes) >= total {
+		c.nextBtn.Disable()
+	} else {
+		c.nextBtn.Enable()
+	}
+
+	c.votesTable.Refresh()
+}
+
+func (c *ConflictingVotesTab) exportConflictingVotes() {
+	votes, err := c.repository.GetAllConflictingVotes()
+	if err != nil {
+		c.exportLabel.SetText(fmt.Sprintf("Export failed: %v", err))
+		return
+	}
+
+	data, err := models.ConflictingVotesToJSON(votes)
+	if err != nil {
+		c.exportLabel.SetText(fmt.Sprintf("Export failed: %v", err))
+		return
+	}
+
+	if err := os.MkdirAll(filepath.Dir(c.exportFile), 0755); err != nil {
+		c.exportLabel.SetText(fmt.Sprintf("Export failed: %v", err))
+		return
+	}
+
+	if err := os.WriteFile(c.exportFile, data, 0644); err != nil {
+		c.exportLabel.SetText(fmt.Sprintf("Export failed: %v", err))
+		return
+	}
+
+	c.exportLabel.SetText(fmt.Sprintf("Exported %d to %s", len(votes), c.exportFile))
+}
+
+func (c *ConflictingVotesTab) updateVoteCell(id widget.TableCellID, co fyne.CanvasObject) {
+	lbl := co.(*widget.Label)
+	if id.Row == 0 {
+		switch id.Col {
+		case 0:
+			lbl.SetText("Voter Key")
+		case 1:
+			lbl.SetText("Rejected Tx ID")
+		case 2:
+			lbl.SetText("Candidate ID")
+		case 3:
+			lbl.SetText("Kept Tx ID")
+		case 4:
+			lbl.SetText("Candidate ID")
+		case 5:
+			lbl.SetText("Kept In")
+		case 6:
+			lbl.SetText("Detected At")
+		case 7:
+			lbl.SetText("Conflicts")
+		}
+		lbl.TextStyle = fyne.TextStyle{Bold: true}
+	} else {
+		vote := c.votes[id.Row-1]
+		switch id.Col {
+		case 0:
+			lbl.SetText(fmt.Sprintf("%x", vote.VoterPublicKey))
+		case 1:
+			lbl.SetText(fmt.Sprintf("%x", vote.Transaction.Id))
+		case 2:
+			lbl.SetText(strconv.Itoa(int(vote.Transaction.CandidateId)))
+		case 3:
+			lbl.SetText(fmt.Sprintf("%x", vote.ConflictingTransaction.Id))
+		case 4:
+			lbl.SetText(strconv.Itoa(int(vote.ConflictingTransaction.CandidateId)))
+		case 5:
+			lbl.SetText(vote.Source)
+		case 6:
+			lbl.SetText(vote.DetectedAt.Format("2006-01-02 15:04:05"))
+		case 7:
+			lbl.SetText(strconv.FormatUint(vote.Conflicts, 10))
+		}
+		lbl.TextStyle = fyne.TextStyle{}
+	}
+}
+
+func (c *ConflictingVotesTab) GetWidget() fyne.CanvasObject {
+	return c.widget
+}
@@ -0,0 +1,189 @@
+package tabs
+
+import (
+	"encoding/hex"
+	"fmt"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/widget"
+
+	"github.com/nivschuman/VotingBlockchain/internal/database/repositories"
+	events "github.com/nivschuman/VotingBlockchain/internal/events"
+	"github.com/nivschuman/VotingBlockchain/internal/models"
+)
+
+// CreditsTab shows the credits every miner earned in the active chain, it's refreshed on every chain tip change so reorgs are reflected
+type CreditsTab struct {
+	pageSize        int
+	blockRepository repositories.BlockRepository
+
+	widget fyne.CanvasObject
+
+	creditsTable *widget.Table
+	credits      []*models.MinerCredits
+	creditsPage  int
+
+	countLabel  *widget.Label
+	lookupEntry *widget.Entry
+	lookupLabel *widget.Label
+
+	prevBtn    *widget.Button
+	nextBtn    *widget.Button
+	refreshBtn *widget.Button
+}
+
+func NewCreditsTab(blockRepository repositories.BlockRepository, eventBus events.EventBus) *CreditsTab {
+	c := &CreditsTab{
+		blockRepository: blockRepository,
+		pageSize:        10,
+	}
+	c.widget = c.buildUI()
+	c.refreshCredits()
+
+	eventBus.Subscribe(func(events.Event) {
+		fyne.Do(c.refreshCredits)
+	}, events.ChainTipChanged)
+
+	return c
+}
+
+func (c *CreditsTab) buildUI() fyne.CanvasObject {
+	c.refreshBtn = widget.NewButton("Refresh", c.refreshCredits)
+	c.countLabel = widget.NewLabel("0 miners")
+
+	header := container.NewHBox(
+		widget.NewLabelWithStyle("Miner Credits", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+		c.countLabel,
+		layout.NewSpacer(),
+		c.refreshBtn,
+	)
+
+	c.lookupEntry = widget.NewEntry()
+	c.lookupEntry.SetPlaceHolder("Miner public key (hex)")
+	c.lookupEntry.OnSubmitted = func(string) { c.lookupCredits() }
+	c.lookupLabel = widget.NewLabel("")
+
+	lookup := container.NewBorder(nil, nil, nil, widget.NewButton("Lookup", c.lookupCredits), c.lookupEntry)
+
+	c.creditsTable = widget.NewTable(
+		func() (int, int) { return len(c.credits) + 1, 4 },
+		func() fyne.CanvasObject {
+			lbl := widget.NewLabel("")
+			lbl.Wrapping = fyne.TextWrap(fyne.TextTruncateClip)
+			return lbl
+		},
+		c.updateCreditsCell,
+	)
+	c.creditsTable.SetColumnWidth(0, 400)
+	c.creditsTable.SetColumnWidth(1, 90)
+	c.creditsTable.SetColumnWidth(2, 90)
+	c.creditsTable.SetColumnWidth(3, 100)
+
+	c.prevBtn = widget.NewButton("Prev", func() {
+		if c.creditsPage > 0 {
+			c.creditsPage--
+			c.refreshCredits()
+		}
+	})
+	c.nextBtn = widget.NewButton("Next", func() {
+		c.creditsPage++
+		c.refreshCredits()
+	})
+
+	nav := container.NewHBox(c.prevBtn, c.nextBtn)
+
+	scroll := container.NewVScroll(c.creditsTable)
+	scroll.SetMinSize(fyne.NewSize(700, 200))
+
+	content := container.NewVBox(
+		header,
+		lookup,
+		c.lookupLabel,
+		scroll,
+		nav,
+	)
+
+	return container.NewPadded(content)
+}
+
+func (c *CreditsTab) refreshCredits() {
+	offset := c.creditsPage * c.pageSize
+	credits, total, err := c.blockRepository.GetMinerCreditsPaged(offset, c.pageSize)
+	if err != nil {
+		c.credits = []*models.MinerCredits{}
+	} else {
+		c.credits = credits
+	}
+
+	c.countLabel.SetText(fmt.Sprintf("%d miners", total))
+
+	if c.creditsPage > 0 {
+		c.prevBtn.Enable()
+	} else {
+		c.prevBtn.Disable()
+	}
+
+	if int64(offset+len(c.credits)) >= total {
+		c.nextBtn.Disable()
+	} else {
+		c.nextBtn.Enable()
+	}
+
+	c.creditsTable.Refresh()
+
+	if c.lookupEntry.Text != "" {
+		c.lookupCredits()
+	}
+}
+
+func (c *CreditsTab) lookupCredits() {
+	minerPublicKey, err := hex.DecodeString(c.lookupEntry.Text)
+	if err != nil {
+		c.lookupLabel.SetText(fmt.Sprintf("Invalid key: %v", err))
+		return
+	}
+
+	credits, err := c.blockRepository.GetMinerCredits(minerPublicKey)
+	if err != nil {
+		c.lookupLabel.SetText(fmt.Sprintf("Lookup failed: %v", err))
+		return
+	}
+
+	c.lookupLabel.SetText(fmt.Sprintf("%d credits for %d blocks with %d votes", credits.Credits, credits.Blocks, credits.Votes))
+}
+
+func (c *CreditsTab) updateCreditsCell(id widget.TableCellID, co fyne.CanvasObject) {
+	lbl := co.(*widget.Label)
+	if id.Row == 0 {
+		switch id.Col {
+		case 0:
+			lbl.SetText("Miner Key")
+		case 1:
+			lbl.SetText("Blocks")
+		case 2:
+			lbl.SetText("Votes")
+		case 3:
+			lbl.SetText("Credits")
+		}
+		lbl.TextStyle = fyne.TextStyle{Bold: true}
+	} else {
+		credits := c.credits[id.Row-1]
+		switch id.Col {
+		case 0:
+			lbl.SetText(fmt.Sprintf("%x", credits.MinerPublicKey))
+		case 1:
+			lbl.SetText(fmt.Sprintf("%d", credits.Blocks))
+		case 2:
+			lbl.SetText(fmt.Sprintf("%d", credits.Votes))
+		case 3:
+			lbl.SetText(fmt.Sprintf("%d", credits.Credits))
+		}
+		lbl.TextStyle = fyne.TextStyle{}
+	}
+}
+
+func (c *CreditsTab) GetWidget() fyne.CanvasObject {
+	return c.widget
+}
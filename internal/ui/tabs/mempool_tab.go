@@ -9,6 +9,7 @@ import (
 	"fyne.io/fyne/v2/layout"
 	"fyne.io/fyne/v2/widget"
 
+	events "github.com/nivschuman/VotingBlockchain/internal/events"
 	"github.com/nivschuman/VotingBlockchain/internal/models"
 	"github.com/nivschuman/VotingBlockchain/internal/nodes"
 )
@@ -22,6 +23,7 @@ type MempoolTab struct {
 	mempoolTable *widget.Table
 	mempoolTxs   []*models.Transaction
 	mempoolPage  int
+	countLabel   *widget.Label
 
 	prevBtn    *widget.Button
 	nextBtn    *widget.Button
@@ -35,14 +37,21 @@ func NewMempoolTab(node nodes.Node) *MempoolTab {
 	}
 	m.widget = m.buildUI()
 	m.refreshMempoolTransactions()
+
+	node.GetEventBus().Subscribe(func(events.Event) {
+		fyne.Do(m.refreshMempoolTransactions)
+	}, events.MempoolTransactionAdded, events.MempoolTransactionRemoved)
+
 	return m
 }
 
 func (m *MempoolTab) buildUI() fyne.CanvasObject {
 	m.refreshBtn = widget.NewButton("Refresh", m.refreshMempoolTransactions)
+	m.countLabel = widget.NewLabel("0 pending")
 
 	header := container.NewHBox(
 		widget.NewLabelWithStyle("Mempool Transactions", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+		m.countLabel,
 		layout.NewSpacer(),
 		m.refreshBtn,
 	)
@@ -88,12 +97,9 @@ func (m *MempoolTab) buildUI() fyne.CanvasObject {
 
 func (m *MempoolTab) refreshMempoolTransactions() {
 	offset := m.mempoolPage * m.pageSize
-	txs, _, err := m.node.GetTransactionRepository().GetMempoolPaged(offset, m.pageSize)
-	if err != nil {
-		m.mempoolTxs = []*models.Transaction{}
-	} else {
-		m.mempoolTxs = txs
-	}
+	txs, total := m.node.GetMempool().GetTransactionsPaged(offset, m.pageSize)
+	m.mempoolTxs = txs
+	m.countLabel.SetText(fmt.Sprintf("%d pending", total))
 
 	if m.mempoolPage > 0 {
 		m.prevBtn.Enable()
@@ -25,6 +25,7 @@ type MiningTab struct {
 	nbitsLabel             *widget.Label
 	currentBlockStartLabel *widget.Label
 	currentDurationLabel   *widget.Label
+	workersLabel           *widget.Label
 
 	stopTicker chan bool
 }
@@ -51,6 +52,7 @@ func (t *MiningTab) buildUI() fyne.CanvasObject {
 	t.nbitsLabel = widget.NewLabel("0")
 	t.currentBlockStartLabel = widget.NewLabel("N/A")
 	t.currentDurationLabel = widget.NewLabel("0s")
+	t.workersLabel = widget.NewLabel("0")
 
 	grid := container.NewGridWithColumns(2,
 		widget.NewLabel("Total Blocks Mined:"), t.totalBlocksLabel,
@@ -60,6 +62,7 @@ func (t *MiningTab) buildUI() fyne.CanvasObject {
 		widget.NewLabel("Current Mining Duration:"), t.currentDurationLabel,
 		widget.NewLabel("Current Block Hashes Tried:"), t.currentHashesLabel,
 		widget.NewLabel("Current Hash Rate:"), t.currentHashRateLabel,
+		widget.NewLabel("Workers:"), t.workersLabel,
 		widget.NewLabel("Last Nonce:"), t.lastNonceLabel,
 		widget.NewLabel("Last Block Time:"), t.lastBlockTimeLabel,
 	)
@@ -96,6 +99,7 @@ func (t *MiningTab) updateUI(stats mining.MiningStatistics) {
 		t.currentHashRateLabel.SetText(fmt.Sprintf("%.2f Hashes/sec", stats.CurrentHashRate()))
 		t.difficultyLabel.SetText(fmt.Sprintf("%.2f", stats.Difficulty()))
 		t.nbitsLabel.SetText(fmt.Sprintf("0x%08x", stats.CurrentNBits))
+		t.workersLabel.SetText(fmt.Sprintf("%d", len(stats.WorkerHashesTried)))
 
 		if stats.CurrentBlockStart > 0 {
 			startTime := time.Unix(0, stats.CurrentBlockStart)
@@ -13,6 +13,7 @@ import (
 	"fyne.io/fyne/v2/layout"
 	"fyne.io/fyne/v2/widget"
 
+	events "github.com/nivschuman/VotingBlockchain/internal/events"
 	models "github.com/nivschuman/VotingBlockchain/internal/networking/models"
 	network "github.com/nivschuman/VotingBlockchain/internal/networking/network"
 	peer "github.com/nivschuman/VotingBlockchain/internal/networking/peer"
@@ -31,15 +32,21 @@ type PeersTab struct {
 	portEntry *widget.Entry
 	dialBtn   *widget.Button
 
-	peersScroll *container.Scroll
+	peersScroll        *container.Scroll
+	disconnectedScroll *container.Scroll
 }
 
-func NewPeersTab(network network.Network) *PeersTab {
+func NewPeersTab(network network.Network, eventBus events.EventBus) *PeersTab {
 	tab := &PeersTab{
 		network: network,
 	}
 	tab.widget = tab.buildUI()
 	tab.loadPeers()
+
+	eventBus.Subscribe(func(events.Event) {
+		fyne.Do(tab.loadPeers)
+	}, events.PeerConnected, events.PeerDisconnected)
+
 	return tab
 }
 
@@ -94,9 +101,14 @@ func (tab *PeersTab) buildUI() fyne.CanvasObject {
 	tab.peersScroll = container.NewVScroll(container.NewVBox())
 	tab.peersScroll.SetMinSize(fyne.NewSize(0, 200))
 
+	tab.disconnectedScroll = container.NewVScroll(container.NewVBox())
+	tab.disconnectedScroll.SetMinSize(fyne.NewSize(0, 150))
+
 	content := container.NewVBox(
 		header,
 		tab.peersScroll,
+		widget.NewLabel("Recently Disconnected"),
+		tab.disconnectedScroll,
 		widget.NewLabel("Manual Connect"),
 		manualDial,
 	)
@@ -124,7 +136,7 @@ func (tab *PeersTab) loadPeers() {
 	for _, p := range tab.allPeers {
 		btn := widget.NewButton("Remove", func(peerToRemove *peer.Peer) func() {
 			return func() {
-				tab.network.RemovePeer(peerToRemove)
+				tab.network.RemovePeer(peerToRemove, peer.DisconnectReasonUserRequest)
 				tab.loadPeers()
 			}
 		}(p))
@@ -143,6 +155,37 @@ func (tab *PeersTab) loadPeers() {
 
 	tab.peersScroll.Content = rows
 	tab.peersScroll.Refresh()
+
+	tab.loadDisconnectedPeers()
+}
+
+func (tab *PeersTab) loadDisconnectedPeers() {
+	disconnectedPeers := tab.network.GetDisconnectedPeers()
+	rows := container.NewVBox()
+	widths := []float32{110, 90, 150, 200}
+	h := float32(30)
+
+	headerGrid := container.NewGridWithColumns(len(widths),
+		tab.makeCell("IP", widths[0], h),
+		tab.makeCell("Port", widths[1], h),
+		tab.makeCell("Reason", widths[2], h),
+		tab.makeCell("Disconnected At", widths[3], h),
+	)
+	rows.Add(headerGrid)
+
+	for i := len(disconnectedPeers) - 1; i >= 0; i-- {
+		d := disconnectedPeers[i]
+		row := container.NewGridWithColumns(len(widths),
+			tab.makeCell(d.Address.Ip.String(), widths[0], h),
+			tab.makeCell(fmt.Sprintf("%d", d.Address.Port), widths[1], h),
+			tab.makeCell(string(d.Reason), widths[2], h),
+			tab.makeCell(d.DisconnectedAt.Format(time.DateTime), widths[3], h),
+		)
+		rows.Add(row)
+	}
+
+	tab.disconnectedScroll.Content = rows
+	tab.disconnectedScroll.Refresh()
 }
 
 func (tab *PeersTab) GetWidget() fyne.CanvasObject {
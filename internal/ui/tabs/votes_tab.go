@@ -9,6 +9,7 @@ import (
 	"fyne.io/fyne/v2/layout"
 	"fyne.io/fyne/v2/widget"
 
+	events "github.com/nivschuman/VotingBlockchain/internal/events"
 	"github.com/nivschuman/VotingBlockchain/internal/nodes"
 	"github.com/nivschuman/VotingBlockchain/internal/voters"
 )
@@ -16,28 +17,41 @@ import (
 type VotesTab struct {
 	node nodes.Node
 
-	widget     fyne.CanvasObject
-	results    []*voters.VotingResult
-	refreshBtn *widget.Button
-	resultsBox *fyne.Container
+	widget      fyne.CanvasObject
+	results     []*voters.VotingResult
+	refreshBtn  *widget.Button
+	heightEntry *widget.Entry //results at a height of the active chain, the latest when empty
+	resultsBox  *fyne.Container
 }
 
 func NewVotesTab(node nodes.Node) *VotesTab {
 	t := &VotesTab{node: node}
 	t.widget = t.buildUI()
 	t.refreshResults()
+
+	node.GetEventBus().Subscribe(func(events.Event) {
+		fyne.Do(t.refreshResults)
+	}, events.ChainTipChanged)
+
 	return t
 }
 
 func (t *VotesTab) buildUI() fyne.CanvasObject {
 	t.resultsBox = container.NewVBox()
 
+	t.heightEntry = widget.NewEntry()
+	t.heightEntry.SetPlaceHolder("Latest height")
+	t.heightEntry.OnSubmitted = func(string) {
+		t.refreshResults()
+	}
+
 	t.refreshBtn = widget.NewButton("Refresh", func() {
 		t.refreshResults()
 	})
 	header := container.NewHBox(
 		widget.NewLabelWithStyle("Voting Results", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
 		layout.NewSpacer(),
+		container.NewGridWrap(fyne.NewSize(140, t.heightEntry.MinSize().Height), t.heightEntry),
 		t.refreshBtn,
 	)
 	headerPadded := container.NewPadded(header)
@@ -51,7 +65,7 @@ func (t *VotesTab) buildUI() fyne.CanvasObject {
 }
 
 func (t *VotesTab) refreshResults() {
-	results, err := t.node.GetTransactionRepository().GetVotingResults()
+	results, err := t.loadResults()
 	if err != nil {
 		fmt.Println("Failed to load results:", err)
 		t.results = []*voters.VotingResult{}
@@ -67,6 +81,19 @@ func (t *VotesTab) refreshResults() {
 	t.resultsBox.Refresh()
 }
 
+func (t *VotesTab) loadResults() ([]*voters.VotingResult, error) {
+	if t.heightEntry.Text == "" {
+		return t.node.GetTransactionRepository().GetVotingResults()
+	}
+
+	height, err := strconv.ParseUint(t.heightEntry.Text, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid height %q", t.heightEntry.Text)
+	}
+
+	return t.node.GetTransactionRepository().GetVotingResultsAtHeight(height)
+}
+
 func (t *VotesTab) GetWidget() fyne.CanvasObject {
 	return t.widget
 }
@@ -0,0 +1,150 @@
+package validation
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+
+	chainparams "github.com/nivschuman/VotingBlockchain/internal/chainparams"
+	consensus "github.com/nivschuman/VotingBlockchain/internal/consensus"
+	repositories "github.com/nivschuman/VotingBlockchain/internal/database/repositories"
+	models "github.com/nivschuman/VotingBlockchain/internal/models"
+)
+
+var ErrChainCorrupted = errors.New("chain corrupted")
+
+// blocks between progress logs of a verification
+const VERIFY_PROGRESS_INTERVAL = 1000
+
+// ChainVerifier checks the stored active chain again, the blocks against the rules and the derived heights, weights and voter index against the blocks
+type ChainVerifier struct {
+	blockRepository       repositories.BlockRepository
+	transactionRepository repositories.TransactionRepository
+	chainParams           *chainparams.ChainParams
+	consensusEngine       consensus.ConsensusEngine
+	governmentPublicKey   []byte
+}
+
+func NewChainVerifier(blockRepository repositories.BlockRepository, transactionRepository repositories.TransactionRepository, chainParams *chainparams.ChainParams, governmentPublicKey []byte) *ChainVerifier {
+	return &ChainVerifier{
+		blockRepository:       blockRepository,
+		transactionRepository: transactionRepository,
+		chainParams:           chainParams,
+		consensusEngine:       consensus.NewConsensusEngine(chainParams),
+		governmentPublicKey:   governmentPublicKey,
+	}
+}
+
+// VerifyChain checks the last depth blocks of the active chain from the tip, the whole chain when depth is 0, it stops at pruned blocks.
+// It returns the number of blocks checked, errors wrapping ErrChainCorrupted mean a stored block or its derived data is wrong.
+func (verifier *ChainVerifier) VerifyChain(depth uint64) (uint64, error) {
+	currentId := verifier.blockRepository.GetActiveChainTipId()
+	checked := uint64(0)
+
+	for currentId != nil && (depth == 0 || checked < depth) {
+		block, err := verifier.blockRepository.GetBlock(currentId)
+		if errors.Is(err, repositories.ErrBlockPruned) {
+			log.Printf("|Chain Verifier| Stopped at pruned block %x", currentId)
+			break
+		}
+
+		if err != nil {
+			return checked, err
+		}
+
+		if err := verifier.verifyBlock(block); err != nil {
+			height, _ := verifier.blockRepository.GetBlockHeight(currentId)
+			return checked, fmt.Errorf("%w: block %x at height %d: %w", ErrChainCorrupted, currentId, height, err)
+		}
+
+		checked++
+		if checked%VERIFY_PROGRESS_INTERVAL == 0 {
+			log.Printf("|Chain Verifier| Verified %d blocks", checked)
+		}
+
+		currentId = block.Header.PreviousBlockId
+	}
+
+	return checked, nil
+}
+
+func (verifier *ChainVerifier) verifyBlock(block *models.Block) error {
+	if !bytes.Equal(block.Header.GetHash(), block.Header.Id) {
+		return errors.New("stored id isn't the hash of the header")
+	}
+
+	if block.Header.PreviousBlockId == nil {
+		if !bytes.Equal(block.Header.Id, verifier.chainParams.GenesisBlock.Header.Id) {
+			return errors.New("chain doesn't start at the genesis block")
+		}
+
+		return nil
+	}
+
+	if err := CheckBlock(block, verifier.consensusEngine, &verifier.chainParams.Election, verifier.governmentPublicKey); err != nil {
+		return err
+	}
+
+	if err := ValidateBlock(block, verifier.blockRepository, verifier.transactionRepository, verifier.consensusEngine, &verifier.chainParams.Consensus); err != nil {
+		return err
+	}
+
+	if err := verifier.verifyChainState(block); err != nil {
+		return err
+	}
+
+	//every vote must be the one the voter index holds, a second vote of a voter would be found by ValidateBlock of the later block
+	for i, tx := range block.Transactions {
+		activeVote, err := verifier.transactionRepository.GetActiveChainVote(tx.VoterPublicKey)
+		if err != nil {
+			return fmt.Errorf("transaction %d isn't in the voter index: %w", i, err)
+		}
+
+		if !bytes.Equal(activeVote.Id, tx.Id) {
+			return fmt.Errorf("voter index holds %x instead of transaction %d", activeVote.Id, i)
+		}
+	}
+
+	return nil
+}
+
+// verifyChainState compares the stored height and chain weight of the block with the ones derived from its previous block
+func (verifier *ChainVerifier) verifyChainState(block *models.Block) error {
+	height, err := verifier.blockRepository.GetBlockHeight(block.Header.Id)
+	if err != nil {
+		return err
+	}
+
+	previousHeight, err := verifier.blockRepository.GetBlockHeight(block.Header.PreviousBlockId)
+	if err != nil {
+		return err
+	}
+
+	if height != previousHeight+1 {
+		return fmt.Errorf("stored height %d, previous block is at height %d", height, previousHeight)
+	}
+
+	checkpoint := verifier.chainParams.Consensus.CheckpointAt(height)
+	if checkpoint != nil && !bytes.Equal(checkpoint.BlockId, block.Header.Id) {
+		return fmt.Errorf("conflicts with checkpoint %x", checkpoint.BlockId)
+	}
+
+	chainWeight, err := verifier.blockRepository.GetBlockChainWeight(block.Header.Id)
+	if err != nil {
+		return err
+	}
+
+	previousChainWeight, err := verifier.blockRepository.GetBlockChainWeight(block.Header.PreviousBlockId)
+	if err != nil {
+		return err
+	}
+
+	expectedChainWeight := new(big.Int).Add(previousChainWeight, verifier.consensusEngine.BlockWeight(&block.Header, height))
+	if chainWeight.Cmp(expectedChainWeight) != 0 {
+		return fmt.Errorf("stored chain weight %s, expected %s", chainWeight, expectedChainWeight)
+	}
+
+	return nil
+}
@@ -0,0 +1,123 @@
+package validation
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	chainparams "github.com/nivschuman/VotingBlockchain/internal/chainparams"
+	consensus "github.com/nivschuman/VotingBlockchain/internal/consensus"
+	repositories "github.com/nivschuman/VotingBlockchain/internal/database/repositories"
+	models "github.com/nivschuman/VotingBlockchain/internal/models"
+	structures "github.com/nivschuman/VotingBlockchain/internal/structures"
+)
+
+// Errors wrapping ErrInvalidBlock mean the block breaks the rules, other errors mean it couldn't be checked
+var ErrInvalidBlock = errors.New("invalid block")
+
+// CheckBlock runs the checks that don't depend on the chain the block extends
+func CheckBlock(block *models.Block, consensusEngine consensus.ConsensusEngine, electionRules *chainparams.ElectionRules, governmentPublicKey []byte) error {
+	//Signed header must be signed by the miner it credits, ValidateBlock checks the height from which headers must be signed
+	if block.Header.IsSigned() {
+		signatureIsValid, err := block.Header.MinerSignatureIsValid()
+		if err != nil || !signatureIsValid {
+			return fmt.Errorf("%w: miner signature is invalid", ErrInvalidBlock)
+		}
+	}
+
+	//Header must satisfy the consensus engine, proof of work or an authorized sealer
+	if err := consensusEngine.CheckHeader(&block.Header); err != nil {
+		if !errors.Is(err, consensus.ErrInvalidHeader) {
+			return err
+		}
+
+		return fmt.Errorf("%w: %w", ErrInvalidBlock, err)
+	}
+
+	//Block must be within the election size limits
+	if len(block.Transactions) > electionRules.MaxBlockVotes {
+		return fmt.Errorf("%w: %d transactions exceeds %d", ErrInvalidBlock, len(block.Transactions), electionRules.MaxBlockVotes)
+	}
+
+	if blockSize := len(block.AsBytes()); blockSize > electionRules.MaxBlockSize {
+		return fmt.Errorf("%w: size %d exceeds %d", ErrInvalidBlock, blockSize, electionRules.MaxBlockSize)
+	}
+
+	//Block transactions must be valid
+	txIds := structures.NewBytesSet()
+	voterKeys := structures.NewBytesSet()
+	for i, tx := range block.Transactions {
+		valid, err := tx.IsValid(governmentPublicKey)
+
+		if err != nil {
+			return err
+		}
+
+		if !valid {
+			return fmt.Errorf("%w: transaction %d is invalid", ErrInvalidBlock, i)
+		}
+
+		if txIds.Contains(tx.Id) {
+			return fmt.Errorf("%w: duplicate transaction id at index %d", ErrInvalidBlock, i)
+		}
+
+		if voterKeys.Contains(tx.VoterPublicKey) {
+			return fmt.Errorf("%w: duplicate voter in same block at index %d", ErrInvalidBlock, i)
+		}
+
+		txIds.Add(tx.Id)
+		voterKeys.Add(tx.VoterPublicKey)
+	}
+
+	//Check merkle root
+	merkleRoot := models.TransactionsMerkleRoot(block.Transactions)
+	if !bytes.Equal(block.Header.MerkleRoot, merkleRoot) {
+		return fmt.Errorf("%w: merkle root mismatch", ErrInvalidBlock)
+	}
+
+	return nil
+}
+
+// ValidateBlock checks the block against the chain it extends, its previous block must be stored
+func ValidateBlock(block *models.Block, blockRepository repositories.BlockRepository, transactionRepository repositories.TransactionRepository, consensusEngine consensus.ConsensusEngine, consensusRules *chainparams.ConsensusRules) error {
+	//Header must be signed by the miner it credits from the height signed headers activate
+	previousHeight, err := blockRepository.GetBlockHeight(block.Header.PreviousBlockId)
+	if err != nil {
+		return err
+	}
+
+	if height := previousHeight + 1; consensusRules.RequiresSignedHeader(height) && !block.Header.IsSigned() {
+		return fmt.Errorf("%w: version %d at height %d isn't signed by its miner", ErrInvalidBlock, block.Header.Version, height)
+	}
+
+	//Timestamp must be greater than the median time of the last 11 blocks
+	medianTimePast, err := blockRepository.GetMedianTimePast(block.Header.PreviousBlockId, 11)
+	if err != nil {
+		return err
+	}
+
+	if block.Header.Timestamp < medianTimePast {
+		return fmt.Errorf("%w: timestamp %d < median past %d", ErrInvalidBlock, block.Header.Timestamp, medianTimePast)
+	}
+
+	//Validate transactions on this blocks chain
+	valid, err := transactionRepository.TransactionsValidInChain(block.Header.PreviousBlockId, block.Transactions)
+	if err != nil {
+		return err
+	}
+
+	if !valid {
+		return fmt.Errorf("%w: transactions invalid in chain", ErrInvalidBlock)
+	}
+
+	//Validate work or the sealer's turn
+	if err := consensusEngine.VerifyHeader(blockRepository, &block.Header); err != nil {
+		if !errors.Is(err, consensus.ErrInvalidHeader) {
+			return err
+		}
+
+		return fmt.Errorf("%w: %w", ErrInvalidBlock, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,78 @@
+package voters
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/binary"
+	"slices"
+
+	hash "github.com/nivschuman/VotingBlockchain/internal/crypto/hash"
+)
+
+// TallySnapshot is the tally of the active chain up to a block, nodes agreeing on the chain have the same snapshot hash
+type TallySnapshot struct {
+	Height  uint64
+	BlockId []byte
+	Results []*VotingResult //ordered by candidate id
+	Hash    []byte
+}
+
+func NewTallySnapshot(height uint64, blockId []byte, results []*VotingResult) *TallySnapshot {
+	snapshot := &TallySnapshot{
+		Height:  height,
+		BlockId: blockId,
+		Results: SortedResults(results),
+	}
+	snapshot.Hash = hash.HashBytes(snapshot.AsBytes())
+
+	return snapshot
+}
+
+func (snapshot *TallySnapshot) AsBytes() []byte {
+	buf := new(bytes.Buffer)
+
+	binary.Write(buf, binary.BigEndian, snapshot.Height)
+	buf.Write(snapshot.BlockId)
+
+	for _, result := range snapshot.Results {
+		binary.Write(buf, binary.BigEndian, result.CandidateId)
+		binary.Write(buf, binary.BigEndian, uint64(result.Votes))
+	}
+
+	return buf.Bytes()
+}
+
+// SortedResults orders results by candidate id and leaves out candidates without votes
+func SortedResults(results []*VotingResult) []*VotingResult {
+	sorted := make([]*VotingResult, 0, len(results))
+	for _, result := range results {
+		if result.Votes != 0 {
+			sorted = append(sorted, result)
+		}
+	}
+
+	slices.SortFunc(sorted, func(a *VotingResult, b *VotingResult) int {
+		return cmp.Compare(a.CandidateId, b.CandidateId)
+	})
+
+	return sorted
+}
+
+// AddResults adds the votes of other to results, both ordered by candidate id
+func AddResults(results []*VotingResult, other []*VotingResult) []*VotingResult {
+	votes := make(map[uint32]int)
+	for _, result := range results {
+		votes[result.CandidateId] += result.Votes
+	}
+
+	for _, result := range other {
+		votes[result.CandidateId] += result.Votes
+	}
+
+	sum := make([]*VotingResult, 0, len(votes))
+	for candidateId, candidateVotes := range votes {
+		sum = append(sum, &VotingResult{CandidateId: candidateId, Votes: candidateVotes})
+	}
+
+	return SortedResults(sum)
+}
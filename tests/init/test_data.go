@@ -5,9 +5,9 @@ import (
 	"log"
 	"time"
 
+	chainparams "github.com/nivschuman/VotingBlockchain/internal/chainparams"
 	hash "github.com/nivschuman/VotingBlockchain/internal/crypto/hash"
 	ppk "github.com/nivschuman/VotingBlockchain/internal/crypto/ppk"
-	"github.com/nivschuman/VotingBlockchain/internal/difficulty"
 	models "github.com/nivschuman/VotingBlockchain/internal/models"
 	"github.com/nivschuman/VotingBlockchain/internal/voters"
 )
@@ -76,12 +76,52 @@ func CreateTestBlock(previousBlockId []byte, transactions []*models.Transaction)
 		return nil, err
 	}
 
+	return CreateTestBlockMinedBy(previousBlockId, transactions, minerKeyPair)
+}
+
+func CreateTestBlockMinedBy(previousBlockId []byte, transactions []*models.Transaction, minerKeyPair *ppk.KeyPair) (*models.Block, error) {
+	blockHeader := models.BlockHeader{
+		Version:         models.BLOCK_VERSION_SIGNED,
+		PreviousBlockId: previousBlockId,
+		MerkleRoot:      models.TransactionsMerkleRoot(transactions),
+		Timestamp:       time.Now().Unix(),
+		NBits:           TestChainParams.Difficulty.MinimumDifficulty,
+		Nonce:           0,
+		MinerPublicKey:  minerKeyPair.PublicKey.AsBytes(),
+	}
+
+	blockHeader.SetId()
+
+	for !blockHeader.IsHashBelowTarget() {
+		blockHeader.Nonce++
+		blockHeader.SetId()
+	}
+
+	if err := blockHeader.Sign(minerKeyPair.PrivateKey); err != nil {
+		return nil, err
+	}
+
+	block := &models.Block{
+		Header:       blockHeader,
+		Transactions: transactions,
+	}
+
+	return block, nil
+}
+
+// CreateTestUnsignedBlock is a block of the version before miners signed block headers
+func CreateTestUnsignedBlock(previousBlockId []byte, transactions []*models.Transaction) (*models.Block, error) {
+	minerKeyPair, err := ppk.GenerateKeyPair()
+	if err != nil {
+		return nil, err
+	}
+
 	blockHeader := models.BlockHeader{
 		Version:         1,
 		PreviousBlockId: previousBlockId,
 		MerkleRoot:      models.TransactionsMerkleRoot(transactions),
 		Timestamp:       time.Now().Unix(),
-		NBits:           uint32(difficulty.MINIMUM_DIFFICULTY),
+		NBits:           TestChainParams.Difficulty.MinimumDifficulty,
 		Nonce:           0,
 		MinerPublicKey:  minerKeyPair.PublicKey.AsBytes(),
 	}
@@ -93,6 +133,50 @@ func CreateTestBlock(previousBlockId []byte, transactions []*models.Transaction)
 		blockHeader.SetId()
 	}
 
+	return &models.Block{Header: blockHeader, Transactions: transactions}, nil
+}
+
+// CreateTestAuthorityChainParams are the test chain's params sealed by proof of authority, the sealers take turns in the given order
+func CreateTestAuthorityChainParams(period int64, outOfTurnDelay int64, sealers ...*ppk.KeyPair) (*chainparams.ChainParams, error) {
+	chainParams, err := chainparams.ParamsForName(TestConfig.NodeConfig.Chain)
+	if err != nil {
+		return nil, err
+	}
+
+	authorityRules := chainparams.AuthorityRules{
+		Period:         period,
+		OutOfTurnDelay: outOfTurnDelay,
+	}
+
+	for _, sealer := range sealers {
+		authorityRules.Sealers = append(authorityRules.Sealers, chainparams.Sealer{PublicKey: sealer.PublicKey.AsBytes(), Weight: 1})
+	}
+
+	chainParams.Consensus = chainparams.ConsensusRules{
+		Engine:    chainparams.ProofOfAuthority,
+		Authority: authorityRules,
+	}
+	chainParams.SetGenesisBlock(TestConfig.GovernmentConfig.PublicKey)
+
+	return chainParams, chainParams.Consensus.Validate()
+}
+
+func CreateTestSealedBlock(previousBlockId []byte, timestamp int64, transactions []*models.Transaction, sealerKeyPair *ppk.KeyPair) (*models.Block, error) {
+	blockHeader := models.BlockHeader{
+		Version:         models.BLOCK_VERSION_SIGNED,
+		PreviousBlockId: previousBlockId,
+		MerkleRoot:      models.TransactionsMerkleRoot(transactions),
+		Timestamp:       timestamp,
+		NBits:           0,
+		Nonce:           0,
+		MinerPublicKey:  sealerKeyPair.PublicKey.AsBytes(),
+	}
+
+	blockHeader.SetId()
+	if err := blockHeader.Sign(sealerKeyPair.PrivateKey); err != nil {
+		return nil, err
+	}
+
 	block := &models.Block{
 		Header:       blockHeader,
 		Transactions: transactions,
@@ -192,3 +276,18 @@ func GenerateTestGovernmentKeyPair() (*ppk.KeyPair, error) {
 	TestConfig.GovernmentConfig.PublicKey = keyPair.PublicKey.AsBytes()
 	return keyPair, nil
 }
+
+// CreateConflictingTestTransaction signs a vote of the same voter as transaction for another candidate
+func CreateConflictingTestTransaction(transaction *models.Transaction, voterKeyPair *ppk.KeyPair, candidateId uint32) (*models.Transaction, error) {
+	conflictingTx := *transaction
+	conflictingTx.CandidateId = candidateId
+	conflictingTx.SetId()
+
+	signature, err := voterKeyPair.PrivateKey.CreateSignature(conflictingTx.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	conflictingTx.Signature = signature
+	return &conflictingTx, nil
+}
@@ -6,22 +6,27 @@ import (
 	"os"
 	"path/filepath"
 
+	chainparams "github.com/nivschuman/VotingBlockchain/internal/chainparams"
 	"github.com/nivschuman/VotingBlockchain/internal/config"
+	ppk "github.com/nivschuman/VotingBlockchain/internal/crypto/ppk"
 	database "github.com/nivschuman/VotingBlockchain/internal/database/connection"
 	repositories "github.com/nivschuman/VotingBlockchain/internal/database/repositories"
-	difficulty "github.com/nivschuman/VotingBlockchain/internal/difficulty"
+	events "github.com/nivschuman/VotingBlockchain/internal/events"
+	mempool "github.com/nivschuman/VotingBlockchain/internal/mempool"
 	"gorm.io/gorm"
 )
 
 var TestConfig *config.Config
+var TestChainParams *chainparams.ChainParams
+var TestMinerKeyPair *ppk.KeyPair
 var TestDb *gorm.DB
 var TestBlockRepository repositories.BlockRepository
 var TestTransactionRepository repositories.TransactionRepository
 var TestAddressRepository repositories.AddressRepository
+var TestConflictingVoteRepository repositories.ConflictingVoteRepository
+var TestEventBus *events.EventBusImpl
 
 func SetupTests() {
-	setupTestingConstants()
-
 	testsRoot, err := getParentDirectory("tests")
 	if err != nil {
 		log.Fatalf("Failed to get project root: %v", err)
@@ -36,6 +41,16 @@ func SetupTests() {
 	if err != nil {
 		log.Fatalf("Failed to initialize test config: %v", err)
 	}
+
+	TestChainParams, err = chainparams.ParamsForName(TestConfig.NodeConfig.Chain)
+	if err != nil {
+		log.Fatalf("Failed to get test chain params: %v", err)
+	}
+
+	TestMinerKeyPair, err = ppk.GenerateKeyPair()
+	if err != nil {
+		log.Fatalf("Failed to generate test miner key pair: %v", err)
+	}
 }
 
 func SetupTestsDatabase() {
@@ -46,9 +61,11 @@ func SetupTestsDatabase() {
 		log.Fatalf("Failed to get database connection: %v", err)
 	}
 
+	TestEventBus = events.NewEventBusImpl()
 	TestTransactionRepository = repositories.NewTransactionRepositoryImpl(TestDb)
-	TestBlockRepository = repositories.NewBlockRepositoryImpl(TestDb, TestTransactionRepository)
+	TestBlockRepository = repositories.NewBlockRepositoryImpl(TestDb, TestEventBus, TestChainParams)
 	TestAddressRepository = repositories.NewAddressRepositoryImpl(TestDb)
+	TestConflictingVoteRepository = repositories.NewConflictingVoteRepositoryImpl(TestDb)
 
 	err = TestBlockRepository.Initialize()
 	if err != nil {
@@ -70,29 +87,26 @@ func ResetTestDatabase() {
 	}
 }
 
-func CloseTestDatabase() {
-	err := database.CloseDatabaseConnection(TestDb)
+// ClearTestDatabase empties the test database for a block repository of other chain params, which starts from another genesis block
+func ClearTestDatabase() {
+	err := database.ResetDatabase(TestDb)
 	if err != nil {
-		log.Fatalf("Failed to close test database: %v", err)
+		log.Fatalf("Failed to reset test database: %v", err)
 	}
 }
 
-func SetupTestEnvironmentConstants() {
-	difficulty.MINIMUM_DIFFICULTY = uint32(0x1d80ffff)
-	difficulty.TARGET_SPACING = int64(5 * 60)
-	difficulty.TARGET_TIMESPAN = 6 * difficulty.TARGET_SPACING
-	difficulty.INTERVAL = difficulty.TARGET_TIMESPAN / difficulty.TARGET_SPACING
-	difficulty.MIN_TIMESPAN = difficulty.TARGET_TIMESPAN / 4
-	difficulty.MAX_TIMESPAN = difficulty.TARGET_TIMESPAN * 4
+// NewTestMempool uses the current government public key, create it after generating the test government key pair
+func NewTestMempool() *mempool.MempoolImpl {
+	return mempool.NewMempoolImpl(TestTransactionRepository, TestConflictingVoteRepository, TestEventBus, TestConfig.GovernmentConfig.PublicKey, &TestConfig.MempoolConfig)
 }
 
-func setupTestingConstants() {
-	difficulty.MINIMUM_DIFFICULTY = uint32(0x207fffff)
-	difficulty.TARGET_TIMESPAN = int64(10 * 60)
-	difficulty.TARGET_SPACING = int64(1 * 60)
-	difficulty.INTERVAL = difficulty.TARGET_TIMESPAN / difficulty.TARGET_SPACING
-	difficulty.MIN_TIMESPAN = difficulty.TARGET_TIMESPAN / 4
-	difficulty.MAX_TIMESPAN = difficulty.TARGET_TIMESPAN * 4
+func CloseTestDatabase() {
+	TestEventBus.Close()
+
+	err := database.CloseDatabaseConnection(TestDb)
+	if err != nil {
+		log.Fatalf("Failed to close test database: %v", err)
+	}
 }
 
 func getParentDirectory(directoryName string) (string, error) {
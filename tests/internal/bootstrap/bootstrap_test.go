@@ -0,0 +1,111 @@
+package bootstrap_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"testing"
+
+	bootstrap "github.com/nivschuman/VotingBlockchain/internal/bootstrap"
+	models "github.com/nivschuman/VotingBlockchain/internal/models"
+	inits "github.com/nivschuman/VotingBlockchain/tests/init"
+)
+
+type recordingImporter struct {
+	blocks []*models.Block
+}
+
+func (importer *recordingImporter) ImportBlock(block *models.Block) (bool, error) {
+	importer.blocks = append(importer.blocks, block)
+	return len(importer.blocks) > 1, nil
+}
+
+func TestMain(m *testing.M) {
+	// === BEFORE ALL TESTS ===
+	inits.SetupTests()
+	inits.SetupTestsDatabase()
+
+	// Run the tests
+	code := m.Run()
+
+	// === AFTER ALL TESTS ===
+	inits.CloseTestDatabase()
+
+	// Exit with the right code
+	os.Exit(code)
+}
+
+func TestExportAndReadChain(t *testing.T) {
+	inits.ResetTestDatabase()
+	_, blocks, _, err := inits.CreateTestData(3, 2)
+	if err != nil {
+		t.Fatalf("failed to setup test data: %v", err)
+	}
+
+	var file bytes.Buffer
+	exported, err := bootstrap.ExportChain(inits.TestBlockRepository, bootstrap.NewBlockFileWriter(&file, inits.TestChainParams.MagicBytes))
+	if err != nil {
+		t.Fatalf("failed to export chain: %v", err)
+	}
+
+	if exported != 4 {
+		t.Fatalf("exported %d blocks, expected the 3 blocks and the genesis block", exported)
+	}
+
+	fileSize := int64(file.Len())
+	reader := bootstrap.NewBlockFileReader(&file, inits.TestChainParams.MagicBytes, inits.TestChainParams.Election.MaxBlockSize)
+	importer := &recordingImporter{}
+
+	result, err := bootstrap.ImportChain(reader, importer, fileSize)
+	if err != nil {
+		t.Fatalf("failed to import chain: %v", err)
+	}
+
+	if result.Read != 4 || result.Imported != 3 || result.Skipped != 1 || reader.BytesRead() != fileSize {
+		t.Fatalf("unexpected import result %+v after reading %d of %d bytes", result, reader.BytesRead(), fileSize)
+	}
+
+	if !bytes.Equal(importer.blocks[0].Header.Id, inits.TestChainParams.GenesisBlock.Header.Id) {
+		t.Fatalf("block file doesn't start with the genesis block")
+	}
+
+	for i, block := range blocks {
+		if !bytes.Equal(importer.blocks[i+1].AsBytes(), block.AsBytes()) {
+			t.Fatalf("block %d of the file differs from the stored block", i+1)
+		}
+	}
+}
+
+func TestReadBlockFileErrors(t *testing.T) {
+	genesisBlock := inits.TestChainParams.GenesisBlock
+	magicBytes := inits.TestChainParams.MagicBytes
+	maxBlockSize := inits.TestChainParams.Election.MaxBlockSize
+
+	var file bytes.Buffer
+	if err := bootstrap.NewBlockFileWriter(&file, magicBytes).WriteBlock(genesisBlock); err != nil {
+		t.Fatalf("failed to write block: %v", err)
+	}
+	record := file.Bytes()
+
+	otherMagicBytes := bytes.Repeat([]byte{0xFF}, len(magicBytes))
+	_, err := bootstrap.NewBlockFileReader(bytes.NewReader(record), otherMagicBytes, maxBlockSize).ReadBlock()
+	if !errors.Is(err, bootstrap.ErrMagicBytesMismatch) {
+		t.Fatalf("block file of another chain was read: %v", err)
+	}
+
+	_, err = bootstrap.NewBlockFileReader(bytes.NewReader(record[:len(record)-1]), magicBytes, maxBlockSize).ReadBlock()
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("truncated block file was read: %v", err)
+	}
+
+	_, err = bootstrap.NewBlockFileReader(bytes.NewReader(record), magicBytes, len(genesisBlock.AsBytes())-1).ReadBlock()
+	if err == nil {
+		t.Fatalf("block exceeding the max block size was read")
+	}
+
+	_, err = bootstrap.NewBlockFileReader(bytes.NewReader(nil), magicBytes, maxBlockSize).ReadBlock()
+	if !errors.Is(err, io.EOF) {
+		t.Fatalf("empty block file didn't end: %v", err)
+	}
+}
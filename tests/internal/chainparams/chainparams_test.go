@@ -0,0 +1,261 @@
+package chainparams_test
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	chainparams "github.com/nivschuman/VotingBlockchain/internal/chainparams"
+	ppk "github.com/nivschuman/VotingBlockchain/internal/crypto/ppk"
+	models "github.com/nivschuman/VotingBlockchain/internal/models"
+	inits "github.com/nivschuman/VotingBlockchain/tests/init"
+)
+
+func TestMain(m *testing.M) {
+	// === BEFORE ALL TESTS ===
+	inits.SetupTests()
+
+	// Run the tests
+	code := m.Run()
+
+	// === AFTER ALL TESTS ===
+
+	// Exit with the right code
+	os.Exit(code)
+}
+
+func TestParamsForName(t *testing.T) {
+	for _, name := range []string{chainparams.MainNet, chainparams.TestNet, chainparams.RegTest} {
+		params, err := chainparams.ParamsForName(name)
+		if err != nil {
+			t.Fatalf("failed to get params of %s: %v", name, err)
+		}
+
+		if params.Name != name {
+			t.Fatalf("params of %s are named %s", name, params.Name)
+		}
+	}
+
+	params, err := chainparams.ParamsForName("")
+	if err != nil || params.Name != chainparams.MainNet {
+		t.Fatalf("empty chain name isn't mainnet")
+	}
+
+	_, err = chainparams.ParamsForName("simnet")
+	if err == nil {
+		t.Fatalf("unknown chain name was accepted")
+	}
+
+	if inits.TestChainParams.Name != chainparams.RegTest {
+		t.Fatalf("tests don't run on regtest, running on %s", inits.TestChainParams.Name)
+	}
+}
+
+func TestNetworksAreDistinct(t *testing.T) {
+	networks := []*chainparams.ChainParams{
+		chainparams.MainNetParams(),
+		chainparams.TestNetParams(),
+		chainparams.RegTestParams(),
+	}
+
+	for i, network := range networks {
+		if network.GenesisBlock.Header.NBits != network.Difficulty.MinimumDifficulty {
+			t.Fatalf("%s genesis block isn't at minimum difficulty", network.Name)
+		}
+
+		for _, other := range networks[i+1:] {
+			if bytes.Equal(network.MagicBytes, other.MagicBytes) {
+				t.Fatalf("%s and %s share magic bytes", network.Name, other.Name)
+			}
+
+			if bytes.Equal(network.GenesisBlock.Header.Id, other.GenesisBlock.Header.Id) {
+				t.Fatalf("%s and %s share a genesis block", network.Name, other.Name)
+			}
+
+			if network.DefaultPort == other.DefaultPort {
+				t.Fatalf("%s and %s share a default port", network.Name, other.Name)
+			}
+		}
+	}
+}
+
+func TestElectionsAreDistinct(t *testing.T) {
+	firstGovernment, err := ppk.GenerateKeyPair()
+	if err != nil {
+		t.Fatalf("failed to generate government key pair: %v", err)
+	}
+
+	secondGovernment, err := ppk.GenerateKeyPair()
+	if err != nil {
+		t.Fatalf("failed to generate government key pair: %v", err)
+	}
+
+	first := chainparams.MainNetParams()
+	first.SetGenesisBlock(firstGovernment.PublicKey.AsBytes())
+
+	again := chainparams.MainNetParams()
+	again.SetGenesisBlock(firstGovernment.PublicKey.AsBytes())
+
+	second := chainparams.MainNetParams()
+	second.SetGenesisBlock(secondGovernment.PublicKey.AsBytes())
+
+	if !bytes.Equal(first.GenesisBlock.Header.Id, again.GenesisBlock.Header.Id) {
+		t.Fatalf("nodes of the same election have different genesis blocks")
+	}
+
+	if bytes.Equal(first.GenesisBlock.Header.Id, second.GenesisBlock.Header.Id) {
+		t.Fatalf("elections of different governments share a genesis block")
+	}
+
+	authority := chainparams.MainNetParams()
+	authority.Consensus.Engine = chainparams.ProofOfAuthority
+	authority.SetGenesisBlock(firstGovernment.PublicKey.AsBytes())
+
+	if authority.GenesisBlock.Header.NBits != 0 {
+		t.Fatalf("proof of authority genesis block carries NBits %d", authority.GenesisBlock.Header.NBits)
+	}
+
+	if bytes.Equal(authority.GenesisBlock.Header.Id, first.GenesisBlock.Header.Id) {
+		t.Fatalf("proof of authority and proof of work chains share a genesis block")
+	}
+}
+
+func TestDifficultyRules(t *testing.T) {
+	rules := chainparams.MainNetParams().Difficulty
+
+	if rules.Interval() != 2016 {
+		t.Fatalf("mainnet interval is %d, expected 2016", rules.Interval())
+	}
+
+	if rules.MinTimespan() != rules.TargetTimespan/4 || rules.MaxTimespan() != rules.TargetTimespan*4 {
+		t.Fatalf("mainnet timespan bounds are wrong")
+	}
+}
+
+func TestConsensusRules(t *testing.T) {
+	for _, network := range []*chainparams.ChainParams{chainparams.MainNetParams(), chainparams.TestNetParams(), chainparams.RegTestParams()} {
+		if network.Consensus.Engine != chainparams.ProofOfWork {
+			t.Fatalf("%s isn't mined with proof of work", network.Name)
+		}
+
+		if err := network.Consensus.Validate(); err != nil {
+			t.Fatalf("%s consensus rules are invalid: %v", network.Name, err)
+		}
+	}
+
+	first, err := inits.GenerateTestGovernmentKeyPair()
+	if err != nil {
+		t.Fatalf("failed to generate sealer key pair: %v", err)
+	}
+
+	second, err := inits.GenerateTestGovernmentKeyPair()
+	if err != nil {
+		t.Fatalf("failed to generate sealer key pair: %v", err)
+	}
+
+	rules := chainparams.ConsensusRules{
+		Engine: chainparams.ProofOfAuthority,
+		Authority: chainparams.AuthorityRules{
+			Sealers: []chainparams.Sealer{
+				{PublicKey: first.PublicKey.AsBytes(), Weight: 2},
+				{PublicKey: second.PublicKey.AsBytes(), Weight: 1},
+			},
+		},
+	}
+
+	if err := rules.Validate(); err != nil {
+		t.Fatalf("valid authority rules were rejected: %v", err)
+	}
+
+	//the first sealer gets two turns per round
+	expectedTurns := [][]byte{first.PublicKey.AsBytes(), first.PublicKey.AsBytes(), second.PublicKey.AsBytes()}
+	for height := range uint64(6) {
+		if !bytes.Equal(rules.Authority.InTurnSealer(height), expectedTurns[height%3]) {
+			t.Fatalf("wrong sealer in turn at height %d", height)
+		}
+	}
+
+	invalidRules := []chainparams.ConsensusRules{
+		{Engine: "pos"},
+		{Engine: chainparams.ProofOfAuthority},
+		{Engine: chainparams.ProofOfAuthority, Authority: chainparams.AuthorityRules{Sealers: []chainparams.Sealer{{PublicKey: []byte{0x01}, Weight: 1}}}},
+		{Engine: chainparams.ProofOfAuthority, Authority: chainparams.AuthorityRules{Sealers: []chainparams.Sealer{{PublicKey: first.PublicKey.AsBytes(), Weight: 0}}}},
+		{Engine: chainparams.ProofOfAuthority, Authority: chainparams.AuthorityRules{Sealers: []chainparams.Sealer{
+			{PublicKey: first.PublicKey.AsBytes(), Weight: 1},
+			{PublicKey: first.PublicKey.AsBytes(), Weight: 1},
+		}}},
+	}
+
+	for i, invalid := range invalidRules {
+		if err := invalid.Validate(); err == nil {
+			t.Fatalf("invalid consensus rules %d were accepted", i)
+		}
+	}
+}
+
+func TestCheckpoints(t *testing.T) {
+	rules := chainparams.RegTestParams().Consensus
+
+	first := models.Checkpoint{Height: 20, BlockId: bytes.Repeat([]byte{0x01}, 32)}
+	second := models.Checkpoint{Height: 10, BlockId: bytes.Repeat([]byte{0x02}, 32)}
+
+	for _, checkpoint := range []models.Checkpoint{first, second, first} {
+		if err := rules.AddCheckpoint(checkpoint); err != nil {
+			t.Fatalf("failed to add checkpoint at height %d: %v", checkpoint.Height, err)
+		}
+	}
+
+	if len(rules.Checkpoints) != 2 || rules.Checkpoints[0].Height != 10 || rules.Checkpoints[1].Height != 20 {
+		t.Fatalf("checkpoints aren't ordered by height: %+v", rules.Checkpoints)
+	}
+
+	if err := rules.Validate(); err != nil {
+		t.Fatalf("valid checkpoints were rejected: %v", err)
+	}
+
+	conflicting := models.Checkpoint{Height: 20, BlockId: bytes.Repeat([]byte{0x03}, 32)}
+	if err := rules.AddCheckpoint(conflicting); err == nil {
+		t.Fatalf("conflicting checkpoint was accepted")
+	}
+
+	if checkpoint := rules.CheckpointAt(20); checkpoint == nil || !bytes.Equal(checkpoint.BlockId, first.BlockId) {
+		t.Fatalf("wrong checkpoint at height 20: %+v", checkpoint)
+	}
+
+	if checkpoint := rules.CheckpointAt(15); checkpoint != nil {
+		t.Fatalf("found checkpoint at height 15 without one: %+v", checkpoint)
+	}
+
+	if checkpoint := rules.CheckpointBetween(10, 19); checkpoint != nil {
+		t.Fatalf("fork at a checkpoint's height doesn't pass it: %+v", checkpoint)
+	}
+
+	if checkpoint := rules.CheckpointBetween(9, 30); checkpoint == nil || checkpoint.Height != 10 {
+		t.Fatalf("wrong checkpoint between heights 9 and 30: %+v", checkpoint)
+	}
+
+	invalidRules := chainparams.RegTestParams().Consensus
+	invalidRules.Checkpoints = []models.Checkpoint{{Height: 5, BlockId: []byte{0x01}}}
+	if err := invalidRules.Validate(); err == nil {
+		t.Fatalf("checkpoint with a short block id was accepted")
+	}
+
+	invalidRules.Checkpoints = []models.Checkpoint{first, second}
+	if err := invalidRules.Validate(); err == nil {
+		t.Fatalf("unordered checkpoints were accepted")
+	}
+}
+
+func TestSignedHeadersOfNetworks(t *testing.T) {
+	//mainnet and testnet chains hold unsigned blocks
+	for _, chainParams := range []*chainparams.ChainParams{chainparams.MainNetParams(), chainparams.TestNetParams()} {
+		if chainParams.Consensus.RequiresSignedHeader(1) || chainParams.Consensus.RequiresSignedHeader(1_000_000) {
+			t.Fatalf("%s requires signed headers without an activation height", chainParams.Name)
+		}
+	}
+
+	regTestParams := chainparams.RegTestParams()
+	if !regTestParams.Consensus.RequiresSignedHeader(1) {
+		t.Fatalf("regtest doesn't require signed headers")
+	}
+}
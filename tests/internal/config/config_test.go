@@ -1,10 +1,14 @@
 package config_test
 
 import (
+	"fmt"
 	"os"
+	"runtime"
 	"testing"
 
+	config "github.com/nivschuman/VotingBlockchain/internal/config"
 	inits "github.com/nivschuman/VotingBlockchain/tests/init"
+	"gopkg.in/yaml.v2"
 )
 
 func TestMain(m *testing.M) {
@@ -31,3 +35,162 @@ func TestGlobalConfig(t *testing.T) {
 		t.Fatalf("Node type wasn't set correctly, is %d", conf.NodeConfig.Version)
 	}
 }
+
+func TestMempoolConflictPolicy(t *testing.T) {
+	if inits.TestConfig.MempoolConfig.ConflictPolicy != config.FirstSeenPolicy {
+		t.Fatalf("Mempool conflict policy wasn't set correctly, is %q", inits.TestConfig.MempoolConfig.ConflictPolicy)
+	}
+
+	var mempoolConfig config.MempoolConfig
+	err := yaml.Unmarshal([]byte("conflict-policy: newest"), &mempoolConfig)
+	if err == nil {
+		t.Fatalf("Unknown mempool conflict policy was accepted")
+	}
+}
+
+func TestMinerWorkers(t *testing.T) {
+	if inits.TestConfig.MinerConfig.Workers != 2 {
+		t.Fatalf("Miner workers weren't set correctly, is %d", inits.TestConfig.MinerConfig.Workers)
+	}
+
+	var minerConfig config.MinerConfig
+	err := yaml.Unmarshal([]byte("enabled: true"), &minerConfig)
+	if err != nil {
+		t.Fatalf("Failed to unmarshal miner config: %v", err)
+	}
+
+	if minerConfig.Workers != runtime.NumCPU() {
+		t.Fatalf("Miner workers didn't default to the number of CPUs, is %d", minerConfig.Workers)
+	}
+
+	if minerConfig.KeystoreFile != config.DEFAULT_KEYSTORE_FILE {
+		t.Fatalf("Miner keystore file didn't default to %s, is %q", config.DEFAULT_KEYSTORE_FILE, minerConfig.KeystoreFile)
+	}
+}
+
+func TestConsensusConfig(t *testing.T) {
+	if inits.TestConfig.ConsensusConfig.Engine != "" {
+		t.Fatalf("Test config overrides the chain's consensus engine with %q", inits.TestConfig.ConsensusConfig.Engine)
+	}
+
+	var consensusConfig config.ConsensusConfig
+	err := yaml.Unmarshal([]byte(`
+engine: poa
+period: 5
+out-of-turn-delay: 10
+sealers:
+  - public-key: 03f0d37776bd2b5f887d975888d6c8f6a1f1a1c7c7dfcd9926983db2960fd58d6a
+  - public-key: 0328388180b9bb0eabf382cfdf86551d3744b694a70f4c26a1e52e6d120ec19570
+    weight: 3
+`), &consensusConfig)
+	if err != nil {
+		t.Fatalf("Failed to unmarshal consensus config: %v", err)
+	}
+
+	if consensusConfig.Engine != "poa" || consensusConfig.Period != 5 || consensusConfig.OutOfTurnDelay != 10 {
+		t.Fatalf("Consensus config wasn't set correctly: %+v", consensusConfig)
+	}
+
+	if len(consensusConfig.Sealers) != 2 || len(consensusConfig.Sealers[0].PublicKey) != 33 {
+		t.Fatalf("Sealers weren't set correctly: %+v", consensusConfig.Sealers)
+	}
+
+	if consensusConfig.Sealers[0].Weight != 1 || consensusConfig.Sealers[1].Weight != 3 {
+		t.Fatalf("Sealer weights weren't set correctly, are %d and %d", consensusConfig.Sealers[0].Weight, consensusConfig.Sealers[1].Weight)
+	}
+
+	err = yaml.Unmarshal([]byte("sealers:\n  - public-key: zz"), &consensusConfig)
+	if err == nil {
+		t.Fatalf("Invalid sealer public key was accepted")
+	}
+
+	consensusConfig = config.ConsensusConfig{}
+	err = yaml.Unmarshal([]byte(`
+max-reorg-depth: 6
+checkpoints:
+  - height: 100
+    block-id: 00000000839a8e6886ab5951d76f411475428afc90947ee320161bbf18eb6048
+    signature: 3045022100c2a7
+  - height: 200
+    block-id: 000000006a625f06636b8bb6ac7b960a8d03705d1ace08b1a19da3fdcc99ddbd
+`), &consensusConfig)
+	if err != nil {
+		t.Fatalf("Failed to unmarshal checkpoints: %v", err)
+	}
+
+	if consensusConfig.MaxReorgDepth != 6 || len(consensusConfig.Checkpoints) != 2 {
+		t.Fatalf("Finality config wasn't set correctly: %+v", consensusConfig)
+	}
+
+	if consensusConfig.Checkpoints[0].Height != 100 || len(consensusConfig.Checkpoints[0].BlockId) != 32 || len(consensusConfig.Checkpoints[0].Signature) != 7 {
+		t.Fatalf("Signed checkpoint wasn't set correctly: %+v", consensusConfig.Checkpoints[0])
+	}
+
+	if len(consensusConfig.Checkpoints[1].Signature) != 0 {
+		t.Fatalf("Hardcoded checkpoint has a signature: %+v", consensusConfig.Checkpoints[1])
+	}
+
+	err = yaml.Unmarshal([]byte("checkpoints:\n  - height: 1\n    block-id: zz"), &consensusConfig)
+	if err == nil {
+		t.Fatalf("Invalid checkpoint block id was accepted")
+	}
+}
+
+func TestDatabaseEngine(t *testing.T) {
+	if inits.TestConfig.DatabaseConfig.Engine != config.SQLiteEngine {
+		t.Fatalf("Storage engine didn't default to %s, is %q", config.SQLiteEngine, inits.TestConfig.DatabaseConfig.Engine)
+	}
+
+	var databaseConfig config.DatabaseConfig
+	err := yaml.Unmarshal([]byte("file: peers.db\nengine: bolt\nchain-file: chain.bolt"), &databaseConfig)
+	if err != nil {
+		t.Fatalf("Failed to unmarshal database config: %v", err)
+	}
+
+	if databaseConfig.Engine != config.BoltEngine || databaseConfig.ChainFile != "chain.bolt" || databaseConfig.File != "peers.db" {
+		t.Fatalf("Database config wasn't set correctly: %+v", databaseConfig)
+	}
+
+	err = yaml.Unmarshal([]byte("engine: bolt"), &databaseConfig)
+	if err == nil {
+		t.Fatalf("Bolt storage engine without a chain file was accepted")
+	}
+
+	err = yaml.Unmarshal([]byte("engine: leveldb"), &databaseConfig)
+	if err == nil {
+		t.Fatalf("Unknown storage engine was accepted")
+	}
+}
+
+func TestDatabaseVerifyBlocks(t *testing.T) {
+	if inits.TestConfig.DatabaseConfig.VerifyBlocks != config.DEFAULT_VERIFY_BLOCKS || inits.TestConfig.DatabaseConfig.Reindex {
+		t.Fatalf("Startup chain checks didn't default correctly: %+v", inits.TestConfig.DatabaseConfig)
+	}
+
+	var databaseConfig config.DatabaseConfig
+	err := yaml.Unmarshal([]byte("reindex: true\nverify-blocks: 0"), &databaseConfig)
+	if err != nil {
+		t.Fatalf("Failed to unmarshal database config: %v", err)
+	}
+
+	if !databaseConfig.Reindex || databaseConfig.VerifyBlocks != 0 {
+		t.Fatalf("Startup chain checks weren't set correctly: %+v", databaseConfig)
+	}
+}
+
+func TestDatabasePrune(t *testing.T) {
+	if inits.TestConfig.DatabaseConfig.Prune != 0 {
+		t.Fatalf("Pruning didn't default to off: %+v", inits.TestConfig.DatabaseConfig)
+	}
+
+	var databaseConfig config.DatabaseConfig
+	err := yaml.Unmarshal([]byte(fmt.Sprintf("prune: %d", config.MIN_PRUNE_BLOCKS)), &databaseConfig)
+	if err != nil || databaseConfig.Prune != config.MIN_PRUNE_BLOCKS {
+		t.Fatalf("Prune wasn't set correctly: %v", err)
+	}
+
+	err = yaml.Unmarshal([]byte("prune: 10"), &databaseConfig)
+	if err == nil {
+		t.Fatalf("Pruning below the minimum was accepted")
+	}
+}
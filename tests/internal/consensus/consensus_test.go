@@ -0,0 +1,284 @@
+package consensus_test
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+	"os"
+	"testing"
+
+	chainparams "github.com/nivschuman/VotingBlockchain/internal/chainparams"
+	consensus "github.com/nivschuman/VotingBlockchain/internal/consensus"
+	ppk "github.com/nivschuman/VotingBlockchain/internal/crypto/ppk"
+	repositories "github.com/nivschuman/VotingBlockchain/internal/database/repositories"
+	difficulty "github.com/nivschuman/VotingBlockchain/internal/difficulty"
+	models "github.com/nivschuman/VotingBlockchain/internal/models"
+	inits "github.com/nivschuman/VotingBlockchain/tests/init"
+)
+
+func TestMain(m *testing.M) {
+	// === BEFORE ALL TESTS ===
+	inits.SetupTests()
+	inits.SetupTestsDatabase()
+
+	// Run the tests
+	code := m.Run()
+
+	// === AFTER ALL TESTS ===
+	inits.CloseTestDatabase()
+
+	// Exit with the right code
+	os.Exit(code)
+}
+
+func generateSealers(t *testing.T, count int) []*ppk.KeyPair {
+	sealers := make([]*ppk.KeyPair, count)
+	for i := range sealers {
+		keyPair, err := ppk.GenerateKeyPair()
+		if err != nil {
+			t.Fatalf("failed to generate sealer key pair: %v", err)
+		}
+		sealers[i] = keyPair
+	}
+
+	return sealers
+}
+
+func TestNewConsensusEngine(t *testing.T) {
+	engine := consensus.NewConsensusEngine(inits.TestChainParams)
+	if engine.Name() != chainparams.ProofOfWork || !engine.RequiresProofOfWork() {
+		t.Fatalf("test chain isn't mined with proof of work, engine is %s", engine.Name())
+	}
+
+	authorityParams, err := inits.CreateTestAuthorityChainParams(0, 0, generateSealers(t, 1)...)
+	if err != nil {
+		t.Fatalf("failed to create authority chain params: %v", err)
+	}
+
+	engine = consensus.NewConsensusEngine(authorityParams)
+	if engine.Name() != chainparams.ProofOfAuthority || engine.RequiresProofOfWork() {
+		t.Fatalf("authority chain isn't sealed with proof of authority, engine is %s", engine.Name())
+	}
+}
+
+func TestProofOfWorkCheckHeader(t *testing.T) {
+	engine := consensus.NewConsensusEngine(inits.TestChainParams)
+
+	block, err := inits.CreateTestBlock(inits.TestChainParams.GenesisBlock.Header.Id, nil)
+	if err != nil {
+		t.Fatalf("failed to create test block: %v", err)
+	}
+
+	if err := engine.CheckHeader(&block.Header); err != nil {
+		t.Fatalf("mined block failed checks: %v", err)
+	}
+
+	//a hash above the target
+	unsolvedHeader := block.Header
+	for unsolvedHeader.SetId(); unsolvedHeader.IsHashBelowTarget(); unsolvedHeader.SetId() {
+		unsolvedHeader.Nonce++
+	}
+
+	if err := engine.CheckHeader(&unsolvedHeader); !errors.Is(err, consensus.ErrInvalidHeader) {
+		t.Fatalf("expected invalid header for unsolved block, got: %v", err)
+	}
+
+	//a target easier than the minimum difficulty
+	easyHeader := block.Header
+	easyHeader.NBits = 0x2100ffff
+	easyHeader.SetId()
+
+	if err := engine.CheckHeader(&easyHeader); !errors.Is(err, consensus.ErrInvalidHeader) {
+		t.Fatalf("expected invalid header for block below minimum difficulty, got: %v", err)
+	}
+
+	weight := engine.BlockWeight(&block.Header, 1)
+	if weight.Cmp(difficulty.CalculateWork(block.Header.NBits)) != 0 {
+		t.Fatalf("proof of work block weight isn't its work")
+	}
+}
+
+func TestProofOfAuthorityCheckHeader(t *testing.T) {
+	sealers := generateSealers(t, 2)
+	authorityParams, err := inits.CreateTestAuthorityChainParams(0, 0, sealers[0])
+	if err != nil {
+		t.Fatalf("failed to create authority chain params: %v", err)
+	}
+	engine := consensus.NewConsensusEngine(authorityParams)
+
+	genesisBlockId := authorityParams.GenesisBlock.Header.Id
+	block, err := inits.CreateTestSealedBlock(genesisBlockId, authorityParams.GenesisBlock.Header.Timestamp+1, nil, sealers[0])
+	if err != nil {
+		t.Fatalf("failed to create sealed block: %v", err)
+	}
+
+	if err := engine.CheckHeader(&block.Header); err != nil {
+		t.Fatalf("sealed block failed checks: %v", err)
+	}
+
+	unauthorizedBlock, err := inits.CreateTestSealedBlock(genesisBlockId, authorityParams.GenesisBlock.Header.Timestamp+1, nil, sealers[1])
+	if err != nil {
+		t.Fatalf("failed to create sealed block: %v", err)
+	}
+
+	err = engine.CheckHeader(&unauthorizedBlock.Header)
+	if !errors.Is(err, consensus.ErrInvalidHeader) || !errors.Is(err, consensus.ErrNotSealer) {
+		t.Fatalf("expected unauthorized sealer error, got: %v", err)
+	}
+
+	minedBlock, err := inits.CreateTestBlockMinedBy(genesisBlockId, nil, sealers[0])
+	if err != nil {
+		t.Fatalf("failed to create test block: %v", err)
+	}
+
+	if err := engine.CheckHeader(&minedBlock.Header); !errors.Is(err, consensus.ErrInvalidHeader) {
+		t.Fatalf("expected invalid header for block with proof of work, got: %v", err)
+	}
+
+	//a sealer's key without its signature could be claimed by anyone
+	unsignedHeader := block.Header
+	unsignedHeader.Version = 1
+	unsignedHeader.MinerSignature = nil
+	if err := engine.CheckHeader(&unsignedHeader); !errors.Is(err, consensus.ErrInvalidHeader) {
+		t.Fatalf("expected invalid header for unsigned sealed block, got: %v", err)
+	}
+}
+
+func TestProofOfAuthorityTurns(t *testing.T) {
+	inits.ClearTestDatabase()
+
+	sealers := generateSealers(t, 2)
+	authorityParams, err := inits.CreateTestAuthorityChainParams(10, 20, sealers...)
+	if err != nil {
+		t.Fatalf("failed to create authority chain params: %v", err)
+	}
+	engine := consensus.NewConsensusEngine(authorityParams)
+
+	blockRepository := repositories.NewBlockRepositoryImpl(inits.TestDb, inits.TestEventBus, authorityParams)
+	if err := blockRepository.Initialize(); err != nil {
+		t.Fatalf("failed to initialize block repository: %v", err)
+	}
+
+	//height 1 is the second sealer's turn
+	genesisHeader := &authorityParams.GenesisBlock.Header
+	inTurn, outOfTurn := sealers[1], sealers[0]
+
+	cases := []struct {
+		sealer *ppk.KeyPair
+		delay  int64
+		valid  bool
+	}{
+		{inTurn, 10, true},
+		{inTurn, 9, false},
+		{outOfTurn, 29, false},
+		{outOfTurn, 30, true},
+	}
+
+	for _, c := range cases {
+		block, err := inits.CreateTestSealedBlock(genesisHeader.Id, genesisHeader.Timestamp+c.delay, nil, c.sealer)
+		if err != nil {
+			t.Fatalf("failed to create sealed block: %v", err)
+		}
+
+		err = engine.VerifyHeader(blockRepository, &block.Header)
+		if c.valid && err != nil {
+			t.Fatalf("block sealed %ds after its parent was rejected: %v", c.delay, err)
+		}
+
+		if !c.valid && !errors.Is(err, consensus.ErrInvalidHeader) {
+			t.Fatalf("expected invalid header for block sealed %ds after its parent, got: %v", c.delay, err)
+		}
+	}
+
+	inTurnBlock, err := inits.CreateTestSealedBlock(genesisHeader.Id, genesisHeader.Timestamp+10, nil, inTurn)
+	if err != nil {
+		t.Fatalf("failed to create sealed block: %v", err)
+	}
+
+	if engine.BlockWeight(&inTurnBlock.Header, 1).Cmp(big.NewInt(consensus.IN_TURN_WEIGHT)) != 0 {
+		t.Fatalf("in turn block doesn't have the in turn weight")
+	}
+
+	if engine.BlockWeight(&inTurnBlock.Header, 2).Cmp(big.NewInt(consensus.OUT_OF_TURN_WEIGHT)) != 0 {
+		t.Fatalf("out of turn block doesn't have the out of turn weight")
+	}
+
+	sealTime, err := engine.SealTime(blockRepository, &models.BlockHeader{PreviousBlockId: genesisHeader.Id, MinerPublicKey: outOfTurn.PublicKey.AsBytes()})
+	if err != nil {
+		t.Fatalf("failed to get seal time: %v", err)
+	}
+
+	if sealTime != genesisHeader.Timestamp+30 {
+		t.Fatalf("out of turn seal time is %d, expected %d", sealTime, genesisHeader.Timestamp+30)
+	}
+}
+
+func TestProofOfAuthorityRecentSealers(t *testing.T) {
+	inits.ClearTestDatabase()
+
+	sealers := generateSealers(t, 3)
+	authorityParams, err := inits.CreateTestAuthorityChainParams(10, 20, sealers...)
+	if err != nil {
+		t.Fatalf("failed to create authority chain params: %v", err)
+	}
+	engine := consensus.NewConsensusEngine(authorityParams)
+
+	blockRepository := repositories.NewBlockRepositoryImpl(inits.TestDb, inits.TestEventBus, authorityParams)
+	if err := blockRepository.Initialize(); err != nil {
+		t.Fatalf("failed to initialize block repository: %v", err)
+	}
+
+	genesisHeader := &authorityParams.GenesisBlock.Header
+	sealBlock := func(previousHeader *models.BlockHeader, delay int64, sealer *ppk.KeyPair) (*models.Block, error) {
+		block, err := inits.CreateTestSealedBlock(previousHeader.Id, previousHeader.Timestamp+delay, nil, sealer)
+		if err != nil {
+			t.Fatalf("failed to create sealed block: %v", err)
+		}
+
+		if err := engine.VerifyHeader(blockRepository, &block.Header); err != nil {
+			return block, err
+		}
+
+		if err := blockRepository.InsertIfNotExists(block); err != nil {
+			t.Fatalf("failed to insert sealed block: %v", err)
+		}
+
+		return block, nil
+	}
+
+	//heights 1 and 2 are the turns of the second and third sealer
+	honestBlock, err := sealBlock(genesisHeader, 10, sealers[1])
+	if err != nil {
+		t.Fatalf("in turn block was rejected: %v", err)
+	}
+
+	if _, err := sealBlock(&honestBlock.Header, 30, sealers[1]); !errors.Is(err, consensus.ErrRecentlySealed) {
+		t.Fatalf("sealer of the previous block sealed the next one: %v", err)
+	}
+
+	honestBlock, err = sealBlock(&honestBlock.Header, 10, sealers[2])
+	if err != nil {
+		t.Fatalf("in turn block was rejected: %v", err)
+	}
+
+	//a lone sealer can't seal a chain of its own, however far ahead its timestamps are
+	loneBlock, err := sealBlock(genesisHeader, 30, sealers[0])
+	if err != nil {
+		t.Fatalf("out of turn block was rejected: %v", err)
+	}
+
+	for _, delay := range []int64{30, 60 * 60} {
+		if _, err := sealBlock(&loneBlock.Header, delay, sealers[0]); !errors.Is(err, consensus.ErrRecentlySealed) {
+			t.Fatalf("lone sealer extended its own chain %ds ahead: %v", delay, err)
+		}
+	}
+
+	if !bytes.Equal(blockRepository.GetActiveChainTipId(), honestBlock.Header.Id) {
+		t.Fatalf("chain of a lone sealer outweighed the chain of the other sealers")
+	}
+
+	//the lone sealer takes its turn on the chain of the others
+	if _, err := sealBlock(&honestBlock.Header, 10, sealers[0]); err != nil {
+		t.Fatalf("in turn block was rejected: %v", err)
+	}
+}
@@ -0,0 +1,73 @@
+package keystore_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	keystore "github.com/nivschuman/VotingBlockchain/internal/crypto/keystore"
+	ppk "github.com/nivschuman/VotingBlockchain/internal/crypto/ppk"
+)
+
+func TestLoadOrCreateKeyPair(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keystore", "miner-key.json")
+
+	keyPair, created, err := keystore.LoadOrCreateKeyPair(path)
+	if err != nil {
+		t.Fatalf("failed to create key pair: %v", err)
+	}
+
+	if !created {
+		t.Fatalf("missing keystore file wasn't reported as created")
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("keystore file wasn't written: %v", err)
+	}
+
+	if info.Mode().Perm() != 0600 {
+		t.Fatalf("keystore file permissions are %v", info.Mode().Perm())
+	}
+
+	loadedKeyPair, created, err := keystore.LoadOrCreateKeyPair(path)
+	if err != nil {
+		t.Fatalf("failed to load key pair: %v", err)
+	}
+
+	if created {
+		t.Fatalf("existing keystore file was replaced")
+	}
+
+	if !bytes.Equal(loadedKeyPair.PublicKey.AsBytes(), keyPair.PublicKey.AsBytes()) {
+		t.Fatalf("loaded public key doesn't match the created one")
+	}
+}
+
+func TestLoadMismatchedKeyPair(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "miner-key.json")
+
+	keyPair, err := ppk.GenerateKeyPair()
+	if err != nil {
+		t.Fatalf("failed to generate key pair: %v", err)
+	}
+
+	otherKeyPair, err := ppk.GenerateKeyPair()
+	if err != nil {
+		t.Fatalf("failed to generate key pair: %v", err)
+	}
+
+	mismatchedKeyPair := &ppk.KeyPair{PublicKey: otherKeyPair.PublicKey, PrivateKey: keyPair.PrivateKey}
+	if err := keystore.SaveKeyPair(path, mismatchedKeyPair); err != nil {
+		t.Fatalf("failed to save key pair: %v", err)
+	}
+
+	if _, err := keystore.LoadKeyPair(path); err == nil {
+		t.Fatalf("loaded a public key that doesn't belong to the private key")
+	}
+
+	if _, _, err := keystore.LoadOrCreateKeyPair(path); err == nil {
+		t.Fatalf("invalid keystore file was replaced instead of failing")
+	}
+}
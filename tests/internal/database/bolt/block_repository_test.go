@@ -0,0 +1,468 @@
+package db_bolt_test
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	chainparams "github.com/nivschuman/VotingBlockchain/internal/chainparams"
+	hash "github.com/nivschuman/VotingBlockchain/internal/crypto/hash"
+	ppk "github.com/nivschuman/VotingBlockchain/internal/crypto/ppk"
+	db_bolt "github.com/nivschuman/VotingBlockchain/internal/database/bolt"
+	repositories "github.com/nivschuman/VotingBlockchain/internal/database/repositories"
+	events "github.com/nivschuman/VotingBlockchain/internal/events"
+	models "github.com/nivschuman/VotingBlockchain/internal/models"
+	structures "github.com/nivschuman/VotingBlockchain/internal/structures"
+	voters "github.com/nivschuman/VotingBlockchain/internal/voters"
+	inits "github.com/nivschuman/VotingBlockchain/tests/init"
+)
+
+func TestMain(m *testing.M) {
+	// === BEFORE ALL TESTS ===
+	inits.SetupTests()
+	inits.SetupTestsDatabase()
+
+	// Run the tests
+	code := m.Run()
+
+	// === AFTER ALL TESTS ===
+	inits.CloseTestDatabase()
+
+	// Exit with the right code
+	os.Exit(code)
+}
+
+func newTestBoltStorage(t *testing.T, chainParams *chainparams.ChainParams) (*db_bolt.BlockRepositoryImpl, *db_bolt.TransactionRepositoryImpl, string) {
+	t.Helper()
+
+	dbFile := filepath.Join(t.TempDir(), "chain.bolt")
+	blockRepository, transactionRepository := openTestBoltStorage(t, dbFile, chainParams)
+
+	return blockRepository, transactionRepository, dbFile
+}
+
+func openTestBoltStorage(t *testing.T, dbFile string, chainParams *chainparams.ChainParams) (*db_bolt.BlockRepositoryImpl, *db_bolt.TransactionRepositoryImpl) {
+	t.Helper()
+
+	db, err := db_bolt.GetDatabaseConnection(dbFile)
+	if err != nil {
+		t.Fatalf("failed to open bolt database: %v", err)
+	}
+
+	eventBus := events.NewEventBusImpl()
+	t.Cleanup(func() {
+		eventBus.Close()
+		db_bolt.CloseDatabaseConnection(db)
+	})
+
+	blockRepository := db_bolt.NewBlockRepositoryImpl(db, eventBus, chainParams)
+	if err := blockRepository.Initialize(); err != nil {
+		t.Fatalf("failed to initialize bolt block repository: %v", err)
+	}
+
+	return blockRepository, db_bolt.NewTransactionRepositoryImpl(db)
+}
+
+func createTestVote(t *testing.T, govKeyPair *ppk.KeyPair, voterKeyPair *ppk.KeyPair, candidateId uint32) *models.Transaction {
+	t.Helper()
+
+	if voterKeyPair == nil {
+		var err error
+		voterKeyPair, err = ppk.GenerateKeyPair()
+		if err != nil {
+			t.Fatalf("failed to generate voter key pair: %v", err)
+		}
+	}
+
+	tx := &models.Transaction{
+		Version:        1,
+		CandidateId:    candidateId,
+		VoterPublicKey: voterKeyPair.PublicKey.AsBytes(),
+	}
+	tx.SetId()
+
+	signature, err := voterKeyPair.PrivateKey.CreateSignature(tx.Id)
+	if err != nil {
+		t.Fatalf("failed to sign vote: %v", err)
+	}
+
+	govSignature, err := govKeyPair.PrivateKey.CreateSignature(hash.HashBytes(tx.VoterPublicKey))
+	if err != nil {
+		t.Fatalf("failed to sign voter: %v", err)
+	}
+
+	tx.Signature = signature
+	tx.GovernmentSignature = govSignature
+	return tx
+}
+
+func createTestBlock(t *testing.T, previousBlockId []byte, transactions ...*models.Transaction) *models.Block {
+	t.Helper()
+
+	block, err := inits.CreateTestBlock(previousBlockId, transactions)
+	if err != nil {
+		t.Fatalf("failed to create block: %v", err)
+	}
+
+	return block
+}
+
+func insertBlocks(t *testing.T, blockRepositories []repositories.BlockRepository, blocks ...*models.Block) {
+	t.Helper()
+
+	for _, block := range blocks {
+		for _, blockRepository := range blockRepositories {
+			if err := blockRepository.InsertIfNotExists(block); err != nil {
+				t.Fatalf("failed to insert block %x: %v", block.Header.Id, err)
+			}
+		}
+	}
+}
+
+func TestBoltStorageMatchesSQLite(t *testing.T) {
+	inits.ResetTestDatabase()
+
+	boltBlockRepository, boltTransactionRepository, _ := newTestBoltStorage(t, inits.TestChainParams)
+	blockRepositories := []repositories.BlockRepository{inits.TestBlockRepository, boltBlockRepository}
+	transactionRepositories := []repositories.TransactionRepository{inits.TestTransactionRepository, boltTransactionRepository}
+
+	govKeyPair, err := ppk.GenerateKeyPair()
+	if err != nil {
+		t.Fatalf("failed to generate government key pair: %v", err)
+	}
+
+	voterKeyPair, err := ppk.GenerateKeyPair()
+	if err != nil {
+		t.Fatalf("failed to generate voter key pair: %v", err)
+	}
+
+	genesisId := inits.TestChainParams.GenesisBlock.Header.Id
+
+	//active chain of three blocks, the voter votes in the last one
+	first := createTestBlock(t, genesisId, createTestVote(t, govKeyPair, nil, 1), createTestVote(t, govKeyPair, nil, 2))
+	second := createTestBlock(t, first.Header.Id, createTestVote(t, govKeyPair, nil, 2))
+	third := createTestBlock(t, second.Header.Id, createTestVote(t, govKeyPair, voterKeyPair, 3))
+	insertBlocks(t, blockRepositories, first, second, third)
+
+	//a longer fork from the first block, the voter votes again in it
+	forkVote := createTestVote(t, govKeyPair, voterKeyPair, 1)
+	forkSecond := createTestBlock(t, first.Header.Id, forkVote, createTestVote(t, govKeyPair, nil, 3))
+	forkThird := createTestBlock(t, forkSecond.Header.Id)
+	forkFourth := createTestBlock(t, forkThird.Header.Id, createTestVote(t, govKeyPair, nil, 2), createTestVote(t, govKeyPair, nil, 2))
+	insertBlocks(t, blockRepositories, forkSecond, forkThird)
+
+	if !bytes.Equal(boltBlockRepository.GetActiveChainTipId(), third.Header.Id) {
+		t.Fatalf("fork of the same length replaced the active chain")
+	}
+
+	insertBlocks(t, blockRepositories, forkFourth)
+
+	for _, blockRepository := range blockRepositories {
+		if !bytes.Equal(blockRepository.GetActiveChainTipId(), forkFourth.Header.Id) {
+			t.Fatalf("longer fork didn't become the active chain")
+		}
+	}
+
+	sqliteBlocks, boltBlocks := blockRepositories[0], blockRepositories[1]
+	sqliteTransactions, boltTransactions := transactionRepositories[0], transactionRepositories[1]
+
+	sqliteHeight, _ := sqliteBlocks.GetActiveChainHeight()
+	boltHeight, err := boltBlocks.GetActiveChainHeight()
+	if err != nil || boltHeight != sqliteHeight {
+		t.Fatalf("active chain height is %d, sqlite has %d: %v", boltHeight, sqliteHeight, err)
+	}
+
+	for _, block := range []*models.Block{first, third, forkFourth} {
+		sqliteWeight, _ := sqliteBlocks.GetBlockChainWeight(block.Header.Id)
+		boltWeight, err := boltBlocks.GetBlockChainWeight(block.Header.Id)
+		if err != nil || boltWeight.Cmp(sqliteWeight) != 0 {
+			t.Fatalf("chain weight of %x is %s, sqlite has %s: %v", block.Header.Id, boltWeight, sqliteWeight, err)
+		}
+
+		storedBlock, err := boltBlocks.GetBlock(block.Header.Id)
+		if err != nil || !bytes.Equal(storedBlock.AsBytes(), block.AsBytes()) {
+			t.Fatalf("stored block %x doesn't match the inserted block: %v", block.Header.Id, err)
+		}
+	}
+
+	sqliteResults, _ := sqliteTransactions.GetVotingResults()
+	boltResults, err := boltTransactions.GetVotingResults()
+	if err != nil || len(boltResults) != len(sqliteResults) {
+		t.Fatalf("voting results have %d candidates, sqlite has %d: %v", len(boltResults), len(sqliteResults), err)
+	}
+
+	for i := range sqliteResults {
+		if *boltResults[i] != *sqliteResults[i] {
+			t.Fatalf("voting result %d is %+v, sqlite has %+v", i, *boltResults[i], *sqliteResults[i])
+		}
+	}
+
+	sqliteConfirmed, sqliteTotal, _ := sqliteTransactions.GetConfirmedTransactionsPaged(1, 3)
+	boltConfirmed, boltTotal, err := boltTransactions.GetConfirmedTransactionsPaged(1, 3)
+	if err != nil || boltTotal != sqliteTotal || !slices.EqualFunc(boltConfirmed, sqliteConfirmed, sameTransaction) {
+		t.Fatalf("confirmed transactions page doesn't match sqlite, total %d and %d: %v", boltTotal, sqliteTotal, err)
+	}
+
+	sqliteLedger, _, _ := sqliteBlocks.GetMinerCreditsPaged(0, 10)
+	boltLedger, _, err := boltBlocks.GetMinerCreditsPaged(0, 10)
+	if err != nil || !slices.EqualFunc(boltLedger, sqliteLedger, func(a *models.MinerCredits, b *models.MinerCredits) bool {
+		return bytes.Equal(a.MinerPublicKey, b.MinerPublicKey) && a.Blocks == b.Blocks && a.Votes == b.Votes && a.Credits == b.Credits
+	}) {
+		t.Fatalf("miner credits ledger doesn't match sqlite: %v", err)
+	}
+
+	sqlitePage, sqlitePageTotal, _ := sqliteBlocks.GetActiveBlocksPaged("", 1, 2, true)
+	boltPage, boltPageTotal, err := boltBlocks.GetActiveBlocksPaged("", 1, 2, true)
+	if err != nil || boltPageTotal != sqlitePageTotal || len(boltPage) != len(sqlitePage) {
+		t.Fatalf("active blocks page doesn't match sqlite: %v", err)
+	}
+
+	for i := range sqlitePage {
+		if !bytes.Equal(boltPage[i].BlockHeaderId, sqlitePage[i].BlockHeaderId) || boltPage[i].Height != sqlitePage[i].Height {
+			t.Fatalf("active block %d is %x at %d, sqlite has %x at %d", i, boltPage[i].BlockHeaderId, boltPage[i].Height, sqlitePage[i].BlockHeaderId, sqlitePage[i].Height)
+		}
+	}
+
+	sqliteLocator, _ := sqliteBlocks.GetActiveChainBlockLocator()
+	boltLocator, err := boltBlocks.GetActiveChainBlockLocator()
+	if err != nil || !slices.EqualFunc(boltLocator.Ids(), sqliteLocator.Ids(), bytes.Equal) {
+		t.Fatalf("active chain block locator doesn't match sqlite: %v", err)
+	}
+
+	genesisLocator := structures.NewBlockLocator()
+	genesisLocator.Add(genesisId)
+
+	sqliteNextIds, _ := sqliteBlocks.GetNextBlocksIds(genesisLocator, nil, 10)
+	boltNextIds, err := boltBlocks.GetNextBlocksIds(genesisLocator, nil, 10)
+	if err != nil || boltNextIds.Length() != sqliteNextIds.Length() || !boltNextIds.Contains(forkFourth.Header.Id) || boltNextIds.Contains(third.Header.Id) {
+		t.Fatalf("next block ids don't match sqlite: %v", err)
+	}
+
+	for _, tip := range []*models.Block{first, third, forkFourth} {
+		for _, vote := range []*models.Transaction{third.Transactions[0], forkVote, first.Transactions[1]} {
+			sqliteValid, _ := sqliteTransactions.TransactionsValidInChain(tip.Header.Id, []*models.Transaction{vote})
+			boltValid, err := boltTransactions.TransactionsValidInChain(tip.Header.Id, []*models.Transaction{vote})
+			if err != nil || boltValid != sqliteValid {
+				t.Fatalf("vote %x valid in chain of %x is %v, sqlite has %v: %v", vote.Id, tip.Header.Id, boltValid, sqliteValid, err)
+			}
+		}
+	}
+
+	activeVote, err := boltTransactions.GetActiveChainVote(voterKeyPair.PublicKey.AsBytes())
+	if err != nil || !bytes.Equal(activeVote.Id, forkVote.Id) {
+		t.Fatalf("active chain vote isn't the fork's vote: %v", err)
+	}
+}
+
+func sameTransaction(a *models.Transaction, b *models.Transaction) bool {
+	return bytes.Equal(a.Id, b.Id)
+}
+
+func TestBoltStorageReopen(t *testing.T) {
+	chainParams, err := chainparams.ParamsForName(inits.TestConfig.NodeConfig.Chain)
+	if err != nil {
+		t.Fatalf("failed to get chain params: %v", err)
+	}
+
+	dbFile := filepath.Join(t.TempDir(), "chain.bolt")
+	db, err := db_bolt.GetDatabaseConnection(dbFile)
+	if err != nil {
+		t.Fatalf("failed to open bolt database: %v", err)
+	}
+
+	eventBus := events.NewEventBusImpl()
+	defer eventBus.Close()
+
+	blockRepository := db_bolt.NewBlockRepositoryImpl(db, eventBus, chainParams)
+	if err := blockRepository.Initialize(); err != nil {
+		t.Fatalf("failed to initialize bolt block repository: %v", err)
+	}
+
+	block := createTestBlock(t, chainParams.GenesisBlock.Header.Id)
+	insertBlocks(t, []repositories.BlockRepository{blockRepository}, block)
+
+	if err := db_bolt.CloseDatabaseConnection(db); err != nil {
+		t.Fatalf("failed to close bolt database: %v", err)
+	}
+
+	reopenedBlockRepository, _ := openTestBoltStorage(t, dbFile, chainParams)
+	if !bytes.Equal(reopenedBlockRepository.GetActiveChainTipId(), block.Header.Id) {
+		t.Fatalf("reopened storage lost the active chain tip")
+	}
+
+	height, err := reopenedBlockRepository.GetActiveChainHeight()
+	if err != nil || height != 1 {
+		t.Fatalf("reopened storage has height %d: %v", height, err)
+	}
+}
+
+func TestBoltStorageRefusesDeepReorganization(t *testing.T) {
+	chainParams, err := chainparams.ParamsForName(inits.TestConfig.NodeConfig.Chain)
+	if err != nil {
+		t.Fatalf("failed to get chain params: %v", err)
+	}
+	chainParams.Consensus.MaxReorgDepth = 1
+
+	blockRepository, _, _ := newTestBoltStorage(t, chainParams)
+	blockRepositories := []repositories.BlockRepository{blockRepository}
+
+	genesisId := chainParams.GenesisBlock.Header.Id
+	first := createTestBlock(t, genesisId)
+	second := createTestBlock(t, first.Header.Id)
+	insertBlocks(t, blockRepositories, first, second)
+
+	forkFirst := createTestBlock(t, genesisId)
+	forkSecond := createTestBlock(t, forkFirst.Header.Id)
+	insertBlocks(t, blockRepositories, forkFirst, forkSecond)
+
+	err = blockRepository.InsertIfNotExists(createTestBlock(t, forkSecond.Header.Id))
+	if !errors.Is(err, repositories.ErrReorgTooDeep) {
+		t.Fatalf("reorganization deeper than the maximum wasn't refused, got %v", err)
+	}
+
+	if !bytes.Equal(blockRepository.GetActiveChainTipId(), second.Header.Id) {
+		t.Fatalf("active chain tip changed after a refused reorganization")
+	}
+
+	height, err := blockRepository.GetActiveChainHeight()
+	if err != nil || height != 2 {
+		t.Fatalf("active chain index changed after a refused reorganization, height is %d: %v", height, err)
+	}
+}
+
+func TestBoltStorageReindex(t *testing.T) {
+	chainParams, err := chainparams.ParamsForName(inits.TestConfig.NodeConfig.Chain)
+	if err != nil {
+		t.Fatalf("failed to get chain params: %v", err)
+	}
+
+	blockRepository, transactionRepository, _ := newTestBoltStorage(t, chainParams)
+	blockRepositories := []repositories.BlockRepository{blockRepository}
+
+	govKeyPair, err := ppk.GenerateKeyPair()
+	if err != nil {
+		t.Fatalf("failed to generate government key pair: %v", err)
+	}
+
+	genesisId := chainParams.GenesisBlock.Header.Id
+	vote := createTestVote(t, govKeyPair, nil, 1)
+	first := createTestBlock(t, genesisId, vote)
+	second := createTestBlock(t, first.Header.Id, createTestVote(t, govKeyPair, nil, 2))
+	forkSecond := createTestBlock(t, first.Header.Id, createTestVote(t, govKeyPair, nil, 3))
+	forkThird := createTestBlock(t, forkSecond.Header.Id, createTestVote(t, govKeyPair, nil, 3))
+	insertBlocks(t, blockRepositories, first, second, forkSecond, forkThird)
+
+	resultsBefore, _ := transactionRepository.GetVotingResults()
+	ledgerBefore, _, _ := blockRepository.GetMinerCreditsPaged(0, 10)
+
+	if err := blockRepository.Reindex(); err != nil {
+		t.Fatalf("failed to reindex chain: %v", err)
+	}
+
+	if !bytes.Equal(blockRepository.GetActiveChainTipId(), forkThird.Header.Id) {
+		t.Fatalf("reindex didn't keep the heaviest chain tip")
+	}
+
+	height, err := blockRepository.GetBlockHeight(forkThird.Header.Id)
+	if err != nil || height != 3 {
+		t.Fatalf("tip is at height %d after reindexing: %v", height, err)
+	}
+
+	resultsAfter, err := transactionRepository.GetVotingResults()
+	if err != nil || !slices.EqualFunc(resultsAfter, resultsBefore, func(a *voters.VotingResult, b *voters.VotingResult) bool { return *a == *b }) {
+		t.Fatalf("voting results changed after reindexing: %v", err)
+	}
+
+	ledgerAfter, _, err := blockRepository.GetMinerCreditsPaged(0, 10)
+	if err != nil || len(ledgerAfter) != len(ledgerBefore) || len(ledgerAfter) != 3 {
+		t.Fatalf("miner credits ledger changed after reindexing: %v", err)
+	}
+
+	valid, err := transactionRepository.TransactionValidInActiveChain(vote)
+	if err != nil || valid {
+		t.Fatalf("vote of the active chain isn't in the voter index after reindexing: %v", err)
+	}
+
+	valid, err = transactionRepository.TransactionValidInActiveChain(second.Transactions[0])
+	if err != nil || !valid {
+		t.Fatalf("vote of a side chain block is in the active chain after reindexing: %v", err)
+	}
+}
+
+func TestBoltStoragePrune(t *testing.T) {
+	chainParams, err := chainparams.ParamsForName(inits.TestConfig.NodeConfig.Chain)
+	if err != nil {
+		t.Fatalf("failed to get chain params: %v", err)
+	}
+
+	blockRepository, transactionRepository, _ := newTestBoltStorage(t, chainParams)
+	blockRepositories := []repositories.BlockRepository{blockRepository}
+
+	govKeyPair, err := ppk.GenerateKeyPair()
+	if err != nil {
+		t.Fatalf("failed to generate government key pair: %v", err)
+	}
+
+	genesisId := chainParams.GenesisBlock.Header.Id
+	vote := createTestVote(t, govKeyPair, nil, 1)
+	first := createTestBlock(t, genesisId, vote)
+	second := createTestBlock(t, first.Header.Id, createTestVote(t, govKeyPair, nil, 2))
+	third := createTestBlock(t, second.Header.Id, createTestVote(t, govKeyPair, nil, 2))
+	insertBlocks(t, blockRepositories, first, second, third)
+
+	//a side block holding the same vote keeps it stored
+	sideFirst := createTestBlock(t, genesisId, vote)
+	insertBlocks(t, blockRepositories, sideFirst)
+
+	pruned, err := blockRepository.Prune(1)
+	if err != nil || pruned != 3 {
+		t.Fatalf("pruned %d blocks, expected the 2 active and 1 side blocks below the last one: %v", pruned, err)
+	}
+
+	prunedHeight, err := blockRepository.GetPrunedHeight()
+	if err != nil || prunedHeight != 2 {
+		t.Fatalf("pruned height is %d: %v", prunedHeight, err)
+	}
+
+	if _, err := blockRepository.GetBlock(first.Header.Id); !errors.Is(err, repositories.ErrBlockPruned) {
+		t.Fatalf("pruned block was returned: %v", err)
+	}
+
+	if _, err := transactionRepository.GetTransaction(vote.Id); err == nil {
+		t.Fatalf("vote held only by pruned blocks wasn't deleted")
+	}
+
+	if _, err := blockRepository.GetBlock(third.Header.Id); err != nil {
+		t.Fatalf("block that wasn't pruned is gone: %v", err)
+	}
+
+	results, err := transactionRepository.GetVotingResults()
+	if err != nil || len(results) != 2 || results[0].Votes != 1 || results[1].Votes != 2 {
+		t.Fatalf("tally changed after pruning: %v", err)
+	}
+
+	valid, err := transactionRepository.TransactionValidInActiveChain(vote)
+	if err != nil || valid {
+		t.Fatalf("vote of a pruned block left the voter index: %v", err)
+	}
+
+	if _, _, err := transactionRepository.GetConfirmedTransactionsPaged(0, 10); err != nil {
+		t.Fatalf("failed to page confirmed transactions of a pruned chain: %v", err)
+	}
+
+	if err := blockRepository.Reindex(); !errors.Is(err, repositories.ErrBlockPruned) {
+		t.Fatalf("reindex of a pruned chain wasn't refused: %v", err)
+	}
+
+	sideSecond := createTestBlock(t, sideFirst.Header.Id)
+	sideThird := createTestBlock(t, sideSecond.Header.Id)
+	insertBlocks(t, blockRepositories, sideSecond, sideThird)
+
+	err = blockRepository.InsertIfNotExists(createTestBlock(t, sideThird.Header.Id))
+	if !errors.Is(err, repositories.ErrReorgTooDeep) {
+		t.Fatalf("reorganization into pruned blocks wasn't refused: %v", err)
+	}
+}
@@ -0,0 +1,153 @@
+package db_bolt_test
+
+import (
+	"bytes"
+	"testing"
+
+	chainparams "github.com/nivschuman/VotingBlockchain/internal/chainparams"
+	ppk "github.com/nivschuman/VotingBlockchain/internal/crypto/ppk"
+	repositories "github.com/nivschuman/VotingBlockchain/internal/database/repositories"
+	models "github.com/nivschuman/VotingBlockchain/internal/models"
+	structures "github.com/nivschuman/VotingBlockchain/internal/structures"
+	voters "github.com/nivschuman/VotingBlockchain/internal/voters"
+	inits "github.com/nivschuman/VotingBlockchain/tests/init"
+)
+
+func TestVoterIndexFollowsReorganization(t *testing.T) {
+	chainParams, err := chainparams.ParamsForName(inits.TestConfig.NodeConfig.Chain)
+	if err != nil {
+		t.Fatalf("failed to get chain params: %v", err)
+	}
+
+	blockRepository, transactionRepository, _ := newTestBoltStorage(t, chainParams)
+	blockRepositories := []repositories.BlockRepository{blockRepository}
+
+	govKeyPair, err := ppk.GenerateKeyPair()
+	if err != nil {
+		t.Fatalf("failed to generate government key pair: %v", err)
+	}
+
+	genesisId := chainParams.GenesisBlock.Header.Id
+	vote := createTestVote(t, govKeyPair, nil, 1)
+	voted := createTestBlock(t, genesisId, vote)
+	insertBlocks(t, blockRepositories, voted)
+
+	voters := structures.NewBytesSet()
+	voters.Add(vote.VoterPublicKey)
+
+	activeVoters, err := transactionRepository.GetVotersInActiveChain(voters)
+	if err != nil || !activeVoters.Contains(vote.VoterPublicKey) {
+		t.Fatalf("voter of the active chain wasn't found: %v", err)
+	}
+
+	//a longer fork without the vote disconnects it
+	forkFirst := createTestBlock(t, genesisId)
+	forkSecond := createTestBlock(t, forkFirst.Header.Id)
+	insertBlocks(t, blockRepositories, forkFirst, forkSecond)
+
+	activeVoters, err = transactionRepository.GetVotersInActiveChain(voters)
+	if err != nil || activeVoters.Length() != 0 {
+		t.Fatalf("voter of a disconnected block is still in the active chain: %v", err)
+	}
+
+	valid, err := transactionRepository.TransactionValidInActiveChain(vote)
+	if err != nil || !valid {
+		t.Fatalf("disconnected vote isn't valid in the active chain: %v", err)
+	}
+
+	valid, err = transactionRepository.TransactionsValidInChain(voted.Header.Id, []*models.Transaction{vote})
+	if err != nil || valid {
+		t.Fatalf("vote is valid again in the side chain that holds it: %v", err)
+	}
+
+	results, err := transactionRepository.GetVotingResults()
+	if err != nil || len(results) != 0 {
+		t.Fatalf("disconnected vote is still in the tally: %v", err)
+	}
+
+	missingIds, err := transactionRepository.GetMissingTransactionIds(structures.NewBytesSet())
+	if err != nil || missingIds.Length() != 0 {
+		t.Fatalf("empty ids have missing transactions: %v", err)
+	}
+
+	ids := structures.NewBytesSet()
+	ids.Add(vote.Id)
+	ids.Add(make([]byte, 32))
+
+	missingIds, err = transactionRepository.GetMissingTransactionIds(ids)
+	if err != nil || missingIds.Length() != 1 || missingIds.Contains(vote.Id) {
+		t.Fatalf("transactions of side chain blocks aren't stored: %v", err)
+	}
+}
+
+func TestTallySnapshotsFollowReorganization(t *testing.T) {
+	chainParams, err := chainparams.ParamsForName(inits.TestConfig.NodeConfig.Chain)
+	if err != nil {
+		t.Fatalf("failed to get chain params: %v", err)
+	}
+	chainParams.Election.TallySnapshotInterval = 2
+
+	blockRepository, transactionRepository, _ := newTestBoltStorage(t, chainParams)
+	blockRepositories := []repositories.BlockRepository{blockRepository}
+
+	govKeyPair, err := ppk.GenerateKeyPair()
+	if err != nil {
+		t.Fatalf("failed to generate government key pair: %v", err)
+	}
+
+	first := createTestBlock(t, chainParams.GenesisBlock.Header.Id, createTestVote(t, govKeyPair, nil, 1))
+	second := createTestBlock(t, first.Header.Id, createTestVote(t, govKeyPair, nil, 2))
+	third := createTestBlock(t, second.Header.Id, createTestVote(t, govKeyPair, nil, 2))
+	insertBlocks(t, blockRepositories, first, second, third)
+
+	snapshot, err := transactionRepository.GetTallySnapshot(2)
+	if err != nil {
+		t.Fatalf("failed to get tally snapshot: %v", err)
+	}
+
+	expected := voters.NewTallySnapshot(2, second.Header.Id, []*voters.VotingResult{{CandidateId: 2, Votes: 1}, {CandidateId: 1, Votes: 1}})
+	if !bytes.Equal(snapshot.Hash, expected.Hash) || len(snapshot.Results) != 2 {
+		t.Fatalf("snapshot at height 2 doesn't match the tally of the chain")
+	}
+
+	results, err := transactionRepository.GetVotingResultsAtHeight(3)
+	if err != nil || len(results) != 2 || results[0].Votes != 1 || results[1].Votes != 2 {
+		t.Fatalf("wrong results at height 3: %v", err)
+	}
+
+	//a longer fork of empty blocks from the first block disconnects the votes for the second candidate
+	forkFirst := createTestBlock(t, first.Header.Id)
+	forkSecond := createTestBlock(t, forkFirst.Header.Id)
+	forkThird := createTestBlock(t, forkSecond.Header.Id)
+	insertBlocks(t, blockRepositories, forkFirst, forkSecond, forkThird)
+
+	snapshot, err = transactionRepository.GetTallySnapshot(2)
+	if err != nil || !bytes.Equal(snapshot.BlockId, forkFirst.Header.Id) || len(snapshot.Results) != 1 {
+		t.Fatalf("snapshot at height 2 wasn't taken again for the fork: %v", err)
+	}
+
+	snapshotHash := snapshot.Hash
+	if err := blockRepository.Reindex(); err != nil {
+		t.Fatalf("failed to reindex: %v", err)
+	}
+
+	snapshot, err = transactionRepository.GetTallySnapshot(4)
+	if err != nil || !bytes.Equal(snapshot.BlockId, forkThird.Header.Id) {
+		t.Fatalf("snapshot at height 4 is missing after reindexing: %v", err)
+	}
+
+	snapshot, err = transactionRepository.GetTallySnapshot(2)
+	if err != nil || !bytes.Equal(snapshot.Hash, snapshotHash) {
+		t.Fatalf("reindexed snapshot differs: %v", err)
+	}
+
+	//the votes of pruned blocks still count in the results at their height
+	if _, err := blockRepository.Prune(1); err != nil {
+		t.Fatalf("failed to prune: %v", err)
+	}
+
+	results, err = transactionRepository.GetVotingResultsAtHeight(1)
+	if err != nil || len(results) != 1 || results[0].CandidateId != 1 || results[0].Votes != 1 {
+		t.Fatalf("wrong results at a pruned height: %v", err)
+	}
+}
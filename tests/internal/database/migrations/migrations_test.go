@@ -0,0 +1,256 @@
+package db_migrations_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	database "github.com/nivschuman/VotingBlockchain/internal/database/connection"
+	migrations "github.com/nivschuman/VotingBlockchain/internal/database/migrations"
+	db_models "github.com/nivschuman/VotingBlockchain/internal/database/models"
+	inits "github.com/nivschuman/VotingBlockchain/tests/init"
+)
+
+func TestMain(m *testing.M) {
+	// === BEFORE ALL TESTS ===
+	inits.SetupTests()
+	inits.SetupTestsDatabase()
+
+	// Run the tests
+	code := m.Run()
+
+	// === AFTER ALL TESTS ===
+	inits.CloseTestDatabase()
+
+	// Exit with the right code
+	os.Exit(code)
+}
+
+func TestNewDatabaseIsAtLatestVersion(t *testing.T) {
+	inits.ResetTestDatabase()
+
+	version, err := migrations.CurrentVersion(inits.TestDb)
+	if err != nil {
+		t.Fatalf("failed to get schema version: %v", err)
+	}
+
+	if version != migrations.LatestVersion() {
+		t.Fatalf("schema version is %d, expected %d", version, migrations.LatestVersion())
+	}
+
+	pending, err := migrations.PendingMigrations(inits.TestDb)
+	if err != nil || len(pending) != 0 {
+		t.Fatalf("new database has %d pending migrations: %v", len(pending), err)
+	}
+}
+
+func TestSpentVotersMigration(t *testing.T) {
+	inits.ResetTestDatabase()
+	_, blocks, _, err := inits.CreateTestData(2, 2)
+	if err != nil {
+		t.Fatalf("failed to setup test data: %v", err)
+	}
+
+	//roll the schema back to before the spent voter index
+	if err := inits.TestDb.Where("version >= ?", 2).Delete(&db_models.SchemaVersionDB{}).Error; err != nil {
+		t.Fatalf("failed to roll back schema version: %v", err)
+	}
+
+	if err := inits.TestDb.Migrator().DropTable(&db_models.SpentVoterDB{}); err != nil {
+		t.Fatalf("failed to drop spent voters: %v", err)
+	}
+
+	applied, err := migrations.Migrate(inits.TestDb)
+	if err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+
+	if len(applied) != int(migrations.LatestVersion())-1 || applied[0].Version != 2 {
+		t.Fatalf("unexpected applied migrations: %+v", applied)
+	}
+
+	for _, block := range blocks {
+		for _, tx := range block.Transactions {
+			isValid, err := inits.TestTransactionRepository.TransactionValidInActiveChain(tx)
+			if err != nil || isValid {
+				t.Fatalf("confirmed vote %x is valid in the active chain after migrating: %v", tx.Id, err)
+			}
+		}
+	}
+}
+
+func TestNewerSchemaIsRefused(t *testing.T) {
+	dbFile := filepath.Join(t.TempDir(), "node.db")
+
+	db, err := database.GetDatabaseConnection(dbFile)
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+
+	newerVersion := &db_models.SchemaVersionDB{Version: migrations.LatestVersion() + 1, Name: "from a newer node"}
+	if err := db.Create(newerVersion).Error; err != nil {
+		t.Fatalf("failed to insert newer schema version: %v", err)
+	}
+
+	if err := database.CloseDatabaseConnection(db); err != nil {
+		t.Fatalf("failed to close database: %v", err)
+	}
+
+	_, err = database.GetDatabaseConnection(dbFile)
+	if !errors.Is(err, migrations.ErrNewerSchema) {
+		t.Fatalf("expected %v, got %v", migrations.ErrNewerSchema, err)
+	}
+}
+
+func TestUnversionedDatabaseIsBackedUp(t *testing.T) {
+	dbFile := filepath.Join(t.TempDir(), "node.db")
+
+	//databases from before versioning were created by AutoMigrate
+	db, err := database.OpenDatabaseConnection(dbFile)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer database.CloseDatabaseConnection(db)
+
+	if err := db.AutoMigrate(&db_models.TransactionDB{}, &db_models.BlockDB{}, &db_models.BlockHeaderDB{}, &db_models.TransactionBlockDB{}); err != nil {
+		t.Fatalf("failed to create unversioned tables: %v", err)
+	}
+
+	if err := database.MigrateDatabase(db, dbFile); err != nil {
+		t.Fatalf("failed to migrate database: %v", err)
+	}
+
+	if _, err := os.Stat(dbFile + ".v0.bak"); err != nil {
+		t.Fatalf("database wasn't backed up before migrating: %v", err)
+	}
+
+	version, err := migrations.CurrentVersion(db)
+	if err != nil || version != migrations.LatestVersion() {
+		t.Fatalf("schema version is %d after migrating: %v", version, err)
+	}
+
+	if !db.Migrator().HasTable(&db_models.AddressDB{}) || !db.Migrator().HasTable(&db_models.SpentVoterDB{}) {
+		t.Fatalf("migrations didn't create the missing tables")
+	}
+}
+
+func TestMigrationsAreFrozen(t *testing.T) {
+	db, err := database.OpenDatabaseConnection(filepath.Join(t.TempDir(), "node.db"))
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer database.CloseDatabaseConnection(db)
+
+	if _, err := migrations.MigrateTo(db, 1); err != nil {
+		t.Fatalf("failed to migrate to version 1: %v", err)
+	}
+
+	if db.Migrator().HasColumn(&db_models.BlockDB{}, "Pruned") || db.Migrator().HasTable(&db_models.SpentVoterDB{}) {
+		t.Fatalf("version 1 created tables of later versions")
+	}
+
+	if _, err := migrations.MigrateTo(db, 2); err != nil {
+		t.Fatalf("failed to migrate to version 2: %v", err)
+	}
+
+	if db.Migrator().HasColumn(&db_models.SpentVoterDB{}, "CandidateId") || db.Migrator().HasTable(&db_models.TallyDB{}) {
+		t.Fatalf("version 2 created tables of later versions")
+	}
+
+	if _, err := migrations.Migrate(db); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+
+	if !db.Migrator().HasColumn(&db_models.BlockDB{}, "Pruned") || !db.Migrator().HasColumn(&db_models.SpentVoterDB{}, "CandidateId") {
+		t.Fatalf("version 3 didn't add its columns")
+	}
+
+	if !db.Migrator().HasTable(&db_models.TallyDB{}) || !db.Migrator().HasTable(&db_models.TallySnapshotVoteDB{}) {
+		t.Fatalf("version 4 didn't create the tally tables")
+	}
+}
+
+func TestMigrationIsRetriedAfterFailure(t *testing.T) {
+	dbFile := filepath.Join(t.TempDir(), "node.db")
+
+	db, err := database.OpenDatabaseConnection(dbFile)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer database.CloseDatabaseConnection(db)
+
+	if _, err := migrations.MigrateTo(db, 3); err != nil {
+		t.Fatalf("failed to migrate to version 3: %v", err)
+	}
+
+	//a tally table that can't take the counted votes fails migration 4
+	if err := db.Exec("CREATE TABLE tally (candidate_id integer PRIMARY KEY, votes integer NOT NULL, broken integer NOT NULL)").Error; err != nil {
+		t.Fatalf("failed to create broken tally: %v", err)
+	}
+
+	spentVoter := &db_models.SpentVoterDB{VoterPublicKey: []byte{1}, TransactionId: []byte{2}, BlockHeaderId: []byte{3}, Height: 1, CandidateId: 1}
+	if err := db.Create(spentVoter).Error; err != nil {
+		t.Fatalf("failed to insert spent voter: %v", err)
+	}
+
+	if err := database.MigrateDatabase(db, dbFile); err == nil {
+		t.Fatalf("migration into a broken tally succeeded")
+	}
+
+	if version, err := migrations.CurrentVersion(db); err != nil || version != 3 {
+		t.Fatalf("schema version is %d after a failed migration: %v", version, err)
+	}
+
+	if err := db.Migrator().DropTable("tally"); err != nil {
+		t.Fatalf("failed to drop broken tally: %v", err)
+	}
+
+	if err := database.MigrateDatabase(db, dbFile); err != nil {
+		t.Fatalf("failed to retry migration: %v", err)
+	}
+
+	if version, err := migrations.CurrentVersion(db); err != nil || version != migrations.LatestVersion() {
+		t.Fatalf("schema version is %d after retrying: %v", version, err)
+	}
+
+	for _, backupFile := range []string{dbFile + ".v3.bak", dbFile + ".v3.1.bak"} {
+		if _, err := os.Stat(backupFile); err != nil {
+			t.Fatalf("backup %s is missing: %v", backupFile, err)
+		}
+	}
+}
+
+func TestConflictingVotesMigrationKeepsOnePerVoter(t *testing.T) {
+	db, err := database.OpenDatabaseConnection(filepath.Join(t.TempDir(), "node.db"))
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer database.CloseDatabaseConnection(db)
+
+	if _, err := migrations.MigrateTo(db, 4); err != nil {
+		t.Fatalf("failed to migrate to version 4: %v", err)
+	}
+
+	for i := byte(1); i <= 3; i++ {
+		err := db.Exec(`INSERT INTO conflicting_votes (voter_public_key, transaction_id, "transaction", conflicting_transaction_id, conflicting_transaction, source, detected_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?)`, []byte{1}, []byte{i}, []byte{i}, []byte{0}, []byte{0}, "mempool", time.Now()).Error
+		if err != nil {
+			t.Fatalf("failed to insert conflicting vote: %v", err)
+		}
+	}
+
+	if _, err := migrations.Migrate(db); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+
+	var conflictingVotes []db_models.ConflictingVoteDB
+	if err := db.Find(&conflictingVotes).Error; err != nil {
+		t.Fatalf("failed to get conflicting votes: %v", err)
+	}
+
+	if len(conflictingVotes) != 1 || conflictingVotes[0].TransactionId[0] != 1 || conflictingVotes[0].Conflicts != 3 {
+		t.Fatalf("unexpected conflicting votes after migrating: %+v", conflictingVotes)
+	}
+}
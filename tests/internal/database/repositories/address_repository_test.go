@@ -64,3 +64,64 @@ func TestGetAddresses(t *testing.T) {
 		t.Logf("Returned: %s:%d", a.Ip.String(), a.Port)
 	}
 }
+
+func TestRecordFailedAttempt(t *testing.T) {
+	inits.ResetTestDatabase()
+
+	now := time.Now()
+	backoff := func(attempts uint32) time.Duration {
+		return time.Duration(attempts) * time.Hour
+	}
+
+	address := &networking_models.Address{Ip: net.ParseIP("192.168.1.1"), Port: 8333, NodeType: 1}
+	if err := inits.TestAddressRepository.InsertIfNotExists(address); err != nil {
+		t.Fatalf("failed to insert test address: %v", err)
+	}
+
+	for range 2 {
+		if err := inits.TestAddressRepository.RecordFailedAttempt(address, &now, backoff); err != nil {
+			t.Fatalf("failed to record failed attempt: %v", err)
+		}
+	}
+
+	addresses, _, err := inits.TestAddressRepository.GetAddressesPaged(0, 10, nil)
+	if err != nil {
+		t.Fatalf("failed to get addresses: %v", err)
+	}
+
+	if len(addresses) != 1 || addresses[0].Attempts != 2 {
+		t.Fatalf("expected 2 attempts to be recorded")
+	}
+
+	dialable, err := inits.TestAddressRepository.GetDialableAddresses(10, nil, &now)
+	if err != nil {
+		t.Fatalf("failed to get dialable addresses: %v", err)
+	}
+
+	if len(dialable) != 0 {
+		t.Fatalf("address was returned before its backoff elapsed")
+	}
+
+	later := now.Add(3 * time.Hour)
+	dialable, err = inits.TestAddressRepository.GetDialableAddresses(10, nil, &later)
+	if err != nil {
+		t.Fatalf("failed to get dialable addresses: %v", err)
+	}
+
+	if len(dialable) != 1 {
+		t.Fatalf("address was not returned after its backoff elapsed")
+	}
+
+	if err := inits.TestAddressRepository.UpdateLastSeen(address, &now); err != nil {
+		t.Fatalf("failed to update last seen: %v", err)
+	}
+
+	dialable, err = inits.TestAddressRepository.GetDialableAddresses(10, nil, &now)
+	if err != nil {
+		t.Fatalf("failed to get dialable addresses: %v", err)
+	}
+
+	if len(dialable) != 1 {
+		t.Fatalf("backoff was not reset after address was seen")
+	}
+}
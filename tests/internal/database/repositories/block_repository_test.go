@@ -2,11 +2,17 @@ package repositories_test
 
 import (
 	"bytes"
+	"errors"
 	"os"
 	"slices"
 	"testing"
+	"time"
 
+	chainparams "github.com/nivschuman/VotingBlockchain/internal/chainparams"
+	db_models "github.com/nivschuman/VotingBlockchain/internal/database/models"
+	repositories "github.com/nivschuman/VotingBlockchain/internal/database/repositories"
 	difficulty "github.com/nivschuman/VotingBlockchain/internal/difficulty"
+	events "github.com/nivschuman/VotingBlockchain/internal/events"
 	models "github.com/nivschuman/VotingBlockchain/internal/models"
 	structures "github.com/nivschuman/VotingBlockchain/internal/structures"
 	inits "github.com/nivschuman/VotingBlockchain/tests/init"
@@ -36,13 +42,13 @@ func TestGenesisBlock(t *testing.T) {
 		t.Fatalf("hash isn't below target")
 	}
 
-	cumulativeWork, err := inits.TestBlockRepository.GetBlockCumulativeWork(genesisBlock.Header.Id)
+	cumulativeWork, err := inits.TestBlockRepository.GetBlockChainWeight(genesisBlock.Header.Id)
 
 	if err != nil {
 		t.Fatalf("failed to get genesis block cumulative work: %v", err)
 	}
 
-	if cumulativeWork.Cmp(difficulty.CalculateWork(difficulty.MINIMUM_DIFFICULTY)) != 0 {
+	if cumulativeWork.Cmp(difficulty.CalculateWork(inits.TestChainParams.Difficulty.MinimumDifficulty)) != 0 {
 		t.Fatalf("genesis block cumulative work is wrong: %s", cumulativeWork.String())
 	}
 }
@@ -103,7 +109,7 @@ func TestGetMissingBlockIds(t *testing.T) {
 	}
 }
 
-func TestGetBlockCumulativeWork(t *testing.T) {
+func TestGetBlockChainWeight(t *testing.T) {
 	inits.ResetTestDatabase()
 	_, blocks, _, err := inits.CreateTestData(4, 2)
 	if err != nil {
@@ -116,7 +122,7 @@ func TestGetBlockCumulativeWork(t *testing.T) {
 	}
 
 	lastBlock := blocks[len(blocks)-1]
-	cumulativeWork, err := inits.TestBlockRepository.GetBlockCumulativeWork(lastBlock.Header.Id)
+	cumulativeWork, err := inits.TestBlockRepository.GetBlockChainWeight(lastBlock.Header.Id)
 
 	if err != nil {
 		t.Fatalf("failed to get block cumulative work: %v", err)
@@ -254,14 +260,28 @@ func TestGetNextBlockIds(t *testing.T) {
 
 func TestGetNextWorkRequired(t *testing.T) {
 	inits.ResetTestDatabase()
-	_, blocks, _, err := inits.CreateTestData(10, 1)
+	_, blocks, _, err := inits.CreateTestData(20, 1)
 	if err != nil {
 		t.Fatalf("failed to create test data: %v", err)
 	}
 
+	//regtest blocks stay at minimum difficulty, even at the difficulty adjustment level
+	nBits, err := inits.TestBlockRepository.GetNextWorkRequired(blocks[8].Header.Id)
+	if err != nil {
+		t.Fatalf("GetNextWorkRequired error: %v", err)
+	}
+
+	if nBits != inits.TestChainParams.Difficulty.MinimumDifficulty {
+		t.Fatalf("Expected minimum nBits %08x, got %08x", inits.TestChainParams.Difficulty.MinimumDifficulty, nBits)
+	}
+
+	retargetingParams := *inits.TestChainParams
+	retargetingParams.Difficulty.NoRetargeting = false
+	retargetingRepository := repositories.NewBlockRepositoryImpl(inits.TestDb, inits.TestEventBus, &retargetingParams)
+
 	//next block is not at difficulty adjustment level
 	lastBlock := blocks[7] // height 8
-	nBits, err := inits.TestBlockRepository.GetNextWorkRequired(lastBlock.Header.Id)
+	nBits, err = retargetingRepository.GetNextWorkRequired(lastBlock.Header.Id)
 	if err != nil {
 		t.Fatalf("GetNextWorkRequired error: %v", err)
 	}
@@ -270,17 +290,15 @@ func TestGetNextWorkRequired(t *testing.T) {
 		t.Fatalf("Expected nBits %08x, got %08x", lastBlock.Header.NBits, nBits)
 	}
 
-	//next block is at difficulty adjustment level
-	lastBlock = blocks[8] // height 9
-	nBits, err = inits.TestBlockRepository.GetNextWorkRequired(lastBlock.Header.Id)
+	//next block is at the second difficulty adjustment level, the blocks were created much faster than the target spacing
+	lastBlock = blocks[18] // height 19
+	nBits, err = retargetingRepository.GetNextWorkRequired(lastBlock.Header.Id)
 	if err != nil {
 		t.Fatalf("GetNextWorkRequired error: %v", err)
 	}
 
-	if nBits == lastBlock.Header.NBits {
-		t.Logf("Difficulty did not change at interval (could be expected if timestamps are perfect)")
-	} else {
-		t.Logf("Difficulty adjusted at interval: new nBits %08x", nBits)
+	if difficulty.GetTargetFromNBits(nBits).Cmp(difficulty.GetTargetFromNBits(lastBlock.Header.NBits)) >= 0 {
+		t.Fatalf("Difficulty did not increase at interval, nBits %08x", nBits)
 	}
 }
 
@@ -383,3 +401,530 @@ func TestGetBlocks(t *testing.T) {
 		t.Fatalf("GetBlocks returned wrong number of blocks, got %d", len(fetched))
 	}
 }
+
+func TestInsertPublishesChainEvents(t *testing.T) {
+	inits.ResetTestDatabase()
+
+	received := make(chan events.Event, 10)
+	subscription := inits.TestEventBus.Subscribe(func(event events.Event) {
+		received <- event
+	}, events.ChainTipChanged, events.ChainReorganized)
+	t.Cleanup(func() {
+		inits.TestEventBus.Unsubscribe(subscription)
+	})
+
+	genesisId := inits.TestBlockRepository.GetActiveChainTipId()
+
+	govKeyPair, err := inits.GenerateTestGovernmentKeyPair()
+	if err != nil {
+		t.Fatalf("failed to generate government key pair: %v", err)
+	}
+
+	vote, _, err := inits.CreateTestTransaction(govKeyPair)
+	if err != nil {
+		t.Fatalf("failed to create test transaction: %v", err)
+	}
+
+	a1, err := inits.CreateTestBlock(genesisId, []*models.Transaction{vote})
+	if err != nil {
+		t.Fatalf("failed to create block a1: %v", err)
+	}
+
+	b1, err := inits.CreateTestBlock(genesisId, []*models.Transaction{})
+	if err != nil {
+		t.Fatalf("failed to create block b1: %v", err)
+	}
+
+	b2, err := inits.CreateTestBlock(b1.Header.Id, []*models.Transaction{})
+	if err != nil {
+		t.Fatalf("failed to create block b2: %v", err)
+	}
+
+	for _, block := range []*models.Block{a1, b1, b2} {
+		if err := inits.TestBlockRepository.InsertIfNotExists(block); err != nil {
+			t.Fatalf("failed to insert block: %v", err)
+		}
+	}
+
+	nextEvent := func() events.Event {
+		select {
+		case event := <-received:
+			return event
+		case <-time.After(2 * time.Second):
+			t.Fatalf("timed out waiting for event")
+			return nil
+		}
+	}
+
+	tipChanged, ok := nextEvent().(events.ChainTipChangedEvent)
+	if !ok || !bytes.Equal(tipChanged.NewTipId, a1.Header.Id) {
+		t.Fatalf("expected tip change to a1")
+	}
+
+	reorganized, ok := nextEvent().(events.ChainReorganizedEvent)
+	if !ok {
+		t.Fatalf("expected reorganization event")
+	}
+
+	if !bytes.Equal(reorganized.OldTipId, a1.Header.Id) || !bytes.Equal(reorganized.NewTipId, b2.Header.Id) || !bytes.Equal(reorganized.ForkPointId, genesisId) {
+		t.Fatalf("reorganization event has wrong ids")
+	}
+
+	if len(reorganized.ConnectedBlocks) != 2 || !bytes.Equal(reorganized.ConnectedBlocks[0].Header.Id, b1.Header.Id) || !bytes.Equal(reorganized.ConnectedBlocks[1].Header.Id, b2.Header.Id) {
+		t.Fatalf("reorganization event has wrong connected blocks")
+	}
+
+	if len(reorganized.DisconnectedBlocks) != 1 || len(reorganized.DisconnectedBlocks[0].Transactions) != 1 || !bytes.Equal(reorganized.DisconnectedBlocks[0].Transactions[0].Id, vote.Id) {
+		t.Fatalf("reorganization event has wrong disconnected blocks")
+	}
+
+	valid, err := inits.TestTransactionRepository.TransactionValidInActiveChain(vote)
+	if err != nil {
+		t.Fatalf("failed to validate disconnected vote: %v", err)
+	}
+
+	if !valid {
+		t.Fatalf("disconnected vote is still counted in the active chain")
+	}
+
+	tipChanged, ok = nextEvent().(events.ChainTipChangedEvent)
+	if !ok || !bytes.Equal(tipChanged.NewTipId, b2.Header.Id) {
+		t.Fatalf("expected tip change to b2")
+	}
+}
+
+func TestGetMinerCreditsAfterReorganization(t *testing.T) {
+	inits.ResetTestDatabase()
+
+	_, blocks, _, err := inits.CreateTestData(3, 2)
+	if err != nil {
+		t.Fatalf("failed to setup test data: %v", err)
+	}
+
+	rewards := inits.TestChainParams.Rewards
+	for _, block := range blocks {
+		credits, err := inits.TestBlockRepository.GetMinerCredits(block.Header.MinerPublicKey)
+		if err != nil {
+			t.Fatalf("failed to get miner credits: %v", err)
+		}
+
+		if credits.Blocks != 1 || credits.Votes != 2 || credits.Credits != rewards.Credits(1, 2) {
+			t.Fatalf("unexpected credits for block %x: %+v", block.Header.Id, credits)
+		}
+	}
+
+	//a longer branch from the first block, mined by one miner, replaces the last two blocks
+	forkMinerKeyPair, err := inits.GenerateTestGovernmentKeyPair()
+	if err != nil {
+		t.Fatalf("failed to generate fork miner key pair: %v", err)
+	}
+
+	previousBlockId := blocks[0].Header.Id
+	for range 3 {
+		forkBlock, err := inits.CreateTestBlock(previousBlockId, []*models.Transaction{})
+		if err != nil {
+			t.Fatalf("failed to create fork block: %v", err)
+		}
+
+		forkBlock.Header.MinerPublicKey = forkMinerKeyPair.PublicKey.AsBytes()
+		for forkBlock.Header.SetId(); !forkBlock.Header.IsHashBelowTarget(); forkBlock.Header.SetId() {
+			forkBlock.Header.Nonce++
+		}
+
+		if err := forkBlock.Header.Sign(forkMinerKeyPair.PrivateKey); err != nil {
+			t.Fatalf("failed to sign fork block: %v", err)
+		}
+
+		if err := inits.TestBlockRepository.InsertIfNotExists(forkBlock); err != nil {
+			t.Fatalf("failed to insert fork block: %v", err)
+		}
+		previousBlockId = forkBlock.Header.Id
+	}
+
+	if !bytes.Equal(inits.TestBlockRepository.GetActiveChainTipId(), previousBlockId) {
+		t.Fatalf("fork didn't become the active chain")
+	}
+
+	for _, block := range blocks[1:] {
+		credits, err := inits.TestBlockRepository.GetMinerCredits(block.Header.MinerPublicKey)
+		if err != nil {
+			t.Fatalf("failed to get miner credits: %v", err)
+		}
+
+		if credits.Blocks != 0 || credits.Credits != 0 {
+			t.Fatalf("disconnected block %x still earns credits: %+v", block.Header.Id, credits)
+		}
+	}
+
+	ledger, total, err := inits.TestBlockRepository.GetMinerCreditsPaged(0, 10)
+	if err != nil {
+		t.Fatalf("failed to get miner credits ledger: %v", err)
+	}
+
+	if total != 2 || len(ledger) != 2 {
+		t.Fatalf("expected 2 miners in the ledger, got %d", total)
+	}
+
+	if !bytes.Equal(ledger[0].MinerPublicKey, forkMinerKeyPair.PublicKey.AsBytes()) || ledger[0].Credits != rewards.Credits(3, 0) {
+		t.Fatalf("fork miner isn't first in the ledger: %+v", ledger[0])
+	}
+
+	if !bytes.Equal(ledger[1].MinerPublicKey, blocks[0].Header.MinerPublicKey) || ledger[1].Credits != rewards.Credits(1, 2) {
+		t.Fatalf("first block miner isn't second in the ledger: %+v", ledger[1])
+	}
+}
+
+func TestProofOfAuthorityForkChoice(t *testing.T) {
+	inits.ClearTestDatabase()
+
+	first, err := inits.GenerateTestGovernmentKeyPair()
+	if err != nil {
+		t.Fatalf("failed to generate sealer key pair: %v", err)
+	}
+
+	second, err := inits.GenerateTestGovernmentKeyPair()
+	if err != nil {
+		t.Fatalf("failed to generate sealer key pair: %v", err)
+	}
+
+	authorityParams, err := inits.CreateTestAuthorityChainParams(0, 0, first, second)
+	if err != nil {
+		t.Fatalf("failed to create authority chain params: %v", err)
+	}
+
+	blockRepository := repositories.NewBlockRepositoryImpl(inits.TestDb, inits.TestEventBus, authorityParams)
+	if err := blockRepository.Initialize(); err != nil {
+		t.Fatalf("failed to initialize block repository: %v", err)
+	}
+
+	genesisHeader := authorityParams.GenesisBlock.Header
+
+	//height 1 is the second sealer's turn, the first sealer's block arrives first
+	outOfTurnBlock, err := inits.CreateTestSealedBlock(genesisHeader.Id, genesisHeader.Timestamp+1, []*models.Transaction{}, first)
+	if err != nil {
+		t.Fatalf("failed to create sealed block: %v", err)
+	}
+
+	if err := blockRepository.InsertIfNotExists(outOfTurnBlock); err != nil {
+		t.Fatalf("failed to insert out of turn block: %v", err)
+	}
+
+	if !bytes.Equal(blockRepository.GetActiveChainTipId(), outOfTurnBlock.Header.Id) {
+		t.Fatalf("out of turn block extending the tip isn't the active chain tip")
+	}
+
+	//a chain of the same length sealed in turn is heavier
+	inTurnBlock, err := inits.CreateTestSealedBlock(genesisHeader.Id, genesisHeader.Timestamp+1, []*models.Transaction{}, second)
+	if err != nil {
+		t.Fatalf("failed to create sealed block: %v", err)
+	}
+
+	if err := blockRepository.InsertIfNotExists(inTurnBlock); err != nil {
+		t.Fatalf("failed to insert in turn block: %v", err)
+	}
+
+	if !bytes.Equal(blockRepository.GetActiveChainTipId(), inTurnBlock.Header.Id) {
+		t.Fatalf("in turn block didn't replace the out of turn block")
+	}
+
+	inTurnWeight, err := blockRepository.GetBlockChainWeight(inTurnBlock.Header.Id)
+	if err != nil {
+		t.Fatalf("failed to get chain weight: %v", err)
+	}
+
+	outOfTurnWeight, err := blockRepository.GetBlockChainWeight(outOfTurnBlock.Header.Id)
+	if err != nil {
+		t.Fatalf("failed to get chain weight: %v", err)
+	}
+
+	if inTurnWeight.Cmp(outOfTurnWeight) <= 0 {
+		t.Fatalf("in turn chain weight %s isn't above out of turn chain weight %s", inTurnWeight, outOfTurnWeight)
+	}
+}
+
+func TestReorganizationPastCheckpoint(t *testing.T) {
+	inits.ResetTestDatabase()
+
+	chainParams, err := chainparams.ParamsForName(inits.TestConfig.NodeConfig.Chain)
+	if err != nil {
+		t.Fatalf("failed to get chain params: %v", err)
+	}
+
+	blockRepository := repositories.NewBlockRepositoryImpl(inits.TestDb, inits.TestEventBus, chainParams)
+	if err := blockRepository.Initialize(); err != nil {
+		t.Fatalf("failed to initialize block repository: %v", err)
+	}
+
+	activeBlocks := insertTestBranch(t, blockRepository, chainParams.GenesisBlock.Header.Id, 2)
+	forkBlocks := insertTestBranch(t, blockRepository, chainParams.GenesisBlock.Header.Id, 2)
+
+	//the node learns of a checkpoint at the tip after the fork was received
+	if err := chainParams.Consensus.AddCheckpoint(models.Checkpoint{Height: 2, BlockId: activeBlocks[1].Header.Id}); err != nil {
+		t.Fatalf("failed to add checkpoint: %v", err)
+	}
+
+	heavierForkBlock, err := inits.CreateTestBlock(forkBlocks[1].Header.Id, []*models.Transaction{})
+	if err != nil {
+		t.Fatalf("failed to create fork block: %v", err)
+	}
+
+	err = blockRepository.InsertIfNotExists(heavierForkBlock)
+	if !errors.Is(err, repositories.ErrCheckpointViolation) {
+		t.Fatalf("reorganization past a checkpoint wasn't refused, got %v", err)
+	}
+
+	if !bytes.Equal(blockRepository.GetActiveChainTipId(), activeBlocks[1].Header.Id) {
+		t.Fatalf("active chain tip changed after a refused reorganization")
+	}
+
+	haveBlock, err := blockRepository.HaveBlock(heavierForkBlock.Header.Id)
+	if err != nil {
+		t.Fatalf("failed to check block: %v", err)
+	}
+
+	if haveBlock {
+		t.Fatalf("block of a refused reorganization was stored")
+	}
+
+	//a block at the checkpoint's height must be the checkpoint's block
+	conflictingBlock, err := inits.CreateTestBlock(activeBlocks[0].Header.Id, []*models.Transaction{})
+	if err != nil {
+		t.Fatalf("failed to create conflicting block: %v", err)
+	}
+
+	err = blockRepository.InsertIfNotExists(conflictingBlock)
+	if !errors.Is(err, repositories.ErrCheckpointViolation) {
+		t.Fatalf("block conflicting with a checkpoint wasn't refused, got %v", err)
+	}
+
+	insertTestBranch(t, blockRepository, activeBlocks[1].Header.Id, 1)
+}
+
+func TestReorganizationTooDeep(t *testing.T) {
+	inits.ResetTestDatabase()
+
+	chainParams, err := chainparams.ParamsForName(inits.TestConfig.NodeConfig.Chain)
+	if err != nil {
+		t.Fatalf("failed to get chain params: %v", err)
+	}
+	chainParams.Consensus.MaxReorgDepth = 1
+
+	blockRepository := repositories.NewBlockRepositoryImpl(inits.TestDb, inits.TestEventBus, chainParams)
+	if err := blockRepository.Initialize(); err != nil {
+		t.Fatalf("failed to initialize block repository: %v", err)
+	}
+
+	activeBlocks := insertTestBranch(t, blockRepository, chainParams.GenesisBlock.Header.Id, 2)
+
+	//replacing the tip is within the maximum depth
+	shallowForkBlocks := insertTestBranch(t, blockRepository, activeBlocks[0].Header.Id, 2)
+	if !bytes.Equal(blockRepository.GetActiveChainTipId(), shallowForkBlocks[1].Header.Id) {
+		t.Fatalf("reorganization within the maximum depth didn't happen")
+	}
+
+	deepForkBlocks := insertTestBranch(t, blockRepository, chainParams.GenesisBlock.Header.Id, 3)
+
+	heavierForkBlock, err := inits.CreateTestBlock(deepForkBlocks[2].Header.Id, []*models.Transaction{})
+	if err != nil {
+		t.Fatalf("failed to create fork block: %v", err)
+	}
+
+	err = blockRepository.InsertIfNotExists(heavierForkBlock)
+	if !errors.Is(err, repositories.ErrReorgTooDeep) {
+		t.Fatalf("reorganization deeper than the maximum wasn't refused, got %v", err)
+	}
+
+	if !bytes.Equal(blockRepository.GetActiveChainTipId(), shallowForkBlocks[1].Header.Id) {
+		t.Fatalf("active chain tip changed after a refused reorganization")
+	}
+}
+
+func TestReindexRestoresActiveChain(t *testing.T) {
+	inits.ResetTestDatabase()
+	_, blocks, _, err := inits.CreateTestData(3, 2)
+	if err != nil {
+		t.Fatalf("failed to setup test data: %v", err)
+	}
+
+	sideBlocks := insertTestBranch(t, inits.TestBlockRepository, blocks[0].Header.Id, 1)
+
+	//the side block is marked as the active one instead of the last two blocks
+	err = inits.TestDb.Model(&db_models.BlockDB{}).
+		Where("block_header_id IN ?", [][]byte{blocks[1].Header.Id, blocks[2].Header.Id}).
+		Updates(map[string]any{"in_active_chain": false, "height": 0}).Error
+
+	if err != nil {
+		t.Fatalf("failed to corrupt blocks: %v", err)
+	}
+
+	err = inits.TestDb.Model(&db_models.BlockDB{}).
+		Where("block_header_id = ?", sideBlocks[0].Header.Id).
+		Update("in_active_chain", true).Error
+
+	if err != nil {
+		t.Fatalf("failed to corrupt side block: %v", err)
+	}
+
+	if err := inits.TestBlockRepository.Reindex(); err != nil {
+		t.Fatalf("failed to reindex chain: %v", err)
+	}
+
+	if !bytes.Equal(inits.TestBlockRepository.GetActiveChainTipId(), blocks[2].Header.Id) {
+		t.Fatalf("reindex didn't restore the heaviest chain tip")
+	}
+
+	for i, block := range blocks {
+		height, err := inits.TestBlockRepository.GetBlockHeight(block.Header.Id)
+		if err != nil || height != uint64(i+1) {
+			t.Fatalf("block %d is at height %d after reindexing: %v", i, height, err)
+		}
+	}
+
+	activeChainHeight, err := inits.TestBlockRepository.GetActiveChainHeight()
+	if err != nil || activeChainHeight != 3 {
+		t.Fatalf("active chain height is %d after reindexing: %v", activeChainHeight, err)
+	}
+
+	valid, err := inits.TestTransactionRepository.TransactionValidInActiveChain(blocks[2].Transactions[0])
+	if err != nil || valid {
+		t.Fatalf("vote of the restored active chain isn't in the spent voter index: %v", err)
+	}
+
+	if err := inits.TestBlockRepository.SetActiveChainTipId(); err != nil || !bytes.Equal(inits.TestBlockRepository.GetActiveChainTipId(), blocks[2].Header.Id) {
+		t.Fatalf("stored active chain doesn't end at the reindexed tip: %v", err)
+	}
+}
+
+func TestPruneKeepsHeadersAndTally(t *testing.T) {
+	inits.ResetTestDatabase()
+	_, blocks, _, err := inits.CreateTestData(6, 2)
+	if err != nil {
+		t.Fatalf("failed to setup test data: %v", err)
+	}
+
+	pruned, err := inits.TestBlockRepository.Prune(2)
+	if err != nil || pruned != 4 {
+		t.Fatalf("pruned %d blocks, expected the 4 blocks below the last 2: %v", pruned, err)
+	}
+
+	prunedHeight, err := inits.TestBlockRepository.GetPrunedHeight()
+	if err != nil || prunedHeight != 4 {
+		t.Fatalf("pruned height is %d: %v", prunedHeight, err)
+	}
+
+	if _, err := inits.TestBlockRepository.GetBlock(blocks[0].Header.Id); !errors.Is(err, repositories.ErrBlockPruned) {
+		t.Fatalf("pruned block was returned: %v", err)
+	}
+
+	if _, err := inits.TestBlockRepository.GetBlockHeader(blocks[0].Header.Id); err != nil {
+		t.Fatalf("header of a pruned block is gone: %v", err)
+	}
+
+	if _, err := inits.TestTransactionRepository.GetTransaction(blocks[0].Transactions[0].Id); err == nil {
+		t.Fatalf("transaction of a pruned block wasn't deleted")
+	}
+
+	ids := structures.NewBytesSet()
+	ids.Add(blocks[0].Header.Id)
+	ids.Add(blocks[5].Header.Id)
+
+	kept, err := inits.TestBlockRepository.GetBlocks(ids)
+	if err != nil || len(kept) != 1 || len(kept[0].Transactions) != 2 {
+		t.Fatalf("expected only the block that wasn't pruned: %v", err)
+	}
+
+	results, err := inits.TestTransactionRepository.GetVotingResults()
+	if err != nil {
+		t.Fatalf("failed to get voting results: %v", err)
+	}
+
+	votes := 0
+	for _, result := range results {
+		votes += result.Votes
+	}
+
+	if votes != 12 {
+		t.Fatalf("tally holds %d votes after pruning, expected 12", votes)
+	}
+
+	valid, err := inits.TestTransactionRepository.TransactionValidInActiveChain(blocks[0].Transactions[0])
+	if err != nil || valid {
+		t.Fatalf("vote of a pruned block left the spent voter index: %v", err)
+	}
+
+	credits, _, err := inits.TestBlockRepository.GetMinerCreditsPaged(0, 10)
+	if err != nil {
+		t.Fatalf("failed to get miner credits: %v", err)
+	}
+
+	creditedBlocks, creditedVotes := uint64(0), uint64(0)
+	for _, minerCredits := range credits {
+		creditedBlocks += minerCredits.Blocks
+		creditedVotes += minerCredits.Votes
+	}
+
+	if creditedBlocks != 6 || creditedVotes != 12 {
+		t.Fatalf("miners are credited %d blocks and %d votes after pruning", creditedBlocks, creditedVotes)
+	}
+
+	if pruned, err := inits.TestBlockRepository.Prune(2); err != nil || pruned != 0 {
+		t.Fatalf("pruned %d blocks again: %v", pruned, err)
+	}
+
+	if err := inits.TestBlockRepository.Reindex(); !errors.Is(err, repositories.ErrBlockPruned) {
+		t.Fatalf("reindex of a pruned chain wasn't refused: %v", err)
+	}
+
+	//a fork below the pruned height would connect and disconnect pruned blocks
+	forkBlocks := insertTestBranch(t, inits.TestBlockRepository, blocks[2].Header.Id, 3)
+	heavierForkBlock, err := inits.CreateTestBlock(forkBlocks[2].Header.Id, []*models.Transaction{})
+	if err != nil {
+		t.Fatalf("failed to create fork block: %v", err)
+	}
+
+	if err := inits.TestBlockRepository.InsertIfNotExists(heavierForkBlock); !errors.Is(err, repositories.ErrReorgTooDeep) {
+		t.Fatalf("reorganization into pruned blocks wasn't refused: %v", err)
+	}
+}
+
+func TestInitializeRefusesOtherGenesis(t *testing.T) {
+	inits.ResetTestDatabase()
+
+	govKeyPair, err := inits.GenerateTestGovernmentKeyPair()
+	if err != nil {
+		t.Fatalf("failed to generate government key pair: %v", err)
+	}
+
+	chainParams, err := chainparams.ParamsForName(inits.TestConfig.NodeConfig.Chain)
+	if err != nil {
+		t.Fatalf("failed to get chain params: %v", err)
+	}
+	chainParams.SetGenesisBlock(govKeyPair.PublicKey.AsBytes())
+
+	blockRepository := repositories.NewBlockRepositoryImpl(inits.TestDb, inits.TestEventBus, chainParams)
+	if err := blockRepository.Initialize(); !errors.Is(err, repositories.ErrGenesisMismatch) {
+		t.Fatalf("chain of another election was opened: %v", err)
+	}
+}
+
+// insertTestBranch inserts a branch of empty blocks on top of a block, the branch isn't necessarily the active chain
+func insertTestBranch(t *testing.T, blockRepository repositories.BlockRepository, previousBlockId []byte, length int) []*models.Block {
+	t.Helper()
+
+	branch := make([]*models.Block, 0, length)
+	for range length {
+		block, err := inits.CreateTestBlock(previousBlockId, []*models.Transaction{})
+		if err != nil {
+			t.Fatalf("failed to create block: %v", err)
+		}
+
+		if err := blockRepository.InsertIfNotExists(block); err != nil {
+			t.Fatalf("failed to insert block: %v", err)
+		}
+
+		branch = append(branch, block)
+		previousBlockId = block.Header.Id
+	}
+
+	return branch
+}
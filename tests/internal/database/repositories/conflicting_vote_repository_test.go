@@ -0,0 +1,95 @@
+package repositories_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	models "github.com/nivschuman/VotingBlockchain/internal/models"
+	inits "github.com/nivschuman/VotingBlockchain/tests/init"
+)
+
+func TestConflictingVoteInsertIfNotExists(t *testing.T) {
+	inits.ResetTestDatabase()
+	govKeyPair, err := inits.GenerateTestGovernmentKeyPair()
+	if err != nil {
+		t.Fatalf("failed to generate government key pair: %v", err)
+	}
+
+	tx, voterKeyPair, err := inits.CreateTestTransaction(govKeyPair)
+	if err != nil {
+		t.Fatalf("failed to create test tx: %v", err)
+	}
+
+	conflictingTx, err := inits.CreateConflictingTestTransaction(tx, voterKeyPair, 2)
+	if err != nil {
+		t.Fatalf("failed to create conflicting tx: %v", err)
+	}
+
+	conflictingVote := &models.ConflictingVote{
+		VoterPublicKey:         tx.VoterPublicKey,
+		Transaction:            conflictingTx,
+		ConflictingTransaction: tx,
+		Source:                 models.ConflictInMempool,
+		DetectedAt:             time.Now(),
+	}
+
+	inserted, err := inits.TestConflictingVoteRepository.InsertIfNotExists(conflictingVote)
+	if err != nil || !inserted {
+		t.Fatalf("failed to insert conflicting vote: %v", err)
+	}
+
+	inserted, err = inits.TestConflictingVoteRepository.InsertIfNotExists(conflictingVote)
+	if err != nil || inserted {
+		t.Fatalf("same conflicting vote was inserted twice: %v", err)
+	}
+
+	//a voter signing votes for other candidates doesn't add rows, the conflicts are counted
+	for candidateId := uint32(3); candidateId < 6; candidateId++ {
+		otherTx, err := inits.CreateConflictingTestTransaction(tx, voterKeyPair, candidateId)
+		if err != nil {
+			t.Fatalf("failed to create conflicting tx: %v", err)
+		}
+
+		otherVote := &models.ConflictingVote{
+			VoterPublicKey:         tx.VoterPublicKey,
+			Transaction:            otherTx,
+			ConflictingTransaction: tx,
+			Source:                 models.ConflictInMempool,
+			DetectedAt:             time.Now(),
+		}
+
+		inserted, err := inits.TestConflictingVoteRepository.InsertIfNotExists(otherVote)
+		if err != nil || inserted {
+			t.Fatalf("another conflicting vote of the voter was inserted: %v", err)
+		}
+	}
+
+	conflictingVotes, err := inits.TestConflictingVoteRepository.GetConflictingVotes(tx.VoterPublicKey)
+	if err != nil {
+		t.Fatalf("failed to get conflicting votes: %v", err)
+	}
+
+	if len(conflictingVotes) != 1 {
+		t.Fatalf("got %d conflicting votes, expected 1", len(conflictingVotes))
+	}
+
+	if !bytes.Equal(conflictingVotes[0].Transaction.AsBytes(), conflictingTx.AsBytes()) || !bytes.Equal(conflictingVotes[0].ConflictingTransaction.AsBytes(), tx.AsBytes()) {
+		t.Fatalf("conflicting vote transactions weren't stored correctly")
+	}
+
+	if conflictingVotes[0].Conflicts != 4 {
+		t.Fatalf("counted %d conflicts of the voter, expected 4", conflictingVotes[0].Conflicts)
+	}
+
+	data, err := models.ConflictingVotesToJSON(conflictingVotes)
+	if err != nil {
+		t.Fatalf("failed to export conflicting votes: %v", err)
+	}
+
+	var exported []map[string]any
+	if err := json.Unmarshal(data, &exported); err != nil || len(exported) != 1 || exported[0]["source"] != models.ConflictInMempool {
+		t.Fatalf("exported conflicting votes are wrong: %s", data)
+	}
+}
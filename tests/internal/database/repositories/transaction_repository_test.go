@@ -1,68 +1,48 @@
 package repositories_test
 
 import (
+	"bytes"
 	"testing"
 
+	chainparams "github.com/nivschuman/VotingBlockchain/internal/chainparams"
+	repositories "github.com/nivschuman/VotingBlockchain/internal/database/repositories"
 	models "github.com/nivschuman/VotingBlockchain/internal/models"
 	structures "github.com/nivschuman/VotingBlockchain/internal/structures"
+	voters "github.com/nivschuman/VotingBlockchain/internal/voters"
 	inits "github.com/nivschuman/VotingBlockchain/tests/init"
 )
 
-func TestGetMempool(t *testing.T) {
+func TestGetVotersInActiveChain(t *testing.T) {
 	inits.ResetTestDatabase()
-	govKeyPair, _, _, err := inits.CreateTestData(4, 2)
+	govKeyPair, blocks, _, err := inits.CreateTestData(4, 2)
 
 	if err != nil {
 		t.Fatalf("failed to setup test data: %v", err)
 	}
 
-	tx1, _, err := inits.CreateTestTransaction(govKeyPair)
+	pendingTx, _, err := inits.CreateTestTransaction(govKeyPair)
 	if err != nil {
-		t.Fatalf("failed to create test tx1: %v", err)
+		t.Fatalf("failed to create pending tx: %v", err)
 	}
 
-	tx2, _, err := inits.CreateTestTransaction(govKeyPair)
+	err = inits.TestTransactionRepository.InsertIfNotExists(pendingTx)
 	if err != nil {
-		t.Fatalf("failed to create test tx2: %v", err)
+		t.Fatalf("failed to insert pending tx: %v", err)
 	}
 
-	tx3 := *tx2
-	tx3.CandidateId = 2
-	tx3.SetId()
+	confirmedTx := blocks[2].Transactions[1]
 
-	err = inits.TestTransactionRepository.InsertIfNotExists(tx1)
-	if err != nil {
-		t.Fatalf("failed to create insert tx1: %v", err)
-	}
+	voterPublicKeys := structures.NewBytesSet()
+	voterPublicKeys.Add(pendingTx.VoterPublicKey)
+	voterPublicKeys.Add(confirmedTx.VoterPublicKey)
 
-	err = inits.TestTransactionRepository.InsertIfNotExists(tx2)
+	voted, err := inits.TestTransactionRepository.GetVotersInActiveChain(voterPublicKeys)
 	if err != nil {
-		t.Fatalf("failed to create insert tx2: %v", err)
+		t.Fatalf("failed to get voters in active chain: %v", err)
 	}
 
-	err = inits.TestTransactionRepository.InsertIfNotExists(&tx3)
-	if err != nil {
-		t.Fatalf("failed to create insert tx3: %v", err)
-	}
-
-	addedByteSet := structures.NewBytesSet()
-	addedByteSet.Add(tx1.VoterPublicKey)
-	addedByteSet.Add(tx2.VoterPublicKey)
-
-	txs, err := inits.TestTransactionRepository.GetMempool(10)
-
-	if err != nil {
-		t.Fatalf("failed to get mempool: %v", err)
-	}
-
-	if len(txs) != addedByteSet.Length() {
-		t.Fatalf("received incorrect amount of transactions from mempool: %d", len(txs))
-	}
-
-	for _, tx := range txs {
-		if !addedByteSet.Contains(tx.VoterPublicKey) {
-			t.Fatalf("received invalid transaction: %x", tx.Id)
-		}
+	if voted.Length() != 1 || !voted.Contains(confirmedTx.VoterPublicKey) {
+		t.Fatalf("expected only the confirmed voter, got %d voters", voted.Length())
 	}
 }
 
@@ -224,3 +204,176 @@ func TestGetVotingResults(t *testing.T) {
 		t.Fatalf("incorrect amount of votes: %d", results[0].Votes)
 	}
 }
+
+func TestSpentVotersFollowReorganization(t *testing.T) {
+	inits.ResetTestDatabase()
+	govKeyPair, blocks, _, err := inits.CreateTestData(3, 2)
+	if err != nil {
+		t.Fatalf("failed to setup test data: %v", err)
+	}
+
+	forkTx, _, err := inits.CreateTestTransaction(govKeyPair)
+	if err != nil {
+		t.Fatalf("failed to create fork tx: %v", err)
+	}
+
+	//the fork from the first block holds a vote of the last block as well
+	sharedTx := blocks[2].Transactions[0]
+	forkBlock, err := inits.CreateTestBlock(blocks[0].Header.Id, []*models.Transaction{sharedTx, forkTx})
+	if err != nil {
+		t.Fatalf("failed to create fork block: %v", err)
+	}
+
+	if err := inits.TestBlockRepository.InsertIfNotExists(forkBlock); err != nil {
+		t.Fatalf("failed to insert fork block: %v", err)
+	}
+
+	isValid, err := inits.TestTransactionRepository.TransactionsValidInChain(forkBlock.Header.Id, []*models.Transaction{blocks[1].Transactions[0]})
+	if err != nil || !isValid {
+		t.Fatalf("vote of a block past the fork point is invalid in the side chain: %v", err)
+	}
+
+	isValid, err = inits.TestTransactionRepository.TransactionsValidInChain(forkBlock.Header.Id, []*models.Transaction{blocks[0].Transactions[1]})
+	if err != nil || isValid {
+		t.Fatalf("vote of the fork point is valid in the side chain: %v", err)
+	}
+
+	isValid, err = inits.TestTransactionRepository.TransactionsValidInChain(forkBlock.Header.Id, []*models.Transaction{forkTx})
+	if err != nil || isValid {
+		t.Fatalf("vote of the side chain is valid in the side chain: %v", err)
+	}
+
+	previousBlockId := forkBlock.Header.Id
+	for range 2 {
+		block, err := inits.CreateTestBlock(previousBlockId, []*models.Transaction{})
+		if err != nil {
+			t.Fatalf("failed to create fork block: %v", err)
+		}
+
+		if err := inits.TestBlockRepository.InsertIfNotExists(block); err != nil {
+			t.Fatalf("failed to insert fork block: %v", err)
+		}
+		previousBlockId = block.Header.Id
+	}
+
+	if !bytes.Equal(inits.TestBlockRepository.GetActiveChainTipId(), previousBlockId) {
+		t.Fatalf("fork didn't become the active chain")
+	}
+
+	isValid, err = inits.TestTransactionRepository.TransactionValidInActiveChain(blocks[1].Transactions[0])
+	if err != nil || !isValid {
+		t.Fatalf("vote of a disconnected block is invalid in the active chain: %v", err)
+	}
+
+	for _, tx := range []*models.Transaction{sharedTx, forkTx} {
+		activeVote, err := inits.TestTransactionRepository.GetActiveChainVote(tx.VoterPublicKey)
+		if err != nil {
+			t.Fatalf("failed to get active chain vote: %v", err)
+		}
+
+		if !bytes.Equal(activeVote.Id, tx.Id) {
+			t.Fatalf("active chain vote of voter %x is %x, expected %x", tx.VoterPublicKey, activeVote.Id, tx.Id)
+		}
+	}
+}
+
+func TestTallySnapshotsFollowReorganization(t *testing.T) {
+	inits.ResetTestDatabase()
+
+	chainParams, err := chainparams.ParamsForName(inits.TestConfig.NodeConfig.Chain)
+	if err != nil {
+		t.Fatalf("failed to get chain params: %v", err)
+	}
+	chainParams.Election.TallySnapshotInterval = 2
+
+	blockRepository := repositories.NewBlockRepositoryImpl(inits.TestDb, inits.TestEventBus, chainParams)
+	if err := blockRepository.Initialize(); err != nil {
+		t.Fatalf("failed to initialize block repository: %v", err)
+	}
+
+	govKeyPair, err := inits.GenerateTestGovernmentKeyPair()
+	if err != nil {
+		t.Fatalf("failed to generate government key pair: %v", err)
+	}
+
+	blocks := make([]*models.Block, 4)
+	previousBlockId := chainParams.GenesisBlock.Header.Id
+	for i := range blocks {
+		tx, _, err := inits.CreateTestTransaction(govKeyPair)
+		if err != nil {
+			t.Fatalf("failed to create tx: %v", err)
+		}
+
+		blocks[i], err = inits.CreateTestBlock(previousBlockId, []*models.Transaction{tx})
+		if err != nil {
+			t.Fatalf("failed to create block: %v", err)
+		}
+
+		if err := blockRepository.InsertIfNotExists(blocks[i]); err != nil {
+			t.Fatalf("failed to insert block: %v", err)
+		}
+		previousBlockId = blocks[i].Header.Id
+	}
+
+	for height, expectedVotes := range map[uint64]int{0: 0, 1: 1, 2: 2, 3: 3, 4: 4, 10: 4} {
+		results, err := inits.TestTransactionRepository.GetVotingResultsAtHeight(height)
+		if err != nil {
+			t.Fatalf("failed to get voting results at height %d: %v", height, err)
+		}
+
+		if countVotes(results) != expectedVotes {
+			t.Fatalf("results at height %d hold %d votes, expected %d", height, countVotes(results), expectedVotes)
+		}
+	}
+
+	snapshot, err := inits.TestTransactionRepository.GetTallySnapshot(2)
+	if err != nil {
+		t.Fatalf("failed to get tally snapshot: %v", err)
+	}
+
+	expected := voters.NewTallySnapshot(2, blocks[1].Header.Id, []*voters.VotingResult{{CandidateId: 1, Votes: 2}})
+	if !bytes.Equal(snapshot.Hash, expected.Hash) || !bytes.Equal(snapshot.BlockId, blocks[1].Header.Id) {
+		t.Fatalf("snapshot at height 2 doesn't match the tally of the chain")
+	}
+
+	if _, err := inits.TestTransactionRepository.GetTallySnapshot(3); err == nil {
+		t.Fatalf("snapshot between snapshot heights was found")
+	}
+
+	//a longer fork of empty blocks from the first block disconnects three votes
+	forkBlocks := insertTestBranch(t, blockRepository, blocks[0].Header.Id, 4)
+
+	results, err := inits.TestTransactionRepository.GetVotingResults()
+	if err != nil || countVotes(results) != 1 {
+		t.Fatalf("tally holds %d votes after reorganization: %v", countVotes(results), err)
+	}
+
+	snapshot, err = inits.TestTransactionRepository.GetTallySnapshot(2)
+	if err != nil || !bytes.Equal(snapshot.BlockId, forkBlocks[0].Header.Id) || countVotes(snapshot.Results) != 1 {
+		t.Fatalf("snapshot at height 2 wasn't taken again for the fork: %v", err)
+	}
+
+	snapshotHash := snapshot.Hash
+	if err := blockRepository.Reindex(); err != nil {
+		t.Fatalf("failed to reindex: %v", err)
+	}
+
+	snapshot, err = inits.TestTransactionRepository.GetTallySnapshot(2)
+	if err != nil || !bytes.Equal(snapshot.Hash, snapshotHash) {
+		t.Fatalf("reindexed snapshot differs: %v", err)
+	}
+
+	results, err = inits.TestTransactionRepository.GetVotingResults()
+	if err != nil || countVotes(results) != 1 {
+		t.Fatalf("tally holds %d votes after reindexing: %v", countVotes(results), err)
+	}
+}
+
+func countVotes(results []*voters.VotingResult) int {
+	votes := 0
+	for _, result := range results {
+		votes += result.Votes
+	}
+
+	return votes
+}
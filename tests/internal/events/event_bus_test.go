@@ -0,0 +1,130 @@
+package events_test
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	events "github.com/nivschuman/VotingBlockchain/internal/events"
+	inits "github.com/nivschuman/VotingBlockchain/tests/init"
+)
+
+func TestMain(m *testing.M) {
+	// === BEFORE ALL TESTS ===
+	inits.SetupTests()
+
+	// Run the tests
+	code := m.Run()
+
+	// === AFTER ALL TESTS ===
+
+	// Exit with the right code
+	os.Exit(code)
+}
+
+func TestSubscriberReceivesEventsInOrder(t *testing.T) {
+	bus := events.NewEventBusImpl()
+	t.Cleanup(bus.Close)
+
+	received := make(chan events.Event, 10)
+	bus.Subscribe(func(event events.Event) {
+		received <- event
+	}, events.ChainTipChanged)
+
+	for i := range 5 {
+		bus.Publish(events.ChainTipChangedEvent{ChainUpdate: events.ChainUpdate{NewTipId: []byte{byte(i)}}})
+	}
+
+	for i := range 5 {
+		select {
+		case event := <-received:
+			tipChanged := event.(events.ChainTipChangedEvent)
+			if tipChanged.NewTipId[0] != byte(i) {
+				t.Fatalf("received event %d out of order", tipChanged.NewTipId[0])
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("timed out waiting for event %d", i)
+		}
+	}
+}
+
+func TestSubscriberOnlyReceivesSubscribedTypes(t *testing.T) {
+	bus := events.NewEventBusImpl()
+	t.Cleanup(bus.Close)
+
+	received := make(chan events.Event, 10)
+	bus.Subscribe(func(event events.Event) {
+		received <- event
+	}, events.PeerConnected)
+
+	bus.Publish(events.ChainTipChangedEvent{})
+	bus.Publish(events.PeerConnectedEvent{})
+
+	select {
+	case event := <-received:
+		if event.Type() != events.PeerConnected {
+			t.Fatalf("received unsubscribed event %s", event.Type().String())
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("timed out waiting for event")
+	}
+}
+
+func TestSlowSubscriberDoesNotBlockPublisher(t *testing.T) {
+	bus := events.NewEventBusImpl()
+	t.Cleanup(bus.Close)
+
+	release := make(chan struct{})
+	bus.Subscribe(func(event events.Event) {
+		<-release
+	}, events.BlockReceived)
+
+	published := make(chan struct{})
+	go func() {
+		for range 1000 {
+			bus.Publish(events.BlockReceivedEvent{})
+		}
+		close(published)
+	}()
+
+	select {
+	case <-published:
+	case <-time.After(2 * time.Second):
+		t.Fatalf("publisher was blocked by slow subscriber")
+	}
+
+	close(release)
+}
+
+func TestUnsubscribeStopsDelivery(t *testing.T) {
+	bus := events.NewEventBusImpl()
+	t.Cleanup(bus.Close)
+
+	received := make(chan events.Event, 10)
+	subscription := bus.Subscribe(func(event events.Event) {
+		received <- event
+	}, events.PeerConnected, events.PeerDisconnected)
+
+	bus.UnsubscribeAll(events.PeerConnected)
+	bus.Publish(events.PeerConnectedEvent{})
+	bus.Publish(events.PeerDisconnectedEvent{})
+
+	select {
+	case event := <-received:
+		if event.Type() != events.PeerDisconnected {
+			t.Fatalf("received event %s after unsubscribing from it", event.Type().String())
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("timed out waiting for event")
+	}
+
+	bus.Unsubscribe(subscription)
+	subscription.Wait()
+	bus.Publish(events.PeerDisconnectedEvent{})
+
+	select {
+	case event := <-received:
+		t.Fatalf("received event %s after unsubscribing", event.Type().String())
+	case <-time.After(100 * time.Millisecond):
+	}
+}
@@ -0,0 +1,672 @@
+package mempool_test
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	config "github.com/nivschuman/VotingBlockchain/internal/config"
+	repositories "github.com/nivschuman/VotingBlockchain/internal/database/repositories"
+	events "github.com/nivschuman/VotingBlockchain/internal/events"
+	mempool "github.com/nivschuman/VotingBlockchain/internal/mempool"
+	models "github.com/nivschuman/VotingBlockchain/internal/models"
+	inits "github.com/nivschuman/VotingBlockchain/tests/init"
+)
+
+func TestMain(m *testing.M) {
+	// === BEFORE ALL TESTS ===
+	inits.SetupTests()
+	inits.SetupTestsDatabase()
+
+	// Run the tests
+	code := m.Run()
+
+	// === AFTER ALL TESTS ===
+	inits.CloseTestDatabase()
+
+	// Exit with the right code
+	os.Exit(code)
+}
+
+func TestAddIndexesTransaction(t *testing.T) {
+	inits.ResetTestDatabase()
+	govKeyPair, err := inits.GenerateTestGovernmentKeyPair()
+	if err != nil {
+		t.Fatalf("failed to generate government key pair: %v", err)
+	}
+
+	pool := inits.NewTestMempool()
+	t.Cleanup(pool.Stop)
+
+	tx, _, err := inits.CreateTestTransaction(govKeyPair)
+	if err != nil {
+		t.Fatalf("failed to create test tx: %v", err)
+	}
+
+	if err := pool.Add(tx); err != nil {
+		t.Fatalf("failed to add tx: %v", err)
+	}
+
+	if !pool.Contains(tx.Id) || pool.Count() != 1 {
+		t.Fatalf("tx isn't in mempool")
+	}
+
+	byVoter, exists := pool.GetByVoterPublicKey(tx.VoterPublicKey)
+	if !exists || !bytes.Equal(byVoter.Id, tx.Id) {
+		t.Fatalf("tx isn't indexed by voter public key")
+	}
+
+	txs, total := pool.GetTransactionsPaged(0, 10)
+	if total != 1 || len(txs) != 1 || !bytes.Equal(txs[0].Id, tx.Id) {
+		t.Fatalf("paged transactions are wrong, total %d", total)
+	}
+}
+
+func TestAddRejectsInvalidTransactions(t *testing.T) {
+	inits.ResetTestDatabase()
+	govKeyPair, blocks, _, err := inits.CreateTestData(2, 1)
+	if err != nil {
+		t.Fatalf("failed to setup test data: %v", err)
+	}
+
+	pool := inits.NewTestMempool()
+	t.Cleanup(pool.Stop)
+
+	tx1, _, err := inits.CreateTestTransaction(govKeyPair)
+	if err != nil {
+		t.Fatalf("failed to create test tx1: %v", err)
+	}
+
+	if err := pool.Add(tx1); err != nil {
+		t.Fatalf("failed to add tx1: %v", err)
+	}
+
+	if err := pool.Add(tx1); !errors.Is(err, mempool.ErrAlreadyInMempool) {
+		t.Fatalf("expected already in mempool, got %v", err)
+	}
+
+	conflictingTx := *tx1
+	conflictingTx.CandidateId = 2
+	conflictingTx.SetId()
+	if err := pool.Add(&conflictingTx); !errors.Is(err, mempool.ErrInvalidTransaction) {
+		t.Fatalf("expected invalid transaction for stale voter signature, got %v", err)
+	}
+
+	tx2, voterKeyPair, err := inits.CreateTestTransaction(govKeyPair)
+	if err != nil {
+		t.Fatalf("failed to create test tx2: %v", err)
+	}
+
+	conflictingTx2, err := inits.CreateConflictingTestTransaction(tx2, voterKeyPair, 2)
+	if err != nil {
+		t.Fatalf("failed to create conflicting tx: %v", err)
+	}
+
+	if err := pool.Add(tx2); err != nil {
+		t.Fatalf("failed to add tx2: %v", err)
+	}
+
+	if err := pool.Add(conflictingTx2); !errors.Is(err, mempool.ErrConflict) {
+		t.Fatalf("expected conflict, got %v", err)
+	}
+
+	if err := pool.Add(blocks[0].Transactions[0]); !errors.Is(err, mempool.ErrAlreadyVoted) {
+		t.Fatalf("expected already voted, got %v", err)
+	}
+
+	if pool.Count() != 2 {
+		t.Fatalf("mempool has %d transactions, expected 2", pool.Count())
+	}
+}
+
+func TestAddRespectsMaxTransactions(t *testing.T) {
+	inits.ResetTestDatabase()
+	govKeyPair, err := inits.GenerateTestGovernmentKeyPair()
+	if err != nil {
+		t.Fatalf("failed to generate government key pair: %v", err)
+	}
+
+	mempoolConfig := &config.MempoolConfig{MaxTransactions: 1}
+	pool := mempool.NewMempoolImpl(inits.TestTransactionRepository, inits.TestConflictingVoteRepository, inits.TestEventBus, inits.TestConfig.GovernmentConfig.PublicKey, mempoolConfig)
+	t.Cleanup(pool.Stop)
+
+	tx1, _, err := inits.CreateTestTransaction(govKeyPair)
+	if err != nil {
+		t.Fatalf("failed to create test tx1: %v", err)
+	}
+
+	tx2, _, err := inits.CreateTestTransaction(govKeyPair)
+	if err != nil {
+		t.Fatalf("failed to create test tx2: %v", err)
+	}
+
+	if err := pool.Add(tx1); err != nil {
+		t.Fatalf("failed to add tx1: %v", err)
+	}
+
+	if err := pool.Add(tx2); !errors.Is(err, mempool.ErrMempoolFull) {
+		t.Fatalf("expected mempool full, got %v", err)
+	}
+}
+
+func TestRemoveExpired(t *testing.T) {
+	inits.ResetTestDatabase()
+	govKeyPair, err := inits.GenerateTestGovernmentKeyPair()
+	if err != nil {
+		t.Fatalf("failed to generate government key pair: %v", err)
+	}
+
+	pool := inits.NewTestMempool()
+	t.Cleanup(pool.Stop)
+
+	removed := make(chan events.MempoolTransactionRemovedEvent, 1)
+	subscription := inits.TestEventBus.Subscribe(func(event events.Event) {
+		removed <- event.(events.MempoolTransactionRemovedEvent)
+	}, events.MempoolTransactionRemoved)
+	t.Cleanup(func() { inits.TestEventBus.Unsubscribe(subscription) })
+
+	tx, _, err := inits.CreateTestTransaction(govKeyPair)
+	if err != nil {
+		t.Fatalf("failed to create test tx: %v", err)
+	}
+
+	if err := pool.Add(tx); err != nil {
+		t.Fatalf("failed to add tx: %v", err)
+	}
+
+	if expired := pool.RemoveExpired(time.Now()); expired != 0 {
+		t.Fatalf("removed %d transactions before expiry", expired)
+	}
+
+	expiry := time.Duration(inits.TestConfig.MempoolConfig.Expiry) * time.Second
+	if expired := pool.RemoveExpired(time.Now().Add(expiry)); expired != 1 {
+		t.Fatalf("removed %d transactions after expiry, expected 1", expired)
+	}
+
+	select {
+	case event := <-removed:
+		if !bytes.Equal(event.Transaction.Id, tx.Id) || event.Reason != string(mempool.Expired) {
+			t.Fatalf("wrong removal event for expired tx")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("timed out waiting for removal event")
+	}
+}
+
+func TestChainTipChangeRemovesVotedVoters(t *testing.T) {
+	inits.ResetTestDatabase()
+	govKeyPair, err := inits.GenerateTestGovernmentKeyPair()
+	if err != nil {
+		t.Fatalf("failed to generate government key pair: %v", err)
+	}
+
+	pool := inits.NewTestMempool()
+	t.Cleanup(pool.Stop)
+
+	removed := make(chan events.MempoolTransactionRemovedEvent, 2)
+	subscription := inits.TestEventBus.Subscribe(func(event events.Event) {
+		removed <- event.(events.MempoolTransactionRemovedEvent)
+	}, events.MempoolTransactionRemoved)
+	t.Cleanup(func() { inits.TestEventBus.Unsubscribe(subscription) })
+
+	confirmedTx, _, err := inits.CreateTestTransaction(govKeyPair)
+	if err != nil {
+		t.Fatalf("failed to create confirmed tx: %v", err)
+	}
+
+	pendingTx, voterKeyPair, err := inits.CreateTestTransaction(govKeyPair)
+	if err != nil {
+		t.Fatalf("failed to create pending tx: %v", err)
+	}
+
+	conflictingTx, err := inits.CreateConflictingTestTransaction(pendingTx, voterKeyPair, 2)
+	if err != nil {
+		t.Fatalf("failed to create conflicting tx: %v", err)
+	}
+
+	for _, tx := range []*models.Transaction{confirmedTx, pendingTx} {
+		if err := pool.Add(tx); err != nil {
+			t.Fatalf("failed to add tx %x: %v", tx.Id, err)
+		}
+	}
+
+	block, err := inits.CreateTestBlock(inits.TestBlockRepository.GetActiveChainTipId(), []*models.Transaction{confirmedTx, conflictingTx})
+	if err != nil {
+		t.Fatalf("failed to create block: %v", err)
+	}
+
+	if err := inits.TestBlockRepository.InsertIfNotExists(block); err != nil {
+		t.Fatalf("failed to insert block: %v", err)
+	}
+
+	reasons := make(map[string]string)
+	for range 2 {
+		select {
+		case event := <-removed:
+			reasons[string(event.Transaction.Id)] = event.Reason
+		case <-time.After(2 * time.Second):
+			t.Fatalf("timed out waiting for removal events")
+		}
+	}
+
+	if reasons[string(confirmedTx.Id)] != string(mempool.Confirmed) || reasons[string(pendingTx.Id)] != string(mempool.Conflicted) {
+		t.Fatalf("wrong removal reasons: %v", reasons)
+	}
+
+	if pool.Count() != 0 {
+		t.Fatalf("mempool still has %d transactions", pool.Count())
+	}
+}
+
+func TestGetBlockCandidatesSkipsVotedVoters(t *testing.T) {
+	inits.ResetTestDatabase()
+	govKeyPair, err := inits.GenerateTestGovernmentKeyPair()
+	if err != nil {
+		t.Fatalf("failed to generate government key pair: %v", err)
+	}
+
+	//not subscribed to the chain updates of the test block repository, so voted voters stay in the mempool
+	eventBus := events.NewEventBusImpl()
+	t.Cleanup(eventBus.Close)
+
+	pool := mempool.NewMempoolImpl(inits.TestTransactionRepository, inits.TestConflictingVoteRepository, eventBus, inits.TestConfig.GovernmentConfig.PublicKey, &inits.TestConfig.MempoolConfig)
+	t.Cleanup(pool.Stop)
+
+	votedTx, _, err := inits.CreateTestTransaction(govKeyPair)
+	if err != nil {
+		t.Fatalf("failed to create voted tx: %v", err)
+	}
+
+	pendingTx, _, err := inits.CreateTestTransaction(govKeyPair)
+	if err != nil {
+		t.Fatalf("failed to create pending tx: %v", err)
+	}
+
+	for _, tx := range []*models.Transaction{votedTx, pendingTx} {
+		if err := pool.Add(tx); err != nil {
+			t.Fatalf("failed to add tx %x: %v", tx.Id, err)
+		}
+	}
+
+	block, err := inits.CreateTestBlock(inits.TestBlockRepository.GetActiveChainTipId(), []*models.Transaction{votedTx})
+	if err != nil {
+		t.Fatalf("failed to create block: %v", err)
+	}
+
+	if err := inits.TestBlockRepository.InsertIfNotExists(block); err != nil {
+		t.Fatalf("failed to insert block: %v", err)
+	}
+
+	candidates, err := pool.GetBlockCandidates(10, inits.TestConfig.MinerConfig.MaxBlockSize)
+	if err != nil {
+		t.Fatalf("failed to get block candidates: %v", err)
+	}
+
+	if len(candidates) != 1 || !bytes.Equal(candidates[0].Id, pendingTx.Id) {
+		t.Fatalf("expected only the pending tx, got %d candidates", len(candidates))
+	}
+}
+
+func TestGetBlockCandidatesLimits(t *testing.T) {
+	inits.ResetTestDatabase()
+	govKeyPair, err := inits.GenerateTestGovernmentKeyPair()
+	if err != nil {
+		t.Fatalf("failed to generate government key pair: %v", err)
+	}
+
+	pool := inits.NewTestMempool()
+	t.Cleanup(pool.Stop)
+
+	txs := make([]*models.Transaction, 30)
+	for i := range txs {
+		txs[i], _, err = inits.CreateTestTransaction(govKeyPair)
+		if err != nil {
+			t.Fatalf("failed to create tx %d: %v", i, err)
+		}
+
+		if err := pool.Add(txs[i]); err != nil {
+			t.Fatalf("failed to add tx %d: %v", i, err)
+		}
+	}
+
+	candidates, err := pool.GetBlockCandidates(20, inits.TestConfig.MinerConfig.MaxBlockSize)
+	if err != nil {
+		t.Fatalf("failed to get block candidates: %v", err)
+	}
+
+	if len(candidates) != 20 {
+		t.Fatalf("expected 20 candidates, got %d", len(candidates))
+	}
+
+	for i, candidate := range candidates {
+		if !bytes.Equal(candidate.Id, txs[i].Id) {
+			t.Fatalf("candidate %d isn't the tx added %d", i, i)
+		}
+	}
+
+	maxSize := 0
+	for _, tx := range txs[:5] {
+		maxSize += models.TransactionSizeInBlock(tx)
+	}
+
+	candidates, err = pool.GetBlockCandidates(len(txs), maxSize)
+	if err != nil {
+		t.Fatalf("failed to get block candidates: %v", err)
+	}
+
+	size := 0
+	for _, candidate := range candidates {
+		size += models.TransactionSizeInBlock(candidate)
+	}
+
+	if len(candidates) < 4 || size > maxSize {
+		t.Fatalf("expected candidates to fill up to %d bytes, got %d candidates of %d bytes", maxSize, len(candidates), size)
+	}
+}
+
+func TestGetBlockCandidatesPagesPastVotedVoters(t *testing.T) {
+	inits.ResetTestDatabase()
+	govKeyPair, err := inits.GenerateTestGovernmentKeyPair()
+	if err != nil {
+		t.Fatalf("failed to generate government key pair: %v", err)
+	}
+
+	//not subscribed to the chain updates of the test block repository, so voted voters stay in the mempool
+	eventBus := events.NewEventBusImpl()
+	t.Cleanup(eventBus.Close)
+
+	pool := mempool.NewMempoolImpl(inits.TestTransactionRepository, inits.TestConflictingVoteRepository, eventBus, inits.TestConfig.GovernmentConfig.PublicKey, &inits.TestConfig.MempoolConfig)
+	t.Cleanup(pool.Stop)
+
+	txs := make([]*models.Transaction, 25)
+	for i := range txs {
+		txs[i], _, err = inits.CreateTestTransaction(govKeyPair)
+		if err != nil {
+			t.Fatalf("failed to create tx %d: %v", i, err)
+		}
+
+		if err := pool.Add(txs[i]); err != nil {
+			t.Fatalf("failed to add tx %d: %v", i, err)
+		}
+	}
+
+	//the first two pages are voted, so the candidates come from the pages after them
+	block, err := inits.CreateTestBlock(inits.TestBlockRepository.GetActiveChainTipId(), txs[:12])
+	if err != nil {
+		t.Fatalf("failed to create block: %v", err)
+	}
+
+	if err := inits.TestBlockRepository.InsertIfNotExists(block); err != nil {
+		t.Fatalf("failed to insert block: %v", err)
+	}
+
+	candidates, err := pool.GetBlockCandidates(6, inits.TestConfig.MinerConfig.MaxBlockSize)
+	if err != nil {
+		t.Fatalf("failed to get block candidates: %v", err)
+	}
+
+	if len(candidates) != 6 {
+		t.Fatalf("expected 6 candidates, got %d", len(candidates))
+	}
+
+	for i, candidate := range candidates {
+		if !bytes.Equal(candidate.Id, txs[12+i].Id) {
+			t.Fatalf("candidate %d isn't the tx added %d", i, 12+i)
+		}
+	}
+}
+
+// blockingTransactionRepository holds the active chain check of the first transaction added until it is released
+type blockingTransactionRepository struct {
+	repositories.TransactionRepository
+	checking chan struct{}
+	release  chan struct{}
+	once     sync.Once
+}
+
+func (repo *blockingTransactionRepository) TransactionValidInActiveChain(transaction *models.Transaction) (bool, error) {
+	repo.once.Do(func() {
+		close(repo.checking)
+		<-repo.release
+	})
+
+	return repo.TransactionRepository.TransactionValidInActiveChain(transaction)
+}
+
+func TestAddChecksActiveChainWithoutLock(t *testing.T) {
+	inits.ResetTestDatabase()
+	govKeyPair, err := inits.GenerateTestGovernmentKeyPair()
+	if err != nil {
+		t.Fatalf("failed to generate government key pair: %v", err)
+	}
+
+	transactionRepository := &blockingTransactionRepository{
+		TransactionRepository: inits.TestTransactionRepository,
+		checking:              make(chan struct{}),
+		release:               make(chan struct{}),
+	}
+
+	pool := mempool.NewMempoolImpl(transactionRepository, inits.TestConflictingVoteRepository, inits.TestEventBus, inits.TestConfig.GovernmentConfig.PublicKey, &inits.TestConfig.MempoolConfig)
+	t.Cleanup(pool.Stop)
+
+	tx, _, err := inits.CreateTestTransaction(govKeyPair)
+	if err != nil {
+		t.Fatalf("failed to create tx: %v", err)
+	}
+
+	added := make(chan error, 1)
+	go func() {
+		added <- pool.Add(tx)
+	}()
+	<-transactionRepository.checking
+
+	counted := make(chan int, 1)
+	go func() {
+		counted <- pool.Count()
+	}()
+
+	select {
+	case count := <-counted:
+		if count != 0 {
+			t.Fatalf("transaction is in the mempool before its check finished")
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("mempool is locked while checking the active chain")
+	}
+
+	close(transactionRepository.release)
+	if err := <-added; err != nil {
+		t.Fatalf("failed to add tx: %v", err)
+	}
+
+	if !pool.Contains(tx.Id) {
+		t.Fatalf("tx wasn't added after its check")
+	}
+}
+
+func TestSaveAndLoadFromFile(t *testing.T) {
+	inits.ResetTestDatabase()
+	govKeyPair, err := inits.GenerateTestGovernmentKeyPair()
+	if err != nil {
+		t.Fatalf("failed to generate government key pair: %v", err)
+	}
+
+	pool := inits.NewTestMempool()
+	t.Cleanup(pool.Stop)
+
+	var txs []*models.Transaction
+	for range 3 {
+		tx, _, err := inits.CreateTestTransaction(govKeyPair)
+		if err != nil {
+			t.Fatalf("failed to create test tx: %v", err)
+		}
+
+		if err := pool.Add(tx); err != nil {
+			t.Fatalf("failed to add tx: %v", err)
+		}
+
+		txs = append(txs, tx)
+	}
+
+	path := filepath.Join(t.TempDir(), "mempool", "mempool.dat")
+	if err := pool.SaveToFile(path); err != nil {
+		t.Fatalf("failed to save mempool: %v", err)
+	}
+
+	loadedPool := inits.NewTestMempool()
+	t.Cleanup(loadedPool.Stop)
+
+	if err := loadedPool.LoadFromFile(path); err != nil {
+		t.Fatalf("failed to load mempool: %v", err)
+	}
+
+	loaded := loadedPool.GetTransactions(10)
+	if len(loaded) != len(txs) {
+		t.Fatalf("loaded %d transactions, expected %d", len(loaded), len(txs))
+	}
+
+	for idx, tx := range txs {
+		if !bytes.Equal(loaded[idx].AsBytes(), tx.AsBytes()) {
+			t.Fatalf("loaded transaction %d differs or is out of order", idx)
+		}
+	}
+
+	if err := loadedPool.LoadFromFile(filepath.Join(t.TempDir(), "missing.dat")); err != nil {
+		t.Fatalf("missing mempool file should load as empty: %v", err)
+	}
+
+	//an unset mempool file disables persistence
+	if err := pool.SaveToFile(""); err != nil {
+		t.Fatalf("saving without a mempool file failed: %v", err)
+	}
+
+	if err := loadedPool.LoadFromFile(""); err != nil {
+		t.Fatalf("loading without a mempool file failed: %v", err)
+	}
+}
+
+func TestLowestIdConflictPolicy(t *testing.T) {
+	inits.ResetTestDatabase()
+	govKeyPair, err := inits.GenerateTestGovernmentKeyPair()
+	if err != nil {
+		t.Fatalf("failed to generate government key pair: %v", err)
+	}
+
+	mempoolConfig := inits.TestConfig.MempoolConfig
+	mempoolConfig.ConflictPolicy = config.LowestIdPolicy
+	pool := mempool.NewMempoolImpl(inits.TestTransactionRepository, inits.TestConflictingVoteRepository, inits.TestEventBus, inits.TestConfig.GovernmentConfig.PublicKey, &mempoolConfig)
+	t.Cleanup(pool.Stop)
+
+	tx, voterKeyPair, err := inits.CreateTestTransaction(govKeyPair)
+	if err != nil {
+		t.Fatalf("failed to create test tx: %v", err)
+	}
+
+	conflictingTx, err := inits.CreateConflictingTestTransaction(tx, voterKeyPair, 2)
+	if err != nil {
+		t.Fatalf("failed to create conflicting tx: %v", err)
+	}
+
+	lowTx, highTx := tx, conflictingTx
+	if bytes.Compare(highTx.Id, lowTx.Id) < 0 {
+		lowTx, highTx = highTx, lowTx
+	}
+
+	if err := pool.Add(highTx); err != nil {
+		t.Fatalf("failed to add high tx: %v", err)
+	}
+
+	if err := pool.Add(lowTx); err != nil {
+		t.Fatalf("lower id should replace the pending vote: %v", err)
+	}
+
+	if err := pool.Add(highTx); !errors.Is(err, mempool.ErrConflict) {
+		t.Fatalf("expected conflict for higher id, got %v", err)
+	}
+
+	kept, exists := pool.GetByVoterPublicKey(tx.VoterPublicKey)
+	if !exists || !bytes.Equal(kept.Id, lowTx.Id) || pool.Count() != 1 {
+		t.Fatalf("mempool should only keep the lower id vote")
+	}
+}
+
+func TestConflictingVotesAreRecorded(t *testing.T) {
+	inits.ResetTestDatabase()
+	govKeyPair, err := inits.GenerateTestGovernmentKeyPair()
+	if err != nil {
+		t.Fatalf("failed to generate government key pair: %v", err)
+	}
+
+	pool := inits.NewTestMempool()
+	t.Cleanup(pool.Stop)
+
+	pendingTx, pendingVoterKeyPair, err := inits.CreateTestTransaction(govKeyPair)
+	if err != nil {
+		t.Fatalf("failed to create pending tx: %v", err)
+	}
+
+	conflictingPendingTx, err := inits.CreateConflictingTestTransaction(pendingTx, pendingVoterKeyPair, 2)
+	if err != nil {
+		t.Fatalf("failed to create conflicting pending tx: %v", err)
+	}
+
+	if err := pool.Add(pendingTx); err != nil {
+		t.Fatalf("failed to add pending tx: %v", err)
+	}
+
+	//announced by two peers, recorded once
+	for range 2 {
+		if err := pool.Add(conflictingPendingTx); !errors.Is(err, mempool.ErrConflict) {
+			t.Fatalf("expected conflict, got %v", err)
+		}
+	}
+
+	confirmedTx, confirmedVoterKeyPair, err := inits.CreateTestTransaction(govKeyPair)
+	if err != nil {
+		t.Fatalf("failed to create confirmed tx: %v", err)
+	}
+
+	block, err := inits.CreateTestBlock(inits.TestBlockRepository.GetActiveChainTipId(), []*models.Transaction{confirmedTx})
+	if err != nil {
+		t.Fatalf("failed to create block: %v", err)
+	}
+
+	if err := inits.TestBlockRepository.InsertIfNotExists(block); err != nil {
+		t.Fatalf("failed to insert block: %v", err)
+	}
+
+	//the mined vote itself is not a conflict
+	if err := pool.Add(confirmedTx); !errors.Is(err, mempool.ErrAlreadyVoted) {
+		t.Fatalf("expected already voted, got %v", err)
+	}
+
+	conflictingConfirmedTx, err := inits.CreateConflictingTestTransaction(confirmedTx, confirmedVoterKeyPair, 2)
+	if err != nil {
+		t.Fatalf("failed to create conflicting confirmed tx: %v", err)
+	}
+
+	if err := pool.Add(conflictingConfirmedTx); !errors.Is(err, mempool.ErrAlreadyVoted) {
+		t.Fatalf("expected already voted, got %v", err)
+	}
+
+	conflictingVotes, err := inits.TestConflictingVoteRepository.GetAllConflictingVotes()
+	if err != nil {
+		t.Fatalf("failed to get conflicting votes: %v", err)
+	}
+
+	if len(conflictingVotes) != 2 {
+		t.Fatalf("recorded %d conflicting votes, expected 2", len(conflictingVotes))
+	}
+
+	if !bytes.Equal(conflictingVotes[0].Transaction.Id, conflictingPendingTx.Id) || !bytes.Equal(conflictingVotes[0].ConflictingTransaction.Id, pendingTx.Id) || conflictingVotes[0].Source != models.ConflictInMempool {
+		t.Fatalf("wrong evidence recorded for mempool conflict")
+	}
+
+	if !bytes.Equal(conflictingVotes[1].Transaction.Id, conflictingConfirmedTx.Id) || !bytes.Equal(conflictingVotes[1].ConflictingTransaction.Id, confirmedTx.Id) || conflictingVotes[1].Source != models.ConflictInActiveChain {
+		t.Fatalf("wrong evidence recorded for active chain conflict")
+	}
+}
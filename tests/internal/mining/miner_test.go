@@ -2,10 +2,14 @@ package mining_test
 
 import (
 	"bytes"
+	"errors"
 	"os"
 	"testing"
 	"time"
 
+	consensus "github.com/nivschuman/VotingBlockchain/internal/consensus"
+	ppk "github.com/nivschuman/VotingBlockchain/internal/crypto/ppk"
+	repositories "github.com/nivschuman/VotingBlockchain/internal/database/repositories"
 	mining "github.com/nivschuman/VotingBlockchain/internal/mining"
 	data_models "github.com/nivschuman/VotingBlockchain/internal/models"
 	inits "github.com/nivschuman/VotingBlockchain/tests/init"
@@ -39,9 +43,12 @@ func TestCreateBlockTemplate(t *testing.T) {
 		t.Fatalf("failed to create test tx1: %v", err)
 	}
 
-	err = inits.TestTransactionRepository.InsertIfNotExists(tx1)
+	mempool := inits.NewTestMempool()
+	t.Cleanup(mempool.Stop)
+
+	err = mempool.Add(tx1)
 	if err != nil {
-		t.Fatalf("failed to insert test tx1: %v", err)
+		t.Fatalf("failed to add test tx1 to mempool: %v", err)
 	}
 
 	tx2, _, err := inits.CreateTestTransaction(govKeyPair)
@@ -49,17 +56,19 @@ func TestCreateBlockTemplate(t *testing.T) {
 		t.Fatalf("failed to create test tx2: %v", err)
 	}
 
-	err = inits.TestTransactionRepository.InsertIfNotExists(tx2)
+	err = mempool.Add(tx2)
 	if err != nil {
-		t.Fatalf("failed to insert test tx2: %v", err)
+		t.Fatalf("failed to add test tx2 to mempool: %v", err)
 	}
 
 	getNetworkTime := func() int64 { return time.Now().Unix() }
 	minerProps := mining.MinerProperties{
-		NodeVersion:    inits.TestConfig.NodeConfig.Version,
-		MinerPublicKey: inits.TestConfig.GovernmentConfig.PublicKey,
+		MinerKeyPair:         inits.TestMinerKeyPair,
+		MaxBlockSize:         inits.TestConfig.MinerConfig.MaxBlockSize,
+		MaxBlockTransactions: inits.TestConfig.MinerConfig.MaxBlockTransactions,
+		Workers:              inits.TestConfig.MinerConfig.Workers,
 	}
-	miner := mining.NewMinerImpl(getNetworkTime, inits.TestBlockRepository, inits.TestTransactionRepository, minerProps)
+	miner := mining.NewMinerImpl(getNetworkTime, inits.TestBlockRepository, mempool, inits.TestEventBus, inits.TestChainParams, minerProps)
 
 	template, err := miner.CreateBlockTemplate()
 	if err != nil {
@@ -84,6 +93,51 @@ func TestCreateBlockTemplate(t *testing.T) {
 	}
 }
 
+func TestCreateBlockTemplateRespectsElectionRules(t *testing.T) {
+	inits.ResetTestDatabase()
+
+	govKeyPair, _, _, err := inits.CreateTestData(2, 2)
+	if err != nil {
+		t.Fatalf("failed to setup test data: %v", err)
+	}
+
+	mempool := inits.NewTestMempool()
+	t.Cleanup(mempool.Stop)
+
+	for i := range 5 {
+		tx, _, err := inits.CreateTestTransaction(govKeyPair)
+		if err != nil {
+			t.Fatalf("failed to create test tx %d: %v", i, err)
+		}
+
+		err = mempool.Add(tx)
+		if err != nil {
+			t.Fatalf("failed to add test tx %d to mempool: %v", i, err)
+		}
+	}
+
+	chainParams := *inits.TestChainParams
+	chainParams.Election.MaxBlockVotes = 3
+
+	getNetworkTime := func() int64 { return time.Now().Unix() }
+	minerProps := mining.MinerProperties{
+		MinerKeyPair:         inits.TestMinerKeyPair,
+		MaxBlockSize:         inits.TestConfig.MinerConfig.MaxBlockSize,
+		MaxBlockTransactions: inits.TestConfig.MinerConfig.MaxBlockTransactions,
+		Workers:              inits.TestConfig.MinerConfig.Workers,
+	}
+	miner := mining.NewMinerImpl(getNetworkTime, inits.TestBlockRepository, mempool, inits.TestEventBus, &chainParams, minerProps)
+
+	template, err := miner.CreateBlockTemplate()
+	if err != nil {
+		t.Fatalf("failed to create block template: %v", err)
+	}
+
+	if len(template.Transactions) != 3 {
+		t.Fatalf("template has %d transactions, election rules allow 3", len(template.Transactions))
+	}
+}
+
 func TestMineBlockTemplate(t *testing.T) {
 	inits.ResetTestDatabase()
 
@@ -98,7 +152,7 @@ func TestMineBlockTemplate(t *testing.T) {
 	}
 
 	lastBlock := blocks[len(blocks)-1]
-	template, err := inits.CreateTestBlock(lastBlock.Header.Id, []*data_models.Transaction{tx1})
+	template, err := inits.CreateTestBlockMinedBy(lastBlock.Header.Id, []*data_models.Transaction{tx1}, inits.TestMinerKeyPair)
 	template.Header.Nonce = 0
 
 	if err != nil {
@@ -107,10 +161,15 @@ func TestMineBlockTemplate(t *testing.T) {
 
 	getNetworkTime := func() int64 { return time.Now().Unix() }
 	minerProps := mining.MinerProperties{
-		NodeVersion:    inits.TestConfig.NodeConfig.Version,
-		MinerPublicKey: inits.TestConfig.GovernmentConfig.PublicKey,
+		MinerKeyPair:         inits.TestMinerKeyPair,
+		MaxBlockSize:         inits.TestConfig.MinerConfig.MaxBlockSize,
+		MaxBlockTransactions: inits.TestConfig.MinerConfig.MaxBlockTransactions,
+		Workers:              inits.TestConfig.MinerConfig.Workers,
 	}
-	miner := mining.NewMinerImpl(getNetworkTime, inits.TestBlockRepository, inits.TestTransactionRepository, minerProps)
+	mempool := inits.NewTestMempool()
+	t.Cleanup(mempool.Stop)
+
+	miner := mining.NewMinerImpl(getNetworkTime, inits.TestBlockRepository, mempool, inits.TestEventBus, inits.TestChainParams, minerProps)
 
 	checkBlock := func(block *data_models.Block) {
 		t.Logf("mined block nonce is %d", block.Header.Nonce)
@@ -122,12 +181,72 @@ func TestMineBlockTemplate(t *testing.T) {
 		if !bytes.Equal(lastBlock.Header.Id, block.Header.PreviousBlockId) {
 			t.Fatalf("mined block previous block is wrong")
 		}
+
+		if valid, err := block.Header.MinerSignatureIsValid(); err != nil || !valid {
+			t.Fatalf("mined block isn't signed by the miner")
+		}
 	}
 
 	miner.AddHandler(checkBlock)
 	miner.MineBlockTemplate(template)
 }
 
+func TestMinerRestartsOnChainTipChange(t *testing.T) {
+	inits.ResetTestDatabase()
+
+	_, blocks, _, err := inits.CreateTestData(1, 0)
+	if err != nil {
+		t.Fatalf("failed to setup test data: %v", err)
+	}
+
+	lastBlock := blocks[len(blocks)-1]
+
+	getNetworkTime := func() int64 { return time.Now().Unix() }
+	minerProps := mining.MinerProperties{
+		MinerKeyPair:         inits.TestMinerKeyPair,
+		MaxBlockSize:         inits.TestConfig.MinerConfig.MaxBlockSize,
+		MaxBlockTransactions: inits.TestConfig.MinerConfig.MaxBlockTransactions,
+		Workers:              inits.TestConfig.MinerConfig.Workers,
+	}
+	mempool := inits.NewTestMempool()
+	t.Cleanup(mempool.Stop)
+
+	miner := mining.NewMinerImpl(getNetworkTime, inits.TestBlockRepository, mempool, inits.TestEventBus, inits.TestChainParams, minerProps)
+	t.Cleanup(miner.Stop)
+
+	template, err := miner.CreateBlockTemplate()
+	if err != nil {
+		t.Fatalf("failed to create block template: %v", err)
+	}
+	template.Header.NBits = 0x03000001 //unreachable target
+
+	miner.AddHandler(func(block *data_models.Block) {
+		t.Errorf("miner mined block on stale chain tip")
+	})
+
+	done := make(chan struct{})
+	go func() {
+		miner.MineBlockTemplate(template)
+		close(done)
+	}()
+
+	newTip, err := inits.CreateTestBlock(lastBlock.Header.Id, []*data_models.Transaction{})
+	if err != nil {
+		t.Fatalf("failed to create test block: %v", err)
+	}
+
+	err = inits.TestBlockRepository.InsertIfNotExists(newTip)
+	if err != nil {
+		t.Fatalf("failed to insert test block: %v", err)
+	}
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("miner did not restart after chain tip changed")
+	}
+}
+
 func BenchmarkMineBlockTemplate(b *testing.B) {
 	inits.ResetTestDatabase()
 
@@ -145,12 +264,17 @@ func BenchmarkMineBlockTemplate(b *testing.B) {
 
 	getNetworkTime := func() int64 { return time.Now().Unix() }
 	minerProps := mining.MinerProperties{
-		NodeVersion:    inits.TestConfig.NodeConfig.Version,
-		MinerPublicKey: inits.TestConfig.GovernmentConfig.PublicKey,
+		MinerKeyPair:         inits.TestMinerKeyPair,
+		MaxBlockSize:         inits.TestConfig.MinerConfig.MaxBlockSize,
+		MaxBlockTransactions: inits.TestConfig.MinerConfig.MaxBlockTransactions,
+		Workers:              inits.TestConfig.MinerConfig.Workers,
 	}
-	miner := mining.NewMinerImpl(getNetworkTime, inits.TestBlockRepository, inits.TestTransactionRepository, minerProps)
+	mempool := inits.NewTestMempool()
+	b.Cleanup(mempool.Stop)
+
+	miner := mining.NewMinerImpl(getNetworkTime, inits.TestBlockRepository, mempool, inits.TestEventBus, inits.TestChainParams, minerProps)
 
-	template, err := inits.CreateTestBlock(lastBlock.Header.Id, []*data_models.Transaction{tx1})
+	template, err := inits.CreateTestBlockMinedBy(lastBlock.Header.Id, []*data_models.Transaction{tx1}, inits.TestMinerKeyPair)
 	if err != nil {
 		b.Fatalf("failed to create test block: %v", err)
 	}
@@ -164,3 +288,343 @@ func BenchmarkMineBlockTemplate(b *testing.B) {
 		miner.MineBlockTemplate(template)
 	}
 }
+
+func TestGenerate(t *testing.T) {
+	inits.ResetTestDatabase()
+
+	_, blocks, _, err := inits.CreateTestData(1, 0)
+	if err != nil {
+		t.Fatalf("failed to setup test data: %v", err)
+	}
+
+	minerKeyPair, err := ppk.GenerateKeyPair()
+	if err != nil {
+		t.Fatalf("failed to generate miner key pair: %v", err)
+	}
+
+	getNetworkTime := func() int64 { return time.Now().Unix() }
+	minerProps := mining.MinerProperties{
+		MinerKeyPair:         minerKeyPair,
+		MaxBlockSize:         inits.TestConfig.MinerConfig.MaxBlockSize,
+		MaxBlockTransactions: inits.TestConfig.MinerConfig.MaxBlockTransactions,
+		Workers:              inits.TestConfig.MinerConfig.Workers,
+	}
+	mempool := inits.NewTestMempool()
+	t.Cleanup(mempool.Stop)
+
+	miner := mining.NewOnDemandMiner(mining.NewMinerImpl(getNetworkTime, inits.TestBlockRepository, mempool, inits.TestEventBus, inits.TestChainParams, minerProps))
+	t.Cleanup(miner.Stop)
+
+	miner.AddHandler(func(block *data_models.Block) {
+		if err := inits.TestBlockRepository.InsertIfNotExists(block); err != nil {
+			t.Errorf("failed to insert generated block: %v", err)
+		}
+	})
+
+	generated, err := miner.Generate(3)
+	if err != nil {
+		t.Fatalf("failed to generate blocks: %v", err)
+	}
+
+	if len(generated) != 3 {
+		t.Fatalf("generated %d blocks, expected 3", len(generated))
+	}
+
+	previousBlockId := blocks[len(blocks)-1].Header.Id
+	for i, block := range generated {
+		if !bytes.Equal(block.Header.PreviousBlockId, previousBlockId) {
+			t.Fatalf("generated block %d doesn't extend the previous one", i)
+		}
+
+		if !bytes.Equal(block.Header.MinerPublicKey, minerKeyPair.PublicKey.AsBytes()) {
+			t.Fatalf("generated block %d isn't mined to the given key", i)
+		}
+
+		if valid, err := block.Header.MinerSignatureIsValid(); err != nil || !valid {
+			t.Fatalf("generated block %d isn't signed by the miner", i)
+		}
+
+		previousBlockId = block.Header.Id
+	}
+
+	if !bytes.Equal(inits.TestBlockRepository.GetActiveChainTipId(), previousBlockId) {
+		t.Fatalf("last generated block isn't the active chain tip")
+	}
+}
+
+func TestGenerateRejectedBlock(t *testing.T) {
+	inits.ResetTestDatabase()
+
+	getNetworkTime := func() int64 { return time.Now().Unix() }
+	minerProps := mining.MinerProperties{
+		MinerKeyPair:         inits.TestMinerKeyPair,
+		MaxBlockSize:         inits.TestConfig.MinerConfig.MaxBlockSize,
+		MaxBlockTransactions: inits.TestConfig.MinerConfig.MaxBlockTransactions,
+		Workers:              inits.TestConfig.MinerConfig.Workers,
+	}
+	mempool := inits.NewTestMempool()
+	t.Cleanup(mempool.Stop)
+
+	miner := mining.NewMinerImpl(getNetworkTime, inits.TestBlockRepository, mempool, inits.TestEventBus, inits.TestChainParams, minerProps)
+	t.Cleanup(miner.Stop)
+
+	_, err := miner.Generate(1)
+	if err == nil {
+		t.Fatalf("block that no handler accepted was reported as generated")
+	}
+
+	_, err = mining.NewDisabledMiner().Generate(1)
+	if err == nil {
+		t.Fatalf("disabled miner generated blocks")
+	}
+}
+
+func TestMineBlockTemplateOfAnotherMiner(t *testing.T) {
+	inits.ResetTestDatabase()
+
+	_, blocks, _, err := inits.CreateTestData(1, 0)
+	if err != nil {
+		t.Fatalf("failed to setup test data: %v", err)
+	}
+
+	template, err := inits.CreateTestBlock(blocks[len(blocks)-1].Header.Id, []*data_models.Transaction{})
+	if err != nil {
+		t.Fatalf("failed to create test block: %v", err)
+	}
+	template.Header.Nonce = 0
+
+	getNetworkTime := func() int64 { return time.Now().Unix() }
+	minerProps := mining.MinerProperties{
+		MinerKeyPair:         inits.TestMinerKeyPair,
+		MaxBlockSize:         inits.TestConfig.MinerConfig.MaxBlockSize,
+		MaxBlockTransactions: inits.TestConfig.MinerConfig.MaxBlockTransactions,
+		Workers:              inits.TestConfig.MinerConfig.Workers,
+	}
+	mempool := inits.NewTestMempool()
+	t.Cleanup(mempool.Stop)
+
+	miner := mining.NewMinerImpl(getNetworkTime, inits.TestBlockRepository, mempool, inits.TestEventBus, inits.TestChainParams, minerProps)
+	t.Cleanup(miner.Stop)
+
+	miner.AddHandler(func(block *data_models.Block) {
+		t.Fatalf("block mined to another miner's key was signed and handled")
+	})
+	miner.MineBlockTemplate(template)
+}
+
+func TestMineBlockTemplateWithWorkers(t *testing.T) {
+	inits.ResetTestDatabase()
+
+	_, _, _, err := inits.CreateTestData(2, 2)
+	if err != nil {
+		t.Fatalf("failed to setup test data: %v", err)
+	}
+
+	mempool := inits.NewTestMempool()
+	t.Cleanup(mempool.Stop)
+
+	getNetworkTime := func() int64 { return time.Now().Unix() }
+	minerProps := mining.MinerProperties{
+		MinerKeyPair:         inits.TestMinerKeyPair,
+		MaxBlockSize:         inits.TestConfig.MinerConfig.MaxBlockSize,
+		MaxBlockTransactions: inits.TestConfig.MinerConfig.MaxBlockTransactions,
+		Workers:              4,
+	}
+	miner := mining.NewMinerImpl(getNetworkTime, inits.TestBlockRepository, mempool, inits.TestEventBus, inits.TestChainParams, minerProps)
+	t.Cleanup(miner.Stop)
+
+	template, err := miner.CreateBlockTemplate()
+	if err != nil {
+		t.Fatalf("failed to create block template: %v", err)
+	}
+
+	//harder than the test chain so the workers share some work
+	template.Header.NBits = 0x1f00ffff
+	miner.MineBlockTemplate(template)
+
+	if template.Header.Id == nil {
+		t.Fatalf("block template wasn't mined")
+	}
+
+	if !template.Header.IsHashBelowTarget() {
+		t.Fatalf("mined block hash isn't below target")
+	}
+
+	if !bytes.Equal(template.Header.Id, template.Header.GetHash()) {
+		t.Fatalf("mined block id doesn't match its header")
+	}
+
+	stats := miner.GetMiningStatistics()
+	if len(stats.WorkerHashesTried) != 4 {
+		t.Fatalf("expected statistics of 4 workers, got %d", len(stats.WorkerHashesTried))
+	}
+
+	totalHashesTried := int64(0)
+	for _, hashesTried := range stats.WorkerHashesTried {
+		totalHashesTried += hashesTried
+	}
+
+	if totalHashesTried != stats.CurrentBlockHashesTried {
+		t.Fatalf("worker hashes %d don't add up to current block hashes %d", totalHashesTried, stats.CurrentBlockHashesTried)
+	}
+
+	if stats.TotalBlocksMined != 1 {
+		t.Fatalf("expected 1 mined block, got %d", stats.TotalBlocksMined)
+	}
+}
+
+func TestGenerateProofOfAuthority(t *testing.T) {
+	inits.ClearTestDatabase()
+
+	authorityParams, err := inits.CreateTestAuthorityChainParams(0, 0, inits.TestMinerKeyPair)
+	if err != nil {
+		t.Fatalf("failed to create authority chain params: %v", err)
+	}
+
+	blockRepository := repositories.NewBlockRepositoryImpl(inits.TestDb, inits.TestEventBus, authorityParams)
+	if err := blockRepository.Initialize(); err != nil {
+		t.Fatalf("failed to initialize block repository: %v", err)
+	}
+
+	getNetworkTime := func() int64 { return time.Now().Unix() }
+	minerProps := mining.MinerProperties{
+		MinerKeyPair:         inits.TestMinerKeyPair,
+		MaxBlockSize:         inits.TestConfig.MinerConfig.MaxBlockSize,
+		MaxBlockTransactions: inits.TestConfig.MinerConfig.MaxBlockTransactions,
+		Workers:              inits.TestConfig.MinerConfig.Workers,
+	}
+	mempool := inits.NewTestMempool()
+	t.Cleanup(mempool.Stop)
+
+	miner := mining.NewMinerImpl(getNetworkTime, blockRepository, mempool, inits.TestEventBus, authorityParams, minerProps)
+	t.Cleanup(miner.Stop)
+
+	miner.AddHandler(func(block *data_models.Block) {
+		if err := blockRepository.InsertIfNotExists(block); err != nil {
+			t.Errorf("failed to insert sealed block: %v", err)
+		}
+	})
+
+	generated, err := miner.Generate(2)
+	if err != nil {
+		t.Fatalf("failed to generate blocks: %v", err)
+	}
+
+	for i, block := range generated {
+		if block.Header.NBits != 0 {
+			t.Fatalf("sealed block %d has NBits %d", i, block.Header.NBits)
+		}
+
+		if valid, err := block.Header.MinerSignatureIsValid(); err != nil || !valid {
+			t.Fatalf("sealed block %d isn't signed by the sealer", i)
+		}
+	}
+
+	if stats := miner.GetMiningStatistics(); stats.CurrentBlockHashesTried != 0 {
+		t.Fatalf("sealer tried %d hashes", stats.CurrentBlockHashesTried)
+	}
+}
+
+func TestGenerateWaitsForOtherSealers(t *testing.T) {
+	inits.ClearTestDatabase()
+
+	otherSealerKeyPair, err := ppk.GenerateKeyPair()
+	if err != nil {
+		t.Fatalf("failed to generate sealer key pair: %v", err)
+	}
+
+	//height 1 is the miner's turn, height 2 the other sealer's
+	authorityParams, err := inits.CreateTestAuthorityChainParams(0, 0, otherSealerKeyPair, inits.TestMinerKeyPair)
+	if err != nil {
+		t.Fatalf("failed to create authority chain params: %v", err)
+	}
+
+	blockRepository := repositories.NewBlockRepositoryImpl(inits.TestDb, inits.TestEventBus, authorityParams)
+	if err := blockRepository.Initialize(); err != nil {
+		t.Fatalf("failed to initialize block repository: %v", err)
+	}
+
+	getNetworkTime := func() int64 { return time.Now().Unix() }
+	minerProps := mining.MinerProperties{
+		MinerKeyPair:         inits.TestMinerKeyPair,
+		MaxBlockSize:         inits.TestConfig.MinerConfig.MaxBlockSize,
+		MaxBlockTransactions: inits.TestConfig.MinerConfig.MaxBlockTransactions,
+		Workers:              inits.TestConfig.MinerConfig.Workers,
+	}
+	mempool := inits.NewTestMempool()
+	t.Cleanup(mempool.Stop)
+
+	miner := mining.NewMinerImpl(getNetworkTime, blockRepository, mempool, inits.TestEventBus, authorityParams, minerProps)
+	t.Cleanup(miner.Stop)
+
+	miner.AddHandler(func(block *data_models.Block) {
+		if err := blockRepository.InsertIfNotExists(block); err != nil {
+			t.Errorf("failed to insert sealed block: %v", err)
+		}
+	})
+
+	//one of two sealers can't seal the other's turn after its own
+	generated, err := miner.Generate(2)
+	if !errors.Is(err, consensus.ErrRecentlySealed) || len(generated) != 1 {
+		t.Fatalf("generated %d blocks, expected 1 and %v, got %v", len(generated), consensus.ErrRecentlySealed, err)
+	}
+}
+
+func TestMinerThatIsNotSealer(t *testing.T) {
+	inits.ResetTestDatabase()
+
+	sealerKeyPair, err := ppk.GenerateKeyPair()
+	if err != nil {
+		t.Fatalf("failed to generate sealer key pair: %v", err)
+	}
+
+	authorityParams, err := inits.CreateTestAuthorityChainParams(0, 0, sealerKeyPair)
+	if err != nil {
+		t.Fatalf("failed to create authority chain params: %v", err)
+	}
+
+	getNetworkTime := func() int64 { return time.Now().Unix() }
+	minerProps := mining.MinerProperties{
+		MinerKeyPair:         inits.TestMinerKeyPair,
+		MaxBlockSize:         inits.TestConfig.MinerConfig.MaxBlockSize,
+		MaxBlockTransactions: inits.TestConfig.MinerConfig.MaxBlockTransactions,
+		Workers:              inits.TestConfig.MinerConfig.Workers,
+	}
+	mempool := inits.NewTestMempool()
+	t.Cleanup(mempool.Stop)
+
+	miner := mining.NewMinerImpl(getNetworkTime, inits.TestBlockRepository, mempool, inits.TestEventBus, authorityParams, minerProps)
+	miner.AddHandler(func(block *data_models.Block) {
+		t.Errorf("block of a miner that isn't a sealer was handled")
+	})
+
+	//generating fails instead of waiting for a sealer to extend the chain
+	if _, err := miner.Generate(1); !errors.Is(err, consensus.ErrNotSealer) {
+		t.Fatalf("expected %v from generating, got %v", consensus.ErrNotSealer, err)
+	}
+
+	template, err := miner.CreateBlockTemplate()
+	if err != nil {
+		t.Fatalf("failed to create block template: %v", err)
+	}
+
+	//the miner waits for the chain tip to change, stopping it ends the wait
+	done := make(chan struct{})
+	go func() {
+		miner.MineBlockTemplate(template)
+		close(done)
+	}()
+
+	time.Sleep(100 * time.Millisecond)
+	miner.Stop()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("miner that isn't a sealer didn't stop")
+	}
+
+	if template.Header.Id != nil {
+		t.Fatalf("miner that isn't a sealer sealed a block")
+	}
+}
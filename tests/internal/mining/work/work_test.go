@@ -0,0 +1,202 @@
+package mining_work_test
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	mempool "github.com/nivschuman/VotingBlockchain/internal/mempool"
+	mining "github.com/nivschuman/VotingBlockchain/internal/mining"
+	work "github.com/nivschuman/VotingBlockchain/internal/mining/work"
+	data_models "github.com/nivschuman/VotingBlockchain/internal/models"
+	inits "github.com/nivschuman/VotingBlockchain/tests/init"
+)
+
+func TestMain(m *testing.M) {
+	// === BEFORE ALL TESTS ===
+	inits.SetupTests()
+	inits.SetupTestsDatabase()
+
+	// Run the tests
+	code := m.Run()
+
+	// === AFTER ALL TESTS ===
+	inits.CloseTestDatabase()
+
+	// Exit with the right code
+	os.Exit(code)
+}
+
+func TestGetBlockTemplateAndSubmitBlock(t *testing.T) {
+	inits.ResetTestDatabase()
+
+	govKeyPair, blocks, _, err := inits.CreateTestData(2, 2)
+	if err != nil {
+		t.Fatalf("failed to setup test data: %v", err)
+	}
+
+	mempool := inits.NewTestMempool()
+	t.Cleanup(mempool.Stop)
+
+	tx, _, err := inits.CreateTestTransaction(govKeyPair)
+	if err != nil {
+		t.Fatalf("failed to create test tx: %v", err)
+	}
+
+	if err := mempool.Add(tx); err != nil {
+		t.Fatalf("failed to add test tx to mempool: %v", err)
+	}
+
+	submitBlock := func(block *data_models.Block) error {
+		if !block.Header.IsHashBelowTarget() {
+			return errors.New("hash does not satisfy target")
+		}
+		return inits.TestBlockRepository.InsertIfNotExists(block)
+	}
+
+	client := startWorkServer(t, mempool, submitBlock)
+
+	minerKeyPair, err := inits.GenerateTestGovernmentKeyPair()
+	if err != nil {
+		t.Fatalf("failed to generate miner key pair: %v", err)
+	}
+
+	template, err := client.GetBlockTemplate(minerKeyPair.PublicKey.AsBytes())
+	if err != nil {
+		t.Fatalf("failed to get block template: %v", err)
+	}
+
+	lastBlock := blocks[len(blocks)-1]
+	if template.PreviousBlockId != hex.EncodeToString(lastBlock.Header.Id) {
+		t.Fatalf("template isn't on top of the active chain, previous block %s", template.PreviousBlockId)
+	}
+
+	if template.Height != uint64(len(blocks)+1) {
+		t.Fatalf("expected template height %d, got %d", len(blocks)+1, template.Height)
+	}
+
+	block, err := template.GetBlock()
+	if err != nil {
+		t.Fatalf("failed to decode template block: %v", err)
+	}
+
+	if !bytes.Equal(block.Header.MinerPublicKey, minerKeyPair.PublicKey.AsBytes()) {
+		t.Fatalf("template isn't mined to the requested miner key")
+	}
+
+	if len(block.Transactions) != 1 || !bytes.Equal(block.Transactions[0].Id, tx.Id) {
+		t.Fatalf("template doesn't contain the mempool transaction")
+	}
+
+	if block.Header.Timestamp < template.MinTimestamp {
+		t.Fatalf("template timestamp %d is below the minimum %d", block.Header.Timestamp, template.MinTimestamp)
+	}
+
+	for !block.Header.IsHashBelowTarget() {
+		block.Header.Nonce++
+	}
+	block.Header.SetId()
+
+	if err := block.Header.Sign(minerKeyPair.PrivateKey); err != nil {
+		t.Fatalf("failed to sign block: %v", err)
+	}
+
+	response, err := client.SubmitBlock(block)
+	if err != nil {
+		t.Fatalf("failed to submit block: %v", err)
+	}
+
+	if !response.Accepted {
+		t.Fatalf("submitted block was rejected: %s", response.Reason)
+	}
+
+	haveBlock, err := inits.TestBlockRepository.HaveBlock(block.Header.Id)
+	if err != nil {
+		t.Fatalf("failed to check for submitted block: %v", err)
+	}
+
+	if !haveBlock {
+		t.Fatalf("submitted block wasn't inserted")
+	}
+
+	credits, err := client.GetMinerCredits(minerKeyPair.PublicKey.AsBytes())
+	if err != nil {
+		t.Fatalf("failed to get miner credits: %v", err)
+	}
+
+	if credits.Blocks != 1 || credits.Votes != 1 || credits.Credits != inits.TestChainParams.Rewards.Credits(1, 1) {
+		t.Fatalf("unexpected miner credits: %+v", credits)
+	}
+}
+
+func TestSubmitRejectedBlock(t *testing.T) {
+	inits.ResetTestDatabase()
+
+	mempool := inits.NewTestMempool()
+	t.Cleanup(mempool.Stop)
+
+	submitBlock := func(block *data_models.Block) error {
+		return errors.New("block failed checks")
+	}
+
+	client := startWorkServer(t, mempool, submitBlock)
+
+	block, err := inits.CreateTestBlock(inits.TestChainParams.GenesisBlock.Header.Id, nil)
+	if err != nil {
+		t.Fatalf("failed to create test block: %v", err)
+	}
+
+	response, err := client.SubmitBlock(block)
+	if err != nil {
+		t.Fatalf("failed to submit block: %v", err)
+	}
+
+	if response.Accepted {
+		t.Fatalf("rejected block was reported as accepted")
+	}
+
+	if response.Reason != "block failed checks" {
+		t.Fatalf("unexpected rejection reason %q", response.Reason)
+	}
+
+	if response.BlockId != hex.EncodeToString(block.Header.Id) {
+		t.Fatalf("unexpected block id %s", response.BlockId)
+	}
+}
+
+func TestGetBlockTemplateGivenInvalidMinerKey(t *testing.T) {
+	inits.ResetTestDatabase()
+
+	mempool := inits.NewTestMempool()
+	t.Cleanup(mempool.Stop)
+
+	client := startWorkServer(t, mempool, func(block *data_models.Block) error { return nil })
+
+	_, err := client.GetBlockTemplate([]byte{0x01, 0x02})
+	if err == nil {
+		t.Fatalf("got block template for an invalid miner key")
+	}
+}
+
+func startWorkServer(t *testing.T, memPool mempool.Mempool, submitBlock work.BlockSubmitter) *work.WorkClient {
+	getNetworkTime := func() int64 { return time.Now().Unix() }
+	minerProps := mining.MinerProperties{
+		MinerKeyPair:         inits.TestMinerKeyPair,
+		MaxBlockSize:         inits.TestConfig.MinerConfig.MaxBlockSize,
+		MaxBlockTransactions: inits.TestConfig.MinerConfig.MaxBlockTransactions,
+		Workers:              inits.TestConfig.MinerConfig.Workers,
+	}
+	miner := mining.NewMinerImpl(getNetworkTime, inits.TestBlockRepository, memPool, inits.TestEventBus, inits.TestChainParams, minerProps)
+	t.Cleanup(miner.Stop)
+
+	workServer := work.NewWorkServer("127.0.0.1:0", miner, inits.TestBlockRepository, inits.TestChainParams, getNetworkTime, submitBlock)
+	if err := workServer.Start(); err != nil {
+		t.Fatalf("failed to start work server: %v", err)
+	}
+	t.Cleanup(func() { workServer.Stop() })
+
+	return work.NewWorkClient(workServer.Addr())
+}
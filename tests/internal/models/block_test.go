@@ -2,8 +2,10 @@ package models_test
 
 import (
 	"bytes"
+	"encoding/binary"
 	"testing"
 
+	"github.com/nivschuman/VotingBlockchain/internal/crypto/ppk"
 	"github.com/nivschuman/VotingBlockchain/internal/models"
 )
 
@@ -86,3 +88,123 @@ func TestBlockFromBytes(t *testing.T) {
 		t.Fatalf("bad transaction id")
 	}
 }
+
+func TestBlockFromBytesBounds(t *testing.T) {
+	block, err := getTestBlock()
+
+	if err != nil {
+		t.Fatalf("error in get test block: %v", err)
+	}
+
+	//transaction count claiming more transactions than the block has bytes for
+	blockBytes := block.AsBytes()
+	binary.BigEndian.PutUint32(blockBytes[models.BLOCK_HEADER_SIZE:], 0xFFFFFFFF)
+	_, err = models.BlockFromBytes(blockBytes)
+
+	if err == nil {
+		t.Fatalf("block with a transaction count past its end was parsed")
+	}
+
+	//transaction length claiming more bytes than the block has
+	blockBytes = block.AsBytes()
+	binary.BigEndian.PutUint32(blockBytes[models.BLOCK_HEADER_SIZE+4:], 0xFFFFFFFF) //unsigned, the transaction count follows the header
+	_, err = models.BlockFromBytes(blockBytes)
+
+	if err == nil {
+		t.Fatalf("block with a transaction length past its end was parsed")
+	}
+}
+
+func getTestSignedBlock() (*models.Block, *ppk.KeyPair, error) {
+	block, err := getTestBlock()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	minerKeyPair, err := ppk.GenerateKeyPair()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	block.Header.Version = models.BLOCK_VERSION_SIGNED
+	block.Header.MinerPublicKey = minerKeyPair.PublicKey.AsBytes()
+	block.Header.SetId()
+
+	if err := block.Header.Sign(minerKeyPair.PrivateKey); err != nil {
+		return nil, nil, err
+	}
+
+	return block, minerKeyPair, nil
+}
+
+func TestSignedBlockFromBytes(t *testing.T) {
+	block, _, err := getTestSignedBlock()
+
+	if err != nil {
+		t.Fatalf("error in get test signed block: %v", err)
+	}
+
+	parsedBlock, err := models.BlockFromBytes(block.AsBytes())
+
+	if err != nil {
+		t.Fatalf("error in block from bytes: %v", err)
+	}
+
+	if !bytes.Equal(parsedBlock.Header.Id, block.Header.Id) {
+		t.Fatalf("bad id for parsed block")
+	}
+
+	if !bytes.Equal(parsedBlock.Header.MinerSignature, block.Header.MinerSignature) {
+		t.Fatalf("bad miner signature for parsed block")
+	}
+
+	if len(parsedBlock.Transactions) != len(block.Transactions) {
+		t.Fatalf("have %d transactions but %d transactions were parsed", len(block.Transactions), len(parsedBlock.Transactions))
+	}
+
+	//signature length claiming more than a signature can have
+	blockBytes := block.AsBytes()
+	binary.BigEndian.PutUint32(blockBytes[models.BLOCK_HEADER_SIZE:], models.MAX_MINER_SIGNATURE_SIZE+1)
+	_, err = models.BlockFromBytes(blockBytes)
+
+	if err == nil {
+		t.Fatalf("block with an oversized miner signature was parsed")
+	}
+}
+
+func TestMinerSignatureIsValid(t *testing.T) {
+	block, _, err := getTestSignedBlock()
+
+	if err != nil {
+		t.Fatalf("error in get test signed block: %v", err)
+	}
+
+	valid, err := block.Header.MinerSignatureIsValid()
+	if err != nil || !valid {
+		t.Fatalf("miner signature should be valid: %v", err)
+	}
+
+	//the signature covers the header, changing it invalidates the signature
+	block.Header.Nonce++
+	block.Header.SetId()
+
+	valid, err = block.Header.MinerSignatureIsValid()
+	if err != nil || valid {
+		t.Fatalf("miner signature of a changed header should be invalid: %v", err)
+	}
+
+	//a signature by another key doesn't credit the miner
+	otherKeyPair, err := ppk.GenerateKeyPair()
+	if err != nil {
+		t.Fatalf("failed to generate key pair: %v", err)
+	}
+
+	if err := block.Header.Sign(otherKeyPair.PrivateKey); err != nil {
+		t.Fatalf("failed to sign block: %v", err)
+	}
+
+	valid, err = block.Header.MinerSignatureIsValid()
+	if err != nil || valid {
+		t.Fatalf("miner signature by another key should be invalid: %v", err)
+	}
+}
@@ -0,0 +1,47 @@
+package models_test
+
+import (
+	"testing"
+
+	"github.com/nivschuman/VotingBlockchain/internal/crypto/ppk"
+	"github.com/nivschuman/VotingBlockchain/internal/models"
+)
+
+func TestCheckpointSignatureIsValid(t *testing.T) {
+	governmentKeyPair, err := ppk.GenerateKeyPair()
+	if err != nil {
+		t.Fatalf("failed to generate key pair: %v", err)
+	}
+
+	blockHeader := getTestBlockHeader()
+	checkpoint := models.Checkpoint{Height: 10, BlockId: blockHeader.Id}
+
+	if err := checkpoint.Sign(governmentKeyPair.PrivateKey); err != nil {
+		t.Fatalf("failed to sign checkpoint: %v", err)
+	}
+
+	valid, err := checkpoint.SignatureIsValid(governmentKeyPair.PublicKey.AsBytes())
+	if err != nil || !valid {
+		t.Fatalf("checkpoint signature should be valid: %v", err)
+	}
+
+	//the signature covers the height, moving the checkpoint invalidates it
+	checkpoint.Height++
+
+	valid, err = checkpoint.SignatureIsValid(governmentKeyPair.PublicKey.AsBytes())
+	if err != nil || valid {
+		t.Fatalf("signature of a moved checkpoint should be invalid: %v", err)
+	}
+
+	otherKeyPair, err := ppk.GenerateKeyPair()
+	if err != nil {
+		t.Fatalf("failed to generate key pair: %v", err)
+	}
+
+	checkpoint.Height--
+
+	valid, err = checkpoint.SignatureIsValid(otherKeyPair.PublicKey.AsBytes())
+	if err != nil || valid {
+		t.Fatalf("checkpoint signature of another key should be invalid: %v", err)
+	}
+}
@@ -1,10 +1,12 @@
 package networking_connection_test
 
 import (
+	"errors"
 	"net"
 	"testing"
 	"time"
 
+	chainparams "github.com/nivschuman/VotingBlockchain/internal/chainparams"
 	connection "github.com/nivschuman/VotingBlockchain/internal/networking/connection"
 	models "github.com/nivschuman/VotingBlockchain/internal/networking/models"
 	_ "github.com/nivschuman/VotingBlockchain/tests/init"
@@ -37,13 +39,13 @@ func TestReader_ReadMessage(t *testing.T) {
 	peer1Conn, peer2Conn := net.Pipe()
 
 	go func() {
-		peer2Conn.Write(models.MAGIC_BYTES)
+		peer2Conn.Write(chainparams.RegTestParams().MagicBytes)
 		peer2Conn.Write(testMessage.MessageHeader.AsBytes())
 		peer2Conn.Write(testMessage.Payload)
 		peer2Conn.Close()
 	}()
 
-	reader := connection.NewReader()
+	reader := connection.NewReader(chainparams.RegTestParams().MagicBytes)
 	message, err := reader.ReadMessageWithTimeout(peer1Conn, time.Second*5)
 
 	if err != nil {
@@ -67,18 +69,18 @@ func TestReader_ReadMultipleMessages(t *testing.T) {
 	peer1Conn, peer2Conn := net.Pipe()
 
 	go func() {
-		peer2Conn.Write(models.MAGIC_BYTES)
+		peer2Conn.Write(chainparams.RegTestParams().MagicBytes)
 		peer2Conn.Write(testMessage.MessageHeader.AsBytes())
 		peer2Conn.Write(testMessage.Payload)
 
-		peer2Conn.Write(models.MAGIC_BYTES)
+		peer2Conn.Write(chainparams.RegTestParams().MagicBytes)
 		peer2Conn.Write(testMessage.MessageHeader.AsBytes())
 		peer2Conn.Write(testMessage.Payload)
 
 		peer2Conn.Close()
 	}()
 
-	reader := connection.NewReader()
+	reader := connection.NewReader(chainparams.RegTestParams().MagicBytes)
 	message, err := reader.ReadMessageWithTimeout(peer1Conn, time.Second*5)
 
 	if err != nil {
@@ -109,3 +111,29 @@ func TestReader_ReadMultipleMessages(t *testing.T) {
 
 	peer1Conn.Close()
 }
+
+func TestReader_ReadMessageGivenOtherNetworkMagicBytes(t *testing.T) {
+	testMessage := getTestMessage()
+
+	peer1Conn, peer2Conn := net.Pipe()
+
+	go func() {
+		peer2Conn.Write(chainparams.MainNetParams().MagicBytes)
+		peer2Conn.Write(testMessage.MessageHeader.AsBytes())
+		peer2Conn.Write(testMessage.Payload)
+		peer2Conn.Close()
+	}()
+
+	reader := connection.NewReader(chainparams.RegTestParams().MagicBytes)
+	message, err := reader.ReadMessageWithTimeout(peer1Conn, time.Second*5)
+
+	if !errors.Is(err, connection.ErrMagicBytesMismatch) {
+		t.Fatalf("expected magic bytes mismatch, got: %v", err)
+	}
+
+	if message != nil {
+		t.Fatalf("read message from another network")
+	}
+
+	peer1Conn.Close()
+}
@@ -6,8 +6,8 @@ import (
 	"net"
 	"testing"
 
+	chainparams "github.com/nivschuman/VotingBlockchain/internal/chainparams"
 	connection "github.com/nivschuman/VotingBlockchain/internal/networking/connection"
-	models "github.com/nivschuman/VotingBlockchain/internal/networking/models"
 	_ "github.com/nivschuman/VotingBlockchain/tests/init"
 )
 
@@ -17,7 +17,7 @@ func TestSender_SendMessage(t *testing.T) {
 	peer1Conn, peer2Conn := net.Pipe()
 
 	go func() {
-		sender := connection.NewSender()
+		sender := connection.NewSender(chainparams.RegTestParams().MagicBytes)
 		err := sender.SendMessage(peer1Conn, &testMessage)
 
 		if err != nil {
@@ -34,7 +34,7 @@ func TestSender_SendMessage(t *testing.T) {
 		t.Fatalf("error in reading sent magic bytes: %v", err)
 	}
 
-	if amount != 4 && !bytes.Equal(receivedMagicBytes[:], models.MAGIC_BYTES) {
+	if amount != 4 && !bytes.Equal(receivedMagicBytes[:], chainparams.RegTestParams().MagicBytes) {
 		t.Fatalf("received bad magic bytes %x", receivedMagicBytes)
 	}
 
@@ -13,5 +13,6 @@ func MockVersionProvider() (*models.Version, error) {
 		NodeType:        inits.TestConfig.NodeConfig.Type,
 		Timestamp:       time.Now().Unix(),
 		Nonce:           0,
+		GenesisBlockId:  inits.TestChainParams.GenesisBlock.Header.Id,
 	}, nil
 }
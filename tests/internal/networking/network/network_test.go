@@ -11,6 +11,7 @@ import (
 	connection "github.com/nivschuman/VotingBlockchain/internal/networking/connection"
 	models "github.com/nivschuman/VotingBlockchain/internal/networking/models"
 	network "github.com/nivschuman/VotingBlockchain/internal/networking/network"
+	peer "github.com/nivschuman/VotingBlockchain/internal/networking/peer"
 	nonce "github.com/nivschuman/VotingBlockchain/internal/networking/utils/nonce"
 	inits "github.com/nivschuman/VotingBlockchain/tests/init"
 	mocks "github.com/nivschuman/VotingBlockchain/tests/internal/networking/mocks"
@@ -34,7 +35,7 @@ func TestMain(m *testing.M) {
 func TestSendPingToNetwork(t *testing.T) {
 	ip := inits.TestConfig.NetworkConfig.Ip
 	port := inits.TestConfig.NetworkConfig.Port
-	network := network.NewNetworkImpl(inits.TestAddressRepository, &inits.TestConfig.NetworkConfig, mocks.MockVersionProvider)
+	network := network.NewNetworkImpl(inits.TestAddressRepository, &inits.TestConfig.NetworkConfig, inits.TestChainParams, mocks.MockVersionProvider, inits.TestEventBus)
 	network.Start()
 
 	t.Cleanup(func() {
@@ -58,8 +59,8 @@ func TestSendPingToNetwork(t *testing.T) {
 		t.Fatalf("Failed to generate nonce: %v", err)
 	}
 
-	reader := connection.NewReader()
-	sender := connection.NewSender()
+	reader := connection.NewReader(inits.TestChainParams.MagicBytes)
+	sender := connection.NewSender(inits.TestChainParams.MagicBytes)
 
 	pingMessage := models.NewMessage(models.CommandPing, nonce.NonceToBytes(n))
 	sender.SendMessage(conn, pingMessage)
@@ -82,7 +83,7 @@ func TestSendPingToNetwork(t *testing.T) {
 func TestSendGetAddrToNetwork(t *testing.T) {
 	ip := inits.TestConfig.NetworkConfig.Ip
 	port := inits.TestConfig.NetworkConfig.Port
-	network := network.NewNetworkImpl(inits.TestAddressRepository, &inits.TestConfig.NetworkConfig, mocks.MockVersionProvider)
+	network := network.NewNetworkImpl(inits.TestAddressRepository, &inits.TestConfig.NetworkConfig, inits.TestChainParams, mocks.MockVersionProvider, inits.TestEventBus)
 	network.Start()
 
 	t.Cleanup(func() {
@@ -101,8 +102,8 @@ func TestSendGetAddrToNetwork(t *testing.T) {
 
 	doHandshake(conn)
 
-	reader := connection.NewReader()
-	sender := connection.NewSender()
+	reader := connection.NewReader(inits.TestChainParams.MagicBytes)
+	sender := connection.NewSender(inits.TestChainParams.MagicBytes)
 
 	getAddrMessage := models.NewGetAddrMessage()
 	sender.SendMessage(conn, getAddrMessage)
@@ -127,6 +128,64 @@ func TestSendGetAddrToNetwork(t *testing.T) {
 	}
 }
 
+func TestRemovePeerRecordsReason(t *testing.T) {
+	ip := inits.TestConfig.NetworkConfig.Ip
+	port := inits.TestConfig.NetworkConfig.Port
+	network := network.NewNetworkImpl(inits.TestAddressRepository, &inits.TestConfig.NetworkConfig, inits.TestChainParams, mocks.MockVersionProvider, inits.TestEventBus)
+	network.Start()
+
+	t.Cleanup(func() {
+		network.Stop()
+	})
+
+	address := net.JoinHostPort(ip.String(), fmt.Sprint(port))
+	conn, err := net.Dial("tcp", address)
+	if err != nil {
+		t.Fatalf("Failed to dial network: %v", err)
+	}
+
+	t.Cleanup(func() {
+		conn.Close()
+	})
+
+	doHandshake(conn)
+
+	var peers []*peer.Peer
+	for range 50 {
+		peers = network.GetPeers()
+		if len(peers) == 1 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if len(peers) != 1 {
+		t.Fatalf("Expected 1 peer, got %d", len(peers))
+	}
+
+	network.RemovePeer(peers[0], peer.DisconnectReasonUserRequest)
+
+	if len(network.GetPeers()) != 0 {
+		t.Fatalf("Peer was not removed")
+	}
+
+	disconnected := network.GetDisconnectedPeers()
+	if len(disconnected) != 1 || disconnected[0].Reason != peer.DisconnectReasonUserRequest {
+		t.Fatalf("Disconnect reason was not recorded")
+	}
+}
+
+func TestReconnectBackoff(t *testing.T) {
+	for attempts := uint32(1); attempts <= 20; attempts++ {
+		maxDelay := min(network.RECONNECT_BASE_DELAY<<min(attempts-1, 16), network.RECONNECT_MAX_DELAY)
+
+		delay := network.ReconnectBackoff(attempts)
+		if delay < maxDelay/2 || delay > maxDelay {
+			t.Fatalf("Backoff %s for %d attempts is not within [%s, %s]", delay, attempts, maxDelay/2, maxDelay)
+		}
+	}
+}
+
 func doHandshake(conn net.Conn) {
 	version := models.Version{
 		ProtocolVersion: 1,
@@ -134,10 +193,11 @@ func doHandshake(conn net.Conn) {
 		Timestamp:       time.Now().Unix(),
 		Nonce:           1,
 		LastBlockHeight: 0,
+		GenesisBlockId:  inits.TestChainParams.GenesisBlock.Header.Id,
 	}
 
-	reader := connection.NewReader()
-	sender := connection.NewSender()
+	reader := connection.NewReader(inits.TestChainParams.MagicBytes)
+	sender := connection.NewSender(inits.TestChainParams.MagicBytes)
 
 	versionMessage := models.NewVersionMessage(&version)
 	sender.SendMessage(conn, versionMessage)
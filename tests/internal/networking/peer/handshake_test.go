@@ -1,11 +1,13 @@
 package networking_peer_test
 
 import (
+	"errors"
 	"net"
 	"os"
 	"testing"
 	"time"
 
+	chainparams "github.com/nivschuman/VotingBlockchain/internal/chainparams"
 	models "github.com/nivschuman/VotingBlockchain/internal/networking/models"
 	peer "github.com/nivschuman/VotingBlockchain/internal/networking/peer"
 	nonce "github.com/nivschuman/VotingBlockchain/internal/networking/utils/nonce"
@@ -20,6 +22,7 @@ func getTestVersionMessage() *models.Message {
 		Timestamp:       time.Now().Unix(),
 		Nonce:           1,
 		LastBlockHeight: 0,
+		GenesisBlockId:  inits.TestChainParams.GenesisBlock.Header.Id,
 	}
 
 	return models.NewVersionMessage(&version)
@@ -45,11 +48,11 @@ func TestWaitForHandshake_GivenValidHandshake(t *testing.T) {
 
 	go func() {
 		versionMessage := getTestVersionMessage()
-		peer2Conn.Write(models.MAGIC_BYTES)
+		peer2Conn.Write(inits.TestChainParams.MagicBytes)
 		peer2Conn.Write(versionMessage.AsBytes())
 
 		verAckMessage := models.NewVerAckMessage()
-		peer2Conn.Write(models.MAGIC_BYTES)
+		peer2Conn.Write(inits.TestChainParams.MagicBytes)
 		peer2Conn.Write(verAckMessage.AsBytes())
 	}()
 
@@ -57,6 +60,7 @@ func TestWaitForHandshake_GivenValidHandshake(t *testing.T) {
 		SendDataInterval: 5 * time.Second,
 		PingInterval:     30 * time.Second,
 		GetAddrInterval:  1 * time.Minute,
+		MagicBytes:       inits.TestChainParams.MagicBytes,
 	}
 	p := peer.NewPeer(peer1Conn, true, peerConfig, mocks.MockVersionProvider)
 	p.Start()
@@ -79,10 +83,10 @@ func TestWaitForHandshake_GivenInvalidHandshake(t *testing.T) {
 
 	go func() {
 		verAckMessage := models.NewVerAckMessage()
-		peer2Conn.Write(models.MAGIC_BYTES)
+		peer2Conn.Write(inits.TestChainParams.MagicBytes)
 		peer2Conn.Write(verAckMessage.AsBytes())
 
-		peer2Conn.Write(models.MAGIC_BYTES)
+		peer2Conn.Write(inits.TestChainParams.MagicBytes)
 		peer2Conn.Write(verAckMessage.AsBytes())
 	}()
 
@@ -90,6 +94,7 @@ func TestWaitForHandshake_GivenInvalidHandshake(t *testing.T) {
 		SendDataInterval: 5 * time.Second,
 		PingInterval:     30 * time.Second,
 		GetAddrInterval:  1 * time.Minute,
+		MagicBytes:       inits.TestChainParams.MagicBytes,
 	}
 	p := peer.NewPeer(peer1Conn, true, peerConfig, mocks.MockVersionProvider)
 	p.Start()
@@ -112,7 +117,7 @@ func TestWaitForHandshake_GivenIncompleteHandshake(t *testing.T) {
 
 	go func() {
 		versionMessage := getTestVersionMessage()
-		peer2Conn.Write(models.MAGIC_BYTES)
+		peer2Conn.Write(inits.TestChainParams.MagicBytes)
 		peer2Conn.Write(versionMessage.AsBytes())
 	}()
 
@@ -120,6 +125,7 @@ func TestWaitForHandshake_GivenIncompleteHandshake(t *testing.T) {
 		SendDataInterval: 5 * time.Second,
 		PingInterval:     30 * time.Second,
 		GetAddrInterval:  1 * time.Minute,
+		MagicBytes:       inits.TestChainParams.MagicBytes,
 	}
 	p := peer.NewPeer(peer1Conn, true, peerConfig, mocks.MockVersionProvider)
 	p.Start()
@@ -134,3 +140,101 @@ func TestWaitForHandshake_GivenIncompleteHandshake(t *testing.T) {
 	peer1Conn.Close()
 	peer2Conn.Close()
 }
+
+func TestWaitForHandshake_GivenOtherGenesisBlock(t *testing.T) {
+	nonce.Generator = &mocks.NonceGeneratorMock{}
+
+	peer1Conn, peer2Conn := net.Pipe()
+
+	go func() {
+		version := models.Version{
+			ProtocolVersion: 1,
+			NodeType:        1,
+			Timestamp:       time.Now().Unix(),
+			Nonce:           1,
+			LastBlockHeight: 0,
+			GenesisBlockId:  chainparams.MainNetParams().GenesisBlock.Header.Id,
+		}
+
+		versionMessage := models.NewVersionMessage(&version)
+		peer2Conn.Write(inits.TestChainParams.MagicBytes)
+		peer2Conn.Write(versionMessage.AsBytes())
+	}()
+
+	peerConfig := peer.PeerConfig{
+		SendDataInterval: 5 * time.Second,
+		PingInterval:     30 * time.Second,
+		GetAddrInterval:  1 * time.Minute,
+		MagicBytes:       inits.TestChainParams.MagicBytes,
+	}
+	p := peer.NewPeer(peer1Conn, true, peerConfig, mocks.MockVersionProvider)
+	p.Start()
+
+	err := p.WaitForHandshake(time.Second * 2)
+	if !errors.Is(err, peer.ErrWrongNetwork) {
+		t.Fatalf("expected wrong network error, got: %v", err)
+	}
+
+	p.Disconnect()
+
+	peer1Conn.Close()
+	peer2Conn.Close()
+}
+
+func TestWaitForHandshake_GivenOtherNetworkMagicBytes(t *testing.T) {
+	nonce.Generator = &mocks.NonceGeneratorMock{}
+
+	peer1Conn, peer2Conn := net.Pipe()
+
+	go func() {
+		versionMessage := getTestVersionMessage()
+		peer2Conn.Write(chainparams.MainNetParams().MagicBytes)
+		peer2Conn.Write(versionMessage.AsBytes())
+	}()
+
+	peerConfig := peer.PeerConfig{
+		SendDataInterval: 5 * time.Second,
+		PingInterval:     30 * time.Second,
+		GetAddrInterval:  1 * time.Minute,
+		MagicBytes:       inits.TestChainParams.MagicBytes,
+	}
+	p := peer.NewPeer(peer1Conn, true, peerConfig, mocks.MockVersionProvider)
+	p.Start()
+
+	err := p.WaitForHandshake(time.Second * 2)
+	if !errors.Is(err, peer.ErrWrongNetwork) {
+		t.Fatalf("expected wrong network error, got: %v", err)
+	}
+
+	if p.GetDisconnectReason() != peer.DisconnectReasonWrongNetwork {
+		t.Fatalf("expected disconnect reason %q, got %q", peer.DisconnectReasonWrongNetwork, p.GetDisconnectReason())
+	}
+
+	p.Disconnect()
+
+	peer1Conn.Close()
+	peer2Conn.Close()
+}
+
+func TestVersionPrunedHeight(t *testing.T) {
+	version := models.Version{
+		ProtocolVersion: 1,
+		NodeType:        1,
+		Timestamp:       time.Now().Unix(),
+		LastBlockHeight: 500,
+		GenesisBlockId:  inits.TestChainParams.GenesisBlock.Header.Id,
+		PrunedHeight:    200,
+	}
+
+	versionBytes := version.AsBytes()
+	parsed := models.VersionFromBytes(versionBytes)
+	if parsed == nil || parsed.PrunedHeight != 200 || parsed.LastBlockHeight != 500 {
+		t.Fatalf("version wasn't parsed correctly: %+v", parsed)
+	}
+
+	//versions of nodes that don't know about pruning end after the genesis block id
+	parsed = models.VersionFromBytes(versionBytes[:60])
+	if parsed == nil || parsed.PrunedHeight != 0 {
+		t.Fatalf("version without a pruned height wasn't parsed correctly: %+v", parsed)
+	}
+}
@@ -0,0 +1,205 @@
+package nodes_test
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	bootstrap "github.com/nivschuman/VotingBlockchain/internal/bootstrap"
+	config "github.com/nivschuman/VotingBlockchain/internal/config"
+	repositories "github.com/nivschuman/VotingBlockchain/internal/database/repositories"
+	data_models "github.com/nivschuman/VotingBlockchain/internal/models"
+	nodes "github.com/nivschuman/VotingBlockchain/internal/nodes"
+	inits "github.com/nivschuman/VotingBlockchain/tests/init"
+)
+
+func TestOpenChainStorageLeavesNodeStateAlone(t *testing.T) {
+	conf := newChainStorageConfig(t)
+
+	chainStorage, err := nodes.OpenChainStorage(conf)
+	if err != nil {
+		t.Fatalf("failed to open chain storage: %v", err)
+	}
+
+	checked, err := chainStorage.VerifyChain(0)
+	if err != nil || checked != 1 {
+		t.Fatalf("verified %d blocks of a new chain: %v", checked, err)
+	}
+
+	if err := chainStorage.Close(); err != nil {
+		t.Fatalf("failed to close chain storage: %v", err)
+	}
+
+	for _, file := range []string{conf.MinerConfig.KeystoreFile, conf.MempoolConfig.File} {
+		if _, err := os.Stat(file); !os.IsNotExist(err) {
+			t.Fatalf("chain storage wrote %s: %v", file, err)
+		}
+	}
+}
+
+func TestImportBlocksIntoChainStorage(t *testing.T) {
+	govKeyPair, err := inits.GenerateTestGovernmentKeyPair()
+	if err != nil {
+		t.Fatalf("failed to generate government key pair: %v", err)
+	}
+
+	chainStorage, err := nodes.OpenChainStorage(newChainStorageConfig(t))
+	if err != nil {
+		t.Fatalf("failed to open chain storage: %v", err)
+	}
+	t.Cleanup(func() {
+		chainStorage.Close()
+	})
+
+	chainParams := chainStorage.GetChainParams()
+
+	var file bytes.Buffer
+	writer := bootstrap.NewBlockFileWriter(&file, chainParams.MagicBytes)
+	if err := writer.WriteBlock(chainParams.GenesisBlock); err != nil {
+		t.Fatalf("failed to write genesis block: %v", err)
+	}
+
+	blocks := make([]*data_models.Block, 3)
+	previousBlockId := chainParams.GenesisBlock.Header.Id
+	for i := range blocks {
+		tx, _, err := inits.CreateTestTransaction(govKeyPair)
+		if err != nil {
+			t.Fatalf("failed to create test transaction: %v", err)
+		}
+
+		blocks[i], err = inits.CreateTestBlock(previousBlockId, []*data_models.Transaction{tx})
+		if err != nil {
+			t.Fatalf("failed to create test block: %v", err)
+		}
+
+		if err := writer.WriteBlock(blocks[i]); err != nil {
+			t.Fatalf("failed to write block: %v", err)
+		}
+
+		previousBlockId = blocks[i].Header.Id
+	}
+	fileBytes := file.Bytes()
+
+	reader := bootstrap.NewBlockFileReader(bytes.NewReader(fileBytes), chainParams.MagicBytes, chainParams.Election.MaxBlockSize)
+	result, err := bootstrap.ImportChain(reader, chainStorage, int64(len(fileBytes)))
+	if err != nil {
+		t.Fatalf("failed to import blocks: %v", err)
+	}
+
+	if result.Imported != 3 || result.Skipped != 1 {
+		t.Fatalf("unexpected import result %+v", result)
+	}
+
+	if !bytes.Equal(chainStorage.GetBlockRepository().GetActiveChainTipId(), blocks[2].Header.Id) {
+		t.Fatalf("active chain tip isn't the last imported block")
+	}
+
+	//importing again skips every block
+	reader = bootstrap.NewBlockFileReader(bytes.NewReader(fileBytes), chainParams.MagicBytes, chainParams.Election.MaxBlockSize)
+	result, err = bootstrap.ImportChain(reader, chainStorage, int64(len(fileBytes)))
+	if err != nil || result.Imported != 0 || result.Skipped != 4 {
+		t.Fatalf("unexpected result %+v of importing stored blocks: %v", result, err)
+	}
+
+	orphanBlock, err := inits.CreateTestBlock(make([]byte, 32), nil)
+	if err != nil {
+		t.Fatalf("failed to create test block: %v", err)
+	}
+
+	if _, err := chainStorage.ImportBlock(orphanBlock); !errors.Is(err, nodes.ErrBlockRejected) {
+		t.Fatalf("block with an unknown parent was imported: %v", err)
+	}
+
+	spentVoteBlock, err := inits.CreateTestBlock(blocks[2].Header.Id, []*data_models.Transaction{blocks[0].Transactions[0]})
+	if err != nil {
+		t.Fatalf("failed to create test block: %v", err)
+	}
+
+	if _, err := chainStorage.ImportBlock(spentVoteBlock); !errors.Is(err, nodes.ErrBlockRejected) {
+		t.Fatalf("block repeating a confirmed vote was imported: %v", err)
+	}
+}
+
+// newChainStorageConfig is the test config with every file of the node in a temporary directory
+func newChainStorageConfig(t *testing.T) *config.Config {
+	dir := t.TempDir()
+
+	conf := *inits.TestConfig
+	conf.DatabaseConfig.File = filepath.Join(dir, "node.db")
+	conf.DatabaseConfig.ChainFile = filepath.Join(dir, "chain.db")
+	conf.MinerConfig.KeystoreFile = filepath.Join(dir, "miner.key")
+	conf.MempoolConfig.File = filepath.Join(dir, "mempool.dat")
+
+	return &conf
+}
+
+func TestReindexKeepsChainTipOfEqualWeight(t *testing.T) {
+	for _, engine := range []config.StorageEngine{config.SQLiteEngine, config.BoltEngine} {
+		conf := newChainStorageConfig(t)
+		conf.DatabaseConfig.Engine = engine
+
+		chainStorage, err := nodes.OpenChainStorage(conf)
+		if err != nil {
+			t.Fatalf("failed to open chain storage: %v", err)
+		}
+
+		//two chains of one block each weigh the same, the one stored first stays the tip
+		genesisId := chainStorage.GetChainParams().GenesisBlock.Header.Id
+		blocks := make([]*data_models.Block, 2)
+		for i := range blocks {
+			blocks[i], err = inits.CreateTestBlock(genesisId, nil)
+			if err != nil {
+				t.Fatalf("failed to create test block: %v", err)
+			}
+		}
+
+		//the tip has the higher id, so headers read in id order don't pick it by chance
+		if bytes.Compare(blocks[0].Header.Id, blocks[1].Header.Id) < 0 {
+			blocks[0], blocks[1] = blocks[1], blocks[0]
+		}
+
+		for _, block := range blocks {
+			if _, err := chainStorage.ImportBlock(block); err != nil {
+				t.Fatalf("failed to import block: %v", err)
+			}
+		}
+
+		if err := chainStorage.Close(); err != nil {
+			t.Fatalf("failed to close chain storage: %v", err)
+		}
+
+		conf.DatabaseConfig.Reindex = true
+		chainStorage, err = nodes.OpenChainStorage(conf)
+		if err != nil {
+			t.Fatalf("failed to reindex chain storage: %v", err)
+		}
+
+		tipId := chainStorage.GetBlockRepository().GetActiveChainTipId()
+		chainStorage.Close()
+
+		if !bytes.Equal(tipId, blocks[0].Header.Id) {
+			t.Fatalf("reindexing %s moved the tip from %x to %x", engine, blocks[0].Header.Id, tipId)
+		}
+	}
+}
+
+func TestReindexRefusesOtherGenesis(t *testing.T) {
+	conf := newChainStorageConfig(t)
+
+	chainStorage, err := nodes.OpenChainStorage(conf)
+	if err != nil {
+		t.Fatalf("failed to open chain storage: %v", err)
+	}
+
+	if err := chainStorage.Close(); err != nil {
+		t.Fatalf("failed to close chain storage: %v", err)
+	}
+
+	conf.NodeConfig.ElectionGenesis = true
+	conf.DatabaseConfig.Reindex = true
+	if _, err := nodes.OpenChainStorage(conf); !errors.Is(err, repositories.ErrGenesisMismatch) {
+		t.Fatalf("expected %v, got %v", repositories.ErrGenesisMismatch, err)
+	}
+}
@@ -2,12 +2,18 @@ package nodes_test
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"net"
 	"os"
 	"testing"
 	"time"
 
+	chainparams "github.com/nivschuman/VotingBlockchain/internal/chainparams"
+	ppk "github.com/nivschuman/VotingBlockchain/internal/crypto/ppk"
+	repositories "github.com/nivschuman/VotingBlockchain/internal/database/repositories"
+	events "github.com/nivschuman/VotingBlockchain/internal/events"
+	mempool "github.com/nivschuman/VotingBlockchain/internal/mempool"
 	"github.com/nivschuman/VotingBlockchain/internal/mining"
 	data_models "github.com/nivschuman/VotingBlockchain/internal/models"
 	connection "github.com/nivschuman/VotingBlockchain/internal/networking/connection"
@@ -41,32 +47,25 @@ func TestSendMemPoolToFullNode(t *testing.T) {
 		t.Fatalf("Failed to generate government key pair: %v", err)
 	}
 
-	tx1, _, err := inits.CreateTestTransaction(govKeyPair)
-	if err != nil {
-		t.Fatalf("failed to create test tx1: %v", err)
-	}
-
-	tx2, _, err := inits.CreateTestTransaction(govKeyPair)
-	if err != nil {
-		t.Fatalf("failed to create test tx2: %v", err)
-	}
-
-	err = inits.TestTransactionRepository.InsertIfNotExists(tx1)
-	if err != nil {
-		t.Fatalf("failed to create insert tx1: %v", err)
-	}
-
-	err = inits.TestTransactionRepository.InsertIfNotExists(tx2)
-	if err != nil {
-		t.Fatalf("failed to create insert tx2: %v", err)
-	}
-
 	fullNode := newFullNode()
 	fullNode.Start()
 	t.Cleanup(func() {
 		fullNode.Stop()
 	})
 
+	txs := make([]*data_models.Transaction, 25)
+	for i := range txs {
+		txs[i], _, err = inits.CreateTestTransaction(govKeyPair)
+		if err != nil {
+			t.Fatalf("failed to create test tx %d: %v", i, err)
+		}
+
+		err = fullNode.GetMempool().Add(txs[i])
+		if err != nil {
+			t.Fatalf("failed to add tx %d to mempool: %v", i, err)
+		}
+	}
+
 	ip := inits.TestConfig.NetworkConfig.Ip
 	port := inits.TestConfig.NetworkConfig.Port
 	address := net.JoinHostPort(ip.String(), fmt.Sprint(port))
@@ -82,8 +81,8 @@ func TestSendMemPoolToFullNode(t *testing.T) {
 
 	doHandshake(conn)
 
-	reader := connection.NewReader()
-	sender := connection.NewSender()
+	reader := connection.NewReader(inits.TestChainParams.MagicBytes)
+	sender := connection.NewSender(inits.TestChainParams.MagicBytes)
 
 	memPoolMessage := models.NewMemPoolMessage()
 	sender.SendMessage(conn, memPoolMessage)
@@ -100,8 +99,14 @@ func TestSendMemPoolToFullNode(t *testing.T) {
 		t.Fatalf("Failed to read parse inv message: %v", err)
 	}
 
-	if !inv.Contains(models.MSG_TX, tx1.Id) || !inv.Contains(models.MSG_TX, tx2.Id) {
-		t.Fatalf("Inv returned doesn't contain transactions")
+	if inv.Count != uint64(len(txs)) {
+		t.Fatalf("Inv returned %d items, expected %d", inv.Count, len(txs))
+	}
+
+	for _, tx := range txs {
+		if !inv.Contains(models.MSG_TX, tx.Id) {
+			t.Fatalf("Inv returned doesn't contain transaction %x", tx.Id)
+		}
 	}
 }
 
@@ -142,10 +147,10 @@ func TestSendGetDataToFullNode(t *testing.T) {
 
 	doHandshake(conn)
 
-	sender := connection.NewSender()
+	sender := connection.NewSender(inits.TestChainParams.MagicBytes)
 	sender.SendMessage(conn, getDataMessage)
 
-	reader := connection.NewReader()
+	reader := connection.NewReader(inits.TestChainParams.MagicBytes)
 
 	msg1, err := reader.ReadMessage(conn)
 	if err != nil {
@@ -236,15 +241,80 @@ func TestSendTransactionToFullNode(t *testing.T) {
 		t.Fatalf("Failed to create test transaction: %v", err)
 	}
 
-	sender := connection.NewSender()
+	sender := connection.NewSender(inits.TestChainParams.MagicBytes)
 	sender.SendMessage(conn, models.NewMessage(models.CommandTx, tx.AsBytes()))
 
-	//wait for transaction to get inserted
+	//wait for transaction to get added
 	time.Sleep(time.Second * 1)
 
-	_, err = inits.TestTransactionRepository.GetTransaction(tx.Id)
+	if !fullNode.GetMempool().Contains(tx.Id) {
+		t.Fatalf("Transaction wasn't added to the mempool")
+	}
+}
+
+func TestSendConflictingTransactionToFullNode(t *testing.T) {
+	inits.ResetTestDatabase()
+	govKeyPair, err := inits.GenerateTestGovernmentKeyPair()
 	if err != nil {
-		t.Fatalf("Failed get transaction from database: %v", err)
+		t.Fatalf("Failed to generate government key pair: %v", err)
+	}
+
+	tx, voterKeyPair, err := inits.CreateTestTransaction(govKeyPair)
+	if err != nil {
+		t.Fatalf("Failed to create test transaction: %v", err)
+	}
+
+	conflictingTx, err := inits.CreateConflictingTestTransaction(tx, voterKeyPair, 2)
+	if err != nil {
+		t.Fatalf("Failed to create conflicting transaction: %v", err)
+	}
+
+	fullNode := newFullNode()
+	fullNode.Start()
+	t.Cleanup(func() {
+		fullNode.Stop()
+	})
+
+	err = fullNode.GetMempool().Add(tx)
+	if err != nil {
+		t.Fatalf("Failed to add transaction to mempool: %v", err)
+	}
+
+	ip := inits.TestConfig.NetworkConfig.Ip
+	port := inits.TestConfig.NetworkConfig.Port
+	address := net.JoinHostPort(ip.String(), fmt.Sprint(port))
+
+	conn, err := net.Dial("tcp", address)
+	if err != nil {
+		t.Fatalf("Failed to dial network: %v", err)
+	}
+
+	t.Cleanup(func() {
+		conn.Close()
+	})
+
+	doHandshake(conn)
+
+	sender := connection.NewSender(inits.TestChainParams.MagicBytes)
+	sender.SendMessage(conn, models.NewMessage(models.CommandTx, conflictingTx.AsBytes()))
+
+	reader := connection.NewReader(inits.TestChainParams.MagicBytes)
+	rejectMessage, err := reader.ReadMessage(conn)
+	if err != nil {
+		t.Fatalf("Failed to read reject message: %v", err)
+	}
+
+	if !bytes.Equal(rejectMessage.MessageHeader.Command[:], models.CommandReject[:]) {
+		t.Fatalf("received bad command: %x", rejectMessage.MessageHeader.Command[:])
+	}
+
+	reject, err := models.RejectFromBytes(rejectMessage.Payload)
+	if err != nil {
+		t.Fatalf("Failed to parse reject message: %v", err)
+	}
+
+	if reject.Code != models.REJECT_CONFLICT || reject.Message != models.CommandTx || !bytes.Equal(reject.Hash, conflictingTx.Id) {
+		t.Fatalf("Reject message is wrong: code 0x%02x, hash %x", reject.Code, reject.Hash)
 	}
 }
 
@@ -281,7 +351,7 @@ func TestSendBlockToFullNode(t *testing.T) {
 		t.Fatalf("Failed to create test block: %v", err)
 	}
 
-	sender := connection.NewSender()
+	sender := connection.NewSender(inits.TestChainParams.MagicBytes)
 	sender.SendMessage(conn, models.NewMessage(models.CommandBlock, block.AsBytes()))
 
 	//wait for block to get inserted
@@ -326,7 +396,7 @@ func TestSendAddrToFullNode(t *testing.T) {
 		t.Fatalf("Failed to create addr message: %v", err)
 	}
 
-	sender := connection.NewSender()
+	sender := connection.NewSender(inits.TestChainParams.MagicBytes)
 	sender.SendMessage(conn, addrMessage)
 
 	//wait for addresses to get processed
@@ -351,10 +421,11 @@ func doHandshake(conn net.Conn) {
 		Timestamp:       time.Now().Unix(),
 		Nonce:           1,
 		LastBlockHeight: 0,
+		GenesisBlockId:  inits.TestChainParams.GenesisBlock.Header.Id,
 	}
 
-	reader := connection.NewReader()
-	sender := connection.NewSender()
+	reader := connection.NewReader(inits.TestChainParams.MagicBytes)
+	sender := connection.NewSender(inits.TestChainParams.MagicBytes)
 
 	versionMessage := models.NewVersionMessage(&version)
 	sender.SendMessage(conn, versionMessage)
@@ -368,11 +439,215 @@ func doHandshake(conn net.Conn) {
 }
 
 func newFullNode() *nodes.FullNode {
-	ntwrk := network.NewNetworkImpl(inits.TestAddressRepository, &inits.TestConfig.NetworkConfig, networking_mocks.MockVersionProvider)
+	return newFullNodeWithChain(inits.TestBlockRepository, inits.TestChainParams)
+}
+
+func newFullNodeWithChain(blockRepository repositories.BlockRepository, chainParams *chainparams.ChainParams) *nodes.FullNode {
+	eventBus := events.NewEventBusImpl()
+	ntwrk := network.NewNetworkImpl(inits.TestAddressRepository, &inits.TestConfig.NetworkConfig, chainParams, networking_mocks.MockVersionProvider, eventBus)
 	miner := mining.NewDisabledMiner()
+	memPool := mempool.NewMempoolImpl(inits.TestTransactionRepository, inits.TestConflictingVoteRepository, eventBus, inits.TestConfig.GovernmentConfig.PublicKey, &inits.TestConfig.MempoolConfig)
 
-	fullNode := nodes.NewFullNode(ntwrk, miner, inits.TestBlockRepository, inits.TestTransactionRepository, inits.TestConfig.GovernmentConfig.PublicKey)
-	ntwrk.RemovePeerEventHandlers("new_peer")
+	fullNode := nodes.NewFullNode(ntwrk, miner, memPool, blockRepository, inits.TestTransactionRepository, eventBus, chainParams, inits.TestConfig.GovernmentConfig.PublicKey)
+	eventBus.UnsubscribeAll(events.PeerConnected)
 
 	return fullNode
 }
+
+func TestSubmitBlockToFullNode(t *testing.T) {
+	inits.ResetTestDatabase()
+
+	fullNode := newFullNode()
+	fullNode.Start()
+	t.Cleanup(func() {
+		fullNode.Stop()
+	})
+
+	genesisBlockId := inits.TestChainParams.GenesisBlock.Header.Id
+	block, err := inits.CreateTestBlock(genesisBlockId, nil)
+	if err != nil {
+		t.Fatalf("failed to create test block: %v", err)
+	}
+
+	if err := fullNode.SubmitBlock(block); err != nil {
+		t.Fatalf("valid block was rejected: %v", err)
+	}
+
+	haveBlock, err := inits.TestBlockRepository.HaveBlock(block.Header.Id)
+	if err != nil {
+		t.Fatalf("failed to check for submitted block: %v", err)
+	}
+
+	if !haveBlock {
+		t.Fatalf("submitted block wasn't inserted")
+	}
+
+	futureBlock, err := inits.CreateTestBlockMinedBy(block.Header.Id, nil, inits.TestMinerKeyPair)
+	if err != nil {
+		t.Fatalf("failed to create test block: %v", err)
+	}
+
+	futureBlock.Header.Timestamp = time.Now().Unix() + 3*60*60
+	for futureBlock.Header.SetId(); !futureBlock.Header.IsHashBelowTarget(); futureBlock.Header.SetId() {
+		futureBlock.Header.Nonce++
+	}
+
+	if err := futureBlock.Header.Sign(inits.TestMinerKeyPair.PrivateKey); err != nil {
+		t.Fatalf("failed to sign test block: %v", err)
+	}
+
+	err = fullNode.SubmitBlock(futureBlock)
+	if !errors.Is(err, nodes.ErrBlockRejected) {
+		t.Fatalf("expected block rejected error, got: %v", err)
+	}
+}
+
+func TestSubmitUnsignedBlockToFullNode(t *testing.T) {
+	inits.ResetTestDatabase()
+
+	fullNode := newFullNode()
+	fullNode.Start()
+	t.Cleanup(func() {
+		fullNode.Stop()
+	})
+
+	genesisBlockId := inits.TestChainParams.GenesisBlock.Header.Id
+	unsignedBlock, err := inits.CreateTestBlock(genesisBlockId, nil)
+	if err != nil {
+		t.Fatalf("failed to create test block: %v", err)
+	}
+
+	unsignedBlock.Header.Version = 1
+	unsignedBlock.Header.MinerSignature = nil
+	for unsignedBlock.Header.SetId(); !unsignedBlock.Header.IsHashBelowTarget(); unsignedBlock.Header.SetId() {
+		unsignedBlock.Header.Nonce++
+	}
+
+	err = fullNode.SubmitBlock(unsignedBlock)
+	if !errors.Is(err, nodes.ErrBlockRejected) {
+		t.Fatalf("expected unsigned block to be rejected, got: %v", err)
+	}
+
+	//signed by a key other than the one the block credits
+	forgedBlock, err := inits.CreateTestBlock(genesisBlockId, nil)
+	if err != nil {
+		t.Fatalf("failed to create test block: %v", err)
+	}
+
+	if err := forgedBlock.Header.Sign(inits.TestMinerKeyPair.PrivateKey); err != nil {
+		t.Fatalf("failed to sign test block: %v", err)
+	}
+
+	err = fullNode.SubmitBlock(forgedBlock)
+	if !errors.Is(err, nodes.ErrBlockRejected) {
+		t.Fatalf("expected block with a forged signature to be rejected, got: %v", err)
+	}
+
+	haveBlock, err := inits.TestBlockRepository.HaveBlock(forgedBlock.Header.Id)
+	if err != nil {
+		t.Fatalf("failed to check for submitted block: %v", err)
+	}
+
+	if haveBlock {
+		t.Fatalf("block with a forged signature was inserted")
+	}
+}
+
+func TestSubmitSealedBlockToFullNode(t *testing.T) {
+	inits.ClearTestDatabase()
+
+	sealerKeyPair, err := ppk.GenerateKeyPair()
+	if err != nil {
+		t.Fatalf("failed to generate sealer key pair: %v", err)
+	}
+
+	authorityParams, err := inits.CreateTestAuthorityChainParams(0, 0, sealerKeyPair)
+	if err != nil {
+		t.Fatalf("failed to create authority chain params: %v", err)
+	}
+
+	blockRepository := repositories.NewBlockRepositoryImpl(inits.TestDb, inits.TestEventBus, authorityParams)
+	if err := blockRepository.Initialize(); err != nil {
+		t.Fatalf("failed to initialize block repository: %v", err)
+	}
+
+	fullNode := newFullNodeWithChain(blockRepository, authorityParams)
+	fullNode.Start()
+	t.Cleanup(func() {
+		fullNode.Stop()
+	})
+
+	genesisBlockId := authorityParams.GenesisBlock.Header.Id
+
+	//mined by a key that isn't a sealer, proof of work doesn't count
+	minedBlock, err := inits.CreateTestBlockMinedBy(genesisBlockId, nil, inits.TestMinerKeyPair)
+	if err != nil {
+		t.Fatalf("failed to create test block: %v", err)
+	}
+
+	err = fullNode.SubmitBlock(minedBlock)
+	if !errors.Is(err, nodes.ErrBlockRejected) {
+		t.Fatalf("expected mined block to be rejected, got: %v", err)
+	}
+
+	sealedBlock, err := inits.CreateTestSealedBlock(genesisBlockId, time.Now().Unix(), nil, sealerKeyPair)
+	if err != nil {
+		t.Fatalf("failed to create sealed block: %v", err)
+	}
+
+	if err := fullNode.SubmitBlock(sealedBlock); err != nil {
+		t.Fatalf("sealed block was rejected: %v", err)
+	}
+
+	if !bytes.Equal(blockRepository.GetActiveChainTipId(), sealedBlock.Header.Id) {
+		t.Fatalf("sealed block isn't the active chain tip")
+	}
+}
+
+func TestSubmitBlockConflictingWithCheckpoint(t *testing.T) {
+	inits.ResetTestDatabase()
+
+	chainParams, err := chainparams.ParamsForName(inits.TestConfig.NodeConfig.Chain)
+	if err != nil {
+		t.Fatalf("failed to get chain params: %v", err)
+	}
+
+	genesisBlockId := chainParams.GenesisBlock.Header.Id
+	checkpointBlock, err := inits.CreateTestBlock(genesisBlockId, nil)
+	if err != nil {
+		t.Fatalf("failed to create test block: %v", err)
+	}
+
+	if err := chainParams.Consensus.AddCheckpoint(data_models.Checkpoint{Height: 1, BlockId: checkpointBlock.Header.Id}); err != nil {
+		t.Fatalf("failed to add checkpoint: %v", err)
+	}
+
+	blockRepository := repositories.NewBlockRepositoryImpl(inits.TestDb, inits.TestEventBus, chainParams)
+	if err := blockRepository.Initialize(); err != nil {
+		t.Fatalf("failed to initialize block repository: %v", err)
+	}
+
+	fullNode := newFullNodeWithChain(blockRepository, chainParams)
+	fullNode.Start()
+	t.Cleanup(func() {
+		fullNode.Stop()
+	})
+
+	conflictingBlock, err := inits.CreateTestBlock(genesisBlockId, nil)
+	if err != nil {
+		t.Fatalf("failed to create test block: %v", err)
+	}
+
+	err = fullNode.SubmitBlock(conflictingBlock)
+	if !errors.Is(err, nodes.ErrBlockRejected) || !errors.Is(err, repositories.ErrCheckpointViolation) {
+		t.Fatalf("expected block conflicting with a checkpoint to be rejected, got: %v", err)
+	}
+
+	if err := fullNode.SubmitBlock(checkpointBlock); err != nil {
+		t.Fatalf("checkpoint block was rejected: %v", err)
+	}
+
+	if !bytes.Equal(blockRepository.GetActiveChainTipId(), checkpointBlock.Header.Id) {
+		t.Fatalf("checkpoint block isn't the active chain tip")
+	}
+}
@@ -0,0 +1,65 @@
+package nodes_test
+
+import (
+	"bytes"
+	"testing"
+
+	chainparams "github.com/nivschuman/VotingBlockchain/internal/chainparams"
+	config "github.com/nivschuman/VotingBlockchain/internal/config"
+	nodes "github.com/nivschuman/VotingBlockchain/internal/nodes"
+	inits "github.com/nivschuman/VotingBlockchain/tests/init"
+)
+
+func TestChainParamsForConfigGenesis(t *testing.T) {
+	if _, err := inits.GenerateTestGovernmentKeyPair(); err != nil {
+		t.Fatalf("failed to generate government key pair: %v", err)
+	}
+
+	networkParams, err := chainparams.ParamsForName(inits.TestConfig.NodeConfig.Chain)
+	if err != nil {
+		t.Fatalf("failed to get chain params: %v", err)
+	}
+
+	//existing chains keep the genesis block of the network
+	conf := *inits.TestConfig
+	conf.NodeConfig.ElectionGenesis = false
+
+	chainParams, err := nodes.ChainParamsForConfig(&conf)
+	if err != nil {
+		t.Fatalf("failed to get chain params for config: %v", err)
+	}
+
+	if !bytes.Equal(chainParams.GenesisBlock.Header.Id, networkParams.GenesisBlock.Header.Id) {
+		t.Fatalf("genesis block changed without election-genesis")
+	}
+
+	conf.NodeConfig.ElectionGenesis = true
+
+	chainParams, err = nodes.ChainParamsForConfig(&conf)
+	if err != nil {
+		t.Fatalf("failed to get chain params for config: %v", err)
+	}
+
+	networkParams.SetGenesisBlock(conf.GovernmentConfig.PublicKey)
+	if !bytes.Equal(chainParams.GenesisBlock.Header.Id, networkParams.GenesisBlock.Header.Id) {
+		t.Fatalf("election-genesis didn't derive the genesis block from the government key")
+	}
+}
+
+func TestNodeBuilderRefusesShallowPrune(t *testing.T) {
+	//chains without a reorganization limit still keep the fewest pruned blocks
+	conf := newChainStorageConfig(t)
+	conf.ConsensusConfig.MaxReorgDepth = 0
+	conf.DatabaseConfig.Prune = 1
+
+	if _, err := nodes.NewNodeBuilderImpl(conf); err == nil {
+		t.Fatalf("node builder accepted pruning all but 1 block")
+	}
+
+	conf.ConsensusConfig.MaxReorgDepth = config.MIN_PRUNE_BLOCKS + 1
+	conf.DatabaseConfig.Prune = config.MIN_PRUNE_BLOCKS
+
+	if _, err := nodes.NewNodeBuilderImpl(conf); err == nil {
+		t.Fatalf("node builder accepted pruning blocks a reorganization may disconnect")
+	}
+}
@@ -0,0 +1,117 @@
+package sim_test
+
+import (
+	"testing"
+	"time"
+
+	sim "github.com/nivschuman/VotingBlockchain/internal/sim"
+)
+
+func checkVotingResults(t *testing.T, simulation *sim.Simulation, expected map[uint32]uint64) {
+	for _, node := range simulation.Nodes {
+		results, err := node.GetVotingResults()
+		if err != nil {
+			t.Fatalf("failed to get voting results of %s: %v", node.String(), err)
+		}
+
+		if len(results) != len(expected) {
+			t.Fatalf("%s has results for %d candidates, expected %d", node.String(), len(results), len(expected))
+		}
+
+		for _, result := range results {
+			if expected[result.CandidateId] != uint64(result.Votes) {
+				t.Fatalf("%s has %d votes for candidate %d, expected %d", node.String(), result.Votes, result.CandidateId, expected[result.CandidateId])
+			}
+		}
+	}
+}
+
+func TestDeepReorgReturnsDisconnectedVotes(t *testing.T) {
+	simulation := newSimulation(t, sim.Config{
+		NumberOfNodes:  2,
+		NumberOfVoters: 3,
+		Link:           sim.LinkConfig{Latency: 5 * time.Millisecond},
+		Seed:           4,
+	})
+
+	script := sim.Script{
+		sim.MineBlocks{Node: 0, Count: 1},
+		sim.AssertConverged{Timeout: 15 * time.Second},
+		sim.Partition{Groups: [][]int{{0}, {1}}},
+
+		//votes on the short chain, each in its own block
+		sim.SubmitVote{Node: 0, Voter: 0, CandidateId: 1},
+		sim.MineBlocks{Node: 0, Count: 1},
+		sim.SubmitVote{Node: 0, Voter: 1, CandidateId: 1},
+		sim.MineBlocks{Node: 0, Count: 1},
+		sim.SubmitVote{Node: 0, Voter: 2, CandidateId: 1},
+		sim.MineBlocks{Node: 0, Count: 1},
+
+		//conflicting vote of voter 0 on the long chain
+		sim.SubmitVote{Node: 1, Voter: 0, CandidateId: 2},
+		sim.MineBlocks{Node: 1, Count: 5},
+
+		//announcements made during the partition are lost
+		sim.Wait{Duration: 2 * time.Second},
+		sim.Heal{},
+		sim.MineBlocks{Node: 1, Count: 1},
+		sim.AssertConverged{Timeout: 20 * time.Second},
+
+		//votes returned to the mempool of node 0 are announced to node 1
+		sim.Wait{Duration: 3 * time.Second},
+		sim.MineBlocks{Node: 1, Count: 1},
+		sim.AssertConverged{Timeout: 20 * time.Second},
+	}
+
+	if err := simulation.Run(script); err != nil {
+		t.Fatalf("simulation failed: %v", err)
+	}
+
+	for _, node := range simulation.Nodes {
+		height, err := node.BlockRepository.GetActiveChainHeight()
+		if err != nil {
+			t.Fatalf("failed to get height of %s: %v", node.String(), err)
+		}
+
+		if height != 8 {
+			t.Fatalf("%s has height %d, expected 8", node.String(), height)
+		}
+	}
+
+	checkVotingResults(t, simulation, map[uint32]uint64{1: 2, 2: 1})
+}
+
+func TestReorgEvictsConflictingVotes(t *testing.T) {
+	simulation := newSimulation(t, sim.Config{
+		NumberOfNodes:  2,
+		NumberOfVoters: 1,
+		Link:           sim.LinkConfig{Latency: 5 * time.Millisecond},
+		Seed:           5,
+	})
+
+	script := sim.Script{
+		sim.MineBlocks{Node: 0, Count: 1},
+		sim.AssertConverged{Timeout: 15 * time.Second},
+		sim.Partition{Groups: [][]int{{0}, {1}}},
+		sim.SubmitVote{Node: 0, Voter: 0, CandidateId: 1},
+		sim.MineBlocks{Node: 0, Count: 2},
+		sim.SubmitVote{Node: 1, Voter: 0, CandidateId: 2},
+		sim.MineBlocks{Node: 1, Count: 4},
+		sim.Wait{Duration: 2 * time.Second},
+		sim.Heal{},
+		sim.MineBlocks{Node: 1, Count: 1},
+		sim.AssertConverged{Timeout: 20 * time.Second},
+	}
+
+	if err := simulation.Run(script); err != nil {
+		t.Fatalf("simulation failed: %v", err)
+	}
+
+	checkVotingResults(t, simulation, map[uint32]uint64{2: 1})
+
+	for _, node := range simulation.Nodes {
+		if count := node.Mempool.Count(); count != 0 {
+			t.Fatalf("%s kept %d conflicting votes in its mempool", node.String(), count)
+		}
+	}
+}
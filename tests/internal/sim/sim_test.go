@@ -0,0 +1,154 @@
+package sim_test
+
+import (
+	"bytes"
+	"os"
+	"testing"
+	"time"
+
+	sim "github.com/nivschuman/VotingBlockchain/internal/sim"
+	inits "github.com/nivschuman/VotingBlockchain/tests/init"
+)
+
+func TestMain(m *testing.M) {
+	// === BEFORE ALL TESTS ===
+	inits.SetupTests()
+
+	// Run the tests
+	code := m.Run()
+
+	// === AFTER ALL TESTS ===
+
+	// Exit with the right code
+	os.Exit(code)
+}
+
+func newSimulation(t *testing.T, simConfig sim.Config) *sim.Simulation {
+	simConfig.NodeConfig = inits.TestConfig.NodeConfig
+
+	simulation, err := sim.NewSimulation(simConfig)
+	if err != nil {
+		t.Fatalf("failed to create simulation: %v", err)
+	}
+
+	if err := simulation.Start(); err != nil {
+		simulation.Stop()
+		t.Fatalf("failed to start simulation: %v", err)
+	}
+
+	t.Cleanup(simulation.Stop)
+	return simulation
+}
+
+func TestSimulationConvergesWithLatency(t *testing.T) {
+	simulation := newSimulation(t, sim.Config{
+		NumberOfNodes:  3,
+		NumberOfVoters: 2,
+		Link:           sim.LinkConfig{Latency: 20 * time.Millisecond, Jitter: 10 * time.Millisecond},
+		Seed:           1,
+	})
+
+	script := sim.Script{
+		sim.MineBlocks{Node: 0, Count: 3},
+		sim.AssertConverged{Timeout: 15 * time.Second},
+		sim.SubmitVote{Node: 1, Voter: 0, CandidateId: 1},
+		sim.SubmitVote{Node: 2, Voter: 1, CandidateId: 2},
+		sim.Wait{Duration: 3 * time.Second},
+		sim.MineBlocks{Node: 2, Count: 1},
+		sim.AssertConverged{Timeout: 15 * time.Second},
+	}
+
+	if err := simulation.Run(script); err != nil {
+		t.Fatalf("simulation failed: %v", err)
+	}
+
+	for _, node := range simulation.Nodes {
+		height, err := node.BlockRepository.GetActiveChainHeight()
+		if err != nil {
+			t.Fatalf("failed to get height of %s: %v", node.String(), err)
+		}
+
+		if height != 4 {
+			t.Fatalf("%s has height %d, expected 4", node.String(), height)
+		}
+
+		results, err := node.GetVotingResults()
+		if err != nil {
+			t.Fatalf("failed to get voting results of %s: %v", node.String(), err)
+		}
+
+		if len(results) != 2 || results[0].Votes != 1 || results[1].Votes != 1 {
+			t.Fatalf("%s has wrong voting results", node.String())
+		}
+	}
+}
+
+func TestSimulationReorgsAfterPartitionHeals(t *testing.T) {
+	simulation := newSimulation(t, sim.Config{
+		NumberOfNodes:  4,
+		NumberOfVoters: 1,
+		Link:           sim.LinkConfig{Latency: 5 * time.Millisecond},
+		ClockSkews:     map[int]time.Duration{3: 10 * time.Minute},
+		Seed:           2,
+	})
+
+	script := sim.Script{
+		sim.MineBlocks{Node: 0, Count: 1},
+		sim.AssertConverged{Timeout: 15 * time.Second},
+		sim.Partition{Groups: [][]int{{0, 1}, {2, 3}}},
+		sim.SubmitVote{Node: 0, Voter: 0, CandidateId: 1},
+		sim.Wait{Duration: 2 * time.Second},
+		sim.MineBlocks{Node: 0, Count: 1},
+		sim.MineBlocks{Node: 3, Count: 3},
+		sim.Wait{Duration: 2 * time.Second},
+		sim.Heal{},
+		sim.MineBlocks{Node: 2, Count: 1},
+		sim.AssertConverged{Timeout: 20 * time.Second},
+	}
+
+	if err := simulation.Run(script); err != nil {
+		t.Fatalf("simulation failed: %v", err)
+	}
+
+	tip := simulation.Nodes[2].GetActiveChainTipId()
+	for _, node := range simulation.Nodes {
+		if !bytes.Equal(tip, node.GetActiveChainTipId()) {
+			t.Fatalf("%s did not reorganize to the heavier chain", node.String())
+		}
+
+		height, err := node.BlockRepository.GetActiveChainHeight()
+		if err != nil {
+			t.Fatalf("failed to get height of %s: %v", node.String(), err)
+		}
+
+		if height != 5 {
+			t.Fatalf("%s has height %d, expected 5", node.String(), height)
+		}
+	}
+}
+
+func TestSimulationDropsMessagesAcrossPartition(t *testing.T) {
+	simulation := newSimulation(t, sim.Config{
+		NumberOfNodes: 2,
+		Seed:          3,
+	})
+
+	script := sim.Script{
+		sim.Partition{Groups: [][]int{{0}, {1}}},
+		sim.MineBlocks{Node: 0, Count: 1},
+		sim.Wait{Duration: 2 * time.Second},
+	}
+
+	if err := simulation.Run(script); err != nil {
+		t.Fatalf("simulation failed: %v", err)
+	}
+
+	converged, err := simulation.Converged()
+	if err != nil {
+		t.Fatalf("failed to check convergence: %v", err)
+	}
+
+	if converged {
+		t.Fatalf("partitioned nodes converged")
+	}
+}
@@ -0,0 +1,108 @@
+package validation_test
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	db_models "github.com/nivschuman/VotingBlockchain/internal/database/models"
+	validation "github.com/nivschuman/VotingBlockchain/internal/validation"
+	inits "github.com/nivschuman/VotingBlockchain/tests/init"
+)
+
+func TestMain(m *testing.M) {
+	// === BEFORE ALL TESTS ===
+	inits.SetupTests()
+	inits.SetupTestsDatabase()
+
+	// Run the tests
+	code := m.Run()
+
+	// === AFTER ALL TESTS ===
+	inits.CloseTestDatabase()
+
+	// Exit with the right code
+	os.Exit(code)
+}
+
+func TestVerifyChain(t *testing.T) {
+	inits.ResetTestDatabase()
+	govKeyPair, _, _, err := inits.CreateTestData(5, 2)
+	if err != nil {
+		t.Fatalf("failed to setup test data: %v", err)
+	}
+
+	verifier := validation.NewChainVerifier(inits.TestBlockRepository, inits.TestTransactionRepository, inits.TestChainParams, govKeyPair.PublicKey.AsBytes())
+
+	checked, err := verifier.VerifyChain(0)
+	if err != nil {
+		t.Fatalf("failed to verify chain: %v", err)
+	}
+
+	if checked != 6 {
+		t.Fatalf("verified %d blocks, expected the 5 blocks and the genesis block", checked)
+	}
+
+	checked, err = verifier.VerifyChain(2)
+	if err != nil || checked != 2 {
+		t.Fatalf("verified %d blocks at depth 2: %v", checked, err)
+	}
+}
+
+func TestVerifyChainFindsWrongGovernmentSignatures(t *testing.T) {
+	inits.ResetTestDatabase()
+	_, _, _, err := inits.CreateTestData(2, 2)
+	if err != nil {
+		t.Fatalf("failed to setup test data: %v", err)
+	}
+
+	otherGovKeyPair, err := inits.GenerateTestGovernmentKeyPair()
+	if err != nil {
+		t.Fatalf("failed to generate government key pair: %v", err)
+	}
+
+	verifier := validation.NewChainVerifier(inits.TestBlockRepository, inits.TestTransactionRepository, inits.TestChainParams, otherGovKeyPair.PublicKey.AsBytes())
+
+	_, err = verifier.VerifyChain(0)
+	if !errors.Is(err, validation.ErrChainCorrupted) || !errors.Is(err, validation.ErrInvalidBlock) {
+		t.Fatalf("expected an invalid block in a corrupted chain, got %v", err)
+	}
+}
+
+func TestReindexRepairsCorruptedChainState(t *testing.T) {
+	inits.ResetTestDatabase()
+	govKeyPair, blocks, _, err := inits.CreateTestData(4, 2)
+	if err != nil {
+		t.Fatalf("failed to setup test data: %v", err)
+	}
+
+	verifier := validation.NewChainVerifier(inits.TestBlockRepository, inits.TestTransactionRepository, inits.TestChainParams, govKeyPair.PublicKey.AsBytes())
+
+	//a wrong height and a lost vote in the spent voter index
+	err = inits.TestDb.Model(&db_models.BlockDB{}).
+		Where("block_header_id = ?", blocks[2].Header.Id).
+		Update("height", 7).Error
+
+	if err != nil {
+		t.Fatalf("failed to corrupt block height: %v", err)
+	}
+
+	err = inits.TestDb.Where("transaction_id = ?", blocks[1].Transactions[0].Id).Delete(&db_models.SpentVoterDB{}).Error
+	if err != nil {
+		t.Fatalf("failed to corrupt spent voters: %v", err)
+	}
+
+	_, err = verifier.VerifyChain(0)
+	if !errors.Is(err, validation.ErrChainCorrupted) {
+		t.Fatalf("expected a corrupted chain, got %v", err)
+	}
+
+	if err := inits.TestBlockRepository.Reindex(); err != nil {
+		t.Fatalf("failed to reindex chain: %v", err)
+	}
+
+	checked, err := verifier.VerifyChain(0)
+	if err != nil || checked != 5 {
+		t.Fatalf("verified %d blocks after reindexing: %v", checked, err)
+	}
+}
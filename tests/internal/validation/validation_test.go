@@ -0,0 +1,90 @@
+package validation_test
+
+import (
+	"errors"
+	"testing"
+
+	chainparams "github.com/nivschuman/VotingBlockchain/internal/chainparams"
+	consensus "github.com/nivschuman/VotingBlockchain/internal/consensus"
+	repositories "github.com/nivschuman/VotingBlockchain/internal/database/repositories"
+	models "github.com/nivschuman/VotingBlockchain/internal/models"
+	validation "github.com/nivschuman/VotingBlockchain/internal/validation"
+	inits "github.com/nivschuman/VotingBlockchain/tests/init"
+)
+
+func TestSignedHeadersActivation(t *testing.T) {
+	inits.ResetTestDatabase()
+
+	chainParams, err := chainparams.ParamsForName(inits.TestConfig.NodeConfig.Chain)
+	if err != nil {
+		t.Fatalf("failed to get chain params: %v", err)
+	}
+	chainParams.Consensus.SignedHeaders = 2
+
+	blockRepository := repositories.NewBlockRepositoryImpl(inits.TestDb, inits.TestEventBus, chainParams)
+	if err := blockRepository.Initialize(); err != nil {
+		t.Fatalf("failed to initialize block repository: %v", err)
+	}
+
+	consensusEngine := consensus.NewConsensusEngine(chainParams)
+	checkAndValidate := func(block *models.Block, consensusRules *chainparams.ConsensusRules) error {
+		if err := validation.CheckBlock(block, consensusEngine, &chainParams.Election, inits.TestConfig.GovernmentConfig.PublicKey); err != nil {
+			return err
+		}
+
+		return validation.ValidateBlock(block, blockRepository, inits.TestTransactionRepository, consensusEngine, consensusRules)
+	}
+
+	//blocks stored before headers were signed stay valid below the activation height
+	unsignedBlock, err := inits.CreateTestUnsignedBlock(chainParams.GenesisBlock.Header.Id, []*models.Transaction{})
+	if err != nil {
+		t.Fatalf("failed to create unsigned block: %v", err)
+	}
+
+	if err := checkAndValidate(unsignedBlock, &chainParams.Consensus); err != nil {
+		t.Fatalf("unsigned block below the activation height was rejected: %v", err)
+	}
+
+	alwaysSigned := chainParams.Consensus
+	alwaysSigned.SignedHeaders = 1
+	if err := checkAndValidate(unsignedBlock, &alwaysSigned); !errors.Is(err, validation.ErrInvalidBlock) {
+		t.Fatalf("unsigned block was accepted on a chain signing every header: %v", err)
+	}
+
+	neverSigned := chainParams.Consensus
+	neverSigned.SignedHeaders = 0
+	if err := checkAndValidate(unsignedBlock, &neverSigned); err != nil {
+		t.Fatalf("unsigned block was rejected on a chain without an activation height: %v", err)
+	}
+
+	if err := blockRepository.InsertIfNotExists(unsignedBlock); err != nil {
+		t.Fatalf("failed to insert unsigned block: %v", err)
+	}
+
+	lateUnsignedBlock, err := inits.CreateTestUnsignedBlock(unsignedBlock.Header.Id, []*models.Transaction{})
+	if err != nil {
+		t.Fatalf("failed to create unsigned block: %v", err)
+	}
+
+	if err := checkAndValidate(lateUnsignedBlock, &chainParams.Consensus); !errors.Is(err, validation.ErrInvalidBlock) {
+		t.Fatalf("unsigned block at the activation height was accepted: %v", err)
+	}
+
+	signedBlock, err := inits.CreateTestBlock(unsignedBlock.Header.Id, []*models.Transaction{})
+	if err != nil {
+		t.Fatalf("failed to create signed block: %v", err)
+	}
+
+	if err := checkAndValidate(signedBlock, &chainParams.Consensus); err != nil {
+		t.Fatalf("signed block at the activation height was rejected: %v", err)
+	}
+
+	if err := blockRepository.InsertIfNotExists(signedBlock); err != nil {
+		t.Fatalf("failed to insert signed block: %v", err)
+	}
+
+	verifier := validation.NewChainVerifier(blockRepository, inits.TestTransactionRepository, chainParams, inits.TestConfig.GovernmentConfig.PublicKey)
+	if checked, err := verifier.VerifyChain(0); err != nil || checked != 3 {
+		t.Fatalf("verified %d blocks of a chain holding an unsigned block: %v", checked, err)
+	}
+}